@@ -0,0 +1,119 @@
+package ot
+
+import (
+	"maps"
+	"slices"
+)
+
+// Range is a span of text in character offsets, from Start up to but not
+// including End.
+type Range struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Transform returns the range moved through o. Text inserted at either edge
+// stays outside it, so typing just before or after a comment's anchor does
+// not extend it; text inserted inside it is included. An empty range moves
+// like a caret.
+func (r Range) Transform(o *OperationSeq) Range {
+	if r.Start == r.End {
+		i := o.TransformIndex(r.Start)
+		return Range{Start: i, End: i}
+	}
+	start := o.TransformIndex(r.Start)
+	return Range{Start: start, End: max(start, o.transformIndex(r.End, false))}
+}
+
+// OrphanPolicy decides what becomes of an annotation in an AnnotationSet
+// once all of the text it is anchored to is deleted.
+type OrphanPolicy int
+
+const (
+	// OrphanCollapse keeps the annotation as an empty range where its text
+	// was, so it moves with the document like a caret.
+	OrphanCollapse OrphanPolicy = iota
+
+	// OrphanDetach takes the annotation's range away. It stays in the set,
+	// listed by Orphans, so that a comment thread outlives its text.
+	OrphanDetach
+)
+
+// AnnotationSet maps annotation IDs, such as comment threads, to the ranges
+// of text they are anchored to, and keeps them in step with the document:
+// every operation applied to it must be passed to Transform.
+//
+// The zero value is ready to use and collapses orphans. An AnnotationSet is
+// not safe for concurrent use.
+type AnnotationSet struct {
+	// Policy applies to annotations whose text is deleted.
+	Policy OrphanPolicy
+
+	ranges  map[string]Range
+	orphans map[string]struct{}
+}
+
+// Set anchors id to r, which must refer to the current document. An orphan
+// is anchored again.
+func (a *AnnotationSet) Set(id string, r Range) {
+	if a.ranges == nil {
+		a.ranges = make(map[string]Range)
+	}
+	a.ranges[id] = r
+	delete(a.orphans, id)
+}
+
+// Get returns the range id is anchored to. It reports false if there is no
+// annotation id or it is an orphan.
+func (a *AnnotationSet) Get(id string) (Range, bool) {
+	r, ok := a.ranges[id]
+	return r, ok
+}
+
+// Delete removes the annotation id, anchored or not.
+func (a *AnnotationSet) Delete(id string) {
+	delete(a.ranges, id)
+	delete(a.orphans, id)
+}
+
+// Ranges returns a copy of the anchored annotations' ranges by ID.
+func (a *AnnotationSet) Ranges() map[string]Range {
+	return maps.Clone(a.ranges)
+}
+
+// Orphans returns the IDs of the annotations detached by OrphanDetach, in
+// sorted order.
+func (a *AnnotationSet) Orphans() []string {
+	ids := make([]string, 0, len(a.orphans))
+	for id := range a.orphans {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Transform moves every anchored annotation through o, an operation applied
+// to the current document, and returns the IDs, in sorted order, of those
+// whose text it deleted entirely. Under OrphanDetach they become orphans.
+func (a *AnnotationSet) Transform(o *OperationSeq) []string {
+	var orphaned []string
+	for id, r := range a.ranges {
+		t := r.Transform(o)
+		if r.Start == r.End || t.Start != t.End {
+			a.ranges[id] = t
+			continue
+		}
+		orphaned = append(orphaned, id)
+		if a.Policy != OrphanDetach {
+			a.ranges[id] = t
+			continue
+		}
+		delete(a.ranges, id)
+		if a.orphans == nil {
+			a.orphans = make(map[string]struct{})
+		}
+		a.orphans[id] = struct{}{}
+	}
+	slices.Sort(orphaned)
+	return orphaned
+}
@@ -0,0 +1,113 @@
+package ot
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRangeTransform(t *testing.T) {
+	// "hello world", with "world" annotated.
+	r := Range{Start: 6, End: 11}
+	tests := []struct {
+		name string
+		op   func() *OperationSeq
+		want Range
+	}{
+		{"insert before", func() *OperationSeq {
+			op := NewOperationSeq()
+			op.Insert(">> ")
+			op.Retain(11)
+			return op
+		}, Range{9, 14}},
+		{"insert at start", func() *OperationSeq {
+			op := NewOperationSeq()
+			op.Retain(6)
+			op.Insert("big ")
+			op.Retain(5)
+			return op
+		}, Range{10, 15}},
+		{"insert inside", func() *OperationSeq {
+			op := NewOperationSeq()
+			op.Retain(8)
+			op.Insert("--")
+			op.Retain(3)
+			return op
+		}, Range{6, 13}},
+		{"insert at end", func() *OperationSeq {
+			op := NewOperationSeq()
+			op.Retain(11)
+			op.Insert("!")
+			return op
+		}, Range{6, 11}},
+		{"delete overlapping", func() *OperationSeq {
+			op := NewOperationSeq()
+			op.Retain(4)
+			op.Delete(4)
+			op.Retain(3)
+			return op
+		}, Range{4, 7}},
+		{"delete all of it", func() *OperationSeq {
+			op := NewOperationSeq()
+			op.Retain(5)
+			op.Delete(6)
+			return op
+		}, Range{5, 5}},
+	}
+	for _, tt := range tests {
+		if got := r.Transform(tt.op()); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestAnnotationSet(t *testing.T) {
+	deleteWorld := NewOperationSeq()
+	deleteWorld.Retain(5)
+	deleteWorld.Delete(6)
+
+	for _, policy := range []OrphanPolicy{OrphanCollapse, OrphanDetach} {
+		a := &AnnotationSet{Policy: policy}
+		a.Set("hello", Range{0, 5})
+		a.Set("world", Range{6, 11})
+
+		if got := a.Transform(deleteWorld); !slices.Equal(got, []string{"world"}) {
+			t.Errorf("policy %d: expected [world] orphaned, got %v", policy, got)
+		}
+		if r, ok := a.Get("hello"); !ok || r != (Range{0, 5}) {
+			t.Errorf("policy %d: expected hello at {0 5}, got %v, %v", policy, r, ok)
+		}
+		r, ok := a.Get("world")
+		switch policy {
+		case OrphanCollapse:
+			if !ok || r != (Range{5, 5}) || len(a.Orphans()) != 0 {
+				t.Errorf("expected world collapsed to {5 5}, got %v, %v, orphans %v", r, ok, a.Orphans())
+			}
+		case OrphanDetach:
+			if ok || !slices.Equal(a.Orphans(), []string{"world"}) {
+				t.Errorf("expected world detached, got %v, %v, orphans %v", r, ok, a.Orphans())
+			}
+		}
+
+		// A collapsed annotation is not orphaned again.
+		op := NewOperationSeq()
+		op.Retain(5)
+		op.Insert("!")
+		if got := a.Transform(op); len(got) != 0 {
+			t.Errorf("policy %d: expected nothing orphaned, got %v", policy, got)
+		}
+	}
+
+	a := &AnnotationSet{Policy: OrphanDetach}
+	a.Set("c", Range{0, 1})
+	op := NewOperationSeq()
+	op.Delete(1)
+	a.Transform(op)
+	a.Set("c", Range{0, 0})
+	if _, ok := a.Get("c"); !ok || len(a.Orphans()) != 0 {
+		t.Errorf("expected an orphan to be anchored again by Set, orphans %v", a.Orphans())
+	}
+	a.Delete("c")
+	if len(a.Ranges()) != 0 {
+		t.Errorf("expected no annotations, got %v", a.Ranges())
+	}
+}
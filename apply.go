@@ -2,43 +2,73 @@ package ot
 
 import (
 	"strings"
+	"unicode/utf8"
 )
 
 // Apply applies an operation sequence to a string, returning the transformed string.
 //
-// Returns an error if the operation's base length doesn't match the string length.
+// Returns a *LengthMismatchError if the operation's base length doesn't match
+// the string length, or if its components retain or delete past the end of
+// the string or stop short of it.
 //
 // This is a direct port from Rust operational-transform:
 // https://github.com/spebern/operational-transform-rs/blob/master/operational-transform/src/lib.rs#L473-L503
 func (o *OperationSeq) Apply(s string) (string, error) {
-	if charCount(s) != o.baseLen {
-		return "", ErrIncompatibleLengths
+	n := charCount(s)
+	if n != o.baseLen {
+		return "", lengthMismatch(o.baseLen, n)
 	}
 
+	// The result is at most the input plus the inserted text; sizing for that
+	// up front avoids copying a large document each time the buffer grows.
 	var result strings.Builder
-	runes := []rune(s)
-	idx := 0
+	result.Grow(len(s) + insertedBytes(o))
+	pos := 0 // byte offset in s
+	at := 0  // character offset in s
 
-	for _, op := range o.ops {
-		switch v := op.(type) {
-		case Retain:
-			// Copy n characters from input
-			for i := uint64(0); i < v.N && idx < len(runes); i++ {
-				result.WriteRune(runes[idx])
-				idx++
+	for i, v := range o.ops {
+		if v.Kind != KindInsert {
+			if uint64(n-at) < v.N {
+				return "", &LengthMismatchError{Want: at + int(v.N), Got: n, OpIndex: i}
 			}
-		case Delete:
+			at += int(v.N)
+		}
+		switch v.Kind {
+		case KindRetain:
+			// Copy n characters from input
+			end := skipRunes(s, pos, v.N)
+			result.WriteString(s[pos:end])
+			pos = end
+		case KindDelete:
 			// Skip n characters from input
-			idx += int(v.N)
-		case Insert:
+			pos = skipRunes(s, pos, v.N)
+		case KindInsert:
 			// Add the inserted text
 			result.WriteString(v.Text)
 		}
 	}
+	if at != n {
+		return "", &LengthMismatchError{Want: at, Got: n, OpIndex: len(o.ops)}
+	}
 
 	return result.String(), nil
 }
 
+// skipRunes returns the byte offset n characters after byte offset i in s,
+// or len(s) if s ends first. Characters are decoded one at a time, so only
+// the skipped part of s is read.
+func skipRunes(s string, i int, n uint64) int {
+	for ; n > 0 && i < len(s); n-- {
+		if s[i] < utf8.RuneSelf {
+			i++
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return i
+}
+
 // Invert computes the inverse of an operation. The inverse reverts the effects
 // of the operation. For example:
 //   - insert("hello") → delete(5)
@@ -47,27 +77,67 @@ func (o *OperationSeq) Apply(s string) (string, error) {
 //
 // The inverse is useful for implementing undo functionality.
 //
+// Invert does not check that s is the document the operation applies to:
+// characters past the end of s are retained or deleted as if s were long
+// enough, and deleted text missing from s is left out of the inverse. Use
+// InvertChecked to have such a document reported instead.
+//
 // This is a direct port from Rust operational-transform:
 // https://github.com/spebern/operational-transform-rs/blob/master/operational-transform/src/lib.rs#L505-L530
 func (o *OperationSeq) Invert(s string) *OperationSeq {
 	inverse := NewOperationSeq()
-	runes := []rune(s)
-	idx := 0
+	pos := 0 // byte offset in s
+	for _, v := range o.ops {
+		switch v.Kind {
+		case KindRetain:
+			inverse.Retain(v.N)
+			pos = skipRunes(s, pos, v.N)
+		case KindInsert:
+			inverse.Delete(v.N)
+		case KindDelete:
+			// Insert the deleted characters back
+			end := skipRunes(s, pos, v.N)
+			inverse.Insert(s[pos:end])
+			pos = end
+		}
+	}
+	return inverse
+}
+
+// InvertChecked computes the inverse of an operation like Invert, but
+// returns a *LengthMismatchError if s is not the document the operation
+// applies to.
+func (o *OperationSeq) InvertChecked(s string) (*OperationSeq, error) {
+	n := charCount(s)
+	if n != o.baseLen {
+		return nil, lengthMismatch(o.baseLen, n)
+	}
 
-	for _, op := range o.ops {
-		switch v := op.(type) {
-		case Retain:
+	inverse := NewOperationSeq()
+	pos := 0 // byte offset in s
+	at := 0  // character offset in s
+	for i, v := range o.ops {
+		if v.Kind != KindInsert && uint64(n-at) < v.N {
+			return nil, &LengthMismatchError{Want: at + int(v.N), Got: n, OpIndex: i}
+		}
+		switch v.Kind {
+		case KindRetain:
 			inverse.Retain(v.N)
-			idx += int(v.N)
-		case Insert:
-			inverse.Delete(uint64(charCount(v.Text)))
-		case Delete:
+			pos = skipRunes(s, pos, v.N)
+			at += int(v.N)
+		case KindInsert:
+			inverse.Delete(v.N)
+		case KindDelete:
 			// Insert the deleted characters back
-			deleted := string(runes[idx : idx+int(v.N)])
-			inverse.Insert(deleted)
-			idx += int(v.N)
+			end := skipRunes(s, pos, v.N)
+			inverse.insert(s[pos:end], v.N)
+			pos = end
+			at += int(v.N)
 		}
 	}
 
-	return inverse
+	if debug {
+		assert("Invert", checkOp(inverse))
+	}
+	return inverse, nil
 }
@@ -0,0 +1,182 @@
+package ot
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// AuditEntry records how the server rebased one accepted operation, for
+// answering "why did my edit end up there?" after the fact.
+type AuditEntry struct {
+	// Revision is the revision the operation produced.
+	Revision int `json:"revision"`
+
+	// ClientRevision is the revision the client made the operation against.
+	// The operation was transformed past the operations that produced
+	// revisions ClientRevision+1 through Revision-1, in order; if
+	// ClientRevision is Revision-1 it was applied as submitted.
+	ClientRevision int `json:"clientRevision"`
+
+	// Client is the submitting client, if known.
+	Client string `json:"client,omitempty"`
+
+	// Original is the operation as submitted, and Applied the form that was
+	// applied and broadcast.
+	Original *OperationSeq `json:"original"`
+	Applied  *OperationSeq `json:"applied"`
+
+	Time time.Time `json:"time"`
+}
+
+// SetAudit keeps an AuditEntry for each of the last n operations accepted
+// from now on. Zero, the default, turns the audit trail off and discards it.
+// Entries hold both forms of each operation, so n bounds the memory used.
+func (s *Server) SetAudit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auditLen = n
+	if n <= 0 {
+		s.audit = nil
+	} else if len(s.audit) > n {
+		s.audit = s.audit[len(s.audit)-n:]
+	}
+}
+
+// Audit returns the recorded entries for operations that produced revisions
+// after since, oldest first. Operations that were accepted while auditing
+// was off, or have been pushed out of the trail, are missing.
+func (s *Server) Audit(since int) []AuditEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var entries []AuditEntry
+	for _, e := range s.audit {
+		if e.Revision > since {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// record adds an entry for the operation that produced the current revision.
+// Callers hold s.mu.
+func (s *Server) record(client string, clientRevision int, original, applied *OperationSeq) {
+	if s.auditLen <= 0 {
+		return
+	}
+	if len(s.audit) == s.auditLen {
+		s.audit = s.audit[1:]
+	}
+	s.audit = append(s.audit, AuditEntry{
+		Revision:       s.revision(),
+		ClientRevision: clientRevision,
+		Client:         client,
+		Original:       original,
+		Applied:        applied,
+		Time:           s.now(),
+	})
+}
+
+// AuditRecord is one line of the audit log written by Server.ExportAudit:
+// who changed the document, when, and where. The operation took the
+// document from Revision to Revision+1.
+type AuditRecord struct {
+	Revision int           `json:"revision"`
+	Author   string        `json:"author,omitempty"`
+	Time     time.Time     `json:"time"`
+	Changes  []AuditChange `json:"changes"`
+}
+
+// AuditChange is one contiguous change made by an operation. Start is the
+// character offset in the document the operation applied to; Deleted
+// characters from there were replaced by Inserted new ones. Text and
+// Removed hold the inserted and deleted text, unless the log is redacted.
+type AuditChange struct {
+	Start    int    `json:"start"`
+	Deleted  int    `json:"deleted,omitempty"`
+	Inserted int    `json:"inserted,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Removed  string `json:"removed,omitempty"`
+}
+
+// ExportAudit writes an audit log of the history kept since the last
+// compaction to w as JSON Lines, one AuditRecord per operation. With redact
+// set, the log keeps the offsets and lengths of changes but none of the
+// document's text, for reviewers who must not see it.
+//
+// Authors come from operation metadata (see SetStampMeta), or else from the
+// audit trail (see SetAudit). Operations accepted without metadata are
+// logged with the time the server accepted them.
+func (s *Server) ExportAudit(w io.Writer, redact bool) error {
+	s.mu.Lock()
+	doc, base := s.snapshot, s.base
+	history := append([]*OperationSeq(nil), s.history...)
+	accepted := append([]time.Time(nil), s.accepted...)
+	clients := make(map[int]string, len(s.audit))
+	for _, e := range s.audit {
+		clients[e.Revision-1] = e.Client
+	}
+	s.mu.Unlock()
+
+	enc := json.NewEncoder(w)
+	for i, op := range history {
+		m := op.Meta()
+		rec := AuditRecord{Revision: base + i, Author: m.Author, Time: m.Time}
+		if rec.Author == "" {
+			rec.Author = clients[rec.Revision]
+		}
+		if rec.Time.IsZero() {
+			rec.Time = accepted[i]
+		}
+		rec.Changes = auditChanges(op, doc, redact)
+		if !redact {
+			var err error
+			if doc, err = op.Apply(doc); err != nil {
+				return err
+			}
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// auditChanges returns the changes op makes to doc, leaving out their text
+// if redact is set, when doc is not needed.
+func auditChanges(op *OperationSeq, doc string, redact bool) []AuditChange {
+	if redact {
+		doc = ""
+	}
+	changes := []AuditChange{}
+	var (
+		cur   *AuditChange
+		start int // byte offset of pos in doc
+		pos   int
+	)
+	for _, c := range op.ops {
+		if c.Kind == KindRetain {
+			cur = nil
+			pos += int(c.N)
+			start = skipRunes(doc, start, c.N)
+			continue
+		}
+		if cur == nil {
+			changes = append(changes, AuditChange{Start: pos})
+			cur = &changes[len(changes)-1]
+		}
+		switch c.Kind {
+		case KindInsert:
+			cur.Inserted += int(c.N)
+			if !redact {
+				cur.Text += c.Text
+			}
+		case KindDelete:
+			end := skipRunes(doc, start, c.N)
+			cur.Deleted += int(c.N)
+			cur.Removed += doc[start:end]
+			pos, start = pos+int(c.N), end
+		}
+	}
+	return changes
+}
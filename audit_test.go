@@ -0,0 +1,128 @@
+package ot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerAudit(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer("hello")
+
+	first := NewOperationSeq()
+	first.Insert(">> ")
+	first.Retain(5)
+	if _, _, err := s.SubmitOp(ctx, "bob", "", 0, first); err != nil {
+		t.Fatalf("SubmitOp failed: %v", err)
+	}
+	if got := s.Audit(0); len(got) != 0 {
+		t.Errorf("expected no entries while auditing is off, got %d", len(got))
+	}
+
+	s.SetAudit(2)
+	edit := NewOperationSeq()
+	edit.Retain(5)
+	edit.Insert("!")
+	if _, _, err := s.SubmitOp(ctx, "alice", "", 0, edit); err != nil {
+		t.Fatalf("SubmitOp failed: %v", err)
+	}
+
+	entries := s.Audit(0)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.Revision != 2 || e.ClientRevision != 0 || e.Client != "alice" {
+		t.Errorf("expected alice's op at revision 2 from 0, got %+v", e)
+	}
+	if e.Original.String() != `[5,"!"]` || e.Applied.String() != `[8,"!"]` {
+		t.Errorf("expected [5,\"!\"] rebased to [8,\"!\"], got %s and %s", e.Original, e.Applied)
+	}
+
+	for i := 0; i < 2; i++ {
+		op := NewOperationSeq()
+		op.Retain(uint64(9 + i))
+		op.Insert("?")
+		if _, err := s.ReceiveOperation(ctx, 2+i, op); err != nil {
+			t.Fatalf("ReceiveOperation failed: %v", err)
+		}
+	}
+	if got := s.Audit(0); len(got) != 2 || got[0].Revision != 3 {
+		t.Errorf("expected the last 2 entries from revision 3, got %+v", got)
+	}
+	if got := s.Audit(3); len(got) != 1 || got[0].Revision != 4 {
+		t.Errorf("expected the entry for revision 4, got %+v", got)
+	}
+
+	s.SetAudit(0)
+	if got := s.Audit(0); len(got) != 0 {
+		t.Errorf("expected the trail discarded, got %d entries", len(got))
+	}
+}
+
+func TestServerExportAudit(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer("hello wörld")
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	s.now = func() time.Time { return at }
+	s.SetAudit(10)
+
+	replace := NewOperationSeq()
+	replace.Retain(6)
+	replace.Insert("thére")
+	replace.Delete(5)
+	if _, _, err := s.SubmitOp(ctx, "alice", "", 0, replace); err != nil {
+		t.Fatalf("SubmitOp failed: %v", err)
+	}
+	s.SetStampMeta(true)
+	edits := NewOperationSeq()
+	edits.Insert(">")
+	edits.Delete(1)
+	edits.Retain(10)
+	edits.Insert("!")
+	if _, _, err := s.SubmitOp(ctx, "bob", "", 1, edits); err != nil {
+		t.Fatalf("SubmitOp failed: %v", err)
+	}
+
+	tests := []struct {
+		redact bool
+		want   [][]AuditChange
+	}{
+		{false, [][]AuditChange{
+			{{Start: 6, Deleted: 5, Inserted: 5, Text: "thére", Removed: "wörld"}},
+			{{Start: 0, Deleted: 1, Inserted: 1, Text: ">", Removed: "h"}, {Start: 11, Inserted: 1, Text: "!"}},
+		}},
+		{true, [][]AuditChange{
+			{{Start: 6, Deleted: 5, Inserted: 5}},
+			{{Start: 0, Deleted: 1, Inserted: 1}, {Start: 11, Inserted: 1}},
+		}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := s.ExportAudit(&buf, tt.redact); err != nil {
+			t.Fatalf("ExportAudit failed: %v", err)
+		}
+		if tt.redact && (strings.Contains(buf.String(), "thére") || strings.Contains(buf.String(), "hello")) {
+			t.Errorf("expected no text in a redacted log, got %s", buf.String())
+		}
+		dec := json.NewDecoder(&buf)
+		for i, want := range tt.want {
+			var rec AuditRecord
+			if err := dec.Decode(&rec); err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			author := []string{"alice", "bob"}[i]
+			if rec.Revision != i || rec.Author != author || !rec.Time.Equal(at) {
+				t.Errorf("redact %v: expected %s at revision %d, got %+v", tt.redact, author, i, rec)
+			}
+			if !reflect.DeepEqual(rec.Changes, want) {
+				t.Errorf("redact %v: expected changes %+v, got %+v", tt.redact, want, rec.Changes)
+			}
+		}
+	}
+}
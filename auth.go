@@ -0,0 +1,27 @@
+package ot
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrForbidden is returned when a client is not allowed to perform an action
+var ErrForbidden = errors.New("forbidden")
+
+// Authorizer decides what clients may do with documents. A Hub with an
+// Authorizer consults it before subscribing a client and before applying
+// each of its operations, with the context of the request that carried them.
+//
+// Implementations must be safe for concurrent use.
+type Authorizer interface {
+	// Authorize is called before op from client is applied to doc, with op
+	// as the client sent it. Returning an error rejects the operation; wrap
+	// ErrForbidden so transports can report it as an access failure.
+	Authorize(ctx context.Context, client, doc string, op *OperationSeq) error
+
+	// AuthorizeSubscribe is called before client subscribes to doc. It
+	// reports whether the subscription is read-only, or returns an error to
+	// refuse it. A client holding a read-only subscription cannot submit
+	// operations to the document.
+	AuthorizeSubscribe(ctx context.Context, client, doc string) (readOnly bool, err error)
+}
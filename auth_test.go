@@ -0,0 +1,98 @@
+package ot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type ctxKey struct{}
+
+// testAuthorizer lets everyone edit except viewers, refuses strangers, and
+// rejects operations inserting "spam". It also checks that the caller's
+// context arrives intact.
+type testAuthorizer struct{}
+
+func (testAuthorizer) Authorize(ctx context.Context, client, doc string, op *OperationSeq) error {
+	if ctx.Value(ctxKey{}) != "request" {
+		return errors.New("context not passed through")
+	}
+	for _, o := range op.Ops() {
+		if ins, ok := o.(Insert); ok && strings.Contains(ins.Text, "spam") {
+			return fmt.Errorf("%w: %s may not insert spam into %s", ErrForbidden, client, doc)
+		}
+	}
+	return nil
+}
+
+func (testAuthorizer) AuthorizeSubscribe(_ context.Context, client, doc string) (bool, error) {
+	switch client {
+	case "stranger":
+		return false, fmt.Errorf("%w: %s may not open %s", ErrForbidden, client, doc)
+	case "viewer":
+		return true, nil
+	}
+	return false, nil
+}
+
+func TestHubAuthorizer(t *testing.T) {
+	h := &Hub{Authorizer: testAuthorizer{}}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+
+	if _, err := h.Subscribe(ctx, "doc", "stranger"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden for stranger, got %v", err)
+	}
+	viewer, err := h.Subscribe(ctx, "doc", "viewer")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if !viewer.ReadOnly {
+		t.Error("expected read-only subscription for viewer")
+	}
+	editor, err := h.Subscribe(ctx, "doc", "editor")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if editor.ReadOnly {
+		t.Error("expected writable subscription for editor")
+	}
+
+	op := NewOperationSeq()
+	op.Insert("hello")
+	if _, _, err := h.Submit(ctx, "doc", "viewer", 0, op); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden for viewer, got %v", err)
+	}
+	if _, err := h.ApplyAt(ctx, "doc", "viewer", 0, op); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden for viewer, got %v", err)
+	}
+	if _, _, err := h.Submit(ctx, "doc", "editor", 0, op); err != nil {
+		t.Errorf("Submit failed: %v", err)
+	}
+
+	spam := NewOperationSeq()
+	spam.Retain(5)
+	spam.Insert(" spam")
+	if _, _, err := h.Submit(ctx, "doc", "editor", 1, spam); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden for spam, got %v", err)
+	}
+	if _, _, err := h.Submit(context.Background(), "doc", "editor", 1, NewOperationSeq()); err == nil {
+		t.Error("expected the authorizer to see the caller's context")
+	}
+
+	server, err := h.Server(context.Background(), "doc")
+	if err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+	if server.Document() != "hello" {
+		t.Errorf("expected %q, got %q", "hello", server.Document())
+	}
+
+	// Once the read-only subscription ends, the viewer's ops are judged by
+	// Authorize alone.
+	viewer.Close()
+	if _, _, err := h.Submit(ctx, "doc", "viewer", 1, spam); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden for spam, got %v", err)
+	}
+}
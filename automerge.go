@@ -0,0 +1,93 @@
+package ot
+
+import "fmt"
+
+// AutomergeSplice is a single text edit in Automerge's splice form: at Pos,
+// delete Del characters and then insert Insert.
+type AutomergeSplice struct {
+	Pos    int    `json:"pos"`
+	Del    int    `json:"del,omitempty"`
+	Insert string `json:"insert,omitempty"`
+}
+
+// AutomergeChange is one entry of an exported history, shaped after an
+// Automerge change: the author, the author's change counter, the first
+// Automerge op counter used by the change, and its splices. Splices are
+// applied in order, each against the result of the previous one.
+type AutomergeChange struct {
+	Actor   string            `json:"actor"`
+	Seq     int               `json:"seq"`
+	StartOp int               `json:"startOp"`
+	Splices []AutomergeSplice `json:"splices"`
+}
+
+// ExportAutomerge converts a linear history, where each operation applies to
+// the result of the one before it, into Automerge-style changes. actor maps a
+// history index to the author of that operation.
+//
+// Positions and lengths count characters (Unicode code points). Automerge
+// assigns one op counter per inserted character and per deleted character,
+// which StartOp mirrors. No-op entries still produce a change so that Seq
+// stays aligned with the author's edit count.
+//
+// Returns an error if consecutive operations have incompatible lengths.
+func ExportAutomerge(history []*OperationSeq, actor func(i int) string) ([]AutomergeChange, error) {
+	changes := make([]AutomergeChange, 0, len(history))
+	seqs := make(map[string]int)
+	nextOp := 1
+
+	for i, op := range history {
+		if i > 0 && history[i-1].targetLen != op.baseLen {
+			return nil, fmt.Errorf("operation %d: %w", i, lengthMismatch(op.baseLen, history[i-1].targetLen))
+		}
+
+		a := actor(i)
+		seqs[a]++
+		change := AutomergeChange{
+			Actor:   a,
+			Seq:     seqs[a],
+			StartOp: nextOp,
+			Splices: []AutomergeSplice{},
+		}
+
+		pos := 0
+		for _, v := range op.ops {
+			switch v.Kind {
+			case KindRetain:
+				pos += int(v.N)
+			case KindDelete:
+				change.Splices = append(change.Splices, AutomergeSplice{Pos: pos, Del: int(v.N)})
+				nextOp += int(v.N)
+			case KindInsert:
+				n := int(v.N)
+				change.Splices = append(change.Splices, AutomergeSplice{Pos: pos, Insert: v.Text})
+				pos += n
+				nextOp += n
+			}
+		}
+
+		change.Splices = mergeSplices(change.Splices)
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// mergeSplices folds an insert immediately followed by a delete at the
+// position right after it into one replace-style splice.
+func mergeSplices(splices []AutomergeSplice) []AutomergeSplice {
+	merged := splices[:0]
+	for _, s := range splices {
+		if n := len(merged); n > 0 {
+			prev := &merged[n-1]
+			if prev.Del == 0 && s.Insert == "" && prev.Pos+charCount(prev.Insert) == s.Pos {
+				// Deleting after the inserted text equals deleting at the
+				// insert position first, then inserting.
+				prev.Del = s.Del
+				continue
+			}
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
@@ -0,0 +1,96 @@
+package ot
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExportAutomerge(t *testing.T) {
+	first := NewOperationSeq()
+	first.Insert("hello")
+
+	second := NewOperationSeq()
+	second.Retain(1)
+	second.Insert("E")
+	second.Delete(1)
+	second.Retain(3)
+
+	third := NewOperationSeq()
+	third.Retain(5)
+	third.Insert(" wörld")
+
+	actors := []string{"alice", "bob", "alice"}
+	changes, err := ExportAutomerge([]*OperationSeq{first, second, third}, func(i int) string {
+		return actors[i]
+	})
+	if err != nil {
+		t.Fatalf("ExportAutomerge failed: %v", err)
+	}
+
+	expected := []AutomergeChange{
+		{Actor: "alice", Seq: 1, StartOp: 1, Splices: []AutomergeSplice{{Pos: 0, Insert: "hello"}}},
+		{Actor: "bob", Seq: 1, StartOp: 6, Splices: []AutomergeSplice{{Pos: 1, Del: 1, Insert: "E"}}},
+		{Actor: "alice", Seq: 2, StartOp: 8, Splices: []AutomergeSplice{{Pos: 5, Insert: " wörld"}}},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %d", len(expected), len(changes))
+	}
+	for i := range expected {
+		got, want := changes[i], expected[i]
+		if got.Actor != want.Actor || got.Seq != want.Seq || got.StartOp != want.StartOp {
+			t.Errorf("change %d: expected %s/%d/%d, got %s/%d/%d",
+				i, want.Actor, want.Seq, want.StartOp, got.Actor, got.Seq, got.StartOp)
+		}
+		if len(got.Splices) != len(want.Splices) {
+			t.Fatalf("change %d: expected %v, got %v", i, want.Splices, got.Splices)
+		}
+		for j := range want.Splices {
+			if got.Splices[j] != want.Splices[j] {
+				t.Errorf("change %d splice %d: expected %+v, got %+v", i, j, want.Splices[j], got.Splices[j])
+			}
+		}
+	}
+}
+
+func TestExportAutomergeReplay(t *testing.T) {
+	// Replaying the splices must reproduce the same document as Apply.
+	doc := "the quick brown fox"
+	op := NewOperationSeq()
+	op.Delete(4)
+	op.Retain(6)
+	op.Insert("red")
+	op.Delete(5)
+	op.Retain(4)
+	op.Insert("!")
+
+	want, err := op.Apply(doc)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	changes, err := ExportAutomerge([]*OperationSeq{op}, func(int) string { return "a" })
+	if err != nil {
+		t.Fatalf("ExportAutomerge failed: %v", err)
+	}
+
+	runes := []rune(doc)
+	for _, s := range changes[0].Splices {
+		tail := append([]rune(s.Insert), runes[s.Pos+s.Del:]...)
+		runes = append(runes[:s.Pos], tail...)
+	}
+	if string(runes) != want {
+		t.Errorf("expected %q, got %q", want, string(runes))
+	}
+}
+
+func TestExportAutomergeIncompatible(t *testing.T) {
+	a := NewOperationSeq()
+	a.Insert("abc")
+	b := NewOperationSeq()
+	b.Retain(5)
+
+	_, err := ExportAutomerge([]*OperationSeq{a, b}, func(int) string { return "x" })
+	if !errors.Is(err, ErrIncompatibleLengths) {
+		t.Errorf("expected ErrIncompatibleLengths, got %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package ot
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Binary encoding format:
+//
+// Each operation is written as an unsigned varint header whose low two bits
+// carry the operation kind and whose remaining bits carry its size:
+//   - Retain(n) → uvarint(n<<2 | 0)
+//   - Delete(n) → uvarint(n<<2 | 1)
+//   - Insert(s) → uvarint(len(s)<<2 | 2) followed by the UTF-8 bytes of s
+//
+// Insert sizes are byte lengths, not character counts, so the decoder can
+// slice the text without scanning it first. An empty sequence encodes to
+// zero bytes.
+//
+// Metadata, if any, comes first, as uvarint(len(m)<<2 | 3) followed by m,
+// its JSON encoding.
+
+const (
+	binRetain = 0
+	binDelete = 1
+	binInsert = 2
+	binMeta   = 3
+
+	// maxBinaryN is the largest count that still fits in a header once shifted.
+	maxBinaryN = 1<<62 - 1
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler for OperationSeq.
+func (o *OperationSeq) MarshalBinary() ([]byte, error) {
+	if o == nil {
+		return []byte{}, nil
+	}
+
+	buf := make([]byte, 0, len(o.ops)*2)
+	if o.meta != nil {
+		meta, err := o.meta.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		buf = binary.AppendUvarint(buf, uint64(len(meta))<<2|binMeta)
+		buf = append(buf, meta...)
+	}
+	for _, v := range o.ops {
+		switch v.Kind {
+		case KindRetain:
+			if v.N > maxBinaryN {
+				return nil, fmt.Errorf("retain too large for binary encoding: %d", v.N)
+			}
+			buf = binary.AppendUvarint(buf, v.N<<2|binRetain)
+		case KindDelete:
+			if v.N > maxBinaryN {
+				return nil, fmt.Errorf("delete too large for binary encoding: %d", v.N)
+			}
+			buf = binary.AppendUvarint(buf, v.N<<2|binDelete)
+		case KindInsert:
+			buf = binary.AppendUvarint(buf, uint64(len(v.Text))<<2|binInsert)
+			buf = append(buf, v.Text...)
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler for OperationSeq.
+func (o *OperationSeq) UnmarshalBinary(data []byte) error {
+	*o = OperationSeq{
+		ops:       make([]Component, 0),
+		baseLen:   0,
+		targetLen: 0,
+	}
+	return DecodeLimits{}.decodeBinary(o, data)
+}
+
+func (l DecodeLimits) decodeBinary(o *OperationSeq, data []byte) error {
+	count := 0
+	for first := true; len(data) > 0; first = false {
+		header, n := binary.Uvarint(data)
+		if n <= 0 {
+			return fmt.Errorf("invalid binary encoding: malformed header")
+		}
+		data = data[n:]
+
+		size := header >> 2
+		if header&3 == binMeta {
+			if !first {
+				return fmt.Errorf("invalid binary encoding: metadata must come first")
+			}
+			if size > uint64(len(data)) {
+				return fmt.Errorf("invalid binary encoding: metadata exceeds input")
+			}
+			meta, err := unmarshalMeta(data[:size])
+			if err != nil {
+				return fmt.Errorf("invalid binary encoding: %w", err)
+			}
+			o.meta = meta
+			data = data[size:]
+			continue
+		}
+
+		count++
+		if l.MaxOps > 0 && count > l.MaxOps {
+			return ErrTooManyOps
+		}
+
+		var err error
+		switch header & 3 {
+		case binRetain:
+			err = l.retain(o, size)
+		case binDelete:
+			err = l.delete(o, size)
+		case binInsert:
+			if size > uint64(len(data)) {
+				return fmt.Errorf("invalid binary encoding: insert exceeds input")
+			}
+			text := string(data[:size])
+			if !utf8.ValidString(text) {
+				return fmt.Errorf("invalid binary encoding: insert is not valid UTF-8")
+			}
+			data = data[size:]
+			err = l.insert(o, text)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if debug {
+		assert("UnmarshalBinary", checkOp(o))
+	}
+	return nil
+}
+
+// EncodeString returns the binary encoding as unpadded URL-safe base64.
+//
+// The result contains only [A-Za-z0-9_-], so it can be placed in query
+// parameters, cache keys, and log lines without further escaping.
+func (o *OperationSeq) EncodeString() (string, error) {
+	data, err := o.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeString parses an operation sequence produced by EncodeString.
+func DecodeString(s string) (*OperationSeq, error) {
+	return DecodeLimits{}.DecodeString(s)
+}
@@ -0,0 +1,80 @@
+package ot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	o := NewOperationSeq()
+	o.Retain(5)
+	o.Insert("héllo 🌍")
+	o.Delete(3)
+	o.Retain(1000)
+
+	data, err := o.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+
+	var o2 OperationSeq
+	if err := o2.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+
+	if o2.String() != o.String() {
+		t.Errorf("round-trip: expected %s, got %s", o, &o2)
+	}
+	if o2.baseLen != o.baseLen || o2.targetLen != o.targetLen {
+		t.Errorf("round-trip: expected lengths %d/%d, got %d/%d",
+			o.baseLen, o.targetLen, o2.baseLen, o2.targetLen)
+	}
+}
+
+func TestBinaryInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated header", []byte{0x80}},
+		{"insert past end", []byte{0x16, 'a'}},
+		{"invalid utf-8", []byte{0x06, 0xff}},
+		{"unknown kind", []byte{0x07}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var o OperationSeq
+			if err := o.UnmarshalBinary(tt.data); err == nil {
+				t.Errorf("expected error, got %s", &o)
+			}
+		})
+	}
+}
+
+func TestEncodeString(t *testing.T) {
+	o := NewOperationSeq()
+	o.Retain(2)
+	o.Insert("a/b?c=d&e")
+	o.Delete(4)
+
+	s, err := o.EncodeString()
+	if err != nil {
+		t.Fatalf("EncodeString failed: %v", err)
+	}
+	if strings.ContainsAny(s, "+/=") {
+		t.Errorf("expected URL-safe unpadded output, got %q", s)
+	}
+
+	o2, err := DecodeString(s)
+	if err != nil {
+		t.Fatalf("DecodeString failed: %v", err)
+	}
+	if o2.String() != o.String() {
+		t.Errorf("round-trip: expected %s, got %s", o, o2)
+	}
+
+	if _, err := DecodeString("not base64!"); err == nil {
+		t.Error("expected error for invalid base64")
+	}
+}
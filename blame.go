@@ -0,0 +1,151 @@
+package ot
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrBlameDisabled is returned by Server.BlameRange when the server is not
+// tracking authors.
+var ErrBlameDisabled = errors.New("attribution not tracked")
+
+// Span is a run of consecutive characters inserted by the same author.
+type Span struct {
+	Author string `json:"author"`
+	Len    int    `json:"len"`
+}
+
+// Attribution records which author inserted each character of a document,
+// as runs of characters. It is kept in step with the document by applying
+// the same operations to both. An Attribution is not safe for concurrent use.
+type Attribution struct {
+	spans []Span
+	len   int
+}
+
+// NewAttribution returns an attribution for a document of length characters,
+// all credited to author.
+func NewAttribution(author string, length int) *Attribution {
+	a := &Attribution{}
+	a.spans = appendSpan(a.spans, Span{author, length})
+	a.len = length
+	return a
+}
+
+// Len returns the length of the attributed document in characters.
+func (a *Attribution) Len() int {
+	return a.len
+}
+
+// Apply updates the attribution for op, crediting the text it inserts to
+// author. Retained characters keep their author and deleted ones are
+// dropped. Apply the operations in the order the document received them,
+// after any transformation, so concurrent edits are attributed where they
+// ended up.
+func (a *Attribution) Apply(author string, op *OperationSeq) error {
+	if op.baseLen != a.len {
+		return lengthMismatch(op.baseLen, a.len)
+	}
+
+	out := make([]Span, 0, len(a.spans)+1)
+	i, off := 0, 0 // position in a.spans
+	take := func(n int, keep bool) {
+		for n > 0 {
+			sp := a.spans[i]
+			k := min(n, sp.Len-off)
+			if keep {
+				out = appendSpan(out, Span{sp.Author, k})
+			}
+			n -= k
+			if off += k; off == sp.Len {
+				i, off = i+1, 0
+			}
+		}
+	}
+	for _, v := range op.ops {
+		switch v.Kind {
+		case KindRetain:
+			take(int(v.N), true)
+		case KindDelete:
+			take(int(v.N), false)
+		case KindInsert:
+			out = appendSpan(out, Span{author, int(v.N)})
+		}
+	}
+	a.spans = out
+	a.len = op.targetLen
+	return nil
+}
+
+// BlameRange returns the authors of the characters from start up to end, in
+// document order, as runs.
+func (a *Attribution) BlameRange(start, end int) ([]Span, error) {
+	if start < 0 || end < start || end > a.len {
+		return nil, fmt.Errorf("invalid range [%d, %d) for document of length %d", start, end, a.len)
+	}
+	var out []Span
+	pos := 0
+	for _, sp := range a.spans {
+		if pos >= end {
+			break
+		}
+		lo, hi := max(pos, start), min(pos+sp.Len, end)
+		if lo < hi {
+			out = appendSpan(out, Span{sp.Author, hi - lo})
+		}
+		pos += sp.Len
+	}
+	return out, nil
+}
+
+// appendSpan appends sp to spans, merging it into the last span if they have
+// the same author. Empty spans are dropped.
+func appendSpan(spans []Span, sp Span) []Span {
+	switch {
+	case sp.Len == 0:
+		return spans
+	case len(spans) > 0 && spans[len(spans)-1].Author == sp.Author:
+		spans[len(spans)-1].Len += sp.Len
+		return spans
+	}
+	return append(spans, sp)
+}
+
+// TrackAuthors starts attributing the document's characters to the clients
+// whose operations inserted them, for BlameRange. Text already in the
+// document, and text from operations without a known client, such as those
+// from Sync or Submit, is credited to the empty author. Attribution is kept
+// in memory only.
+func (s *Server) TrackAuthors() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.blame == nil {
+		s.blame = NewAttribution("", charCount(s.doc))
+	}
+}
+
+// attribute updates the attribution, if tracked, for op from client. Callers
+// hold s.mu.
+func (s *Server) attribute(client string, op *OperationSeq) {
+	if s.blame == nil {
+		return
+	}
+	if err := s.blame.Apply(client, op); err != nil {
+		// Only possible if the attribution was already out of step.
+		s.logger.Error("attribution out of step; tracking stopped", "revision", s.revision(), "err", err)
+		s.blame = nil
+	}
+}
+
+// BlameRange returns the authors of the characters from start up to end of
+// the current document, as runs, together with the revision they describe.
+// Returns ErrBlameDisabled unless TrackAuthors was called.
+func (s *Server) BlameRange(start, end int) ([]Span, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.blame == nil {
+		return nil, 0, ErrBlameDisabled
+	}
+	spans, err := s.blame.BlameRange(start, end)
+	return spans, s.revision(), err
+}
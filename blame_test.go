@@ -0,0 +1,82 @@
+package ot
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAttribution(t *testing.T) {
+	a := NewAttribution("", 5) // "hello"
+
+	ins := NewOperationSeq()
+	ins.Retain(5)
+	ins.Insert(" wörld")
+	if err := a.Apply("alice", ins); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	// bob replaces "lo wö" with "p", spanning both authors.
+	rep := NewOperationSeq()
+	rep.Retain(3)
+	rep.Delete(5)
+	rep.Insert("p")
+	rep.Retain(3)
+	if err := a.Apply("bob", rep); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	// "help" + "rld"
+	want := []Span{{"", 3}, {"bob", 1}, {"alice", 3}}
+	if got, err := a.BlameRange(0, a.Len()); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v (%v)", want, got, err)
+	}
+	if got, _ := a.BlameRange(2, 5); !reflect.DeepEqual(got, []Span{{"", 1}, {"bob", 1}, {"alice", 1}}) {
+		t.Errorf("unexpected partial blame %v", got)
+	}
+	if got, _ := a.BlameRange(4, 4); len(got) != 0 {
+		t.Errorf("expected empty range to have no spans, got %v", got)
+	}
+	if _, err := a.BlameRange(0, 8); err == nil {
+		t.Error("expected error for range past the end")
+	}
+	if err := a.Apply("bob", rep); !errors.Is(err, ErrIncompatibleLengths) {
+		t.Errorf("expected ErrIncompatibleLengths, got %v", err)
+	}
+}
+
+func TestServerBlameConcurrent(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer("hello")
+	if _, _, err := s.BlameRange(0, 1); !errors.Is(err, ErrBlameDisabled) {
+		t.Errorf("expected ErrBlameDisabled, got %v", err)
+	}
+	s.TrackAuthors()
+
+	// alice and bob both edit revision 0: alice deletes "ell", bob inserts
+	// inside it. bob's text survives, transformed to where it ended up.
+	del := NewOperationSeq()
+	del.Retain(1)
+	del.Delete(3)
+	del.Retain(1)
+	ins := NewOperationSeq()
+	ins.Retain(2)
+	ins.Insert("XY")
+	ins.Retain(3)
+	if _, _, err := s.SubmitOp(ctx, "alice", "", 0, del); err != nil {
+		t.Fatalf("SubmitOp failed: %v", err)
+	}
+	if _, _, err := s.SubmitOp(ctx, "bob", "", 0, ins); err != nil {
+		t.Fatalf("SubmitOp failed: %v", err)
+	}
+	if s.Document() != "hXYo" {
+		t.Fatalf("expected %q, got %q", "hXYo", s.Document())
+	}
+
+	spans, rev, err := s.BlameRange(0, 4)
+	want := []Span{{"", 1}, {"bob", 2}, {"", 1}}
+	if err != nil || rev != 2 || !reflect.DeepEqual(spans, want) {
+		t.Errorf("expected %v at revision 2, got %v at %d (%v)", want, spans, rev, err)
+	}
+}
@@ -0,0 +1,124 @@
+package ot
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrBranchClosed is returned when merging a branch that was already merged
+// or discarded.
+var ErrBranchClosed = errors.New("branch closed")
+
+// Branch is a copy of a document forked from a revision of its parent, with
+// its own history, that can later be merged back. It is a Server in its own
+// right: clients edit it, and a Hub can serve it, like any other document.
+type Branch struct {
+	*Server
+
+	parent *Server
+	fork   *Pin // holds the fork revision in the parent's history
+	start  *Pin // holds the branch's own history from revision 0
+	closed bool // guarded by Server.mu
+}
+
+// Fork returns a branch of the document as of revision. The branch starts
+// at revision 0 with that content and is kept in memory only. Until it is
+// merged or discarded, the parent keeps its history from revision onwards.
+//
+// Returns ErrInvalidRevision or ErrRevisionCompacted if revision is outside
+// the history.
+func (s *Server) Fork(revision int) (*Branch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkRevision(revision); err != nil {
+		return nil, err
+	}
+	doc, err := s.documentAt(revision)
+	if err != nil {
+		return nil, err
+	}
+	fork := &Pin{server: s, revision: revision}
+	s.pins[fork] = struct{}{}
+
+	server := NewServer(doc)
+	start := &Pin{server: server}
+	server.pins[start] = struct{}{}
+	return &Branch{Server: server, parent: s, fork: fork, start: start}, nil
+}
+
+// ForkRevision returns the parent revision the branch was forked from.
+func (b *Branch) ForkRevision() int {
+	return b.fork.Revision()
+}
+
+// Changes returns every change made on the branch as a single operation
+// that applies to the parent document at the fork revision.
+func (b *Branch) Changes() (*OperationSeq, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.changes()
+}
+
+func (b *Branch) changes() (*OperationSeq, error) {
+	composed := NewOperationSeq()
+	composed.Retain(uint64(charCount(b.snapshot)))
+	for _, op := range b.history {
+		var err error
+		if composed, err = composed.Compose(op); err != nil {
+			return nil, err
+		}
+	}
+	return composed, nil
+}
+
+// Merge applies the branch's Changes to the parent, transformed past
+// everything the parent accepted since the fork, as if a client had
+// submitted them against the fork revision. It returns the operation applied
+// to the parent and the revision it produced.
+//
+// On success the branch is closed: it becomes read-only and cannot be merged
+// again. On error it is left open, so a merge that failed with ErrConflict
+// can be retried after syncing the parent.
+func (b *Branch) Merge(ctx context.Context) (*OperationSeq, int, error) {
+	// Branches lock before their parent, never the other way round.
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, 0, ErrBranchClosed
+	}
+	changes, err := b.changes()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	p := b.parent
+	p.mu.Lock()
+	op, _, err := p.receive(ctx, "", b.fork.revision, changes, false)
+	revision := p.revision()
+	p.mu.Unlock()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	b.close()
+	b.mode = ModeReadOnly
+	b.frozenAt = b.revision()
+	return op, revision, nil
+}
+
+// Discard closes the branch without merging it, letting the parent compact
+// past the fork revision. It is safe to call more than once.
+func (b *Branch) Discard() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.close()
+}
+
+// close releases the branch's pins. Callers hold b.mu.
+func (b *Branch) close() {
+	b.closed = true
+	delete(b.pins, b.start)
+	b.fork.Release()
+}
@@ -0,0 +1,127 @@
+package ot
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestBranchMerge(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer("hello")
+	edit := NewOperationSeq()
+	edit.Retain(5)
+	edit.Insert(" world")
+	if _, err := s.ReceiveOperation(ctx, 0, edit); err != nil {
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+
+	// Fork before the edit and suggest a change there.
+	b, err := s.Fork(0)
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	if b.Document() != "hello" || b.Revision() != 0 || b.ForkRevision() != 0 {
+		t.Errorf("expected %q at revision 0 forked from 0, got %q at %d from %d", "hello", b.Document(), b.Revision(), b.ForkRevision())
+	}
+	for _, text := range []string{"H", "i"} {
+		op := NewOperationSeq()
+		op.Insert(text)
+		op.Retain(uint64(charCount(b.Document())))
+		if _, err := b.ReceiveOperation(ctx, b.Revision(), op); err != nil {
+			t.Fatalf("branch ReceiveOperation failed: %v", err)
+		}
+	}
+	if err := s.Compact(ctx, 1); !errors.Is(err, ErrRevisionPinned) {
+		t.Errorf("expected fork to pin the parent, got %v", err)
+	}
+
+	changes, err := b.Changes()
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if changes.String() != `["iH",5]` {
+		t.Errorf("expected %s, got %s", `["iH",5]`, changes)
+	}
+
+	op, rev, err := b.Merge(ctx)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if rev != 2 || op.String() != `["iH",11]` || s.Document() != "iHhello world" {
+		t.Errorf("expected %q at revision 2, got %q at %d via %s", "iHhello world", s.Document(), rev, op)
+	}
+
+	if _, _, err := b.Merge(ctx); !errors.Is(err, ErrBranchClosed) {
+		t.Errorf("expected ErrBranchClosed, got %v", err)
+	}
+	noop := NewOperationSeq()
+	noop.Retain(7)
+	if _, err := b.ReceiveOperation(ctx, 2, noop); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected merged branch to be read-only, got %v", err)
+	}
+	if err := s.Compact(ctx, 2); err != nil {
+		t.Errorf("expected merge to release the parent, got %v", err)
+	}
+}
+
+func TestBranchDiscard(t *testing.T) {
+	s := NewServer("")
+	if _, err := s.Fork(1); !errors.Is(err, ErrInvalidRevision) {
+		t.Errorf("expected ErrInvalidRevision, got %v", err)
+	}
+	b, err := s.Fork(0)
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	b.Discard()
+	b.Discard()
+	if _, _, err := b.Merge(context.Background()); !errors.Is(err, ErrBranchClosed) {
+		t.Errorf("expected ErrBranchClosed, got %v", err)
+	}
+}
+
+func TestHubMerge(t *testing.T) {
+	h := NewHub(func(context.Context, string) (*Server, error) { return NewServer("doc"), nil })
+	ctx := context.Background()
+
+	sub, err := h.Subscribe(ctx, "doc", "alice")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Close()
+	<-sub.C // own join
+
+	server, err := h.Server(ctx, "doc")
+	if err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+	b, err := server.Fork(0)
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	op := NewOperationSeq()
+	op.Retain(3)
+	op.Insert("!")
+	if _, err := b.ReceiveOperation(ctx, 0, op); err != nil {
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+
+	other, err := NewServer("other").Fork(0)
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	if _, _, err := h.Merge(ctx, "doc", "alice", other); err == nil {
+		t.Error("expected error merging a branch of another document")
+	}
+
+	if _, _, err := h.Merge(ctx, "doc", "alice", b); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if ev := <-sub.C; ev.Kind != EventOp || ev.Client != "" || ev.Revision != 1 {
+		t.Errorf("expected merged op at revision 1 with no client, got %+v", ev)
+	}
+	if server.Document() != "doc!" {
+		t.Errorf("expected %q, got %q", "doc!", server.Document())
+	}
+}
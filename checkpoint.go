@@ -0,0 +1,79 @@
+package ot
+
+import (
+	"context"
+	"time"
+)
+
+// CheckpointPolicy controls when a Server saves a snapshot of the document to
+// its Store, so that restoring it replays only the operations since then.
+// Checkpoints are taken as operations are accepted; an idle document is not
+// checkpointed again. A zero field disables that trigger, and the zero value
+// never checkpoints.
+type CheckpointPolicy struct {
+	// Every saves a snapshot once this many operations have been accepted
+	// since the last one.
+	Every int
+
+	// Interval saves a snapshot when an operation is accepted this long or
+	// more after the last one.
+	Interval time.Duration
+}
+
+// SetCheckpointPolicy sets when the server saves snapshots to its Store. It
+// has no effect on a server without a Store.
+func (s *Server) SetCheckpointPolicy(policy CheckpointPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints = policy
+}
+
+// Checkpoint saves a snapshot of the current document to the Store now. It
+// does nothing without a Store.
+func (s *Server) Checkpoint(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.checkpoint(ctx)
+}
+
+// CheckpointErr returns the error from the most recent automatic checkpoint,
+// or nil if it succeeded. A failed checkpoint does not affect the operation
+// that triggered it, which has already been saved; it is retried with the
+// next operation.
+func (s *Server) CheckpointErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.checkpointErr
+}
+
+// checkpoint implements Checkpoint. Callers hold s.mu.
+func (s *Server) checkpoint(ctx context.Context) error {
+	if s.store == nil {
+		return nil
+	}
+	if err := s.store.SaveSnapshot(ctx, s.name, s.revision(), s.doc); err != nil {
+		return err
+	}
+	s.lastCheckpoint = s.revision()
+	s.lastCheckpointAt = s.now()
+	return nil
+}
+
+// maybeCheckpoint applies the checkpoint policy after an operation has been
+// accepted. Callers hold s.mu.
+func (s *Server) maybeCheckpoint(ctx context.Context) {
+	p := s.checkpoints
+	if s.store == nil || s.revision() == s.lastCheckpoint {
+		return
+	}
+	due := p.Every > 0 && s.revision()-s.lastCheckpoint >= p.Every
+	if p.Interval > 0 && s.now().Sub(s.lastCheckpointAt) >= p.Interval {
+		due = true
+	}
+	if due {
+		s.checkpointErr = s.checkpoint(ctx)
+		if s.checkpointErr != nil {
+			s.logger.ErrorContext(ctx, "checkpoint failed", "revision", s.revision(), "err", s.checkpointErr)
+		}
+	}
+}
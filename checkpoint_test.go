@@ -0,0 +1,118 @@
+package ot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func appendText(t *testing.T, s *Server, text string) {
+	t.Helper()
+	doc, rev := s.State()
+	op := NewOperationSeq()
+	op.Retain(uint64(charCount(doc)))
+	op.Insert(text)
+	if _, err := s.ReceiveOperation(context.Background(), rev, op); err != nil {
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+}
+
+func TestCheckpointEvery(t *testing.T) {
+	store := &memStore{}
+	s, err := OpenServer(context.Background(), store, "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	s.SetCheckpointPolicy(CheckpointPolicy{Every: 2})
+
+	appendText(t, s, "a")
+	if store.snapRev != 0 {
+		t.Errorf("expected no checkpoint yet, got revision %d", store.snapRev)
+	}
+	appendText(t, s, "b")
+	if store.snapshot != "ab" || store.snapRev != 2 {
+		t.Errorf("expected checkpoint (%q, 2), got (%q, %d)", "ab", store.snapshot, store.snapRev)
+	}
+	appendText(t, s, "c")
+	appendText(t, s, "d")
+	if store.snapRev != 4 {
+		t.Errorf("expected checkpoint at revision 4, got %d", store.snapRev)
+	}
+}
+
+func TestCheckpointInterval(t *testing.T) {
+	store := &memStore{}
+	s, err := OpenServer(context.Background(), store, "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	now := time.Unix(0, 0)
+	s.now = func() time.Time { return now }
+	s.lastCheckpointAt = now
+	s.SetCheckpointPolicy(CheckpointPolicy{Interval: time.Minute})
+
+	appendText(t, s, "a")
+	if store.snapRev != 0 {
+		t.Errorf("expected no checkpoint yet, got revision %d", store.snapRev)
+	}
+	now = now.Add(time.Minute)
+	appendText(t, s, "b")
+	if store.snapRev != 2 {
+		t.Errorf("expected checkpoint at revision 2, got %d", store.snapRev)
+	}
+}
+
+func TestCheckpointFailure(t *testing.T) {
+	store := &failingSnapshots{memStore: &memStore{}}
+	s, err := OpenServer(context.Background(), store, "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	s.SetCheckpointPolicy(CheckpointPolicy{Every: 1})
+
+	// The operation is accepted even though the checkpoint fails.
+	appendText(t, s, "a")
+	if !errors.Is(s.CheckpointErr(), errSnapshot) {
+		t.Errorf("expected checkpoint error, got %v", s.CheckpointErr())
+	}
+	if s.Document() != "a" {
+		t.Errorf("expected %q, got %q", "a", s.Document())
+	}
+
+	store.ok = true
+	if err := s.Checkpoint(context.Background()); err != nil {
+		t.Errorf("Checkpoint failed: %v", err)
+	}
+	if store.snapshot != "a" || store.snapRev != 1 {
+		t.Errorf("expected snapshot (%q, 1), got (%q, %d)", "a", store.snapshot, store.snapRev)
+	}
+}
+
+var errSnapshot = errors.New("snapshot failed")
+
+type failingSnapshots struct {
+	*memStore
+	ok bool
+}
+
+func (f *failingSnapshots) SaveSnapshot(ctx context.Context, doc string, revision int, content string) error {
+	if !f.ok {
+		return errSnapshot
+	}
+	return f.memStore.SaveSnapshot(ctx, doc, revision, content)
+}
+
+func TestHubCheckpoints(t *testing.T) {
+	store := &memStore{}
+	h := &Hub{Store: store, Checkpoints: CheckpointPolicy{Every: 1}}
+
+	op := NewOperationSeq()
+	op.Insert("x")
+	if _, _, err := h.Submit(context.Background(), "doc", "alice", 0, op); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if store.snapshot != "x" || store.snapRev != 1 {
+		t.Errorf("expected checkpoint (%q, 1), got (%q, %d)", "x", store.snapshot, store.snapRev)
+	}
+}
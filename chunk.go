@@ -0,0 +1,93 @@
+package ot
+
+import "unicode/utf8"
+
+// Chunk splits o into consecutive operations that each insert at most
+// maxBytes bytes of text, for transports with frame limits or to report
+// progress on an enormous paste. Applying the chunks in order has the same
+// effect as applying o, and composing them gives back an equivalent
+// operation. Inserts are split between characters; a chunk holds at least
+// one character even if it is larger than maxBytes. Retains and deletes do
+// not count towards the limit.
+//
+// There is always at least one chunk. A maxBytes of zero or less puts
+// everything in one.
+func (o *OperationSeq) Chunk(maxBytes int) []*OperationSeq {
+	if maxBytes <= 0 {
+		maxBytes = int(^uint(0) >> 1)
+	}
+
+	var (
+		chunks []*OperationSeq
+		cur    *OperationSeq // nil until the chunk changes something
+		size   int           // bytes inserted by cur
+		in     int           // characters of the base document consumed
+		out    int           // characters of the target document produced
+	)
+	start := func() {
+		if cur == nil {
+			cur = NewOperationSeq()
+			cur.Retain(uint64(out))
+			size = 0
+		}
+	}
+	finish := func() {
+		cur.Retain(uint64(o.baseLen - in))
+		cur.meta = o.meta
+		chunks = append(chunks, cur)
+		cur = nil
+	}
+
+	for _, c := range o.ops {
+		switch c.Kind {
+		case KindRetain:
+			if cur != nil {
+				cur.Retain(c.N)
+			}
+			in += int(c.N)
+			out += int(c.N)
+		case KindDelete:
+			start()
+			cur.Delete(c.N)
+			in += int(c.N)
+		case KindInsert:
+			text := c.Text
+			for text != "" {
+				start()
+				cut := len(text)
+				if size+cut > maxBytes {
+					cut = fitRunes(text, maxBytes-size)
+					if cut == 0 && size == 0 {
+						cut = skipRunes(text, 0, 1)
+					}
+				}
+				if cut > 0 {
+					cur.Insert(text[:cut])
+					out += charCount(text[:cut])
+					size += cut
+					text = text[cut:]
+				}
+				if text != "" {
+					finish()
+				}
+			}
+		}
+	}
+	if cur != nil || len(chunks) == 0 {
+		start()
+		finish()
+	}
+	return chunks
+}
+
+// fitRunes returns the length of the longest prefix of s that has at most n
+// bytes and ends between characters.
+func fitRunes(s string, n int) int {
+	if n >= len(s) {
+		return len(s)
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return n
+}
@@ -0,0 +1,59 @@
+package ot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChunk(t *testing.T) {
+	doc := "hello world"
+	op := NewOperationSeq()
+	op.Retain(2)
+	op.Insert(strings.Repeat("é", 10) + "abc")
+	op.Delete(3)
+	op.Retain(4)
+	op.Insert("xyz")
+	op.Delete(2)
+	want, err := op.Apply(doc)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	for _, limit := range []int{0, 1, 3, 7, 100} {
+		chunks := op.Chunk(limit)
+		got := doc
+		for i, c := range chunks {
+			if limit > 0 {
+				n := 0
+				for _, v := range c.Components() {
+					n += len(v.Text)
+				}
+				if n > limit && n > 2 {
+					t.Errorf("limit %d: chunk %d inserts %d bytes", limit, i, n)
+				}
+			}
+			if got, err = c.Apply(got); err != nil {
+				t.Fatalf("limit %d: applying chunk %d failed: %v", limit, i, err)
+			}
+		}
+		if got != want {
+			t.Errorf("limit %d: expected %q, got %q", limit, want, got)
+		}
+	}
+
+	if n := len(op.Chunk(0)); n != 1 {
+		t.Errorf("expected one chunk without a limit, got %d", n)
+	}
+	if n := len(op.Chunk(4)); n != 7 {
+		t.Errorf("expected 7 chunks of at most 4 bytes, got %d", n)
+	}
+}
+
+func TestChunkNoop(t *testing.T) {
+	op := NewOperationSeq()
+	op.Retain(5)
+	chunks := op.Chunk(10)
+	if len(chunks) != 1 || chunks[0].BaseLen() != 5 || !chunks[0].IsNoop() {
+		t.Errorf("expected one retain of 5, got %v", chunks)
+	}
+}
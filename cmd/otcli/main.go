@@ -0,0 +1,216 @@
+// Command otcli applies, composes, transforms, inverts and diffs operations
+// from the command line, for debugging operation logs and scripting
+// migrations.
+//
+// Usage:
+//
+//	otcli apply DOC OP        print the document OP produces from DOC
+//	otcli compose A B         print the composition of A and B
+//	otcli transform A B       print A' and B', one per line
+//	otcli invert DOC OP       print the operation that undoes OP on DOC
+//	otcli diff OLD NEW        print an operation turning OLD into NEW
+//	otcli replay --snapshot FILE --log FILE
+//	                          replay an otlog log against a snapshot and
+//	                          report the first corrupt revision
+//
+// Every operand is a JSON value: operations in their usual JSON form, such
+// as [5,"x",-2], and documents as JSON strings. Operands missing from the
+// command line are read from standard input, in order, so a log can be
+// piped in:
+//
+//	otcli apply '"hello"' '[5," world"]'
+//	echo '"hello" [5," world"]' | otcli apply
+//
+// Documents are printed as JSON strings and operations as JSON arrays.
+//
+// replay checks the snapshot's checksum and each record's, that revisions
+// follow on from one another and that each operation fits the document, and
+// prints the final revision and content hash. Otherwise it prints the first
+// revision that failed and its offset in the log, and exits with status 1.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+const usage = `usage: otcli <command> [operands]
+
+commands:
+  apply DOC OP        print the document OP produces from DOC
+  compose A B         print the composition of A and B
+  transform A B       print A' and B', one per line
+  invert DOC OP       print the operation that undoes OP on DOC
+  diff OLD NEW        print an operation turning OLD into NEW
+  replay --snapshot FILE --log FILE
+                      replay an otlog log against a snapshot and report
+                      the first corrupt revision
+
+Operands are JSON: documents as strings, operations as arrays. Operands
+missing from the command line are read from standard input.
+`
+
+// errUsage is returned for a command line otcli cannot run; main prints the
+// usage after it.
+var errUsage = errors.New("otcli: invalid usage")
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, errUsage) {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+// run runs the command in args, reading missing operands from stdin and
+// writing results to stdout.
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	cmd, args := args[0], args[1:]
+	if cmd == "replay" {
+		return replay(args, stdout)
+	}
+	in := &operands{args: args, dec: json.NewDecoder(stdin)}
+	out := json.NewEncoder(stdout)
+	out.SetEscapeHTML(false)
+
+	switch cmd {
+	case "apply":
+		doc, op, err := docOp(in)
+		if err != nil {
+			return err
+		}
+		result, err := op.Apply(doc)
+		if err != nil {
+			return err
+		}
+		return out.Encode(result)
+	case "compose":
+		a, b, err := twoOps(in)
+		if err != nil {
+			return err
+		}
+		ab, err := a.Compose(b)
+		if err != nil {
+			return err
+		}
+		return out.Encode(ab)
+	case "transform":
+		a, b, err := twoOps(in)
+		if err != nil {
+			return err
+		}
+		aPrime, bPrime, err := a.Transform(b)
+		if err != nil {
+			return err
+		}
+		if err := out.Encode(aPrime); err != nil {
+			return err
+		}
+		return out.Encode(bPrime)
+	case "invert":
+		doc, op, err := docOp(in)
+		if err != nil {
+			return err
+		}
+		inverse, err := op.InvertChecked(doc)
+		if err != nil {
+			return err
+		}
+		return out.Encode(inverse)
+	case "diff":
+		var before, after string
+		if err := in.next(&before); err != nil {
+			return err
+		}
+		if err := in.next(&after); err != nil {
+			return err
+		}
+		if err := in.done(); err != nil {
+			return err
+		}
+		op := ot.NewOperationSeq()
+		op.Delete(uint64(utf8.RuneCountInString(before)))
+		op.Insert(after)
+		op, err := op.Minimize(before)
+		if err != nil {
+			return err
+		}
+		return out.Encode(op)
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, cmd)
+	}
+}
+
+// operands reads JSON operands from the command line, then from stdin once
+// the command line runs out.
+type operands struct {
+	args []string
+	dec  *json.Decoder
+	n    int // operands read so far
+}
+
+// next decodes the next operand into v.
+func (in *operands) next(v any) error {
+	in.n++
+	if len(in.args) > 0 {
+		arg := in.args[0]
+		in.args = in.args[1:]
+		if err := json.Unmarshal([]byte(arg), v); err != nil {
+			return fmt.Errorf("operand %d: %w", in.n, err)
+		}
+		return nil
+	}
+	if err := in.dec.Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("%w: missing operand %d", errUsage, in.n)
+		}
+		return fmt.Errorf("operand %d: %w", in.n, err)
+	}
+	return nil
+}
+
+// done returns an error if operands are left on the command line. Standard
+// input is not checked, so a log with more in it can be piped in.
+func (in *operands) done() error {
+	if len(in.args) > 0 {
+		return fmt.Errorf("%w: too many operands", errUsage)
+	}
+	return nil
+}
+
+// docOp reads a document and an operation.
+func docOp(in *operands) (string, *ot.OperationSeq, error) {
+	var doc string
+	op := ot.NewOperationSeq()
+	if err := in.next(&doc); err != nil {
+		return "", nil, err
+	}
+	if err := in.next(op); err != nil {
+		return "", nil, err
+	}
+	return doc, op, in.done()
+}
+
+// twoOps reads two operations.
+func twoOps(in *operands) (*ot.OperationSeq, *ot.OperationSeq, error) {
+	a, b := ot.NewOperationSeq(), ot.NewOperationSeq()
+	if err := in.next(a); err != nil {
+		return nil, nil, err
+	}
+	if err := in.next(b); err != nil {
+		return nil, nil, err
+	}
+	return a, b, in.done()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		args  []string
+		stdin string
+		want  string
+	}{
+		{[]string{"apply", `"hello"`, `[5," world"]`}, "", `"hello world"` + "\n"},
+		{[]string{"apply"}, `"hello" [5," world"]`, `"hello world"` + "\n"},
+		{[]string{"apply", `"hello"`}, `[5," world"]`, `"hello world"` + "\n"},
+		{[]string{"compose", `[5," world"]`, `[-1,10]`}, "", `[-1,4," world"]` + "\n"},
+		{[]string{"transform", `[1,"a",1]`, `[1,"b",1]`}, "", "[1,\"a\",2]\n[2,\"b\",1]\n"},
+		{[]string{"invert", `"hello"`, `[1,-3,1]`}, "", `[1,"ell",1]` + "\n"},
+		{[]string{"diff", `"hello world"`, `"hello there world"`}, "", `[6,"there ",5]` + "\n"},
+		{[]string{"diff", `"<a>"`, `"<b>"`}, "", `[1,"b",-1,1]` + "\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := run(tt.args, strings.NewReader(tt.stdin), &out); err != nil {
+			t.Errorf("%v: %v", tt.args, err)
+			continue
+		}
+		if out.String() != tt.want {
+			t.Errorf("%v: expected %q, got %q", tt.args, tt.want, out.String())
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		args  []string
+		usage bool
+	}{
+		{nil, true},
+		{[]string{"frobnicate"}, true},
+		{[]string{"apply", `"hello"`}, true},
+		{[]string{"apply", `"hello"`, `[5]`, `[5]`}, true},
+		{[]string{"apply", `hello`, `[5]`}, false},
+		{[]string{"apply", `"hello"`, `[4]`}, false},
+		{[]string{"compose", `[5]`, `[4]`}, false},
+	}
+	for _, tt := range tests {
+		err := run(tt.args, strings.NewReader(""), &bytes.Buffer{})
+		if err == nil {
+			t.Errorf("%v: expected an error", tt.args)
+			continue
+		}
+		if errors.Is(err, errUsage) != tt.usage {
+			t.Errorf("%v: expected a usage error to be %v, got %v", tt.args, tt.usage, err)
+		}
+	}
+}
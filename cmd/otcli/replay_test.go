@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ot "github.com/shiv248/operational-transformation-go"
+	"github.com/shiv248/operational-transformation-go/otlog"
+)
+
+func appendText(t *testing.T, base int, text string) *ot.OperationSeq {
+	t.Helper()
+	op := ot.NewOperationSeq()
+	op.Retain(uint64(base))
+	op.Insert(text)
+	return op
+}
+
+// writeStore writes a snapshot of "ab" at revision 2 and a log holding
+// revisions 0 to 4, of which 0 and 1 are folded into the snapshot. It
+// returns the paths and the log's contents.
+func writeStore(t *testing.T, ops map[int]*ot.OperationSeq) (string, string, []byte) {
+	t.Helper()
+	dir := t.TempDir()
+	var snap bytes.Buffer
+	if err := otlog.SaveSnapshot(&snap, "ab", 2); err != nil {
+		t.Fatal(err)
+	}
+	var log bytes.Buffer
+	w := otlog.NewWriter(&log)
+	for rev := 0; rev < 5; rev++ {
+		op, ok := ops[rev]
+		if !ok {
+			op = appendText(t, rev, string(rune('a'+rev)))
+		}
+		if op == nil {
+			continue
+		}
+		if err := w.Append(rev, op); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snapPath, logPath := filepath.Join(dir, "snap"), filepath.Join(dir, "log")
+	if err := os.WriteFile(snapPath, snap.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(logPath, log.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return snapPath, logPath, log.Bytes()
+}
+
+func TestReplay(t *testing.T) {
+	snap, log, data := writeStore(t, nil)
+	var out bytes.Buffer
+	if err := run([]string{"replay", "--snapshot", snap, "--log", log}, nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	want := "ok: revision 5, 3 records replayed, 5 characters"
+	if !strings.HasPrefix(out.String(), want) {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+
+	// A torn final record is reported but is not corruption.
+	if err := os.WriteFile(log, data[:len(data)-3], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := run([]string{"replay", "--snapshot", snap, "--log", log}, nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "revision 4, 2 records replayed") || !strings.Contains(out.String(), "torn record") {
+		t.Errorf("expected revision 4 and a torn record, got %q", out.String())
+	}
+}
+
+func TestReplayCorrupt(t *testing.T) {
+	t.Run("checksum", func(t *testing.T) {
+		snap, log, data := writeStore(t, nil)
+		// Flip a byte in the payload of the fourth record, revision 3.
+		r := otlog.NewReader(bytes.NewReader(data))
+		for i := 0; i < 3; i++ {
+			if _, err := r.Next(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		data[r.Offset()+9] ^= 0xff
+		if err := os.WriteFile(log, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		checkCorrupt(t, snap, log, 3, otlog.ErrCorrupt)
+	})
+	t.Run("missing", func(t *testing.T) {
+		snap, log, _ := writeStore(t, map[int]*ot.OperationSeq{2: nil})
+		checkCorrupt(t, snap, log, 2, nil)
+	})
+	t.Run("length", func(t *testing.T) {
+		snap, log, _ := writeStore(t, map[int]*ot.OperationSeq{3: appendText(t, 7, "x")})
+		checkCorrupt(t, snap, log, 3, ot.ErrIncompatibleLengths)
+	})
+	t.Run("snapshot", func(t *testing.T) {
+		snap, log, _ := writeStore(t, nil)
+		if err := os.WriteFile(snap, []byte("OTS1"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		err := run([]string{"replay", "--snapshot", snap, "--log", log}, nil, &bytes.Buffer{})
+		if !errors.Is(err, otlog.ErrBadSnapshot) {
+			t.Errorf("expected ErrBadSnapshot, got %v", err)
+		}
+	})
+	t.Run("usage", func(t *testing.T) {
+		for _, args := range [][]string{{"replay"}, {"replay", "--bogus"}, {"replay", "--log", "l", "extra"}} {
+			if err := run(args, nil, &bytes.Buffer{}); !errors.Is(err, errUsage) {
+				t.Errorf("%v: expected a usage error, got %v", args, err)
+			}
+		}
+	})
+}
+
+func checkCorrupt(t *testing.T, snap, log string, revision int, target error) {
+	t.Helper()
+	err := run([]string{"replay", "--snapshot", snap, "--log", log}, nil, &bytes.Buffer{})
+	var corrupt *corruptError
+	if !errors.As(err, &corrupt) || corrupt.Revision != revision {
+		t.Fatalf("expected revision %d to be corrupt, got %v", revision, err)
+	}
+	if target != nil && !errors.Is(err, target) {
+		t.Errorf("expected %v, got %v", target, err)
+	}
+}
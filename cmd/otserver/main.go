@@ -0,0 +1,78 @@
+// Command otserver is a collaborative editor showing how the package's
+// types fit together: an in-memory ot.Hub holds the documents, an
+// otws.Handler serves them over WebSocket, and an embedded page edits them
+// in a textarea and lists who else is connected.
+//
+// Run it and open the page in several windows:
+//
+//	go run ./cmd/otserver -addr localhost:8080
+//
+// The page's client transforms its pending edits with ot.js, a port of the
+// package's Transform that must break ties between concurrent inserts the
+// same way; cmd/otwasm runs the Go code itself in the browser instead. The
+// tests hold ot.js to the package's results and to the otgolden cases, and
+// CI runs them with node.
+// Documents live only as long as the process.
+package main
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"flag"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	ot "github.com/shiv248/operational-transformation-go"
+	"github.com/shiv248/operational-transformation-go/otws"
+)
+
+//go:embed static
+var static embed.FS
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	flag.Parse()
+
+	logger := slog.Default()
+	hub := &ot.Hub{Logger: logger}
+	srv := &http.Server{Addr: *addr, Handler: newMux(hub), ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		// Tell clients first, so they are not left editing a closed hub.
+		if err := hub.Shutdown(shutdown); err != nil {
+			logger.Error("hub shutdown", "err", err)
+		}
+		if err := srv.Shutdown(shutdown); err != nil {
+			logger.Error("http shutdown", "err", err)
+		}
+	}()
+
+	logger.Info("listening", "url", "http://"+*addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("listen", "err", err)
+		os.Exit(1)
+	}
+}
+
+// newMux serves the page at / and the documents of hub at /ws.
+func newMux(hub *ot.Hub) *http.ServeMux {
+	page, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err) // the embedded directory is always there
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(page)))
+	mux.Handle("/ws", &otws.Handler{Hub: hub, Limits: ot.DecodeLimits{MaxOps: 10_000, MaxInsertLen: 1 << 20}, MaxMessageBytes: 4 << 20})
+	return mux
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	ot "github.com/shiv248/operational-transformation-go"
+	"github.com/shiv248/operational-transformation-go/otgolden"
+)
+
+func TestPage(t *testing.T) {
+	srv := httptest.NewServer(newMux(&ot.Hub{}))
+	defer srv.Close()
+
+	for path, want := range map[string]string{"/": "<textarea", "/ot.js": "function transform"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close() //nolint:errcheck // test cleanup
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || !bytes.Contains(body, []byte(want)) {
+			t.Errorf("%s: expected 200 and %q, got %d", path, want, resp.StatusCode)
+		}
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	srv := httptest.NewServer(newMux(&ot.Hub{}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close() //nolint:errcheck // test cleanup
+
+	req := "GET /ws HTTP/1.1\r\nHost: example\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	fmt.Fprint(conn, req) //nolint:errcheck // checked by the read
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("expected %d, got %d", http.StatusSwitchingProtocols, resp.StatusCode)
+	}
+}
+
+// TestOTJS checks that the page's port of the package gives the same results
+// as the package itself, which the clients need to converge with the server.
+func TestOTJS(t *testing.T) {
+	node := lookNode(t)
+
+	type testCase struct {
+		Doc string           `json:"doc"`
+		A   *ot.OperationSeq `json:"a"`
+		B   *ot.OperationSeq `json:"b"`
+		C   *ot.OperationSeq `json:"c"` // applies after A
+	}
+	type result struct {
+		Apply     string              `json:"apply"`
+		Invert    *ot.OperationSeq    `json:"invert"`
+		Transform [2]*ot.OperationSeq `json:"transform"`
+		Compose   *ot.OperationSeq    `json:"compose"`
+		Diff      *ot.OperationSeq    `json:"diff"`
+	}
+
+	// The alphabet mixes a character above the UTF-16 surrogates with one
+	// beyond the BMP, which order differently by code unit and by code
+	// point, and is small so that concurrent inserts often start alike.
+	r := rand.New(rand.NewSource(1))
+	opts := ot.RandomOptions{MaxLen: 4, Alphabet: []rune("ab～🌍")}
+	var cases []testCase
+	var want []result
+	for i := 0; i < 500; i++ {
+		doc := ot.RandomString(r, r.Intn(12))
+		a := ot.RandomOp(r, len([]rune(doc)), opts)
+		b := ot.RandomOp(r, len([]rune(doc)), opts)
+		c := ot.RandomOp(r, a.TargetLen(), opts)
+		after, err := a.Apply(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inverse, err := a.InvertChecked(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		aPrime, bPrime, err := a.Transform(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ac, err := a.Compose(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		diff := ot.NewOperationSeq()
+		diff.Delete(uint64(len([]rune(doc))))
+		diff.Insert(after)
+		if diff, err = diff.Minimize(doc); err != nil {
+			t.Fatal(err)
+		}
+		cases = append(cases, testCase{doc, a, b, c})
+		want = append(want, result{after, inverse, [2]*ot.OperationSeq{aPrime, bPrime}, ac, diff})
+	}
+
+	input, err := json.Marshal(cases)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := `
+const ot = require("./static/ot.js");
+const cases = JSON.parse(require("fs").readFileSync(0, "utf8"));
+const after = (c) => ot.apply(c.a, c.doc);
+console.log(JSON.stringify(cases.map((c) => ({
+  apply: after(c),
+  invert: ot.invert(c.a, c.doc),
+  transform: ot.transform(c.a, c.b),
+  compose: ot.compose(c.a, c.c),
+  diff: ot.diff(c.doc, after(c)),
+}))));`
+	var got []result
+	runNode(t, node, script, input, &got)
+	for i := range cases {
+		wantJSON, _ := json.Marshal(want[i]) //nolint:errcheck // marshalled above
+		gotJSON, _ := json.Marshal(got[i])   //nolint:errcheck // just decoded
+		if !bytes.Equal(wantJSON, gotJSON) {
+			caseJSON, _ := json.Marshal(cases[i]) //nolint:errcheck // marshalled above
+			t.Fatalf("case %s: expected %s, got %s", caseJSON, wantJSON, gotJSON)
+		}
+	}
+}
+
+// TestOTJSGolden runs the otgolden cases against ot.js, so that the port
+// and the package are held to the same recorded results.
+func TestOTJSGolden(t *testing.T) {
+	node := lookNode(t)
+	cases := otgolden.Default()
+	input, err := json.Marshal(cases)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := `
+const ot = require("./static/ot.js");
+const cases = JSON.parse(require("fs").readFileSync(0, "utf8"));
+console.log(JSON.stringify(cases.map((c) => {
+  try {
+    if (c.kind === "transform") {
+      const [aPrime, bPrime] = ot.transform(c.a, c.b);
+      return { aPrime, bPrime, result: ot.apply(bPrime, ot.apply(c.a, c.doc)) };
+    }
+    const compose = ot.compose(c.a, c.b);
+    return { compose, result: ot.apply(compose, c.doc) };
+  } catch (e) {
+    return { error: true };
+  }
+})));`
+	var got []otgolden.Case
+	runNode(t, node, script, input, &got)
+
+	same := func(want, got *ot.OperationSeq) bool {
+		return want == nil || got != nil && want.String() == got.String()
+	}
+	for i, c := range cases {
+		g := got[i]
+		switch {
+		case c.Error || g.Error:
+			if c.Error != g.Error {
+				t.Errorf("%s: expected error %v, got %v", c.Name, c.Error, g.Error)
+			}
+		case !same(c.APrime, g.APrime) || !same(c.BPrime, g.BPrime) || !same(c.Compose, g.Compose):
+			t.Errorf("%s: expected %v %v %v, got %v %v %v", c.Name, c.APrime, c.BPrime, c.Compose, g.APrime, g.BPrime, g.Compose)
+		case c.Result != nil && (g.Result == nil || *g.Result != *c.Result):
+			t.Errorf("%s: expected result %q, got %v", c.Name, *c.Result, g.Result)
+		}
+	}
+}
+
+// lookNode returns the path to node. Tests that need it are skipped if it
+// is missing, except in CI, where they must run so that ot.js is checked.
+func lookNode(t *testing.T) string {
+	t.Helper()
+	node, err := exec.LookPath("node")
+	if err != nil {
+		if os.Getenv("CI") != "" {
+			t.Fatalf("node is required in CI: %v", err)
+		}
+		t.Skip("node not found")
+	}
+	return node
+}
+
+// runNode runs script with node, input on its standard input, and decodes
+// what it prints into v.
+func runNode(t *testing.T, node, script string, input []byte, v any) {
+	t.Helper()
+	cmd := exec.Command(node, "-e", script)
+	cmd.Stdin = bytes.NewReader(input)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("node: %v", err)
+	}
+	if err := json.Unmarshal(out, v); err != nil {
+		t.Fatal(err)
+	}
+}
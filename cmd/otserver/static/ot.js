@@ -0,0 +1,270 @@
+// ot.js ports Apply, Invert, Compose and Transform from the Go package, so that the
+// page transforms its pending edits exactly as the server does; a different
+// tie-break for concurrent inserts would make the two diverge.
+//
+// Operations are arrays in the JSON wire format: a positive number retains
+// that many characters, a negative one deletes them, and a string inserts
+// it. Lengths count Unicode code points, as the Go package counts runes.
+(function (exports) {
+  "use strict";
+
+  function chars(s) {
+    return Array.from(s);
+  }
+
+  // components returns the components of op as {kind, n, text} objects.
+  function components(op) {
+    return op.map(function (c) {
+      if (typeof c === "string") {
+        return { kind: "insert", n: chars(c).length, text: c };
+      }
+      return c > 0 ? { kind: "retain", n: c } : { kind: "delete", n: -c };
+    });
+  }
+
+  // Builder builds an operation in normal form, as OperationSeq does:
+  // adjacent components of a kind merge, and an insert goes before a
+  // delete.
+  function Builder() {
+    this.ops = [];
+  }
+  Builder.prototype.retain = function (n) {
+    if (n === 0) return;
+    var last = this.ops.length - 1;
+    if (last >= 0 && typeof this.ops[last] === "number" && this.ops[last] > 0) {
+      this.ops[last] += n;
+    } else {
+      this.ops.push(n);
+    }
+  };
+  Builder.prototype.delete = function (n) {
+    if (n === 0) return;
+    var last = this.ops.length - 1;
+    if (last >= 0 && typeof this.ops[last] === "number" && this.ops[last] < 0) {
+      this.ops[last] -= n;
+    } else {
+      this.ops.push(-n);
+    }
+  };
+  Builder.prototype.insert = function (s) {
+    if (s === "") return;
+    var ops = this.ops, n = ops.length;
+    if (n > 0 && typeof ops[n - 1] === "string") {
+      ops[n - 1] += s;
+    } else if (n >= 2 && ops[n - 1] < 0 && typeof ops[n - 2] === "string") {
+      ops[n - 2] += s;
+    } else if (n > 0 && ops[n - 1] < 0) {
+      ops.splice(n - 1, 0, s);
+    } else {
+      ops.push(s);
+    }
+  };
+
+  // Reader walks the components of an operation, splitting them with take.
+  function Reader(op) {
+    this.ops = components(op);
+    this.i = 0;
+    this.cur = null;
+    this.advance();
+  }
+  Reader.prototype.advance = function () {
+    this.cur = this.i < this.ops.length ? this.ops[this.i++] : null;
+  };
+  Reader.prototype.next = function () {
+    var c = this.cur;
+    this.advance();
+    return c;
+  };
+  Reader.prototype.take = function (n) {
+    var c = this.cur;
+    if (n >= c.n) return this.next();
+    var part = { kind: c.kind, n: n };
+    if (c.kind === "insert") {
+      var cs = chars(c.text);
+      part.text = cs.slice(0, n).join("");
+      c.text = cs.slice(n).join("");
+    }
+    c.n -= n;
+    return part;
+  };
+
+  function baseLen(op) {
+    return op.reduce(function (n, c) {
+      return typeof c === "number" ? n + Math.abs(c) : n;
+    }, 0);
+  }
+
+  function targetLen(op) {
+    return op.reduce(function (n, c) {
+      if (typeof c === "string") return n + chars(c).length;
+      return c > 0 ? n + c : n;
+    }, 0);
+  }
+
+  // compareText orders strings by code point, as Go orders them by UTF-8
+  // bytes; comparing with < would order them by UTF-16 code unit instead.
+  function compareText(a, b) {
+    var x = chars(a), y = chars(b);
+    for (var i = 0; i < x.length && i < y.length; i++) {
+      var d = x[i].codePointAt(0) - y[i].codePointAt(0);
+      if (d !== 0) return d;
+    }
+    return x.length - y.length;
+  }
+
+  function apply(op, doc) {
+    var cs = chars(doc);
+    if (baseLen(op) !== cs.length) throw new Error("incompatible lengths");
+    var out = [], pos = 0;
+    op.forEach(function (c) {
+      if (typeof c === "string") {
+        out.push(c);
+      } else if (c > 0) {
+        out.push(cs.slice(pos, pos + c).join(""));
+        pos += c;
+      } else {
+        pos -= c;
+      }
+    });
+    return out.join("");
+  }
+
+  // invert returns the operation that undoes op, which applies to doc.
+  function invert(op, doc) {
+    var cs = chars(doc);
+    if (baseLen(op) !== cs.length) throw new Error("incompatible lengths");
+    var out = new Builder(), pos = 0;
+    op.forEach(function (c) {
+      if (typeof c === "string") {
+        out.delete(chars(c).length);
+      } else if (c > 0) {
+        out.retain(c);
+        pos += c;
+      } else {
+        out.insert(cs.slice(pos, pos - c).join(""));
+        pos -= c;
+      }
+    });
+    return out.ops;
+  }
+
+  function compose(a, b) {
+    if (targetLen(a) !== baseLen(b)) throw new Error("operations are not sequential");
+    var out = new Builder(), ops1 = new Reader(a), ops2 = new Reader(b);
+    for (;;) {
+      var op1 = ops1.cur, op2 = ops2.cur;
+      if (!op1 && !op2) return out.ops;
+      if (op1 && op1.kind === "delete") {
+        out.delete(op1.n);
+        ops1.next();
+        continue;
+      }
+      if (op2 && op2.kind === "insert") {
+        out.insert(op2.text);
+        ops2.next();
+        continue;
+      }
+      if (!op1 || !op2) throw new Error("malformed operation");
+      var n = Math.min(op1.n, op2.n);
+      op1 = ops1.take(n);
+      ops2.take(n);
+      if (op1.kind === "retain" && op2.kind === "retain") out.retain(n);
+      else if (op1.kind === "insert" && op2.kind === "retain") out.insert(op1.text);
+      else if (op1.kind === "retain" && op2.kind === "delete") out.delete(n);
+    }
+  }
+
+  function transform(a, b) {
+    if (baseLen(a) !== baseLen(b)) throw new Error("operations are not concurrent");
+    var aPrime = new Builder(), bPrime = new Builder();
+    var ops1 = new Reader(a), ops2 = new Reader(b);
+    for (;;) {
+      var op1 = ops1.cur, op2 = ops2.cur;
+      if (!op1 && !op2) return [aPrime.ops, bPrime.ops];
+      if (op1 && op2 && op1.kind === "insert" && op2.kind === "insert") {
+        var d = compareText(op1.text, op2.text);
+        if (d < 0) {
+          aPrime.insert(op1.text);
+          bPrime.retain(op1.n);
+          ops1.next();
+        } else if (d === 0) {
+          aPrime.insert(op1.text);
+          aPrime.retain(op1.n);
+          bPrime.insert(op2.text);
+          bPrime.retain(op2.n);
+          ops1.next();
+          ops2.next();
+        } else {
+          aPrime.retain(op2.n);
+          bPrime.insert(op2.text);
+          ops2.next();
+        }
+        continue;
+      }
+      if (op1 && op1.kind === "insert") {
+        aPrime.insert(op1.text);
+        bPrime.retain(op1.n);
+        ops1.next();
+        continue;
+      }
+      if (op2 && op2.kind === "insert") {
+        aPrime.retain(op2.n);
+        bPrime.insert(op2.text);
+        ops2.next();
+        continue;
+      }
+      if (!op1 || !op2) throw new Error("malformed operation");
+      var n = Math.min(op1.n, op2.n);
+      ops1.take(n);
+      ops2.take(n);
+      if (op1.kind === "retain" && op2.kind === "retain") {
+        aPrime.retain(n);
+        bPrime.retain(n);
+      } else if (op1.kind === "delete" && op2.kind === "retain") {
+        aPrime.delete(n);
+      } else if (op1.kind === "retain" && op2.kind === "delete") {
+        bPrime.delete(n);
+      }
+    }
+  }
+
+  // diff returns an operation turning before into after, replacing what
+  // lies between their common prefix and suffix.
+  function diff(before, after) {
+    var x = chars(before), y = chars(after), p = 0, s = 0;
+    while (p < x.length && p < y.length && x[p] === y[p]) p++;
+    while (s < x.length - p && s < y.length - p && x[x.length - 1 - s] === y[y.length - 1 - s]) s++;
+    var out = new Builder();
+    out.retain(p);
+    out.insert(y.slice(p, y.length - s).join(""));
+    out.delete(x.length - p - s);
+    out.retain(s);
+    return out.ops;
+  }
+
+  // transformIndex moves a character offset through op. An insert at the
+  // offset itself pushes it along only if own is set, for the offset of
+  // the edit's own author.
+  function transformIndex(op, index, own) {
+    var pos = 0, out = index;
+    for (var i = 0; i < op.length && pos <= index; i++) {
+      var c = op[i];
+      if (typeof c === "string") {
+        if (pos < index || own) out += chars(c).length;
+      } else if (c > 0) {
+        pos += c;
+      } else {
+        out -= Math.min(-c, index - pos);
+        pos -= c;
+      }
+    }
+    return out;
+  }
+
+  exports.apply = apply;
+  exports.invert = invert;
+  exports.compose = compose;
+  exports.transform = transform;
+  exports.diff = diff;
+  exports.transformIndex = transformIndex;
+})(typeof module === "object" ? module.exports : (window.ot = {}));
@@ -0,0 +1,120 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"errors"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+var errNothingInFlight = errors.New("no operation in flight")
+
+// client is the usual OT client loop, as otws describes it: it keeps at
+// most one operation in flight, composes further local edits into a buffer
+// until the ack arrives, and transforms both against every operation from
+// the server before applying it.
+type client struct {
+	revision int
+	doc      string
+	server   string           // the document at revision, without local edits
+	inflight *ot.OperationSeq // sent, not yet acknowledged
+	buffer   *ot.OperationSeq // made while inflight was pending
+}
+
+// local records an edit made in the editor. It returns the operation to
+// send to the server now, or nil if one is already in flight.
+func (c *client) local(op *ot.OperationSeq) (*ot.OperationSeq, error) {
+	doc, err := op.Apply(c.doc)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c.inflight == nil:
+		c.inflight = op
+		c.doc = doc
+		return op, nil
+	case c.buffer == nil:
+		c.buffer = op
+	default:
+		buffer, err := c.buffer.Compose(op)
+		if err != nil {
+			return nil, err
+		}
+		c.buffer = buffer
+	}
+	c.doc = doc
+	return nil, nil
+}
+
+// ack records that the server accepted the operation in flight. It returns
+// the buffered operation to send next, or nil if there is none.
+func (c *client) ack() (*ot.OperationSeq, error) {
+	if c.inflight == nil {
+		return nil, errNothingInFlight
+	}
+	server, err := c.inflight.Apply(c.server)
+	if err != nil {
+		return nil, err
+	}
+	c.revision++
+	c.server = server
+	c.inflight, c.buffer = c.buffer, nil
+	return c.inflight, nil
+}
+
+// amend records that the server applied op in place of the operation in
+// flight, as an "ack" carrying "op" says, because it clipped it. It returns
+// the operation the editor must apply to match; ack must be called next.
+func (c *client) amend(op *ot.OperationSeq) (*ot.OperationSeq, error) {
+	if c.inflight == nil {
+		return nil, errNothingInFlight
+	}
+	undo, err := c.inflight.InvertChecked(c.server)
+	if err != nil {
+		return nil, err
+	}
+	fix, err := undo.Compose(op)
+	if err != nil {
+		return nil, err
+	}
+	buffer := c.buffer
+	if buffer != nil {
+		if buffer, fix, err = buffer.Transform(fix); err != nil {
+			return nil, err
+		}
+	}
+	doc, err := fix.Apply(c.doc)
+	if err != nil {
+		return nil, err
+	}
+	c.doc, c.inflight, c.buffer = doc, op, buffer
+	return fix, nil
+}
+
+// remote applies an operation from another client. It returns the operation
+// transformed against the local edits, which is what the editor must apply.
+func (c *client) remote(op *ot.OperationSeq) (*ot.OperationSeq, error) {
+	server, err := op.Apply(c.server)
+	if err != nil {
+		return nil, err
+	}
+	inflight, buffer := c.inflight, c.buffer
+	if inflight != nil {
+		if inflight, op, err = inflight.Transform(op); err != nil {
+			return nil, err
+		}
+	}
+	if buffer != nil {
+		if buffer, op, err = buffer.Transform(op); err != nil {
+			return nil, err
+		}
+	}
+	doc, err := op.Apply(c.doc)
+	if err != nil {
+		return nil, err
+	}
+	c.revision++
+	c.doc, c.server, c.inflight, c.buffer = doc, server, inflight, buffer
+	return op, nil
+}
@@ -0,0 +1,244 @@
+//go:build js && wasm
+
+// Command otwasm exports the package's transform, compose, apply and invert,
+// and an OT client, to JavaScript, so a browser runs the same code as the Go
+// server and the two can never disagree on how concurrent edits are ordered.
+//
+// Build it with the wasm_exec.js that comes with Go:
+//
+//	GOOS=js GOARCH=wasm go build -o ot.wasm ./cmd/otwasm
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" .
+//
+// Once running it sets a global ot object. Operations are passed and
+// returned in their JSON form, as arrays such as [5,"x",-2], and documents
+// as strings. A function that fails returns an Error instead of throwing:
+//
+//	ot.transform(a, b)    [a', b']
+//	ot.compose(a, b)      the composition of a and b
+//	ot.apply(doc, op)     the document op produces from doc
+//	ot.invert(doc, op)    the operation that undoes op on doc
+//	ot.newClient(revision, doc)
+//
+// newClient returns a client for the document doc at revision, with methods:
+//
+//	local(op)     record a local edit; returns the operation to send, or null
+//	ack()         the server accepted the operation sent; returns the next to
+//	              send, or null
+//	amend(op)     the server applied op in place of the operation sent, as
+//	              an "ack" carrying "op" says; returns the operation the
+//	              editor must apply. Call ack() next.
+//	remote(op)    an operation from the server; returns the operation the
+//	              editor must apply
+//	revision()    the revision the client is at
+//	doc()         the document, with local edits
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"syscall/js"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+func main() {
+	register()
+
+	// The exported functions run on this program, so it must not exit.
+	select {}
+}
+
+// register sets the global ot object.
+func register() {
+	api := map[string]any{
+		"transform": fn(func(args []js.Value) (any, error) {
+			a, b, err := twoOps(args)
+			if err != nil {
+				return nil, err
+			}
+			aPrime, bPrime, err := a.Transform(b)
+			if err != nil {
+				return nil, err
+			}
+			return []any{toJS(aPrime), toJS(bPrime)}, nil
+		}),
+		"compose": fn(func(args []js.Value) (any, error) {
+			a, b, err := twoOps(args)
+			if err != nil {
+				return nil, err
+			}
+			ab, err := a.Compose(b)
+			if err != nil {
+				return nil, err
+			}
+			return toJS(ab), nil
+		}),
+		"apply": fn(func(args []js.Value) (any, error) {
+			doc, err := stringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			op, err := fromJS(arg(args, 1))
+			if err != nil {
+				return nil, err
+			}
+			return op.Apply(doc)
+		}),
+		"invert": fn(func(args []js.Value) (any, error) {
+			doc, err := stringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			op, err := fromJS(arg(args, 1))
+			if err != nil {
+				return nil, err
+			}
+			inverse, err := op.InvertChecked(doc)
+			if err != nil {
+				return nil, err
+			}
+			return toJS(inverse), nil
+		}),
+		"newClient": fn(func(args []js.Value) (any, error) {
+			revision, err := revisionArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			doc, err := stringArg(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			return clientAPI(&client{revision: revision, doc: doc, server: doc}), nil
+		}),
+	}
+	js.Global().Set("ot", js.ValueOf(api))
+}
+
+// clientAPI returns the JavaScript object wrapping c.
+func clientAPI(c *client) js.Value {
+	return js.ValueOf(map[string]any{
+		"local": fn(func(args []js.Value) (any, error) {
+			op, err := fromJS(arg(args, 0))
+			if err != nil {
+				return nil, err
+			}
+			send, err := c.local(op)
+			if err != nil {
+				return nil, err
+			}
+			return toJS(send), nil
+		}),
+		"ack": fn(func([]js.Value) (any, error) {
+			send, err := c.ack()
+			if err != nil {
+				return nil, err
+			}
+			return toJS(send), nil
+		}),
+		"amend": fn(func(args []js.Value) (any, error) {
+			op, err := fromJS(arg(args, 0))
+			if err != nil {
+				return nil, err
+			}
+			apply, err := c.amend(op)
+			if err != nil {
+				return nil, err
+			}
+			return toJS(apply), nil
+		}),
+		"remote": fn(func(args []js.Value) (any, error) {
+			op, err := fromJS(arg(args, 0))
+			if err != nil {
+				return nil, err
+			}
+			apply, err := c.remote(op)
+			if err != nil {
+				return nil, err
+			}
+			return toJS(apply), nil
+		}),
+		"revision": fn(func([]js.Value) (any, error) {
+			return c.revision, nil
+		}),
+		"doc": fn(func([]js.Value) (any, error) {
+			return c.doc, nil
+		}),
+	})
+}
+
+// fn wraps f as a JavaScript function that returns an Error if f fails.
+// The functions are never released, as they live as long as the program.
+func fn(f func(args []js.Value) (any, error)) js.Func {
+	return js.FuncOf(func(_ js.Value, args []js.Value) any {
+		v, err := f(args)
+		if err != nil {
+			return js.Global().Get("Error").New(err.Error())
+		}
+		return v
+	})
+}
+
+// arg returns args[i], or undefined if there are fewer arguments.
+func arg(args []js.Value, i int) js.Value {
+	if i < len(args) {
+		return args[i]
+	}
+	return js.Undefined()
+}
+
+// stringArg returns args[i] if it is a string.
+func stringArg(args []js.Value, i int) (string, error) {
+	v := arg(args, i)
+	if v.Type() != js.TypeString {
+		return "", fmt.Errorf("argument %d: expected a string, got %s", i, v.Type())
+	}
+	return v.String(), nil
+}
+
+// revisionArg returns args[i] if it is a non-negative integer.
+func revisionArg(args []js.Value, i int) (int, error) {
+	v := arg(args, i)
+	if v.Type() != js.TypeNumber {
+		return 0, fmt.Errorf("argument %d: expected a revision, got %s", i, v.Type())
+	}
+	f := v.Float()
+	if f < 0 || f != math.Trunc(f) || f > math.MaxInt32 {
+		return 0, fmt.Errorf("argument %d: invalid revision %v", i, f)
+	}
+	return int(f), nil
+}
+
+func twoOps(args []js.Value) (*ot.OperationSeq, *ot.OperationSeq, error) {
+	a, err := fromJS(arg(args, 0))
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := fromJS(arg(args, 1))
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, b, nil
+}
+
+// fromJS decodes an operation from its JSON form as a JavaScript value.
+func fromJS(v js.Value) (*ot.OperationSeq, error) {
+	data := js.Global().Get("JSON").Call("stringify", v).String()
+	op := ot.NewOperationSeq()
+	if err := json.Unmarshal([]byte(data), op); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// toJS returns op in its JSON form as a JavaScript value, or null for nil.
+func toJS(op *ot.OperationSeq) any {
+	if op == nil {
+		return js.Null()
+	}
+	data, err := json.Marshal(op)
+	if err != nil {
+		return js.Global().Get("Error").New(err.Error())
+	}
+	return js.Global().Get("JSON").Call("parse", string(data))
+}
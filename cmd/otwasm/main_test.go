@@ -0,0 +1,203 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"context"
+	"syscall/js"
+	"testing"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+func parse(t *testing.T, s string) js.Value {
+	t.Helper()
+	return js.Global().Get("JSON").Call("parse", s)
+}
+
+func stringify(v js.Value) string {
+	return js.Global().Get("JSON").Call("stringify", v).String()
+}
+
+func TestExports(t *testing.T) {
+	register()
+	api := js.Global().Get("ot")
+
+	pair := api.Call("transform", parse(t, `[1,"a",1]`), parse(t, `[1,"b",1]`))
+	if got := stringify(pair); got != `[[1,"a",2],[2,"b",1]]` {
+		t.Errorf(`expected [[1,"a",2],[2,"b",1]], got %s`, got)
+	}
+	if got := stringify(api.Call("compose", parse(t, `[5," world"]`), parse(t, `[-1,10]`))); got != `[-1,4," world"]` {
+		t.Errorf(`expected [-1,4," world"], got %s`, got)
+	}
+	if got := api.Call("apply", "hello", parse(t, `[5," world"]`)).String(); got != "hello world" {
+		t.Errorf("expected %q, got %q", "hello world", got)
+	}
+	if got := stringify(api.Call("invert", "hello", parse(t, `[1,-3,1]`))); got != `[1,"ell",1]` {
+		t.Errorf(`expected [1,"ell",1], got %s`, got)
+	}
+
+	for name, args := range map[string][]any{
+		"apply":     {"hi", parse(t, `[5]`)},
+		"invert":    {7, parse(t, `[1]`)},
+		"newClient": {"3", "ab"},
+	} {
+		if err := api.Call(name, args...); !err.InstanceOf(js.Global().Get("Error")) {
+			t.Errorf("%s: expected an Error, got %s", name, stringify(err))
+		}
+	}
+	for _, args := range [][]any{{}, {-1, "ab"}, {1.5, "ab"}, {3}} {
+		if err := api.Call("newClient", args...); !err.InstanceOf(js.Global().Get("Error")) {
+			t.Errorf("newClient%v: expected an Error, got %v", args, err)
+		}
+	}
+}
+
+func TestClientExport(t *testing.T) {
+	register()
+	c := js.Global().Get("ot").Call("newClient", 3, "ab")
+
+	if got := stringify(c.Call("local", parse(t, `[2,"c"]`))); got != `[2,"c"]` {
+		t.Errorf(`expected [2,"c"] to be sent, got %s`, got)
+	}
+	if got := c.Call("local", parse(t, `[3,"d"]`)); !got.IsNull() {
+		t.Errorf("expected nothing to be sent while in flight, got %s", stringify(got))
+	}
+	if got := stringify(c.Call("remote", parse(t, `["x",2]`))); got != `["x",4]` {
+		t.Errorf(`expected ["x",4] to be applied, got %s`, got)
+	}
+	if got := stringify(c.Call("ack")); got != `[4,"d"]` {
+		t.Errorf(`expected the transformed buffer [4,"d"] to be sent, got %s`, got)
+	}
+	if got := c.Call("doc").String(); got != "xabcd" {
+		t.Errorf("expected %q, got %q", "xabcd", got)
+	}
+	if got := c.Call("revision").Int(); got != 5 {
+		t.Errorf("expected revision 5, got %d", got)
+	}
+}
+
+// TestClientConverges runs two clients against a server, delivering
+// messages in an interleaving that exercises both the in-flight and the
+// buffered operation.
+func TestClientConverges(t *testing.T) {
+	ctx := context.Background()
+	server := ot.NewServer("hello")
+	a := &client{doc: "hello", server: "hello"}
+	b := &client{doc: "hello", server: "hello"}
+
+	edit := func(c *client, base int, text string) *ot.OperationSeq {
+		op := ot.NewOperationSeq()
+		op.Retain(uint64(base))
+		op.Insert(text)
+		op.Retain(uint64(len([]rune(c.doc)) - base))
+		send, err := c.local(op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return send
+	}
+	submit := func(revision int, op *ot.OperationSeq) *ot.OperationSeq {
+		t.Helper()
+		out, err := server.ReceiveOperation(ctx, revision, op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	remote := func(c *client, op *ot.OperationSeq) {
+		t.Helper()
+		if _, err := c.remote(op); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Operations are submitted at the revision the client was at when it
+	// sent them.
+	sendA := edit(a, 0, "A")
+	edit(a, 6, "!") // buffered
+	sendB := edit(b, 5, "B")
+
+	ack := func(c *client) *ot.OperationSeq {
+		t.Helper()
+		next, err := c.ack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return next
+	}
+
+	outB := submit(0, sendB)
+	ack(b)
+	remote(a, outB)
+
+	outA := submit(0, sendA)
+	nextA := ack(a)
+	remote(b, outA)
+
+	outA = submit(a.revision, nextA)
+	if ack(a) != nil {
+		t.Fatalf("expected nothing left to send")
+	}
+	remote(b, outA)
+
+	doc := server.Document()
+	if a.doc != doc || b.doc != doc {
+		t.Errorf("expected both clients to have %q, got %q and %q", doc, a.doc, b.doc)
+	}
+	if a.revision != 3 || b.revision != 3 {
+		t.Errorf("expected both clients at revision 3, got %d and %d", a.revision, b.revision)
+	}
+}
+
+// TestClientAmend has the server clip an operation while the client has
+// another buffered, and checks the client follows the server's version.
+func TestClientAmend(t *testing.T) {
+	ctx := context.Background()
+	server := ot.NewServer("hello world")
+	server.SetProtectPolicy(ot.ProtectClip)
+	if err := server.Protect("greeting", ot.Range{Start: 0, End: 5}); err != nil {
+		t.Fatal(err)
+	}
+	c := &client{doc: "hello world", server: "hello world"}
+
+	// Delete "hello " and then append "!".
+	op := ot.NewOperationSeq()
+	op.Delete(6)
+	op.Retain(5)
+	send, err := c.local(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	op = ot.NewOperationSeq()
+	op.Retain(5)
+	op.Insert("!")
+	if _, err := c.local(op); err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := server.ReceiveOperation(ctx, 0, send)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fix, err := c.amend(applied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.doc != "helloworld!" {
+		t.Errorf("expected %q after applying %v, got %q", "helloworld!", fix, c.doc)
+	}
+	next, err := c.ack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.ReceiveOperation(ctx, c.revision, next); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ack(); err != nil {
+		t.Fatal(err)
+	}
+	if doc := server.Document(); c.doc != doc || c.server != doc {
+		t.Errorf("expected the client to have %q, got %q and %q", doc, c.doc, c.server)
+	}
+}
@@ -5,131 +5,88 @@ package ot
 //
 //	apply(apply(S, A), B) = apply(S, compose(A, B))
 //
-// Returns an error if the operations are not composable (A's target length != B's base length).
+// Returns a *ComposeError matching ErrNotSequential if A's target length is
+// not B's base length, or ErrMalformedOp, wrapping a *RemainderError, if the
+// components of either do not add up to its lengths. Both also match
+// ErrIncompatibleLengths.
 //
 // This is a direct port from Rust operational-transform:
 // https://github.com/spebern/operational-transform-rs/blob/master/operational-transform/src/lib.rs#L162-L273
 func (a *OperationSeq) Compose(b *OperationSeq) (*OperationSeq, error) {
-	if a.targetLen != b.baseLen {
-		return nil, ErrIncompatibleLengths
+	result := AcquireOperationSeq()
+	if err := a.ComposeInto(b, result); err != nil {
+		result.Release()
+		return nil, err
 	}
+	return result, nil
+}
 
-	result := NewOperationSeq()
-	ops1 := newOpIterator(a.ops)
-	ops2 := newOpIterator(b.ops)
+// ComposeInto is Compose writing the result into result instead of a new
+// sequence. It is cleared first and keeps its capacity, so a loop that reuses
+// it composes without allocating. It must be distinct from a and b. On error
+// its contents are unspecified.
+func (a *OperationSeq) ComposeInto(b, result *OperationSeq) error {
+	if a.targetLen != b.baseLen {
+		return &ComposeError{Reason: ErrNotSequential, Err: lengthMismatch(a.targetLen, b.baseLen)}
+	}
 
-	op1 := ops1.next()
-	op2 := ops2.next()
+	result.reset()
+	result.grow(len(a.ops) + len(b.ops))
+	ops1 := NewOpReader(a)
+	ops2 := NewOpReader(b)
+	offset := 0 // characters of the intermediate document consumed
 
 	for {
+		op1, op2 := ops1.Peek(), ops2.Peek()
+
 		// Both operations exhausted
-		if op1 == nil && op2 == nil {
-			return result, nil
+		if op1.Kind == 0 && op2.Kind == 0 {
+			result.meta = composeMeta(a.meta, b.meta)
+			if debug {
+				assert("Compose", checkCompose(a, b, result))
+			}
+			return nil
 		}
 
 		// Delete from first operation takes priority
-		if del, ok := op1.(Delete); ok {
-			result.Delete(del.N)
-			op1 = ops1.next()
+		if op1.Kind == KindDelete {
+			result.Delete(op1.N)
+			ops1.Next()
 			continue
 		}
 
 		// Insert from second operation takes priority
-		if ins, ok := op2.(Insert); ok {
-			result.Insert(ins.Text)
-			op2 = ops2.next()
+		if op2.Kind == KindInsert {
+			result.insert(op2.Text, op2.N)
+			ops2.Next()
 			continue
 		}
 
-		// One operation is nil but other isn't
-		if op1 == nil || op2 == nil {
-			return nil, ErrIncompatibleLengths
-		}
-
-		// Handle Retain vs Retain
-		if ret1, ok1 := op1.(Retain); ok1 {
-			if ret2, ok2 := op2.(Retain); ok2 {
-				if ret1.N < ret2.N {
-					result.Retain(ret1.N)
-					op2 = Retain{N: ret2.N - ret1.N}
-					op1 = ops1.next()
-				} else if ret1.N == ret2.N {
-					result.Retain(ret1.N)
-					op1 = ops1.next()
-					op2 = ops2.next()
-				} else {
-					result.Retain(ret2.N)
-					op1 = Retain{N: ret1.N - ret2.N}
-					op2 = ops2.next()
-				}
-				continue
+		// One operation is exhausted but other isn't
+		if op1.Kind == 0 || op2.Kind == 0 {
+			return &ComposeError{
+				AIndex: ops1.index(),
+				BIndex: ops2.index(),
+				Offset: offset,
+				Reason: ErrMalformedOp,
+				Err:    &RemainderError{A: ops1.remaining(KindDelete), B: ops2.remaining(KindInsert)},
 			}
 		}
 
-		// Handle Insert vs Delete
-		if ins, ok1 := op1.(Insert); ok1 {
-			if del, ok2 := op2.(Delete); ok2 {
-				insLen := uint64(charCount(ins.Text))
-				if insLen < del.N {
-					op2 = Delete{N: del.N - insLen}
-					op1 = ops1.next()
-				} else if insLen == del.N {
-					op1 = ops1.next()
-					op2 = ops2.next()
-				} else {
-					// Delete part of the insert
-					runes := []rune(ins.Text)
-					op1 = Insert{Text: string(runes[del.N:])}
-					op2 = ops2.next()
-				}
-				continue
-			}
-		}
-
-		// Handle Insert vs Retain
-		if ins, ok1 := op1.(Insert); ok1 {
-			if ret, ok2 := op2.(Retain); ok2 {
-				insLen := uint64(charCount(ins.Text))
-				if insLen < ret.N {
-					result.Insert(ins.Text)
-					op2 = Retain{N: ret.N - insLen}
-					op1 = ops1.next()
-				} else if insLen == ret.N {
-					result.Insert(ins.Text)
-					op1 = ops1.next()
-					op2 = ops2.next()
-				} else {
-					// Retain part of the insert
-					runes := []rune(ins.Text)
-					result.Insert(string(runes[:ret.N]))
-					op1 = Insert{Text: string(runes[ret.N:])}
-					op2 = ops2.next()
-				}
-				continue
-			}
+		// op1 is Retain or Insert and op2 is Retain or Delete: consume the
+		// shorter, and as much of the longer.
+		n := min(op1.N, op2.N)
+		op1 = ops1.Take(n)
+		ops2.Take(n)
+		offset += int(n)
+		switch {
+		case op1.Kind == KindRetain && op2.Kind == KindRetain:
+			result.Retain(n)
+		case op1.Kind == KindInsert && op2.Kind == KindRetain:
+			result.insert(op1.Text, op1.N)
+		case op1.Kind == KindRetain && op2.Kind == KindDelete:
+			result.Delete(n)
 		}
-
-		// Handle Retain vs Delete
-		if ret, ok1 := op1.(Retain); ok1 {
-			if del, ok2 := op2.(Delete); ok2 {
-				if ret.N < del.N {
-					result.Delete(ret.N)
-					op2 = Delete{N: del.N - ret.N}
-					op1 = ops1.next()
-				} else if ret.N == del.N {
-					result.Delete(del.N)
-					op2 = ops2.next()
-					op1 = ops1.next()
-				} else {
-					result.Delete(del.N)
-					op1 = Retain{N: ret.N - del.N}
-					op2 = ops2.next()
-				}
-				continue
-			}
-		}
-
-		// Should never reach here if operations are valid
-		return nil, ErrIncompatibleLengths
+		// Inserted by a and deleted by b: nothing left to do.
 	}
 }
@@ -1,6 +1,8 @@
 package ot
 
 import (
+	"errors"
+	"reflect"
 	"testing"
 )
 
@@ -169,3 +171,60 @@ func TestComposeProperty(t *testing.T) {
 		}
 	}
 }
+
+func BenchmarkCompose(b *testing.B) {
+	x, y := benchmarkEdits()
+	_, y, err := x.Transform(y)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := x.Compose(y); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestComposeInto(t *testing.T) {
+	a := NewOperationSeq()
+	a.Retain(3)
+	a.Insert("def")
+	b := NewOperationSeq()
+	b.Delete(1)
+	b.Retain(5)
+	want, err := a.Compose(b)
+	if err != nil {
+		t.Fatalf("Compose failed: %v", err)
+	}
+
+	result := NewOperationSeq()
+	result.Insert("stale")
+	for i := 0; i < 3; i++ {
+		if err := a.ComposeInto(b, result); err != nil {
+			t.Fatalf("ComposeInto failed: %v", err)
+		}
+		if !reflect.DeepEqual(result, want) {
+			t.Fatalf("expected %v, got %v", want.ops, result.ops)
+		}
+	}
+
+	if err := b.ComposeInto(b, result); !errors.Is(err, ErrIncompatibleLengths) {
+		t.Errorf("expected ErrIncompatibleLengths, got %v", err)
+	}
+}
+
+func BenchmarkComposeInto(b *testing.B) {
+	x, y := benchmarkEdits()
+	_, y, err := x.Transform(y)
+	if err != nil {
+		b.Fatal(err)
+	}
+	result := NewOperationSeq()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := x.ComposeInto(y, result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package ot
+
+import (
+	"errors"
+	"fmt"
+)
+
+// With the otdebug build tag, public calls check the invariants of the
+// operations they return and panic if one is broken:
+//
+//	go test -tags otdebug ./...
+//
+// Without it, debug is a false constant and the checks compile away.
+
+// checkOp returns an error describing the first invariant o breaks: its
+// lengths must match its components, every component must be non-empty,
+// and it must be normalized, with no two adjacent components of the same
+// kind and no delete directly before an insert.
+func checkOp(o *OperationSeq) error {
+	base, target := 0, 0
+	for i, c := range o.ops {
+		if c.N == 0 {
+			return fmt.Errorf("component %d is empty", i)
+		}
+		switch c.Kind {
+		case KindRetain:
+			base += int(c.N)
+			target += int(c.N)
+		case KindDelete:
+			base += int(c.N)
+		case KindInsert:
+			if n := charCount(c.Text); uint64(n) != c.N {
+				return fmt.Errorf("component %d inserts %d characters but counts %d", i, n, c.N)
+			}
+			target += int(c.N)
+		default:
+			return fmt.Errorf("component %d has unknown kind %d", i, c.Kind)
+		}
+		if i > 0 {
+			prev := o.ops[i-1].Kind
+			if prev == c.Kind {
+				return fmt.Errorf("components %d and %d are both of kind %d", i-1, i, c.Kind)
+			}
+			if prev == KindDelete && c.Kind == KindInsert {
+				return fmt.Errorf("component %d deletes before component %d inserts", i-1, i)
+			}
+		}
+	}
+	if base != o.baseLen || target != o.targetLen {
+		return fmt.Errorf("lengths are %d and %d but components give %d and %d", o.baseLen, o.targetLen, base, target)
+	}
+	return nil
+}
+
+// checkTransform returns an error if the results of transforming a against
+// b are not valid or do not fit: A' must apply after B, B' after A, and both
+// must lead to the same length.
+func checkTransform(a, b, aPrime, bPrime *OperationSeq) error {
+	if err := errors.Join(checkOp(aPrime), checkOp(bPrime)); err != nil {
+		return err
+	}
+	if aPrime.baseLen != b.targetLen || bPrime.baseLen != a.targetLen || aPrime.targetLen != bPrime.targetLen {
+		return fmt.Errorf("results of lengths %d→%d and %d→%d do not fit operations of %d→%d and %d→%d",
+			aPrime.baseLen, aPrime.targetLen, bPrime.baseLen, bPrime.targetLen,
+			a.baseLen, a.targetLen, b.baseLen, b.targetLen)
+	}
+	return nil
+}
+
+// checkCompose returns an error if result, the composition of a and b, is
+// not valid or does not go from the start of a to the end of b.
+func checkCompose(a, b, result *OperationSeq) error {
+	if err := checkOp(result); err != nil {
+		return err
+	}
+	if result.baseLen != a.baseLen || result.targetLen != b.targetLen {
+		return fmt.Errorf("result of lengths %d→%d does not fit operations of %d→%d and %d→%d",
+			result.baseLen, result.targetLen, a.baseLen, a.targetLen, b.baseLen, b.targetLen)
+	}
+	return nil
+}
+
+// assert panics with err, naming where it was found, if err is not nil.
+// Callers guard it with debug, so the check itself is compiled away too.
+func assert(where string, err error) {
+	if err != nil {
+		panic(fmt.Sprintf("ot: %s: broken invariant: %v", where, err))
+	}
+}
@@ -0,0 +1,5 @@
+//go:build !otdebug
+
+package ot
+
+const debug = false
@@ -0,0 +1,73 @@
+package ot
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestCheckOp(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		s := RandomString(r, 20)
+		a := RandomOp(r, charCount(s), RandomOptions{})
+		b := RandomOp(r, charCount(s), RandomOptions{})
+		if err := checkOp(a); err != nil {
+			t.Fatalf("expected %v to be valid, got %v", a, err)
+		}
+		aPrime, bPrime, err := a.Transform(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checkTransform(a, b, aPrime, bPrime); err != nil {
+			t.Fatalf("expected transform of %v and %v to be valid, got %v", a, b, err)
+		}
+		ab, err := a.Compose(bPrime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checkCompose(a, bPrime, ab); err != nil {
+			t.Fatalf("expected compose of %v and %v to be valid, got %v", a, bPrime, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		op   *OperationSeq
+		want string
+	}{
+		{"empty", &OperationSeq{ops: []Component{{Kind: KindRetain}}}, "empty"},
+		{"count", &OperationSeq{ops: []Component{{Kind: KindInsert, N: 2, Text: "é"}}, targetLen: 2}, "inserts 1 characters but counts 2"},
+		{"merge", &OperationSeq{ops: []Component{{Kind: KindRetain, N: 1}, {Kind: KindRetain, N: 1}}, baseLen: 2, targetLen: 2}, "are both"},
+		{"order", &OperationSeq{ops: []Component{{Kind: KindDelete, N: 1}, {Kind: KindInsert, N: 1, Text: "x"}}, baseLen: 1, targetLen: 1}, "deletes before"},
+		{"lengths", &OperationSeq{ops: []Component{{Kind: KindRetain, N: 2}}, baseLen: 1, targetLen: 2}, "lengths are 1 and 2"},
+	}
+	for _, tt := range tests {
+		err := checkOp(tt.op)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestCheckTransformLengths(t *testing.T) {
+	a := NewOperationSeq()
+	a.Retain(2)
+	a.Insert("x")
+	b := NewOperationSeq()
+	b.Retain(2)
+	// B' must retain the character a inserted.
+	if err := checkTransform(a, b, b, b); err == nil {
+		t.Errorf("expected results that do not fit to be reported")
+	}
+}
+
+func TestAssert(t *testing.T) {
+	assert("nothing", nil)
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "ot: Compose: broken invariant") {
+			t.Errorf("expected a panic naming Compose, got %v", r)
+		}
+	}()
+	assert("Compose", checkOp(&OperationSeq{baseLen: 1}))
+}
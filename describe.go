@@ -0,0 +1,67 @@
+package ot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// describeMax is the number of characters of a run Describe shows before
+// eliding the middle of it.
+const describeMax = 40
+
+// Describe returns a breakdown of o for logs and bug reports: its lengths,
+// then a line per component with the text of doc it retains or deletes, or
+// the text it inserts:
+//
+//	base 12 → target 9
+//	retain 6 ⇒ "hello "
+//	insert 2 ⇒ "my"
+//	delete 5 ⇒ "world"
+//	retain 1 ⇒ "!"
+//
+// Runs longer than 40 characters are shortened by eliding their middle.
+// Describe does not fail if o does not apply to doc: the lengths line says
+// so, and components past the end of doc are marked.
+func (o *OperationSeq) Describe(doc string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "base %d → target %d", o.baseLen, o.targetLen)
+	if n := charCount(doc); n != o.baseLen {
+		fmt.Fprintf(&b, " (document has %d)", n)
+	}
+
+	pos := 0 // byte offset in doc
+	for _, c := range o.ops {
+		var name, text string
+		switch c.Kind {
+		case KindRetain, KindDelete:
+			name = "retain"
+			if c.Kind == KindDelete {
+				name = "delete"
+			}
+			end := skipRunes(doc, pos, c.N)
+			text = fmt.Sprintf("%q", elide(doc[pos:end]))
+			if missing := int(c.N) - charCount(doc[pos:end]); missing > 0 {
+				text += fmt.Sprintf(" and %d past the end", missing)
+			}
+			pos = end
+		case KindInsert:
+			name, text = "insert", fmt.Sprintf("%q", elide(c.Text))
+		}
+		fmt.Fprintf(&b, "\n%s %d ⇒ %s", name, c.N, text)
+	}
+	if pos < len(doc) {
+		fmt.Fprintf(&b, "\n%d left over ⇒ %q", charCount(doc[pos:]), elide(doc[pos:]))
+	}
+	return b.String()
+}
+
+// elide returns s, or its start and end around an ellipsis if it is longer
+// than describeMax characters.
+func elide(s string) string {
+	if charCount(s) <= describeMax {
+		return s
+	}
+	r := []rune(s)
+	half := describeMax / 2
+	return string(r[:half]) + "…" + string(r[len(r)-half:])
+}
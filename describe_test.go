@@ -0,0 +1,53 @@
+package ot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	op := NewOperationSeq()
+	op.Retain(6)
+	op.Insert("my")
+	op.Delete(5)
+	op.Retain(1)
+
+	want := `base 12 → target 9
+retain 6 ⇒ "hello "
+insert 2 ⇒ "my"
+delete 5 ⇒ "world"
+retain 1 ⇒ "!"`
+	if got := op.Describe("hello world!"); got != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+
+	want = `base 12 → target 9 (document has 9)
+retain 6 ⇒ "hello "
+insert 2 ⇒ "my"
+delete 5 ⇒ "wor" and 2 past the end
+retain 1 ⇒ "" and 1 past the end`
+	if got := op.Describe("hello wor"); got != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+
+	want = `base 12 → target 9 (document has 14)
+retain 6 ⇒ "hello "
+insert 2 ⇒ "my"
+delete 5 ⇒ "world"
+retain 1 ⇒ "!"
+2 left over ⇒ "!!"`
+	if got := op.Describe("hello world!!!"); got != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestDescribeElides(t *testing.T) {
+	doc := strings.Repeat("é", 30) + strings.Repeat("x", 30)
+	op := NewOperationSeq()
+	op.Retain(60)
+
+	want := `retain 60 ⇒ "` + strings.Repeat("é", 20) + "…" + strings.Repeat("x", 20) + `"`
+	if got := op.Describe(doc); !strings.HasSuffix(got, "\n"+want) {
+		t.Errorf("expected a line %s, got\n%s", want, got)
+	}
+}
@@ -0,0 +1,87 @@
+package ot
+
+// Squash composes the operations that took the document from revision from
+// to revision to into one, such as to show a burst of edits as a single
+// change or export it as a clean patch. Their metadata is merged as Compose
+// merges it. A range with no operations gives the identity on the document
+// at from.
+//
+// Returns ErrInvalidRevision if the range is out of order or outside the
+// history, and ErrRevisionCompacted if from is older than the history kept.
+func (s *Server) Squash(from, to int) (*OperationSeq, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.squash(from, to)
+}
+
+// DiffRevisions returns an operation that turns the document at revision a
+// into the document at revision b. For a before b it is Squash(a, b); for a
+// after b it is the inverse of Squash(b, a), which undoes the operations in
+// between.
+//
+// Returns ErrInvalidRevision or ErrRevisionCompacted if either revision is
+// outside the history kept.
+func (s *Server) DiffRevisions(a, b int) (*OperationSeq, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if a <= b {
+		return s.squash(a, b)
+	}
+	op, err := s.squash(b, a)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := s.documentAt(b)
+	if err != nil {
+		return nil, err
+	}
+	return op.InvertChecked(doc)
+}
+
+// squash implements Squash. Callers hold s.mu.
+func (s *Server) squash(from, to int) (*OperationSeq, error) {
+	if err := s.checkRevision(from); err != nil {
+		return nil, err
+	}
+	if to < from || to > s.revision() {
+		return nil, ErrInvalidRevision
+	}
+	ops := s.history[from-s.base : to-s.base]
+	n := charCount(s.doc)
+	if len(ops) > 0 {
+		n = ops[0].baseLen
+	} else if from < s.revision() {
+		n = s.history[from-s.base].baseLen
+	}
+	return composeAll(n, ops)
+}
+
+// composeAll composes ops, consecutive operations starting from a document
+// of length n, into one. With no operations it returns the identity on such
+// a document.
+func composeAll(n int, ops []*OperationSeq) (*OperationSeq, error) {
+	composed := NewOperationSeq()
+	composed.Retain(uint64(n))
+	for _, op := range ops {
+		var err error
+		if composed, err = composed.Compose(op); err != nil {
+			return nil, err
+		}
+	}
+	return composed, nil
+}
+
+// diffText returns an operation that turns a into b by replacing the span
+// between their common prefix and suffix. It is exact but coarse, for when
+// the operations between the two are no longer known.
+func diffText(a, b string) *OperationSeq {
+	p := commonPrefix(a, b)
+	s := commonSuffix(a[p:], b[p:])
+	op := NewOperationSeq()
+	op.Retain(uint64(charCount(a[:p])))
+	op.Insert(b[p : len(b)-s])
+	op.Delete(uint64(charCount(a[p : len(a)-s])))
+	op.Retain(uint64(charCount(a[len(a)-s:])))
+	return op
+}
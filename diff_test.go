@@ -0,0 +1,83 @@
+package ot
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestServerSquash(t *testing.T) {
+	s := NewServer("")
+	for _, text := range []string{"a", "b", "c"} {
+		appendText(t, s, text)
+	}
+
+	op, err := s.Squash(1, 3)
+	if err != nil {
+		t.Fatalf("Squash failed: %v", err)
+	}
+	if got, err := op.Apply("a"); err != nil || got != "abc" {
+		t.Errorf("expected %q, got %q (%v)", "abc", got, err)
+	}
+	if op, err = s.Squash(2, 2); err != nil || op.BaseLen() != 2 || !op.IsNoop() {
+		t.Errorf("expected the identity on 2 characters, got %v (%v)", op, err)
+	}
+	if op, err = s.Squash(3, 3); err != nil || op.BaseLen() != 3 || !op.IsNoop() {
+		t.Errorf("expected the identity on 3 characters, got %v (%v)", op, err)
+	}
+
+	for _, r := range [][2]int{{2, 1}, {0, 4}, {-1, 1}} {
+		if _, err := s.Squash(r[0], r[1]); !errors.Is(err, ErrInvalidRevision) {
+			t.Errorf("%v: expected ErrInvalidRevision, got %v", r, err)
+		}
+	}
+	if err := s.Compact(context.Background(), 2); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if _, err := s.Squash(1, 3); !errors.Is(err, ErrRevisionCompacted) {
+		t.Errorf("expected ErrRevisionCompacted, got %v", err)
+	}
+}
+
+func TestServerSquashMeta(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer("")
+	s.SetStampMeta(true)
+	for i, client := range []string{"alice", "bob"} {
+		op := NewOperationSeq()
+		op.Retain(uint64(i))
+		op.Insert("x")
+		if _, _, err := s.SubmitOp(ctx, client, "", i, op); err != nil {
+			t.Fatalf("SubmitOp failed: %v", err)
+		}
+	}
+	op, err := s.Squash(0, 2)
+	if err != nil {
+		t.Fatalf("Squash failed: %v", err)
+	}
+	if got := op.Meta().Author; got != "bob" {
+		t.Errorf("expected the later author, got %q", got)
+	}
+}
+
+func TestServerDiffRevisions(t *testing.T) {
+	s := NewServer("")
+	for _, text := range []string{"one", " two", " three"} {
+		appendText(t, s, text)
+	}
+	docs := []string{"", "one", "one two", "one two three"}
+	for a := range docs {
+		for b := range docs {
+			op, err := s.DiffRevisions(a, b)
+			if err != nil {
+				t.Fatalf("DiffRevisions(%d, %d) failed: %v", a, b, err)
+			}
+			if got, err := op.Apply(docs[a]); err != nil || got != docs[b] {
+				t.Errorf("DiffRevisions(%d, %d): expected %q, got %q (%v)", a, b, docs[b], got, err)
+			}
+		}
+	}
+	if _, err := s.DiffRevisions(4, 0); !errors.Is(err, ErrInvalidRevision) {
+		t.Errorf("expected ErrInvalidRevision, got %v", err)
+	}
+}
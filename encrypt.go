@@ -0,0 +1,294 @@
+package ot
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrDecrypt is returned by EncryptedStore when stored data cannot be
+// decrypted, because the key is wrong or the data was altered.
+var ErrDecrypt = errors.New("cannot decrypt stored data")
+
+// KeyFunc returns the AES key, 16, 24 or 32 bytes long, that encrypts doc.
+type KeyFunc func(ctx context.Context, doc string) ([]byte, error)
+
+// DeriveKeys returns a KeyFunc that derives a 32-byte key for each document
+// from master, so only one secret needs managing.
+func DeriveKeys(master []byte) KeyFunc {
+	return func(_ context.Context, doc string) ([]byte, error) {
+		mac := hmac.New(sha256.New, master)
+		mac.Write([]byte(doc)) //nolint:errcheck // hash writes never fail
+		return mac.Sum(nil), nil
+	}
+}
+
+// EncryptedStore wraps a Store, encrypting the text of inserts and
+// snapshots with AES-GCM before they reach it.
+//
+// Stored operations keep their lengths: the text of each insert is replaced
+// by as many U+FFFD characters, and sealed, together with Meta.Origin, into
+// the Origin of the stored operation. Retain and delete counts, Meta.Author
+// and Meta.Time stay in the clear, so the store can still check, apply and
+// compose operations by length and answer history queries, but it cannot
+// read the text, and must save the Origin it is given unchanged. Snapshots
+// are sealed whole, so only the Server can compact the history into them.
+// Each ciphertext is bound to its document and revision, so the store
+// cannot move it elsewhere without ErrDecrypt.
+//
+// EncryptedStore implements Trimmer and HistoryQuerier, passing them on to
+// the wrapped store when it does.
+type EncryptedStore struct {
+	store Store
+	keys  KeyFunc
+}
+
+// NewEncryptedStore returns a Store that encrypts what it saves to store
+// with the keys from keys.
+func NewEncryptedStore(store Store, keys KeyFunc) *EncryptedStore {
+	return &EncryptedStore{store: store, keys: keys}
+}
+
+// SaveOp implements Store.
+func (e *EncryptedStore) SaveOp(ctx context.Context, doc string, revision int, op *OperationSeq) error {
+	aead, err := e.aead(ctx, doc)
+	if err != nil {
+		return err
+	}
+	sealed, err := sealOp(aead, op, opData(doc, revision))
+	if err != nil {
+		return err
+	}
+	return e.store.SaveOp(ctx, doc, revision, sealed)
+}
+
+// LoadOpsSince implements Store.
+func (e *EncryptedStore) LoadOpsSince(ctx context.Context, doc string, revision int) ([]*OperationSeq, error) {
+	ops, err := e.store.LoadOpsSince(ctx, doc, revision)
+	if err != nil || len(ops) == 0 {
+		return ops, err
+	}
+	aead, err := e.aead(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	opened := make([]*OperationSeq, len(ops))
+	for i, op := range ops {
+		if opened[i], err = openOp(aead, doc, revision+i, op); err != nil {
+			return nil, err
+		}
+	}
+	return opened, nil
+}
+
+// SaveSnapshot implements Store.
+func (e *EncryptedStore) SaveSnapshot(ctx context.Context, doc string, revision int, content string) error {
+	aead, err := e.aead(ctx, doc)
+	if err != nil {
+		return err
+	}
+	sealed, err := seal(aead, content, snapshotData(doc, revision))
+	if err != nil {
+		return err
+	}
+	return e.store.SaveSnapshot(ctx, doc, revision, sealed)
+}
+
+// LoadLatestSnapshot implements Store. Empty content is a missing snapshot
+// and is returned as is.
+func (e *EncryptedStore) LoadLatestSnapshot(ctx context.Context, doc string) (string, int, error) {
+	sealed, revision, err := e.store.LoadLatestSnapshot(ctx, doc)
+	if err != nil || sealed == "" {
+		return sealed, revision, err
+	}
+	aead, err := e.aead(ctx, doc)
+	if err != nil {
+		return "", 0, err
+	}
+	content, err := open(aead, sealed, snapshotData(doc, revision))
+	if err != nil {
+		return "", 0, err
+	}
+	return content, revision, nil
+}
+
+// TrimOps implements Trimmer. It does nothing if the wrapped store does not.
+func (e *EncryptedStore) TrimOps(ctx context.Context, doc string, revision int) error {
+	if t, ok := e.store.(Trimmer); ok {
+		return t.TrimOps(ctx, doc, revision)
+	}
+	return nil
+}
+
+// OpsBetween implements HistoryQuerier.
+func (e *EncryptedStore) OpsBetween(ctx context.Context, doc string, from, to time.Time) ([]HistoryEntry, error) {
+	if _, ok := e.store.(HistoryQuerier); !ok {
+		return queryOps(ctx, e, doc, func(m Meta) bool { return inWindow(m.Time, from, to) })
+	}
+	entries, err := OpsBetween(ctx, e.store, doc, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return e.openEntries(ctx, doc, entries)
+}
+
+// OpsByAuthor implements HistoryQuerier.
+func (e *EncryptedStore) OpsByAuthor(ctx context.Context, doc, author string) ([]HistoryEntry, error) {
+	if _, ok := e.store.(HistoryQuerier); !ok {
+		return queryOps(ctx, e, doc, func(m Meta) bool { return m.Author == author })
+	}
+	entries, err := OpsByAuthor(ctx, e.store, doc, author)
+	if err != nil {
+		return nil, err
+	}
+	return e.openEntries(ctx, doc, entries)
+}
+
+func (e *EncryptedStore) openEntries(ctx context.Context, doc string, entries []HistoryEntry) ([]HistoryEntry, error) {
+	if len(entries) == 0 {
+		return entries, nil
+	}
+	aead, err := e.aead(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	opened := make([]HistoryEntry, len(entries))
+	for i, entry := range entries {
+		opened[i] = HistoryEntry{Revision: entry.Revision}
+		if opened[i].Op, err = openOp(aead, doc, entry.Revision, entry.Op); err != nil {
+			return nil, err
+		}
+	}
+	return opened, nil
+}
+
+func (e *EncryptedStore) aead(ctx context.Context, doc string) (cipher.AEAD, error) {
+	key, err := e.keys(ctx, doc)
+	if err != nil {
+		return nil, fmt.Errorf("key for %s: %w", doc, err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("key for %s: %w", doc, err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// redacted stands in for each character of the text of a stored insert.
+const redacted = "\uFFFD"
+
+// sealOp returns a copy of op with the text of each insert replaced by as
+// many redacted characters, and sealed, each text preceded by its length
+// in bytes and followed by op's Meta.Origin, into the Origin of the copy.
+func sealOp(aead cipher.AEAD, op *OperationSeq, data []byte) (*OperationSeq, error) {
+	m := op.Meta()
+	var plain []byte
+	out := WithCapacity(len(op.ops))
+	for _, c := range op.ops {
+		switch c.Kind {
+		case KindRetain:
+			out.Retain(c.N)
+		case KindDelete:
+			out.Delete(c.N)
+		case KindInsert:
+			plain = binary.AppendUvarint(plain, uint64(len(c.Text)))
+			plain = append(plain, c.Text...)
+			out.insert(strings.Repeat(redacted, int(c.N)), c.N)
+		}
+	}
+	if plain == nil && m.Origin == "" {
+		out.meta = op.meta
+		return out, nil
+	}
+	sealed, err := seal(aead, string(append(plain, m.Origin...)), data)
+	if err != nil {
+		return nil, err
+	}
+	m.Origin = sealed
+	out.SetMeta(m)
+	return out, nil
+}
+
+// openOp reverses sealOp for an operation saved at revision of doc.
+func openOp(aead cipher.AEAD, doc string, revision int, op *OperationSeq) (*OperationSeq, error) {
+	m := op.Meta()
+	if m.Origin == "" {
+		for _, c := range op.ops {
+			if c.Kind == KindInsert {
+				return nil, ErrDecrypt
+			}
+		}
+		return op, nil
+	}
+	opened, err := open(aead, m.Origin, opData(doc, revision))
+	if err != nil {
+		return nil, err
+	}
+	plain := []byte(opened)
+	out := WithCapacity(len(op.ops))
+	for _, c := range op.ops {
+		switch c.Kind {
+		case KindRetain:
+			out.Retain(c.N)
+		case KindDelete:
+			out.Delete(c.N)
+		case KindInsert:
+			size, n := binary.Uvarint(plain)
+			if n <= 0 || size > uint64(len(plain)-n) {
+				return nil, ErrDecrypt
+			}
+			text := string(plain[n : n+int(size)])
+			if uint64(charCount(text)) != c.N {
+				return nil, ErrDecrypt
+			}
+			out.insert(text, c.N)
+			plain = plain[n+int(size):]
+		}
+	}
+	m.Origin = string(plain)
+	out.SetMeta(m)
+	return out, nil
+}
+
+// seal encrypts text as base64 of a random nonce followed by the
+// ciphertext, authenticating data with it.
+func seal(aead cipher.AEAD, text string, data []byte) (string, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(text)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawStdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(text), data)), nil
+}
+
+func open(aead cipher.AEAD, sealed string, data []byte) (string, error) {
+	raw, err := base64.RawStdEncoding.DecodeString(sealed)
+	if err != nil || len(raw) < aead.NonceSize() {
+		return "", ErrDecrypt
+	}
+	text, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], data)
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(text), nil
+}
+
+// opData and snapshotData return the additional data that binds a
+// ciphertext to where it was saved.
+func opData(doc string, revision int) []byte {
+	data := append([]byte("op\x00"), doc...)
+	return binary.AppendUvarint(append(data, 0), uint64(revision))
+}
+
+func snapshotData(doc string, revision int) []byte {
+	data := append([]byte("snapshot\x00"), doc...)
+	return binary.AppendUvarint(append(data, 0), uint64(revision))
+}
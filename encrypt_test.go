@@ -0,0 +1,112 @@
+package ot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncryptedStore(t *testing.T) {
+	ctx := context.Background()
+	inner := &memStore{}
+	store := NewEncryptedStore(inner, DeriveKeys([]byte("master")))
+
+	s, err := OpenServer(ctx, store, "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	appendText(t, s, "secret plans")
+	appendText(t, s, " and more")
+	if err := s.Compact(ctx, 1); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+
+	for i, op := range inner.ops {
+		if strings.Contains(op.String(), "secret") || strings.Contains(op.String(), "more") {
+			t.Errorf("op %d: expected text encrypted, got %v", i, op)
+		}
+	}
+	if strings.Contains(inner.snapshot, "secret") {
+		t.Errorf("expected snapshot encrypted, got %q", inner.snapshot)
+	}
+	// The second op still retains the first's text in the clear.
+	if got := inner.ops[1].BaseLen(); got != 12 {
+		t.Errorf("expected base length 12, got %d", got)
+	}
+
+	reopened, err := OpenServer(ctx, store, "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	if got := reopened.Document(); got != "secret plans and more" {
+		t.Errorf("expected the document restored, got %q", got)
+	}
+
+	wrong := NewEncryptedStore(inner, DeriveKeys([]byte("other")))
+	if _, err := OpenServer(ctx, wrong, "doc"); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("expected ErrDecrypt with the wrong key, got %v", err)
+	}
+	// A ciphertext moved to another document does not decrypt.
+	if _, err := store.LoadOpsSince(ctx, "other", 1); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("expected ErrDecrypt for another document, got %v", err)
+	}
+}
+
+// TestEncryptedStoreKeepsLengths checks that the stored operations apply
+// and compose by length like the ones saved, without revealing their text
+// or origin.
+func TestEncryptedStoreKeepsLengths(t *testing.T) {
+	ctx := context.Background()
+	inner := &memStore{}
+	store := NewEncryptedStore(inner, DeriveKeys([]byte("master")))
+
+	first := NewOperationSeq()
+	first.Insert("héllo wörld 🌍")
+	first.SetMeta(Meta{Author: "ada", Origin: "laptop"})
+	second := NewOperationSeq()
+	second.Retain(6)
+	second.Insert("new ")
+	second.Delete(5)
+	second.Retain(2)
+	for i, op := range []*OperationSeq{first, second} {
+		if err := store.SaveOp(ctx, "doc", i, op); err != nil {
+			t.Fatalf("SaveOp failed: %v", err)
+		}
+	}
+
+	doc, err := ReplayLog("", inner.ops)
+	if err != nil {
+		t.Fatalf("stored ops do not apply: %v", err)
+	}
+	composed, err := inner.ops[0].Compose(inner.ops[1])
+	if err != nil {
+		t.Fatalf("stored ops do not compose: %v", err)
+	}
+	if want := strings.Repeat("\uFFFD", 12); doc != want || composed.TargetLen() != 12 {
+		t.Errorf("expected %q of 12 characters, got %q and %d", want, doc, composed.TargetLen())
+	}
+	if origin := inner.ops[0].Meta().Origin; strings.Contains(origin, "laptop") {
+		t.Errorf("expected the origin encrypted, got %q", origin)
+	}
+	if got := inner.ops[0].Meta().Author; got != "ada" {
+		t.Errorf("expected the author in the clear, got %q", got)
+	}
+
+	ops, err := store.LoadOpsSince(ctx, "doc", 0)
+	if err != nil {
+		t.Fatalf("LoadOpsSince failed: %v", err)
+	}
+	if ops[0].String() != first.String() || ops[0].Meta() != first.Meta() || ops[1].String() != second.String() {
+		t.Errorf("expected the saved ops back, got %v and %v", ops[0], ops[1])
+	}
+}
+
+func TestEncryptedStoreBadKey(t *testing.T) {
+	store := NewEncryptedStore(&memStore{}, func(context.Context, string) ([]byte, error) {
+		return []byte("short"), nil
+	})
+	if err := store.SaveSnapshot(context.Background(), "doc", 0, "x"); err == nil {
+		t.Errorf("expected an error for a bad key")
+	}
+}
@@ -0,0 +1,88 @@
+package ot
+
+import "fmt"
+
+// LengthMismatchError reports an operation that does not fit what it was
+// given: a document, or another operation. It matches
+// ErrIncompatibleLengths with errors.Is.
+type LengthMismatchError struct {
+	// Want is the length the operation needed, and Got the length it was
+	// given.
+	Want int
+	Got  int
+
+	// OpIndex is the index of the component at which the mismatch was
+	// found, or -1 if the lengths were compared before any component.
+	OpIndex int
+}
+
+func (e *LengthMismatchError) Error() string {
+	if e.OpIndex < 0 {
+		return fmt.Sprintf("%v: want %d, got %d", ErrIncompatibleLengths, e.Want, e.Got)
+	}
+	return fmt.Sprintf("%v at component %d: want %d, got %d", ErrIncompatibleLengths, e.OpIndex, e.Want, e.Got)
+}
+
+func (e *LengthMismatchError) Unwrap() error { return ErrIncompatibleLengths }
+
+// lengthMismatch returns a *LengthMismatchError for lengths compared before
+// any component.
+func lengthMismatch(want, got int) error {
+	return &LengthMismatchError{Want: want, Got: got, OpIndex: -1}
+}
+
+// RemainderError reports two operations walked side by side in which one ran
+// out of components while the other still had characters to consume, so
+// the components of one of them do not add up to its lengths. A and B are
+// the characters each had left, one of them zero. It matches
+// ErrIncompatibleLengths with errors.Is.
+type RemainderError struct {
+	A int
+	B int
+}
+
+func (e *RemainderError) Error() string {
+	return fmt.Sprintf("%v: %d characters left in a, %d in b", ErrIncompatibleLengths, e.A, e.B)
+}
+
+func (e *RemainderError) Unwrap() error { return ErrIncompatibleLengths }
+
+// TransformError reports where transforming A against B failed: the
+// components of each that had been reached, and the offset in the document
+// they share. Reason is ErrNotConcurrent or ErrMalformedOp, and the error
+// matches it with errors.Is as well as Err.
+type TransformError struct {
+	AIndex int
+	BIndex int
+	Offset int
+	Reason error
+	Err    error
+}
+
+func (e *TransformError) Error() string {
+	return fmt.Sprintf("transform at offset %d (component %d of a, %d of b): %v: %v", e.Offset, e.AIndex, e.BIndex, e.Reason, e.Err)
+}
+
+func (e *TransformError) Unwrap() error { return e.Err }
+
+func (e *TransformError) Is(target error) bool { return target == e.Reason }
+
+// ComposeError reports where composing A with B failed: the components of
+// each that had been reached, and the offset in the document between them,
+// which A produces and B applies to. Reason is ErrNotSequential or
+// ErrMalformedOp, and the error matches it with errors.Is as well as Err.
+type ComposeError struct {
+	AIndex int
+	BIndex int
+	Offset int
+	Reason error
+	Err    error
+}
+
+func (e *ComposeError) Error() string {
+	return fmt.Sprintf("compose at offset %d (component %d of a, %d of b): %v: %v", e.Offset, e.AIndex, e.BIndex, e.Reason, e.Err)
+}
+
+func (e *ComposeError) Unwrap() error { return e.Err }
+
+func (e *ComposeError) Is(target error) bool { return target == e.Reason }
@@ -0,0 +1,107 @@
+package ot
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLengthMismatchError(t *testing.T) {
+	op := NewOperationSeq()
+	op.Retain(3)
+	op.Insert("x")
+
+	_, err := op.Apply("ab")
+	var mismatch *LengthMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Want != 3 || mismatch.Got != 2 || mismatch.OpIndex != -1 {
+		t.Fatalf("expected a mismatch of 3 and 2, got %v", err)
+	}
+	if !errors.Is(err, ErrIncompatibleLengths) {
+		t.Errorf("expected the error to match ErrIncompatibleLengths")
+	}
+	if want := "incompatible lengths: want 3, got 2"; err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+	at := &LengthMismatchError{Want: 3, Got: 2, OpIndex: 1}
+	if want := "incompatible lengths at component 1: want 3, got 2"; at.Error() != want {
+		t.Errorf("expected %q, got %q", want, at.Error())
+	}
+}
+
+func TestTransformComposeErrors(t *testing.T) {
+	a := NewOperationSeq()
+	a.Retain(3)
+	b := NewOperationSeq()
+	b.Retain(2)
+
+	_, _, err := a.Transform(b)
+	var terr *TransformError
+	var mismatch *LengthMismatchError
+	if !errors.As(err, &terr) || !errors.As(err, &mismatch) || !errors.Is(err, ErrIncompatibleLengths) {
+		t.Fatalf("expected a *TransformError wrapping a *LengthMismatchError, got %v", err)
+	}
+	if mismatch.Want != 3 || mismatch.Got != 2 {
+		t.Errorf("expected a mismatch of 3 and 2, got %+v", mismatch)
+	}
+	if !errors.Is(err, ErrNotConcurrent) || errors.Is(err, ErrMalformedOp) {
+		t.Errorf("expected the error to match only ErrNotConcurrent, got %v", err)
+	}
+	if want := "transform at offset 0 (component 0 of a, 0 of b): operations are not concurrent: incompatible lengths: want 3, got 2"; err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+
+	_, err = b.Compose(a)
+	var cerr *ComposeError
+	if !errors.As(err, &cerr) || !errors.Is(err, ErrIncompatibleLengths) {
+		t.Fatalf("expected a *ComposeError, got %v", err)
+	}
+	if !errors.Is(err, ErrNotSequential) || errors.Is(err, ErrMalformedOp) {
+		t.Errorf("expected the error to match only ErrNotSequential, got %v", err)
+	}
+
+	// Lengths that agree with components that do not are caught where
+	// the components run out.
+	bad := &OperationSeq{ops: []Component{{Kind: KindRetain, N: 1}, {Kind: KindInsert, N: 1, Text: "x"}}, baseLen: 3, targetLen: 2}
+	_, _, err = bad.Transform(a)
+	if !errors.As(err, &terr) || terr.AIndex != 2 || terr.BIndex != 0 || terr.Offset != 1 {
+		t.Errorf("expected a failure at offset 1, component 2 of a and 0 of b, got %v", err)
+	}
+	if !errors.Is(err, ErrMalformedOp) || errors.Is(err, ErrNotConcurrent) {
+		t.Errorf("expected the error to match only ErrMalformedOp, got %v", err)
+	}
+	if want := "transform at offset 1 (component 2 of a, 0 of b): malformed operation: incompatible lengths: 0 characters left in a, 2 in b"; err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+	_, err = a.Compose(bad)
+	if !errors.As(err, &cerr) || cerr.AIndex != 0 || cerr.BIndex != 2 || cerr.Offset != 1 {
+		t.Errorf("expected a failure at offset 1, component 0 of a and 2 of b, got %v", err)
+	}
+	if !errors.Is(err, ErrMalformedOp) || errors.Is(err, ErrNotSequential) {
+		t.Errorf("expected the error to match only ErrMalformedOp, got %v", err)
+	}
+	var remainder *RemainderError
+	if !errors.As(err, &remainder) || remainder.A != 2 || remainder.B != 0 {
+		t.Errorf("expected 2 characters left in a and none in b, got %v", err)
+	}
+	if want := "compose at offset 1 (component 0 of a, 2 of b): malformed operation: incompatible lengths: 2 characters left in a, 0 in b"; err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+}
+
+func TestApplyWalksOffDocument(t *testing.T) {
+	// Lengths that agree with the document but not with the components.
+	tests := []struct {
+		op   *OperationSeq
+		want LengthMismatchError
+	}{
+		{&OperationSeq{ops: []Component{{Kind: KindRetain, N: 2}, {Kind: KindRetain, N: 4}}, baseLen: 3, targetLen: 3}, LengthMismatchError{Want: 6, Got: 3, OpIndex: 1}},
+		{&OperationSeq{ops: []Component{{Kind: KindDelete, N: 5}}, baseLen: 3}, LengthMismatchError{Want: 5, Got: 3, OpIndex: 0}},
+		{&OperationSeq{ops: []Component{{Kind: KindRetain, N: 1}}, baseLen: 3, targetLen: 3}, LengthMismatchError{Want: 1, Got: 3, OpIndex: 1}},
+	}
+	for i, tt := range tests {
+		_, err := tt.op.Apply("abc")
+		var mismatch *LengthMismatchError
+		if !errors.As(err, &mismatch) || *mismatch != tt.want {
+			t.Errorf("test %d: expected %+v, got %v", i, tt.want, err)
+		}
+	}
+}
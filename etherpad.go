@@ -0,0 +1,243 @@
+package ot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Etherpad changeset format:
+//
+//	Z:<oldLen><sign><delta><ops>$<charBank>
+//
+// Lengths are base-36 numbers counting UTF-16 code units, as in JavaScript.
+// <sign> is '>' when the document grows and '<' when it shrinks. Each op is
+// an optional list of attributes (*n), an optional newline count (|n), an
+// opcode ('=' keep, '-' remove, '+' insert) and a length. Inserted text is
+// taken in order from <charBank>. A trailing keep is implicit.
+//
+// Because keeps and removes do not carry text, converting lengths between
+// UTF-16 units and characters requires the document the changeset applies to.
+// Attributes are ignored on decode and never emitted on encode.
+//
+// Reference: https://github.com/ether/etherpad-lite/blob/develop/doc/easysync/easysync-full-description.tex
+
+// EncodeEtherpad returns the Etherpad changeset equivalent to o applied to doc.
+func (o *OperationSeq) EncodeEtherpad(doc string) (string, error) {
+	if n := charCount(doc); n != o.baseLen {
+		return "", lengthMismatch(o.baseLen, n)
+	}
+
+	var ops []string
+	var bank strings.Builder
+	newLen := 0
+	emit := func(opcode byte, text string) {
+		if opcode != '-' {
+			newLen += utf16Len(text)
+		}
+		if last := strings.LastIndexByte(text, '\n'); last >= 0 {
+			lines := text[:last+1]
+			ops = append(ops, "|"+base36(strings.Count(lines, "\n"))+string(opcode)+base36(utf16Len(lines)))
+			text = text[last+1:]
+		}
+		if text != "" {
+			ops = append(ops, string(opcode)+base36(utf16Len(text)))
+		}
+	}
+
+	pos := 0 // byte offset into doc
+	take := func(n uint64) string {
+		start := pos
+		for i := uint64(0); i < n; i++ {
+			_, size := utf8.DecodeRuneInString(doc[pos:])
+			pos += size
+		}
+		return doc[start:pos]
+	}
+
+	for i := 0; i < len(o.ops); i++ {
+		switch v := o.ops[i]; v.Kind {
+		case KindRetain:
+			emit('=', take(v.N))
+		case KindDelete:
+			emit('-', take(v.N))
+		case KindInsert:
+			// Etherpad places removals before insertions at the same position.
+			if i+1 < len(o.ops) {
+				if del := o.ops[i+1]; del.Kind == KindDelete {
+					emit('-', take(del.N))
+					i++
+				}
+			}
+			emit('+', v.Text)
+			bank.WriteString(v.Text)
+		}
+	}
+
+	for len(ops) > 0 && strings.ContainsRune(ops[len(ops)-1], '=') {
+		ops = ops[:len(ops)-1]
+	}
+
+	oldLen := utf16Len(doc)
+	var sb strings.Builder
+	sb.WriteString("Z:")
+	sb.WriteString(base36(oldLen))
+	if newLen >= oldLen {
+		sb.WriteString(">" + base36(newLen-oldLen))
+	} else {
+		sb.WriteString("<" + base36(oldLen-newLen))
+	}
+	for _, op := range ops {
+		sb.WriteString(op)
+	}
+	sb.WriteByte('$')
+	sb.WriteString(bank.String())
+	return sb.String(), nil
+}
+
+// DecodeEtherpad parses an Etherpad changeset that applies to doc.
+func DecodeEtherpad(changeset, doc string) (*OperationSeq, error) {
+	if !strings.HasPrefix(changeset, "Z:") {
+		return nil, fmt.Errorf("invalid etherpad changeset: missing Z: prefix")
+	}
+	s := changeset[2:]
+
+	oldLen, s, err := parseBase36(s)
+	if err != nil {
+		return nil, err
+	}
+	if s == "" || (s[0] != '>' && s[0] != '<') {
+		return nil, fmt.Errorf("invalid etherpad changeset: missing length delta")
+	}
+	sign := s[0]
+	delta, s, err := parseBase36(s[1:])
+	if err != nil {
+		return nil, err
+	}
+	newLen := oldLen + delta
+	if sign == '<' {
+		newLen = oldLen - delta
+	}
+
+	if utf16Len(doc) != oldLen {
+		return nil, ErrIncompatibleLengths
+	}
+
+	dollar := strings.IndexByte(s, '$')
+	if dollar < 0 {
+		return nil, fmt.Errorf("invalid etherpad changeset: missing char bank")
+	}
+	ops, bank := s[:dollar], s[dollar+1:]
+
+	o := NewOperationSeq()
+	docPos, bankPos := 0, 0
+	gotLen := 0
+	for ops != "" {
+		// Attributes and newline counts do not affect the text.
+		for ops != "" && (ops[0] == '*' || ops[0] == '|') {
+			if _, ops, err = parseBase36(ops[1:]); err != nil {
+				return nil, err
+			}
+		}
+		if ops == "" {
+			return nil, fmt.Errorf("invalid etherpad changeset: dangling attributes")
+		}
+
+		opcode := ops[0]
+		var n int
+		if n, ops, err = parseBase36(ops[1:]); err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case '=', '-':
+			chars, next, ok := runesForUnits(doc, docPos, n)
+			if !ok {
+				return nil, fmt.Errorf("invalid etherpad changeset: %c%s exceeds document", opcode, base36(n))
+			}
+			docPos = next
+			if opcode == '=' {
+				o.Retain(chars)
+				gotLen += n
+			} else {
+				o.Delete(chars)
+			}
+		case '+':
+			_, next, ok := runesForUnits(bank, bankPos, n)
+			if !ok {
+				return nil, fmt.Errorf("invalid etherpad changeset: +%s exceeds char bank", base36(n))
+			}
+			o.Insert(bank[bankPos:next])
+			bankPos = next
+			gotLen += n
+		default:
+			return nil, fmt.Errorf("invalid etherpad changeset: unknown opcode %q", opcode)
+		}
+	}
+
+	// The final keep is implicit.
+	o.Retain(uint64(charCount(doc[docPos:])))
+	gotLen += utf16Len(doc[docPos:])
+
+	if gotLen != newLen {
+		return nil, fmt.Errorf("invalid etherpad changeset: expected new length %d, got %d", newLen, gotLen)
+	}
+	return o, nil
+}
+
+// runesForUnits advances from byte offset pos in s by n UTF-16 code units,
+// returning the number of characters covered and the new byte offset. It
+// reports false if s is too short or n would split a surrogate pair.
+func runesForUnits(s string, pos, n int) (uint64, int, bool) {
+	var chars uint64
+	for n > 0 {
+		if pos >= len(s) {
+			return 0, 0, false
+		}
+		r, size := utf8.DecodeRuneInString(s[pos:])
+		units := utf16RuneLen(r)
+		if units > n {
+			return 0, 0, false
+		}
+		n -= units
+		pos += size
+		chars++
+	}
+	return chars, pos, true
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16RuneLen(r)
+	}
+	return n
+}
+
+func utf16RuneLen(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+func base36(n int) string {
+	return strconv.FormatInt(int64(n), 36)
+}
+
+// parseBase36 parses a leading base-36 number from s, returning it and the rest of s.
+func parseBase36(s string) (int, string, error) {
+	end := 0
+	for end < len(s) && (s[end] >= '0' && s[end] <= '9' || s[end] >= 'a' && s[end] <= 'z') {
+		end++
+	}
+	if end == 0 {
+		return 0, s, fmt.Errorf("invalid etherpad changeset: expected number at %q", s)
+	}
+	n, err := strconv.ParseInt(s[:end], 36, 64)
+	if err != nil {
+		return 0, s, fmt.Errorf("invalid etherpad changeset: %w", err)
+	}
+	return int(n), s[end:], nil
+}
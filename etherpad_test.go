@@ -0,0 +1,134 @@
+package ot
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEncodeEtherpad(t *testing.T) {
+	tests := []struct {
+		name   string
+		doc    string
+		ops    func() *OperationSeq
+		expect string
+	}{
+		{
+			name: "insert in middle",
+			doc:  "hello\n",
+			ops: func() *OperationSeq {
+				o := NewOperationSeq()
+				o.Retain(5)
+				o.Insert(" world")
+				o.Retain(1)
+				return o
+			},
+			expect: "Z:6>6=5+6$ world",
+		},
+		{
+			name: "multiline keep and remove",
+			doc:  "ab\ncd\nef\n",
+			ops: func() *OperationSeq {
+				o := NewOperationSeq()
+				o.Retain(4)
+				o.Delete(4)
+				o.Retain(1)
+				return o
+			},
+			expect: "Z:9<4|1=3=1|1-2-2$",
+		},
+		{
+			name: "replace puts removal first",
+			doc:  "abc\n",
+			ops: func() *OperationSeq {
+				o := NewOperationSeq()
+				o.Retain(1)
+				o.Insert("X")
+				o.Delete(1)
+				o.Retain(2)
+				return o
+			},
+			expect: "Z:4>0=1-1+1$X",
+		},
+		{
+			name: "astral characters count twice",
+			doc:  "🌍\n",
+			ops: func() *OperationSeq {
+				o := NewOperationSeq()
+				o.Retain(1)
+				o.Insert("🎉")
+				o.Retain(1)
+				return o
+			},
+			expect: "Z:3>2=2+2$🎉",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := tt.ops()
+			cs, err := o.EncodeEtherpad(tt.doc)
+			if err != nil {
+				t.Fatalf("EncodeEtherpad failed: %v", err)
+			}
+			if cs != tt.expect {
+				t.Errorf("expected %q, got %q", tt.expect, cs)
+			}
+
+			decoded, err := DecodeEtherpad(cs, tt.doc)
+			if err != nil {
+				t.Fatalf("DecodeEtherpad failed: %v", err)
+			}
+			want, _ := o.Apply(tt.doc)
+			got, err := decoded.Apply(tt.doc)
+			if err != nil {
+				t.Fatalf("Apply decoded failed: %v", err)
+			}
+			if got != want {
+				t.Errorf("round-trip: expected %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestDecodeEtherpadAttributes(t *testing.T) {
+	o, err := DecodeEtherpad("Z:5>3*0|1=2*1+3$abc", "a\nbcd")
+	if err != nil {
+		t.Fatalf("DecodeEtherpad failed: %v", err)
+	}
+	got, err := o.Apply("a\nbcd")
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if got != "a\nabcbcd" {
+		t.Errorf("expected %q, got %q", "a\nabcbcd", got)
+	}
+}
+
+func TestDecodeEtherpadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cs   string
+		doc  string
+	}{
+		{"missing prefix", "6>0$", "hello\n"},
+		{"missing delta", "Z:6=1$", "hello\n"},
+		{"missing bank", "Z:6>0=1", "hello\n"},
+		{"keep past end", "Z:6>0=7$", "hello\n"},
+		{"insert past bank", "Z:6>3+3$ab", "hello\n"},
+		{"wrong new length", "Z:6>2+1$a", "hello\n"},
+		{"unknown opcode", "Z:6>0?1$", "hello\n"},
+		{"split surrogate", "Z:3>0=1$", "🌍\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeEtherpad(tt.cs, tt.doc); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	if _, err := DecodeEtherpad("Z:5>0$", "hello\n"); !errors.Is(err, ErrIncompatibleLengths) {
+		t.Errorf("expected ErrIncompatibleLengths, got %v", err)
+	}
+}
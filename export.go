@@ -0,0 +1,120 @@
+package ot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ExportRecord is one line of the JSON Lines history format written by
+// Server.ExportHistory and read by ImportHistory. The first line of an
+// export holds the Snapshot the history starts from, at Revision; every
+// line after it holds the operation that takes the document from Revision
+// to Revision+1, with its metadata, if any, in Author, Time, and Origin.
+type ExportRecord struct {
+	Revision int           `json:"revision"`
+	Snapshot *string       `json:"snapshot,omitempty"`
+	Author   string        `json:"author,omitempty"`
+	Time     *time.Time    `json:"time,omitempty"`
+	Origin   string        `json:"origin,omitempty"`
+	Op       *OperationSeq `json:"op,omitempty"`
+}
+
+// ExportHistory writes the history kept since the last compaction to w as
+// JSON Lines, for backups, migrations, and offline analysis: first the
+// snapshot it starts from, then one ExportRecord per operation. Operations
+// accepted without metadata are exported with the time the server accepted
+// them.
+func (s *Server) ExportHistory(w io.Writer) error {
+	s.mu.Lock()
+	snapshot, base := s.snapshot, s.base
+	history := append([]*OperationSeq(nil), s.history...)
+	accepted := append([]time.Time(nil), s.accepted...)
+	s.mu.Unlock()
+
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(ExportRecord{Revision: base, Snapshot: &snapshot}); err != nil {
+		return err
+	}
+	for i, op := range history {
+		m := op.Meta()
+		if m.Time.IsZero() {
+			m.Time = accepted[i]
+		}
+		plain := *op
+		plain.meta = nil
+		rec := ExportRecord{Revision: base + i, Author: m.Author, Origin: m.Origin, Op: &plain}
+		if !m.Time.IsZero() {
+			rec.Time = &m.Time
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportHistory reads a history written by ExportHistory from r and replays
+// it into a new Server, which has no Store. Records must be in revision
+// order without gaps. Operations are decoded like DecodeLimits.DecodeJSON,
+// with their metadata restored; one that does not apply is reported as a
+// *ReplayError.
+func ImportHistory(r io.Reader) (*Server, error) {
+	dec := json.NewDecoder(r)
+	var head ExportRecord
+	if err := dec.Decode(&head); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("importing history: missing snapshot")
+		}
+		return nil, fmt.Errorf("importing history: %w", err)
+	}
+	if head.Snapshot == nil || head.Op != nil || head.Revision < 0 {
+		return nil, errors.New("importing history: first record must be a snapshot")
+	}
+
+	var (
+		ops      []*OperationSeq
+		accepted []time.Time
+	)
+	for {
+		var rec struct {
+			ExportRecord
+			Op json.RawMessage `json:"op"`
+		}
+		err := dec.Decode(&rec)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("importing history: %w", err)
+		}
+		if want := head.Revision + len(ops); rec.Revision != want {
+			return nil, fmt.Errorf("importing history: expected revision %d, got %d", want, rec.Revision)
+		}
+		if rec.Snapshot != nil || rec.Op == nil {
+			return nil, fmt.Errorf("importing history: revision %d has no operation", rec.Revision)
+		}
+		op, err := DecodeLimits{}.DecodeJSON(rec.Op)
+		if err != nil {
+			return nil, fmt.Errorf("importing history: revision %d: %w", rec.Revision, err)
+		}
+		m := Meta{Author: rec.Author, Origin: rec.Origin}
+		if rec.Time != nil {
+			m.Time = *rec.Time
+		}
+		op.SetMeta(m)
+		ops = append(ops, op)
+		accepted = append(accepted, m.Time)
+	}
+
+	doc, err := replay(*head.Snapshot, head.Revision, ops)
+	if err != nil {
+		return nil, err
+	}
+	s := NewServer(doc)
+	s.base, s.snapshot = head.Revision, *head.Snapshot
+	s.history, s.accepted = ops, accepted
+	return s, nil
+}
@@ -0,0 +1,82 @@
+package ot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportImportHistory(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer("")
+	s.SetStampMeta(true)
+	now := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	for i, text := range []string{"hello", " world", "!"} {
+		op := NewOperationSeq()
+		op.Retain(uint64(charCount(s.Document())))
+		op.Insert(text)
+		if _, _, err := s.SubmitOp(ctx, "alice", "", i, op); err != nil {
+			t.Fatalf("SubmitOp failed: %v", err)
+		}
+	}
+	if err := s.Compact(ctx, 1); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := s.ExportHistory(&buf); err != nil {
+		t.Fatalf("ExportHistory failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		`{"revision":1,"snapshot":"hello"}`,
+		`{"revision":1,"author":"alice","time":"2024-01-02T09:00:00Z","op":[5," world"]}`,
+		`{"revision":2,"author":"alice","time":"2024-01-02T09:00:00Z","op":[11,"!"]}`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(want, "\n"), buf.String())
+	}
+
+	imported, err := ImportHistory(&buf)
+	if err != nil {
+		t.Fatalf("ImportHistory failed: %v", err)
+	}
+	if doc, rev := imported.State(); doc != "hello world!" || rev != 3 {
+		t.Errorf("expected (%q, 3), got (%q, %d)", "hello world!", doc, rev)
+	}
+	if got := imported.OpsByAuthor("alice"); len(got) != 2 || got[0].Revision != 1 {
+		t.Errorf("expected two operations by alice from revision 1, got %+v", got)
+	}
+	if doc, err := imported.DocumentAt(2); err != nil || doc != "hello world" {
+		t.Errorf("expected %q at revision 2, got %q (%v)", "hello world", doc, err)
+	}
+}
+
+func TestImportHistoryValidates(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"no snapshot", `{"revision":0,"op":["a"]}`},
+		{"gap", `{"revision":0,"snapshot":""}` + "\n" + `{"revision":1,"op":["a"]}`},
+		{"no operation", `{"revision":0,"snapshot":""}` + "\n" + `{"revision":0}`},
+		{"bad operation", `{"revision":0,"snapshot":""}` + "\n" + `{"revision":0,"op":[1.5]}`},
+		{"malformed", `{"revision":0,"snapshot":""}` + "\n" + `{"revision":`},
+	}
+	for _, tt := range tests {
+		if _, err := ImportHistory(strings.NewReader(tt.input)); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+
+	input := `{"revision":0,"snapshot":"ab"}` + "\n" + `{"revision":0,"op":[3,"c"]}`
+	var replayErr *ReplayError
+	if _, err := ImportHistory(strings.NewReader(input)); !errors.As(err, &replayErr) || replayErr.Revision != 0 {
+		t.Errorf("expected a *ReplayError at revision 0, got %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package ot
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// CorpusEntry encodes ops as an entry for the fuzz corpus of this package's
+// FuzzTransform, FuzzCompose, and FuzzUnmarshalJSON targets, each of which
+// takes operations in their JSON form. Write it to a file under
+// testdata/fuzz/<target>/ to contribute a case found elsewhere as a seed:
+// two operations for FuzzTransform and FuzzCompose, one for
+// FuzzUnmarshalJSON.
+func CorpusEntry(ops ...*OperationSeq) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("go test fuzz v1\n")
+	for _, op := range ops {
+		data, err := op.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&buf, "[]byte(%q)\n", data)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,137 @@
+package ot
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// fuzzMaxLen bounds the documents the fuzz targets build, so a huge retain
+// is skipped rather than allocated.
+const fuzzMaxLen = 1 << 12
+
+func fuzzSeeds(f *testing.F, n int) {
+	f.Helper()
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 16; i++ {
+		baseLen := r.Intn(20)
+		args := make([]any, n)
+		for j := range args {
+			op := RandomOp(r, baseLen, RandomOptions{})
+			data, err := op.MarshalJSON()
+			if err != nil {
+				f.Fatalf("MarshalJSON failed: %v", err)
+			}
+			args[j] = data
+		}
+		f.Add(args...)
+	}
+}
+
+func decodeFuzz(t *testing.T, data []byte) *OperationSeq {
+	t.Helper()
+	op := NewOperationSeq()
+	if err := op.UnmarshalJSON(data); err != nil || op.BaseLen() > fuzzMaxLen || op.TargetLen() > fuzzMaxLen {
+		t.Skip()
+	}
+	return op
+}
+
+func FuzzUnmarshalJSON(f *testing.F) {
+	for _, seed := range []string{`[]`, `[5,"hello",-3,10]`, `[1,{"author":"a"}]`, `[1.5]`, `[-0]`, `[1e30]`, `[{}, 1]`, `null`} {
+		f.Add([]byte(seed))
+	}
+	fuzzSeeds(f, 1)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		limited, limitErr := DecodeLimits{}.DecodeJSON(data)
+		op := NewOperationSeq()
+		if err := op.UnmarshalJSON(data); err != nil {
+			if limitErr == nil {
+				t.Fatalf("DecodeJSON accepted what UnmarshalJSON rejected: %v", err)
+			}
+			return
+		}
+		if op.BaseLen() < 0 || op.TargetLen() < 0 {
+			t.Fatalf("negative lengths %d and %d", op.BaseLen(), op.TargetLen())
+		}
+		if limitErr == nil && limited.String() != op.String() {
+			t.Fatalf("DecodeJSON gave %v, UnmarshalJSON %v", limited, op)
+		}
+		again := NewOperationSeq()
+		if err := again.UnmarshalJSON([]byte(op.String())); err != nil {
+			t.Fatalf("round trip of %v failed: %v", op, err)
+		}
+		if again.String() != op.String() {
+			t.Fatalf("round trip gave %v, expected %v", again, op)
+		}
+	})
+}
+
+func FuzzTransform(f *testing.F) {
+	fuzzSeeds(f, 2)
+	f.Fuzz(func(t *testing.T, aData, bData []byte) {
+		a, b := decodeFuzz(t, aData), decodeFuzz(t, bData)
+		aPrime, bPrime, err := a.Transform(b)
+		if a.BaseLen() != b.BaseLen() {
+			if !errors.Is(err, ErrIncompatibleLengths) {
+				t.Fatalf("expected ErrIncompatibleLengths, got %v", err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("Transform failed: %v", err)
+		}
+		doc := strings.Repeat("x", a.BaseLen())
+		afterAB, err := ReplayLog(doc, []*OperationSeq{a, bPrime})
+		if err != nil {
+			t.Fatalf("applying a, b' failed: %v", err)
+		}
+		afterBA, err := ReplayLog(doc, []*OperationSeq{b, aPrime})
+		if err != nil {
+			t.Fatalf("applying b, a' failed: %v", err)
+		}
+		if afterAB != afterBA {
+			t.Fatalf("TP1 failed for %v and %v: %q != %q", a, b, afterAB, afterBA)
+		}
+	})
+}
+
+func FuzzCompose(f *testing.F) {
+	fuzzSeeds(f, 2)
+	f.Fuzz(func(t *testing.T, aData, bData []byte) {
+		a, b := decodeFuzz(t, aData), decodeFuzz(t, bData)
+		ab, err := a.Compose(b)
+		if a.TargetLen() != b.BaseLen() {
+			if !errors.Is(err, ErrIncompatibleLengths) {
+				t.Fatalf("expected ErrIncompatibleLengths, got %v", err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("Compose failed: %v", err)
+		}
+		doc := strings.Repeat("x", a.BaseLen())
+		want, err := ReplayLog(doc, []*OperationSeq{a, b})
+		if err != nil {
+			t.Fatalf("applying a, b failed: %v", err)
+		}
+		if got, err := ab.Apply(doc); err != nil || got != want {
+			t.Fatalf("composing %v and %v gave %q (%v), expected %q", a, b, got, err, want)
+		}
+	})
+}
+
+func TestCorpusEntry(t *testing.T) {
+	op := NewOperationSeq()
+	op.Retain(2)
+	op.Insert(`"é"`)
+	data, err := CorpusEntry(op, NewOperationSeq())
+	if err != nil {
+		t.Fatalf("CorpusEntry failed: %v", err)
+	}
+	want := "go test fuzz v1\n[]byte(\"[2,\\\"\\\\\\\"é\\\\\\\"\\\"]\")\n[]byte(\"[]\")\n"
+	if string(data) != want {
+		t.Errorf("expected %q, got %q", want, data)
+	}
+}
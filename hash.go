@@ -0,0 +1,45 @@
+package ot
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+)
+
+// Hash returns the SHA-256 of a canonical encoding of o, for deduplication
+// and integrity checks. Equal operations hash the same: components are
+// merged and ordered the same way however an OperationSeq is built, and
+// each is hashed as its kind, its count, and for an insert its text.
+// Metadata is left out, so the same edit by two authors hashes the same.
+func (o *OperationSeq) Hash() [sha256.Size]byte {
+	h := sha256.New()
+	var buf [1 + binary.MaxVarintLen64]byte
+	for _, v := range o.ops {
+		buf[0] = byte(v.Kind)
+		n := binary.PutUvarint(buf[1:], v.N)
+		h.Write(buf[:1+n]) //nolint:errcheck // hash writes never fail
+		if v.Kind == KindInsert {
+			io.WriteString(h, v.Text) //nolint:errcheck // hash writes never fail
+		}
+	}
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// ContentHash returns the SHA-256 of doc's UTF-8 encoding, so that a client
+// and server can check they hold the same document without sending it.
+func ContentHash(doc string) [sha256.Size]byte {
+	h := sha256.New()
+	io.WriteString(h, doc) //nolint:errcheck // hash writes never fail
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// ContentHash returns the ContentHash of the current document and the
+// revision it belongs to.
+func (s *Server) ContentHash() ([sha256.Size]byte, int) {
+	doc, revision := s.State()
+	return ContentHash(doc), revision
+}
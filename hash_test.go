@@ -0,0 +1,48 @@
+package ot
+
+import (
+	"crypto/sha256"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	a := NewOperationSeq()
+	a.Retain(2)
+	a.Insert("hé")
+	a.Insert("llo")
+	a.Delete(1)
+
+	// The same operation, built another way.
+	b := NewOperationSeq()
+	b.Retain(1)
+	b.Retain(1)
+	b.Delete(1)
+	b.Insert("héllo")
+	if a.Hash() != b.Hash() {
+		t.Error("expected equal operations to hash the same")
+	}
+
+	c := NewOperationSeq()
+	c.Retain(2)
+	c.Insert("héllo")
+	c.Retain(1)
+	if a.Hash() == c.Hash() {
+		t.Error("expected a delete and a retain to hash differently")
+	}
+	if NewOperationSeq().Hash() == c.Hash() {
+		t.Error("expected an empty operation to hash differently")
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	if got, want := ContentHash("héllo"), sha256.Sum256([]byte("héllo")); got != want {
+		t.Errorf("expected %x, got %x", want, got)
+	}
+
+	s := NewServer("ab")
+	appendText(t, s, "c")
+	sum, rev := s.ContentHash()
+	if sum != ContentHash("abc") || rev != 1 {
+		t.Errorf("expected the hash of %q at revision 1, got %x at %d", "abc", sum, rev)
+	}
+}
@@ -0,0 +1,133 @@
+package ot
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// HistoryEntry is an operation from a document's history. Revision follows
+// the Store convention: the operation applies to revision Revision and
+// produces Revision+1.
+type HistoryEntry struct {
+	Revision int           `json:"revision"`
+	Op       *OperationSeq `json:"op"`
+}
+
+// HistoryQuerier is implemented by stores that can find saved operations by
+// their metadata without returning the whole log. OpsBetween and OpsByAuthor
+// use it when available.
+type HistoryQuerier interface {
+	// OpsBetween returns the saved operations whose Meta.Time is at or
+	// after from and before to, in revision order.
+	OpsBetween(ctx context.Context, doc string, from, to time.Time) ([]HistoryEntry, error)
+
+	// OpsByAuthor returns the saved operations whose Meta.Author is author,
+	// in revision order.
+	OpsByAuthor(ctx context.Context, doc, author string) ([]HistoryEntry, error)
+}
+
+// OpsBetween returns the operations saved for doc in store whose Meta.Time
+// is at or after from and before to, in revision order, as for a "what
+// changed yesterday" view. Operations without metadata never match; see
+// Server.SetStampMeta. Stores that do not implement HistoryQuerier are read
+// from revision 0, so those that trim operations must implement it to be
+// queried.
+func OpsBetween(ctx context.Context, store Store, doc string, from, to time.Time) ([]HistoryEntry, error) {
+	if q, ok := store.(HistoryQuerier); ok {
+		return q.OpsBetween(ctx, doc, from, to)
+	}
+	return queryOps(ctx, store, doc, func(m Meta) bool { return inWindow(m.Time, from, to) })
+}
+
+// OpsByAuthor returns the operations saved for doc in store whose
+// Meta.Author is author, in revision order. It reads the store like
+// OpsBetween.
+func OpsByAuthor(ctx context.Context, store Store, doc, author string) ([]HistoryEntry, error) {
+	if q, ok := store.(HistoryQuerier); ok {
+		return q.OpsByAuthor(ctx, doc, author)
+	}
+	return queryOps(ctx, store, doc, func(m Meta) bool { return m.Author == author })
+}
+
+func queryOps(ctx context.Context, store Store, doc string, match func(Meta) bool) ([]HistoryEntry, error) {
+	ops, err := store.LoadOpsSince(ctx, doc, 0)
+	if err != nil {
+		return nil, err
+	}
+	var entries []HistoryEntry
+	for i, op := range ops {
+		if match(op.Meta()) {
+			entries = append(entries, HistoryEntry{Revision: i, Op: op})
+		}
+	}
+	return entries, nil
+}
+
+func inWindow(t, from, to time.Time) bool {
+	return !t.IsZero() && !t.Before(from) && t.Before(to)
+}
+
+// SetStampMeta sets whether the server stamps the metadata of the
+// operations it accepts from now on: Meta.Author becomes the submitting
+// client, if known, and Meta.Time the time the operation was accepted. The
+// Origin a client set is kept. Stamped operations are saved and broadcast
+// with their metadata, which clients that predate it may not decode, so it
+// is off by default.
+func (s *Server) SetStampMeta(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stamping = on
+}
+
+// stamp returns op with its metadata stamped, if the server does so, for
+// client at time at. op itself is left alone, and shares nothing with the
+// result, as the caller may still hold it. Callers hold s.mu.
+func (s *Server) stamp(client string, op *OperationSeq, at time.Time) *OperationSeq {
+	if !s.stamping {
+		return op
+	}
+	m := op.Meta()
+	if client != "" {
+		m.Author = client
+	}
+	m.Time = at
+	stamped := *op
+	stamped.ops = slices.Clone(op.ops)
+	stamped.SetMeta(m)
+	return &stamped
+}
+
+// OpsBetween returns the operations in the server's history that were
+// accepted at or after from and before to, in revision order. Only the
+// history kept since the last compaction is searched; use the package-level
+// OpsBetween to query the Store.
+func (s *Server) OpsBetween(from, to time.Time) []HistoryEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []HistoryEntry
+	for i, at := range s.accepted {
+		if inWindow(at, from, to) {
+			entries = append(entries, HistoryEntry{Revision: s.base + i, Op: s.history[i]})
+		}
+	}
+	return entries
+}
+
+// OpsByAuthor returns the operations in the server's history whose
+// Meta.Author is author, in revision order. Operations are only attributed
+// if the server stamps them or their clients set it themselves. Like
+// OpsBetween, only the history since the last compaction is searched.
+func (s *Server) OpsByAuthor(author string) []HistoryEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []HistoryEntry
+	for i, op := range s.history {
+		if op.Meta().Author == author {
+			entries = append(entries, HistoryEntry{Revision: s.base + i, Op: op})
+		}
+	}
+	return entries
+}
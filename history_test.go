@@ -0,0 +1,82 @@
+package ot
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestServerHistoryQueries(t *testing.T) {
+	ctx := context.Background()
+	store := &memStore{}
+	s, err := OpenServer(ctx, store, "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	s.SetStampMeta(true)
+	now := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	for i, client := range []string{"alice", "bob", "alice"} {
+		op := NewOperationSeq()
+		op.Retain(uint64(i))
+		op.Insert("x")
+		op.SetMeta(Meta{Author: "spoofed", Origin: "phone"})
+		applied, _, err := s.SubmitOp(ctx, client, "", i, op)
+		if err != nil {
+			t.Fatalf("SubmitOp failed: %v", err)
+		}
+		if want := (Meta{Author: client, Time: now, Origin: "phone"}); applied.Meta() != want {
+			t.Errorf("expected %+v, got %+v", want, applied.Meta())
+		}
+		if op.Meta().Author != "spoofed" {
+			t.Errorf("expected the submitted operation left alone, got %+v", op.Meta())
+		}
+		// Nor does reusing the submitted operation change the history.
+		want := applied.String()
+		op.Insert("y")
+		if got := applied.String(); got != want {
+			t.Errorf("expected the applied operation to stay %s, got %s", want, got)
+		}
+		now = now.Add(time.Hour)
+	}
+
+	start := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+	got := s.OpsBetween(start.Add(time.Hour), start.Add(3*time.Hour))
+	if len(got) != 2 || got[0].Revision != 1 || got[1].Revision != 2 {
+		t.Errorf("expected revisions 1 and 2, got %+v", got)
+	}
+	got = s.OpsByAuthor("alice")
+	if len(got) != 2 || got[0].Revision != 0 || got[1].Revision != 2 {
+		t.Errorf("expected revisions 0 and 2, got %+v", got)
+	}
+
+	// The store falls back to reading the whole log.
+	stored, err := OpsByAuthor(ctx, store, "doc", "bob")
+	if err != nil || len(stored) != 1 || stored[0].Revision != 1 || stored[0].Op.Meta().Author != "bob" {
+		t.Errorf("expected revision 1 by bob, got %+v (%v)", stored, err)
+	}
+	stored, err = OpsBetween(ctx, store, "doc", start, start.Add(time.Hour))
+	if err != nil || len(stored) != 1 || stored[0].Revision != 0 {
+		t.Errorf("expected revision 0, got %+v (%v)", stored, err)
+	}
+}
+
+func TestServerWithoutStampMeta(t *testing.T) {
+	s := NewServer("")
+	op := NewOperationSeq()
+	op.Insert("x")
+	applied, _, err := s.SubmitOp(context.Background(), "alice", "", 0, op)
+	if err != nil {
+		t.Fatalf("SubmitOp failed: %v", err)
+	}
+	if !applied.Meta().IsZero() {
+		t.Errorf("expected no metadata, got %+v", applied.Meta())
+	}
+	if got := s.OpsByAuthor("alice"); len(got) != 0 {
+		t.Errorf("expected no attributed operations, got %+v", got)
+	}
+	if got := s.OpsBetween(time.Time{}, time.Now().Add(time.Hour)); len(got) != 1 {
+		t.Errorf("expected the operation by acceptance time, got %+v", got)
+	}
+}
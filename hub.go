@@ -0,0 +1,1128 @@
+package ot
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrDocumentNotFound should be returned (or wrapped) by Hub.Open for unknown documents
+	ErrDocumentNotFound = errors.New("document not found")
+
+	// ErrSlowSubscriber is reported by a subscription that was dropped because it fell behind
+	ErrSlowSubscriber = errors.New("subscriber too slow")
+
+	// ErrHubClosed is returned by a Hub after Shutdown, and reported by the
+	// subscriptions Shutdown ended
+	ErrHubClosed = errors.New("hub closed")
+)
+
+// DefaultSubscriptionBuffer is the number of events queued per subscription
+// when Hub.Buffer is zero.
+const DefaultSubscriptionBuffer = 256
+
+// EventKind identifies what a hub Event reports.
+type EventKind int
+
+const (
+	// EventOp reports an accepted operation. It is delivered to every
+	// subscriber, including the client that submitted it, which can treat it
+	// as the acknowledgement unless Undo is set.
+	EventOp EventKind = iota
+
+	// EventJoin reports that a client subscribed to the document.
+	EventJoin
+
+	// EventLeave reports that a client's subscription ended.
+	EventLeave
+
+	// EventMessage carries an application payload passed to Hub.Broadcast.
+	// It is not delivered back to its sender.
+	EventMessage
+
+	// EventSelection reports a client's new selection, as of Revision. It is
+	// not delivered back to its sender.
+	EventSelection
+
+	// EventMeta reports a change to a client's metadata. It is not delivered
+	// back to its sender.
+	EventMeta
+
+	// EventMode reports a change to the document's Mode, whether made with
+	// Hub.SetMode or by reaching the revision given to Hub.FreezeAt.
+	EventMode
+
+	// EventAck repeats the acknowledgement of an operation submitted again
+	// with the ID of one already accepted. Revision and Op are those of the
+	// original EventOp. It is delivered only to the submitter.
+	EventAck
+)
+
+// Event is delivered to the subscribers of a document.
+type Event struct {
+	Kind EventKind
+	Doc  string
+
+	// Client is the client that caused the event.
+	Client string
+
+	// Revision is the revision produced by the operation for EventOp, and
+	// the current revision for other kinds.
+	Revision int
+
+	// Op is the accepted operation, transformed to apply at Revision-1. Set
+	// for EventOp and EventAck only. Subscribers must not modify it.
+	Op *OperationSeq
+
+	// Clients lists the subscribed clients, sorted. Set for EventJoin and EventLeave.
+	Clients []string
+
+	// Payload is the value passed to Broadcast. Set for EventMessage only.
+	Payload any
+
+	// Selection is the client's selection. Set for EventSelection only.
+	Selection Selection
+
+	// Meta is the client's merged metadata. Set for EventSelection and
+	// EventMeta, so cursors can be drawn with the client's name and color.
+	Meta map[string]any
+
+	// Mode is the document's new mode. Set for EventMode only.
+	Mode Mode
+
+	// Checksum is the hex-encoded ContentHash of the document at Revision.
+	// It is set for EventOp and EventAck if the Hub has a ChecksumEvery and
+	// Revision is a multiple of it, unless the server had already moved past
+	// Revision when the event was delivered.
+	Checksum string
+
+	// Clipped is set for an EventOp or EventAck whose operation the server
+	// clipped to its protected ranges under ProtectClip, so Op differs from
+	// what Client submitted by more than the transformation. The submitter
+	// must apply Op in place of its own operation to stay in step.
+	Clipped bool
+
+	// Undo is set for an EventOp produced by Hub.Undo. Client is the client
+	// whose operation was undone, and which did not submit this one, so it
+	// applies it like any other client's operation rather than as an
+	// acknowledgement.
+	Undo bool
+}
+
+// Relay announces accepted operations to the Hubs of other nodes that share
+// the same Store. The receiving side calls Hub.Sync for the named document.
+//
+// Notifications only tell other nodes to look; the operations themselves are
+// read from the Store. A lost notification therefore delays delivery until
+// the next one for that document rather than causing divergence.
+type Relay interface {
+	// Publish announces that doc reached revision. It is called with the
+	// document locked and must not block.
+	Publish(doc string, revision int)
+}
+
+// Hub manages a set of named documents and the clients subscribed to them.
+// Documents are created on first use. Operations on a document are applied
+// one at a time and fanned out to its subscribers in revision order.
+//
+// The zero value is ready to use and starts every document empty. A Hub is
+// safe for concurrent use.
+type Hub struct {
+	// Open creates the server for a document the first time it is used,
+	// with the context of the call that needs it. If nil, documents are
+	// restored from Store, or start empty without one.
+	Open func(ctx context.Context, doc string) (*Server, error)
+
+	// Store persists documents opened by the Hub when Open is nil.
+	Store Store
+
+	// Authorizer, if set, is consulted before subscriptions and operations.
+	Authorizer Authorizer
+
+	// ResumeKey signs the secrets that let clients resume their sessions;
+	// see ResumeToken. If nil, a random key is generated on first use, so
+	// sessions can only be resumed on this Hub. Set the same secret on every
+	// node sharing a Store to resume on any of them.
+	ResumeKey []byte
+
+	// Verifier checks the signatures of operations submitted with
+	// SubmitSigned, which refuses them all if it is nil.
+	Verifier Verifier
+
+	// RateLimit bounds how fast each client may submit operations. Clients
+	// over the limit get a RateLimitError.
+	RateLimit RateLimit
+
+	// Checkpoints is applied to the servers the hub opens from Store.
+	Checkpoints CheckpointPolicy
+
+	// Limits is applied to the servers the hub creates itself, from Store
+	// or empty. Servers returned by Open keep their own limits.
+	Limits ServerLimits
+
+	// Retention is applied to the servers the hub creates itself. Clients
+	// subscribed to a document pin the revisions they may still build on,
+	// so it only drops history no connected client still needs.
+	Retention RetentionPolicy
+
+	// Logger, if set, receives client lifecycle events and refused
+	// subscriptions and operations, with "doc" and "client" attributes. It
+	// is also passed, with a "doc" attribute, to the servers the hub creates
+	// itself; see Server.SetLogger.
+	Logger *slog.Logger
+
+	// Metrics, if set, receives the hub's measurements, and is passed to
+	// the servers the hub creates itself.
+	Metrics Metrics
+
+	// Relay, if set, announces accepted operations to other nodes. Running
+	// several nodes requires a shared Store that reports ErrConflict.
+	Relay Relay
+
+	// ViewerBatch, if positive, coalesces the operations delivered to
+	// read-only subscriptions, such as those from Watch, into one EventOp
+	// per interval of this length, to cut the messages sent to large
+	// audiences during heavy typing. Other subscriptions still receive every
+	// operation at once. A batched EventOp has an empty Client, and its
+	// Revision may advance by more than one. Selections and metadata that
+	// change meanwhile are sent once, after it, as of its Revision.
+	ViewerBatch time.Duration
+
+	// StampMeta is applied to the servers the hub creates itself; see
+	// Server.SetStampMeta.
+	StampMeta bool
+
+	// ChecksumEvery, if positive, sets Event.Checksum on the EventOp and
+	// EventAck of every revision that is a multiple of it, so clients can
+	// check that their document has not silently diverged.
+	ChecksumEvery int
+
+	// Buffer is the number of events queued per subscription. A subscriber
+	// that falls this far behind is dropped. Zero means DefaultSubscriptionBuffer.
+	Buffer int
+
+	resumeOnce   sync.Once
+	generatedKey []byte
+
+	mu      sync.Mutex
+	docs    map[string]*hubDoc
+	opening map[string]*hubOpening
+	closed  bool
+	limiter rateLimiter
+}
+
+// NewHub returns a Hub that creates documents with open.
+func NewHub(open func(ctx context.Context, doc string) (*Server, error)) *Hub {
+	return &Hub{Open: open}
+}
+
+func (h *Hub) logger() *slog.Logger {
+	if h.Logger == nil {
+		return discard
+	}
+	return h.Logger
+}
+
+type hubDoc struct {
+	name     string
+	server   *Server
+	presence Presence
+	metrics  Metrics
+	log      *slog.Logger
+
+	// mu serializes operations with their fanout, so every subscriber sees
+	// events in the same order.
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool // set by Hub.Shutdown
+	batch  viewerBatch
+
+	checksumEvery int
+}
+
+// Subscription receives the events of one document for one client.
+type Subscription struct {
+	// Doc and Client identify the subscription.
+	Doc    string
+	Client string
+
+	// Document and Revision are the document state when the subscription
+	// started. Events on C follow on from exactly this state.
+	Document string
+	Revision int
+
+	// Missed holds, for a subscription started by Resume or
+	// SubscribeSince, the operations accepted from the given revision up to
+	// Revision.
+	Missed []*OperationSeq
+
+	// PendingRevision is, for a subscription started by Resume, the
+	// revision produced by the token's pending operation if the hub knows it
+	// was accepted, and zero otherwise. The operation is then the one at
+	// index PendingRevision-1-token.Revision in Missed.
+	PendingRevision int
+
+	// ResumeSecret is the secret the client must present in a ResumeToken
+	// to resume this session later; see Hub.ResumeSecret. It should only be
+	// sent to the client itself.
+	ResumeSecret string
+
+	// ReadOnly is set if the Authorizer made the subscription read-only.
+	ReadOnly bool
+
+	// Mode is the document's mode at Revision.
+	Mode Mode
+
+	// Selections and Meta hold the other clients' selections at Revision
+	// and their metadata.
+	Selections map[string]Selection
+	Meta       map[string]map[string]any
+
+	// C delivers events. It is closed when the subscription ends.
+	C <-chan Event
+
+	ch  chan Event
+	doc *hubDoc
+	pin *Pin // nil for read-only subscriptions
+	err error
+}
+
+// Server returns the server for a document, creating it if needed.
+func (h *Hub) Server(ctx context.Context, doc string) (*Server, error) {
+	d, err := h.doc(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	return d.server, nil
+}
+
+// hubOpening is a document being opened by one caller of Hub.doc, for the
+// others to wait on.
+type hubOpening struct {
+	done chan struct{}
+	err  error // set before done is closed
+}
+
+// doc returns the named document, opening it with ctx if needed. Opening
+// happens outside h.mu, so a slow Store only holds up callers that want the
+// same document; they wait for the first one's result.
+func (h *Hub) doc(ctx context.Context, name string) (*hubDoc, error) {
+	h.mu.Lock()
+	for {
+		if h.closed {
+			h.mu.Unlock()
+			return nil, ErrHubClosed
+		}
+		if d, ok := h.docs[name]; ok {
+			h.mu.Unlock()
+			return d, nil
+		}
+		o, ok := h.opening[name]
+		if !ok {
+			break
+		}
+		h.mu.Unlock()
+		select {
+		case <-o.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		// An open that failed only because its caller gave up is retried
+		// with this one's context.
+		if o.err != nil && !errors.Is(o.err, context.Canceled) && !errors.Is(o.err, context.DeadlineExceeded) {
+			return nil, o.err
+		}
+		h.mu.Lock()
+	}
+
+	o := &hubOpening{done: make(chan struct{})}
+	if h.opening == nil {
+		h.opening = make(map[string]*hubOpening)
+	}
+	h.opening[name] = o
+	h.mu.Unlock()
+
+	d, err := h.open(ctx, name)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.opening, name)
+	o.err = err
+	close(o.done)
+	if err != nil {
+		return nil, err
+	}
+	if h.closed {
+		// Nothing has been applied to it yet, so there is nothing to save.
+		return nil, ErrHubClosed
+	}
+	if h.docs == nil {
+		h.docs = make(map[string]*hubDoc)
+	}
+	h.docs[name] = d
+	return d, nil
+}
+
+// open creates the named document's server and hubDoc. It is called
+// without h.mu held.
+func (h *Hub) open(ctx context.Context, name string) (*hubDoc, error) {
+	log := h.logger().With("doc", name)
+	var server *Server
+	var err error
+	switch {
+	case h.Open != nil:
+		server, err = h.Open(ctx, name)
+	case h.Store != nil:
+		if server, err = OpenServer(ctx, h.Store, name); err == nil {
+			server.SetCheckpointPolicy(h.Checkpoints)
+			server.SetLimits(h.Limits)
+			server.SetRetention(h.Retention)
+			server.SetStampMeta(h.StampMeta)
+			server.SetMetrics(h.Metrics)
+			server.SetLogger(log)
+		}
+	default:
+		server = NewServer("")
+		server.SetLimits(h.Limits)
+		server.SetRetention(h.Retention)
+		server.SetStampMeta(h.StampMeta)
+		server.SetMetrics(h.Metrics)
+		server.SetLogger(log)
+	}
+	if err != nil {
+		level := slog.LevelError
+		if errors.Is(err, ErrDocumentNotFound) {
+			level = slog.LevelDebug
+		}
+		log.Log(ctx, level, "opening document failed", "err", err)
+		return nil, err
+	}
+
+	d := &hubDoc{name: name, server: server, metrics: h.Metrics, log: log, subs: make(map[*Subscription]struct{})}
+	d.batch.every = h.ViewerBatch
+	d.checksumEvery = h.ChecksumEvery
+	if d.metrics == nil {
+		d.metrics = NopMetrics{}
+	}
+	return d, nil
+}
+
+// Subscribe starts delivering a document's events to a new subscription.
+// Every subscriber, including the new one, receives an EventJoin. The
+// Authorizer, if any, is consulted first.
+//
+// The subscription pins its starting revision against Server.Compact. The
+// pin advances only on revisions the client itself reports: each operation
+// it submits shows it no longer needs the revisions before the one the
+// operation produced, since a client keeps at most one operation in flight
+// and builds the next on the acknowledgement. Receiving operations from C
+// does not advance it, as an operation in flight may still be based on an
+// older revision. Read-only subscriptions submit nothing, and pin nothing.
+func (h *Hub) Subscribe(ctx context.Context, doc, client string) (*Subscription, error) {
+	return h.subscribe(ctx, doc, client, nil, false)
+}
+
+// subscribe implements Subscribe, Watch, Resume, and SubscribeSince.
+func (h *Hub) subscribe(ctx context.Context, doc, client string, resume *ResumeToken, watch bool) (*Subscription, error) {
+	readOnly := watch
+	if h.Authorizer != nil {
+		ro, err := h.Authorizer.AuthorizeSubscribe(ctx, client, doc)
+		if err != nil {
+			h.logger().InfoContext(ctx, "subscription refused", "doc", doc, "client", client, "err", err)
+			return nil, err
+		}
+		readOnly = readOnly || ro
+	}
+
+	d, err := h.doc(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := h.Buffer
+	if buffer <= 0 {
+		buffer = DefaultSubscriptionBuffer
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return nil, ErrHubClosed
+	}
+	// Batched operations precede the new subscription's starting state.
+	d.flush()
+	content, revision := d.server.State()
+	var missed []*OperationSeq
+	pending := 0
+	if resume != nil {
+		if missed, _, err = d.server.OperationsSince(resume.Revision); err != nil {
+			return nil, err
+		}
+		if resume.Pending != "" {
+			pending, _ = d.server.OpRevision(client, resume.Pending)
+		}
+	}
+	var pin *Pin
+	if !readOnly {
+		if pin, err = d.server.Pin(revision); err != nil {
+			return nil, err
+		}
+	}
+	selections := d.presence.State()
+	delete(selections, client)
+	meta := d.presence.MetaState()
+	delete(meta, client)
+	ch := make(chan Event, buffer)
+	sub := &Subscription{
+		Doc:             doc,
+		Client:          client,
+		Document:        content,
+		Revision:        revision,
+		Missed:          missed,
+		PendingRevision: pending,
+		ResumeSecret:    h.ResumeSecret(doc, client),
+		ReadOnly:        readOnly,
+		Mode:            d.server.Mode(),
+		Selections:      selections,
+		Meta:            meta,
+		C:               ch,
+		ch:              ch,
+		doc:             d,
+		pin:             pin,
+	}
+	d.subs[sub] = struct{}{}
+	d.metrics.ClientJoined()
+	d.log.InfoContext(ctx, "client joined", "client", client, "revision", revision, "readOnly", readOnly, "resumed", resume != nil)
+	d.fanout(Event{Kind: EventJoin, Client: client, Revision: revision, Clients: d.clients()}, nil)
+	return sub, nil
+}
+
+// Close ends the subscription and notifies the remaining subscribers.
+// It is safe to call more than once.
+func (s *Subscription) Close() {
+	d := s.doc
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.subs[s]; !ok {
+		return
+	}
+	delete(d.subs, s)
+	s.unpin()
+	d.forget(s.Client)
+	d.metrics.ClientLeft()
+	d.log.Info("client left", "client", s.Client)
+	close(s.ch)
+	d.fanout(Event{Kind: EventLeave, Client: s.Client, Revision: d.server.Revision(), Clients: d.clients()}, nil)
+}
+
+// Err returns ErrSlowSubscriber if the subscription was dropped for falling
+// behind, ErrHubClosed if the hub was shut down, and nil otherwise. It is
+// only meaningful after C is closed.
+func (s *Subscription) Err() error {
+	s.doc.mu.Lock()
+	defer s.doc.mu.Unlock()
+	return s.err
+}
+
+// Submit applies an operation a client made against revision, as
+// Server.Submit does, and delivers the result to every subscriber.
+//
+// The operation is rejected with a RateLimitError if the client is over the
+// hub's RateLimit, with ErrForbidden if the client holds a read-only
+// subscription to the document, or with the Authorizer's error.
+//
+// If another node saved the same revision first, the hub syncs the document
+// from the Store and retries, so the operation is transformed past the
+// other node's changes.
+func (h *Hub) Submit(ctx context.Context, doc, client string, revision int, op *OperationSeq) (*OperationSeq, int, error) {
+	return h.SubmitOp(ctx, doc, client, "", revision, op)
+}
+
+// SubmitOp is Submit for an operation the client identified with id, as
+// Server.SubmitOp does, so that a client resuming its session can learn
+// whether the operation was accepted, and a retried submission is not
+// applied twice. A retry returns the original result and delivers EventAck
+// to the client's subscriptions instead of EventOp to everyone.
+func (h *Hub) SubmitOp(ctx context.Context, doc, client, id string, revision int, op *OperationSeq) (*OperationSeq, int, error) {
+	d, err := h.authorize(ctx, doc, client, op)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer d.mu.Unlock()
+
+	for {
+		ack, duplicate, err := d.server.submitOp(ctx, client, id, revision, op)
+		if errors.Is(err, ErrConflict) {
+			if n, serr := d.sync(ctx); serr != nil || n == 0 {
+				return nil, 0, errors.Join(err, serr)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if duplicate {
+			d.fanout(Event{Kind: EventAck, Client: client, Revision: ack.revision, Op: ack.op, Clipped: ack.clipped}, func(s *Subscription) bool {
+				return s.Client == client
+			})
+			return ack.op, ack.revision, nil
+		}
+		d.advance(client, ack.revision)
+		d.presence.Transform(ack.op)
+		d.fanout(Event{Kind: EventOp, Client: client, Revision: ack.revision, Op: ack.op, Clipped: ack.clipped}, nil)
+		d.announceFreeze(ack.revision-1, ack.revision)
+		h.publish(doc, ack.revision)
+		return ack.op, ack.revision, nil
+	}
+}
+
+// ApplyAt applies an operation only if the document is still at revision, as
+// Server.ApplyAt does, and delivers it to every subscriber. It is authorized
+// like Submit. The operation applied is returned, and is the one delivered.
+func (h *Hub) ApplyAt(ctx context.Context, doc, client string, revision int, op *OperationSeq) (*OperationSeq, error) {
+	d, err := h.authorize(ctx, doc, client, op)
+	if err != nil {
+		return nil, err
+	}
+	defer d.mu.Unlock()
+
+	applied, clipped, err := d.server.applyAt(ctx, client, revision, op)
+	if err != nil {
+		if errors.Is(err, ErrConflict) {
+			// Another node moved the document on.
+			if _, serr := d.sync(ctx); serr != nil {
+				return nil, errors.Join(err, serr)
+			}
+			return nil, ErrStaleRevision
+		}
+		return nil, err
+	}
+	d.advance(client, revision+1)
+	d.presence.Transform(applied)
+	d.fanout(Event{Kind: EventOp, Client: client, Revision: revision + 1, Op: applied, Clipped: clipped}, nil)
+	d.announceFreeze(revision, revision+1)
+	h.publish(doc, revision+1)
+	return applied, nil
+}
+
+// Undo reverts client's most recent operation on doc, as Server.Undo does,
+// and delivers the resulting operation to every subscriber with Undo set.
+// It is authorized like Submit, with the inverse of the operation being
+// undone as the operation.
+func (h *Hub) Undo(ctx context.Context, doc, client string) (*OperationSeq, int, error) {
+	d, err := h.doc(ctx, doc)
+	if err != nil {
+		return nil, 0, err
+	}
+	inverse := d.server.nextUndo(client)
+	if inverse == nil {
+		return nil, 0, ErrNothingToUndo
+	}
+	if d, err = h.authorize(ctx, doc, client, inverse); err != nil {
+		return nil, 0, err
+	}
+	defer d.mu.Unlock()
+
+	applied, revision, err := d.server.Undo(ctx, client)
+	for errors.Is(err, ErrConflict) {
+		if n, serr := d.sync(ctx); serr != nil || n == 0 {
+			return nil, 0, errors.Join(err, serr)
+		}
+		applied, revision, err = d.server.Undo(ctx, client)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	d.presence.Transform(applied)
+	d.fanout(Event{Kind: EventOp, Client: client, Revision: revision, Op: applied, Undo: true}, nil)
+	d.announceFreeze(revision-1, revision)
+	h.publish(doc, revision)
+	return applied, revision, nil
+}
+
+// Revert reverts the operation that produced revision on doc, as
+// Server.Revert does, on behalf of client, and delivers the result to every
+// subscriber with an empty Client, since no subscriber submitted it. It is
+// authorized like Submit for client, with the result as the operation.
+func (h *Hub) Revert(ctx context.Context, doc, client string, revision int) (*OperationSeq, int, error) {
+	return h.serverEdit(ctx, doc, client,
+		func(s *Server) (*OperationSeq, error) { return s.reverting(revision) },
+		func(s *Server) (*OperationSeq, int, error) { return s.Revert(ctx, client, revision) },
+		"operation reverted", "reverted", revision)
+}
+
+// CherryPick makes the operation that produced revision on doc again, as
+// Server.CherryPick does, on behalf of client. It is delivered and
+// authorized like Revert.
+func (h *Hub) CherryPick(ctx context.Context, doc, client string, revision int) (*OperationSeq, int, error) {
+	return h.serverEdit(ctx, doc, client,
+		func(s *Server) (*OperationSeq, error) { return s.cherryPicking(revision) },
+		func(s *Server) (*OperationSeq, int, error) { return s.CherryPick(ctx, client, revision) },
+		"operation cherry-picked", "picked", revision)
+}
+
+// RestoreCheckpoint returns doc to a named checkpoint, as
+// Server.RestoreCheckpoint does, on behalf of client. It is delivered and
+// authorized like Revert.
+func (h *Hub) RestoreCheckpoint(ctx context.Context, doc, client, name string) (*OperationSeq, int, error) {
+	return h.serverEdit(ctx, doc, client,
+		func(s *Server) (*OperationSeq, error) { return s.restoring(name) },
+		func(s *Server) (*OperationSeq, int, error) { return s.RestoreCheckpoint(ctx, client, name) },
+		"checkpoint restored", "checkpoint", name)
+}
+
+// serverEdit applies an operation that doc's server works out for client,
+// such as a revert, and delivers it to every subscriber with an empty
+// Client. plan returns the operation for authorization, and apply applies
+// it, again after syncing if another node got there first. msg is logged
+// with args on success.
+func (h *Hub) serverEdit(ctx context.Context, doc, client string,
+	plan func(*Server) (*OperationSeq, error), apply func(*Server) (*OperationSeq, int, error),
+	msg string, args ...any,
+) (*OperationSeq, int, error) {
+	d, err := h.doc(ctx, doc)
+	if err != nil {
+		return nil, 0, err
+	}
+	op, err := plan(d.server)
+	if err != nil {
+		return nil, 0, err
+	}
+	if d, err = h.authorize(ctx, doc, client, op); err != nil {
+		return nil, 0, err
+	}
+	defer d.mu.Unlock()
+
+	applied, revision, err := apply(d.server)
+	for errors.Is(err, ErrConflict) {
+		if n, serr := d.sync(ctx); serr != nil || n == 0 {
+			return nil, 0, errors.Join(err, serr)
+		}
+		applied, revision, err = apply(d.server)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	d.presence.Transform(applied)
+	args = append(append([]any{"client", client}, args...), "revision", revision)
+	d.log.InfoContext(ctx, msg, args...)
+	d.fanout(Event{Kind: EventOp, Revision: revision, Op: applied}, nil)
+	d.announceFreeze(revision-1, revision)
+	h.publish(doc, revision)
+	return applied, revision, nil
+}
+
+// Merge merges branch into doc, as Branch.Merge does, and delivers the
+// merged operation to doc's subscribers with an empty Client, since no
+// subscriber submitted it. branch must have been forked from doc's Server.
+// The merge is authorized like Submit for client, with the branch's Changes
+// as the operation.
+func (h *Hub) Merge(ctx context.Context, doc, client string, branch *Branch) (*OperationSeq, int, error) {
+	changes, err := branch.Changes()
+	if err != nil {
+		return nil, 0, err
+	}
+	d, err := h.authorize(ctx, doc, client, changes)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer d.mu.Unlock()
+	if branch.parent != d.server {
+		return nil, 0, fmt.Errorf("branch was not forked from %s", doc)
+	}
+
+	applied, revision, err := branch.Merge(ctx)
+	for errors.Is(err, ErrConflict) {
+		if n, serr := d.sync(ctx); serr != nil || n == 0 {
+			return nil, 0, errors.Join(err, serr)
+		}
+		applied, revision, err = branch.Merge(ctx)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	d.presence.Transform(applied)
+	d.log.InfoContext(ctx, "branch merged", "client", client, "fork", branch.ForkRevision(), "revision", revision)
+	d.fanout(Event{Kind: EventOp, Revision: revision, Op: applied}, nil)
+	d.announceFreeze(revision-1, revision)
+	h.publish(doc, revision)
+	return applied, revision, nil
+}
+
+// authorize checks that client may apply op to doc now and returns the
+// document locked.
+func (h *Hub) authorize(ctx context.Context, doc, client string, op *OperationSeq) (*hubDoc, error) {
+	refuse := func(err error) (*hubDoc, error) {
+		h.logger().InfoContext(ctx, "operation refused", "doc", doc, "client", client, "err", err)
+		return nil, err
+	}
+
+	if err := h.limiter.allow(h.RateLimit, client, insertedBytes(op)); err != nil {
+		return refuse(err)
+	}
+	if h.Authorizer != nil {
+		if err := h.Authorizer.Authorize(ctx, client, doc, op); err != nil {
+			return refuse(err)
+		}
+	}
+
+	d, err := h.doc(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil, ErrHubClosed
+	}
+	for s := range d.subs {
+		if s.Client == client && s.ReadOnly {
+			d.mu.Unlock()
+			return refuse(fmt.Errorf("%w: %s has a read-only subscription", ErrForbidden, client))
+		}
+	}
+	return d, nil
+}
+
+// Sync applies the operations other nodes saved to the Store for a document
+// and delivers them to its subscribers, with an empty Client. Relays call it
+// when notified. Documents the hub has not opened are left alone, since they
+// are read from the Store when first used.
+func (h *Hub) Sync(ctx context.Context, doc string) error {
+	h.mu.Lock()
+	d, ok := h.docs[doc]
+	h.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, err := d.sync(ctx)
+	return err
+}
+
+// Documents returns the names of the documents the hub has opened, sorted.
+func (h *Hub) Documents() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	names := make([]string, 0, len(h.docs))
+	for name := range h.docs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Shutdown stops the hub. It refuses new subscriptions and operations with
+// ErrHubClosed, waits for operations in progress, checkpoints every open
+// document to its Store, and ends every subscription; their Err then reports
+// ErrHubClosed, so clients know to resume elsewhere. Checkpoint failures are
+// returned joined.
+//
+// If ctx is done before every document has been handled, Shutdown returns
+// its error and leaves the remaining documents as they are.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closed = true
+	docs := make([]*hubDoc, 0, len(h.docs))
+	for _, d := range h.docs {
+		docs = append(docs, d)
+	}
+	h.mu.Unlock()
+
+	var errs []error
+	for _, d := range docs {
+		if err := ctx.Err(); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		if err := d.shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// shutdown implements Hub.Shutdown for one document.
+func (d *hubDoc) shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.flush()
+	d.closed = true
+	err := d.server.Checkpoint(ctx)
+	for s := range d.subs {
+		delete(d.subs, s)
+		s.unpin()
+		d.forget(s.Client)
+		d.metrics.ClientLeft()
+		s.err = ErrHubClosed
+		close(s.ch)
+	}
+	d.log.InfoContext(ctx, "document closed", "revision", d.server.Revision())
+	return err
+}
+
+func (h *Hub) publish(doc string, revision int) {
+	if h.Relay != nil {
+		h.Relay.Publish(doc, revision)
+	}
+}
+
+// sync implements Hub.Sync and returns the number of operations applied.
+// Callers hold d.mu.
+func (d *hubDoc) sync(ctx context.Context) (int, error) {
+	ops, revision, err := d.server.Sync(ctx)
+	if err != nil {
+		return 0, err
+	}
+	start := revision - len(ops)
+	for i, op := range ops {
+		d.presence.Transform(op)
+		d.fanout(Event{Kind: EventOp, Revision: start + i + 1, Op: op}, nil)
+	}
+	d.announceFreeze(start, revision)
+	return len(ops), nil
+}
+
+// SetMode changes a document's mode, as Server.SetMode does, and delivers
+// the new mode to every subscriber. The mode belongs to this node's Server;
+// the other nodes sharing the Store must be told separately.
+func (h *Hub) SetMode(ctx context.Context, doc string, mode Mode) error {
+	d, err := h.doc(ctx, doc)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.server.SetMode(mode)
+	d.log.InfoContext(ctx, "mode changed", "mode", mode)
+	d.fanout(Event{Kind: EventMode, Revision: d.server.Revision(), Mode: mode}, nil)
+	return nil
+}
+
+// FreezeAt freezes a document once it reaches revision, as Server.FreezeAt
+// does. Subscribers receive EventMode when the freeze takes effect, right
+// after the operation producing revision.
+func (h *Hub) FreezeAt(ctx context.Context, doc string, revision int) error {
+	d, err := h.doc(ctx, doc)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.server.FreezeAt(revision); err != nil {
+		return err
+	}
+	d.log.InfoContext(ctx, "freeze scheduled", "revision", revision)
+	if revision == d.server.Revision() {
+		d.fanout(Event{Kind: EventMode, Revision: revision, Mode: ModeFrozen}, nil)
+	}
+	return nil
+}
+
+// announceFreeze delivers EventMode if the operations from revision from to
+// revision to froze the document. Callers hold d.mu.
+func (d *hubDoc) announceFreeze(from, to int) {
+	if at, ok := d.server.FrozenAt(); ok && at > from && at <= to {
+		d.fanout(Event{Kind: EventMode, Revision: to, Mode: ModeFrozen}, nil)
+	}
+}
+
+// Broadcast delivers payload to every subscriber of a document except those
+// belonging to client.
+func (h *Hub) Broadcast(ctx context.Context, doc, client string, payload any) error {
+	d, err := h.doc(ctx, doc)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.fanout(Event{Kind: EventMessage, Client: client, Revision: d.server.Revision(), Payload: payload}, func(s *Subscription) bool {
+		return s.Client != client
+	})
+	return nil
+}
+
+// SetSelection records client's selection, made against revision, and
+// delivers it to the other subscribers. The selection is transformed up to
+// the current revision first, and is kept in step with later operations
+// until the client's last subscription ends.
+func (h *Hub) SetSelection(ctx context.Context, doc, client string, revision int, sel Selection) error {
+	d, err := h.doc(ctx, doc)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ops, current, err := d.server.OperationsSince(revision)
+	if err != nil {
+		return err
+	}
+	for _, op := range ops {
+		sel = sel.Transform(op)
+	}
+	d.presence.Set(client, sel)
+	ev := Event{Kind: EventSelection, Client: client, Revision: current, Selection: sel, Meta: d.presence.Meta(client)}
+	d.fanout(ev, func(s *Subscription) bool {
+		return s.Client != client
+	})
+	return nil
+}
+
+// SetMeta merges meta into client's metadata, as Presence.Merge does, and
+// delivers the result to the other subscribers. Like the selection, the
+// metadata expires when the client's last subscription ends.
+func (h *Hub) SetMeta(ctx context.Context, doc, client string, meta map[string]any) error {
+	d, err := h.doc(ctx, doc)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.presence.Merge(client, meta, time.Now())
+	ev := Event{Kind: EventMeta, Client: client, Revision: d.server.Revision(), Meta: d.presence.Meta(client)}
+	d.fanout(ev, func(s *Subscription) bool {
+		return s.Client != client
+	})
+	return nil
+}
+
+// Selections returns every client's selection and the revision they refer to.
+func (h *Hub) Selections(ctx context.Context, doc string) (map[string]Selection, int, error) {
+	d, err := h.doc(ctx, doc)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.presence.State(), d.server.Revision(), nil
+}
+
+// Subscribers returns the clients subscribed to a document, sorted.
+func (h *Hub) Subscribers(doc string) []string {
+	h.mu.Lock()
+	d, ok := h.docs[doc]
+	h.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.clients()
+}
+
+// advance moves the pins of client's subscriptions up to revision. Callers
+// hold d.mu.
+func (d *hubDoc) advance(client string, revision int) {
+	for s := range d.subs {
+		if s.Client == client && s.pin != nil {
+			s.pin.Advance(revision)
+		}
+	}
+}
+
+// unpin releases the subscription's pin, if it has one.
+func (s *Subscription) unpin() {
+	if s.pin != nil {
+		s.pin.Release()
+	}
+}
+
+// forget drops client's selection and metadata once it has no subscriptions left.
+// Callers hold d.mu.
+func (d *hubDoc) forget(client string) {
+	for s := range d.subs {
+		if s.Client == client {
+			return
+		}
+	}
+	d.presence.Remove(client)
+}
+
+// clients returns the sorted, deduplicated client IDs. Callers hold d.mu.
+func (d *hubDoc) clients() []string {
+	seen := make(map[string]bool, len(d.subs))
+	clients := make([]string, 0, len(d.subs))
+	for s := range d.subs {
+		if !seen[s.Client] {
+			seen[s.Client] = true
+			clients = append(clients, s.Client)
+		}
+	}
+	sort.Strings(clients)
+	return clients
+}
+
+// deliver queues ev for every subscriber accepted by filter (all if nil),
+// dropping subscribers whose queue is full. Callers hold d.mu.
+func (d *hubDoc) deliver(ev Event, filter func(*Subscription) bool) {
+	ev.Doc = d.name
+	if ev.Kind == EventOp || ev.Kind == EventAck {
+		ev.Checksum = d.checksum(ev.Revision)
+	}
+	var dropped []*Subscription
+	delivered := 0
+	for s := range d.subs {
+		if filter != nil && !filter(s) {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+			delivered++
+		default:
+			dropped = append(dropped, s)
+		}
+	}
+	if ev.Kind == EventOp {
+		d.metrics.Broadcast(delivered)
+	}
+
+	for _, s := range dropped {
+		delete(d.subs, s)
+		s.unpin()
+		d.forget(s.Client)
+		d.metrics.ClientLeft()
+		d.log.Warn("subscriber dropped", "client", s.Client, "err", ErrSlowSubscriber)
+		s.err = ErrSlowSubscriber
+		close(s.ch)
+	}
+	for _, s := range dropped {
+		d.fanout(Event{Kind: EventLeave, Client: s.Client, Revision: d.server.Revision(), Clients: d.clients()}, nil)
+	}
+}
+
+// checksum returns the Event.Checksum for revision, or "" if none is due or
+// the server has moved past it. Callers hold d.mu.
+func (d *hubDoc) checksum(revision int) string {
+	if d.checksumEvery <= 0 || revision%d.checksumEvery != 0 {
+		return ""
+	}
+	sum, current := d.server.ContentHash()
+	if current != revision {
+		return ""
+	}
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,477 @@
+package ot
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHubLazyCreation(t *testing.T) {
+	opened := 0
+	h := NewHub(func(_ context.Context, doc string) (*Server, error) {
+		if doc == "missing" {
+			return nil, ErrDocumentNotFound
+		}
+		opened++
+		return NewServer("hello"), nil
+	})
+
+	s1, err := h.Server(context.Background(), "notes")
+	if err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+	s2, err := h.Server(context.Background(), "notes")
+	if err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+	if s1 != s2 || opened != 1 {
+		t.Errorf("expected one shared server, opened %d", opened)
+	}
+
+	if _, err := h.Server(context.Background(), "missing"); !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("expected ErrDocumentNotFound, got %v", err)
+	}
+}
+
+func TestHubOpensOutsideLock(t *testing.T) {
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	var opens atomic.Int32
+	h := NewHub(func(_ context.Context, doc string) (*Server, error) {
+		if doc == "slow" {
+			opens.Add(1)
+			started <- struct{}{}
+			<-release
+		}
+		return NewServer(doc), nil
+	})
+
+	results := make(chan *Server, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			server, err := h.Server(context.Background(), "slow")
+			if err != nil {
+				t.Errorf("Server failed: %v", err)
+			}
+			results <- server
+		}()
+	}
+
+	<-started
+
+	// Other documents open while "slow" is still loading.
+	if _, err := h.Server(context.Background(), "fast"); err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+	// A caller waiting for "slow" gives up with its context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := h.Server(ctx, "slow"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	close(release)
+	if a, b := <-results, <-results; a != b || a == nil {
+		t.Errorf("expected both callers to get the same server, got %p and %p", a, b)
+	}
+	if n := opens.Load(); n != 1 {
+		t.Errorf("expected 1 open, got %d", n)
+	}
+}
+
+func TestHubFanout(t *testing.T) {
+	var h Hub
+
+	alice, err := h.Subscribe(context.Background(), "notes", "alice")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if ev := <-alice.C; ev.Kind != EventJoin || ev.Client != "alice" {
+		t.Errorf("expected own join event, got %+v", ev)
+	}
+
+	bob, err := h.Subscribe(context.Background(), "notes", "bob")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	<-bob.C
+	if ev := <-alice.C; ev.Kind != EventJoin || len(ev.Clients) != 2 {
+		t.Errorf("expected join with 2 clients, got %+v", ev)
+	}
+
+	op := NewOperationSeq()
+	op.Insert("hi")
+	if _, rev, err := h.Submit(context.Background(), "notes", "alice", 0, op); err != nil || rev != 1 {
+		t.Fatalf("Submit failed: rev %d, err %v", rev, err)
+	}
+
+	for _, sub := range []*Subscription{alice, bob} {
+		ev := <-sub.C
+		if ev.Kind != EventOp || ev.Client != "alice" || ev.Revision != 1 || ev.Doc != "notes" {
+			t.Errorf("%s: unexpected event %+v", sub.Client, ev)
+		}
+	}
+
+	if err := h.Broadcast(context.Background(), "notes", "bob", "cursor"); err != nil {
+		t.Fatalf("Broadcast failed: %v", err)
+	}
+	if ev := <-alice.C; ev.Kind != EventMessage || ev.Payload != "cursor" {
+		t.Errorf("expected message event, got %+v", ev)
+	}
+	select {
+	case ev := <-bob.C:
+		t.Errorf("sender should not receive its own message, got %+v", ev)
+	default:
+	}
+
+	bob.Close()
+	bob.Close()
+	if ev := <-alice.C; ev.Kind != EventLeave || ev.Client != "bob" || len(ev.Clients) != 1 {
+		t.Errorf("expected leave event, got %+v", ev)
+	}
+	if _, ok := <-bob.C; ok {
+		t.Error("expected closed channel after Close")
+	}
+	if got := h.Subscribers("notes"); len(got) != 1 || got[0] != "alice" {
+		t.Errorf("expected [alice], got %v", got)
+	}
+}
+
+func TestHubSubscriptionStartsAtCurrentState(t *testing.T) {
+	var h Hub
+	op := NewOperationSeq()
+	op.Insert("abc")
+	if _, _, err := h.Submit(context.Background(), "notes", "x", 0, op); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	sub, err := h.Subscribe(context.Background(), "notes", "late")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if sub.Document != "abc" || sub.Revision != 1 {
+		t.Errorf("expected (abc, 1), got (%q, %d)", sub.Document, sub.Revision)
+	}
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	h := Hub{Buffer: 2}
+
+	slow, err := h.Subscribe(context.Background(), "notes", "slow")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		op := NewOperationSeq()
+		op.Retain(uint64(i))
+		op.Insert("x")
+		if _, _, err := h.Submit(context.Background(), "notes", "fast", i, op); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+
+	n := 0
+	for range slow.C {
+		n++
+	}
+	if n != 2 {
+		t.Errorf("expected 2 buffered events before drop, got %d", n)
+	}
+	if !errors.Is(slow.Err(), ErrSlowSubscriber) {
+		t.Errorf("expected ErrSlowSubscriber, got %v", slow.Err())
+	}
+	if len(h.Subscribers("notes")) != 0 {
+		t.Errorf("expected no subscribers, got %v", h.Subscribers("notes"))
+	}
+}
+
+type recordingRelay struct {
+	mu        sync.Mutex
+	published []int
+}
+
+func (r *recordingRelay) Publish(_ string, revision int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.published = append(r.published, revision)
+}
+
+func TestHubSharedStore(t *testing.T) {
+	store := &memStore{}
+	relay := &recordingRelay{}
+	a := &Hub{Store: store, Relay: relay}
+	b := &Hub{Store: store}
+
+	sub, err := b.Subscribe(context.Background(), "doc", "bob")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	<-sub.C // own join
+
+	op := NewOperationSeq()
+	op.Insert("a")
+	if _, _, err := a.Submit(context.Background(), "doc", "alice", 0, op); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if len(relay.published) != 1 || relay.published[0] != 1 {
+		t.Errorf("expected revision 1 published, got %v", relay.published)
+	}
+
+	// b missed the notification; its submit conflicts, syncs, and rebases.
+	other := NewOperationSeq()
+	other.Insert("b")
+	_, rev, err := b.Submit(context.Background(), "doc", "bob", 0, other)
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if rev != 2 {
+		t.Errorf("expected revision 2, got %d", rev)
+	}
+
+	remote := <-sub.C
+	if remote.Kind != EventOp || remote.Revision != 1 || remote.Client != "" {
+		t.Errorf("expected remote op at revision 1, got %+v", remote)
+	}
+	if own := <-sub.C; own.Client != "bob" || own.Revision != 2 {
+		t.Errorf("expected own op at revision 2, got %+v", own)
+	}
+
+	if err := a.Sync(context.Background(), "doc"); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	sa, _ := a.Server(context.Background(), "doc")
+	sb, _ := b.Server(context.Background(), "doc")
+	if sa.Document() != sb.Document() {
+		t.Errorf("expected convergence, got %q and %q", sa.Document(), sb.Document())
+	}
+
+	stale := NewOperationSeq()
+	stale.Retain(2)
+	stale.Insert("!")
+	if _, err := a.ApplyAt(context.Background(), "doc", "alice", 2, stale); err != nil {
+		t.Fatalf("ApplyAt failed: %v", err)
+	}
+	if _, err := b.ApplyAt(context.Background(), "doc", "bob", 2, stale); !errors.Is(err, ErrStaleRevision) {
+		t.Errorf("expected ErrStaleRevision, got %v", err)
+	}
+	if got := b.Documents(); len(got) != 1 || got[0] != "doc" {
+		t.Errorf("expected [doc], got %v", got)
+	}
+	if err := b.Sync(context.Background(), "unopened"); err != nil {
+		t.Errorf("expected Sync of unopened document to be a no-op, got %v", err)
+	}
+}
+
+func TestHubPinsSubscriberRevision(t *testing.T) {
+	h := NewHub(func(context.Context, string) (*Server, error) { return NewServer(""), nil })
+
+	sub, err := h.Subscribe(context.Background(), "doc", "alice")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	server, err := h.Server(context.Background(), "doc")
+	if err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		op := NewOperationSeq()
+		op.Retain(uint64(i))
+		op.Insert("x")
+		if _, _, err := h.Submit(context.Background(), "doc", "bob", i, op); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+
+	// alice joined at revision 0 and has not built on anything since.
+	if err := server.Compact(context.Background(), 2); !errors.Is(err, ErrRevisionPinned) {
+		t.Errorf("expected ErrRevisionPinned, got %v", err)
+	}
+
+	// Submitting against revision 2 shows alice no longer needs older ones.
+	op := NewOperationSeq()
+	op.Retain(2)
+	op.Insert("y")
+	if _, _, err := h.Submit(context.Background(), "doc", "alice", 2, op); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if err := server.Compact(context.Background(), 2); err != nil {
+		t.Errorf("Compact failed: %v", err)
+	}
+
+	sub.Close()
+	if err := server.Compact(context.Background(), 3); err != nil {
+		t.Errorf("Compact after unsubscribe failed: %v", err)
+	}
+}
+
+func TestHubChecksum(t *testing.T) {
+	ctx := context.Background()
+	h := &Hub{ChecksumEvery: 2}
+	sub, err := h.Subscribe(ctx, "notes", "ed")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	<-sub.C
+
+	doc := ""
+	for i, text := range []string{"a", "b", "c", "d"} {
+		op := NewOperationSeq()
+		op.Retain(uint64(i))
+		op.Insert(text)
+		if _, _, err := h.Submit(ctx, "notes", "other", i, op); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+		doc += text
+
+		ev := <-sub.C
+		want := ""
+		if ev.Revision%2 == 0 {
+			sum := ContentHash(doc)
+			want = hex.EncodeToString(sum[:])
+		}
+		if ev.Checksum != want {
+			t.Errorf("revision %d: expected checksum %q, got %q", ev.Revision, want, ev.Checksum)
+		}
+	}
+}
+
+func TestHubApplyAtStampsBroadcast(t *testing.T) {
+	ctx := context.Background()
+	h := &Hub{StampMeta: true}
+	sub, err := h.Subscribe(ctx, "notes", "viewer")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	<-sub.C
+
+	op := NewOperationSeq()
+	op.Insert("hi")
+	applied, err := h.ApplyAt(ctx, "notes", "alice", 0, op)
+	if err != nil {
+		t.Fatalf("ApplyAt failed: %v", err)
+	}
+	server, _ := h.Server(ctx, "notes")
+	ops, _, err := server.OperationsSince(0)
+	if err != nil {
+		t.Fatalf("OperationsSince failed: %v", err)
+	}
+	stored := ops[0]
+	if m := stored.Meta(); m.Author != "alice" || m.Time.IsZero() {
+		t.Errorf("expected a stored op stamped for alice, got %+v", m)
+	}
+	if ev := <-sub.C; ev.Op.Meta() != stored.Meta() {
+		t.Errorf("expected the broadcast op to carry %+v, got %+v", stored.Meta(), ev.Op.Meta())
+	}
+	if applied.Meta() != stored.Meta() {
+		t.Errorf("expected ApplyAt to return %+v, got %+v", stored.Meta(), applied.Meta())
+	}
+}
+
+// TestHubClippedSubmit checks that a replica following the events converges
+// on operations the server clipped, and that the events say so.
+func TestHubClippedSubmit(t *testing.T) {
+	ctx := context.Background()
+	h := NewHub(func(context.Context, string) (*Server, error) {
+		s := NewServer("abcdef")
+		s.SetProtectPolicy(ProtectClip)
+		return s, s.Protect("head", Range{Start: 0, End: 3})
+	})
+	sub, err := h.Subscribe(ctx, "d", "viewer")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	<-sub.C
+
+	op := NewOperationSeq()
+	op.Delete(6)
+	applied, err := h.ApplyAt(ctx, "d", "c", 0, op)
+	if err != nil {
+		t.Fatalf("ApplyAt failed: %v", err)
+	}
+	op = NewOperationSeq()
+	op.Retain(1)
+	op.Insert("!")
+	op.Retain(5)
+	if _, _, err := h.Submit(ctx, "d", "c", 0, op); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	server, _ := h.Server(ctx, "d")
+	replica := "abcdef"
+	for _, want := range []*OperationSeq{applied, nil} {
+		ev := <-sub.C
+		if want != nil && ev.Op.String() != want.String() {
+			t.Errorf("expected %v to be delivered, got %v", want, ev.Op)
+		}
+		if !ev.Clipped {
+			t.Errorf("revision %d: expected Clipped", ev.Revision)
+		}
+		if replica, err = ev.Op.Apply(replica); err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+	}
+	if replica != "abc" || server.Document() != "abc" {
+		t.Errorf("expected %q on both, got %q and %q", "abc", replica, server.Document())
+	}
+}
+
+func TestHubCompactsPastConnectedClients(t *testing.T) {
+	ctx := context.Background()
+	h := &Hub{Retention: RetentionPolicy{KeepRevisions: 1}}
+
+	viewer, err := h.Watch(ctx, "doc", "viewer")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	defer viewer.Close()
+	editor, err := h.Subscribe(ctx, "doc", "editor")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer editor.Close()
+	server, err := h.Server(ctx, "doc")
+	if err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+
+	// The editor has an operation in flight against revision 0 while it
+	// receives everyone else's.
+	pending := NewOperationSeq()
+	pending.Insert("y")
+	drain := func(sub *Subscription) {
+		for len(sub.C) > 0 {
+			<-sub.C
+		}
+	}
+	submit := func(i int) {
+		t.Helper()
+		op := NewOperationSeq()
+		op.Retain(uint64(i))
+		op.Insert("x")
+		if _, _, err := h.Submit(ctx, "doc", "writer", i, op); err != nil {
+			t.Fatalf("Submit %d failed: %v", i, err)
+		}
+		drain(viewer)
+		drain(editor)
+	}
+	for i := 0; i < 300; i++ {
+		submit(i)
+	}
+	if _, revision, err := h.Submit(ctx, "doc", "editor", 0, pending); err != nil || revision != 301 {
+		t.Fatalf("expected the pending operation to produce revision 301, got %d, %v", revision, err)
+	}
+
+	// Its acknowledgement shows the editor needs nothing before it; the
+	// viewer never needed anything.
+	submit(301)
+	if _, _, err := server.OperationsSince(200); !errors.Is(err, ErrRevisionCompacted) {
+		t.Errorf("expected retention to have compacted revision 200, got %v", err)
+	}
+	if err := server.Compact(ctx, 301); err != nil {
+		t.Errorf("Compact failed: %v", err)
+	}
+}
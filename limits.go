@@ -0,0 +1,231 @@
+package ot
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// DecodeLimits bounds the resources a decoder will commit to untrusted input.
+// A zero field means no limit for that dimension.
+//
+// Limits are checked while decoding, component by component, so oversized
+// input is rejected before it is fully materialized. They do not bound the
+// size of the raw input itself; wrap network readers with something like
+// http.MaxBytesReader for that.
+type DecodeLimits struct {
+	// MaxOps is the maximum number of components in the input, counted before
+	// adjacent components are merged.
+	MaxOps int
+
+	// MaxInsertLen is the maximum number of characters in a single insert.
+	MaxInsertLen int
+
+	// MaxLen is the maximum base length and target length of the result.
+	MaxLen int
+}
+
+// ServerLimits bounds what a Server accepts from clients. A zero field means
+// no limit for that dimension.
+type ServerLimits struct {
+	// MaxDocLen is the maximum document length in characters. Operations
+	// that would grow the document past it are rejected with
+	// ErrDocumentTooLarge; operations that shrink or keep its length are
+	// always accepted, so a document already over a lowered limit can still
+	// be edited down.
+	MaxDocLen int
+
+	// MaxInsertLen is the maximum number of characters in a single insert.
+	// Larger inserts are rejected with ErrOpTooLarge.
+	MaxInsertLen int
+
+	// MaxPending is the maximum number of operations a client's operation
+	// may be transformed against, that is, how many revisions it may lag
+	// behind. Older operations are rejected with ErrTooFarBehind.
+	MaxPending int
+}
+
+// SetLimits sets the limits applied to operations received from now on.
+func (s *Server) SetLimits(limits ServerLimits) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limits = limits
+}
+
+// check applies the limits that do not depend on the document to an
+// operation lagging behind by pending revisions.
+func (l ServerLimits) check(pending int, op *OperationSeq) error {
+	if l.MaxPending > 0 && pending > l.MaxPending {
+		return ErrTooFarBehind
+	}
+	if l.MaxInsertLen > 0 {
+		for _, o := range op.ops {
+			if o.Kind == KindInsert && int(o.N) > l.MaxInsertLen {
+				return ErrOpTooLarge
+			}
+		}
+	}
+	return nil
+}
+
+// DecodeJSON parses the JSON wire format, enforcing the limits.
+//
+// Unlike UnmarshalJSON, retain and delete counts must be integers; fractional
+// numbers are rejected instead of truncated.
+func (l DecodeLimits) DecodeJSON(data []byte) (*OperationSeq, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	o := NewOperationSeq()
+	if tok == nil {
+		return o, endJSON(dec)
+	}
+	if tok != json.Delim('[') {
+		return nil, fmt.Errorf("invalid operation sequence: expected array, got %v", tok)
+	}
+
+	count := 0
+	for dec.More() {
+		if nextValue(data[dec.InputOffset():]) == '{' {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return nil, err
+			}
+			if dec.More() {
+				return nil, fmt.Errorf("invalid operation sequence: metadata must come last")
+			}
+			if o.meta, err = unmarshalMeta(raw); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		count++
+		if l.MaxOps > 0 && count > l.MaxOps {
+			return nil, ErrTooManyOps
+		}
+
+		switch v := tok.(type) {
+		case string:
+			err = l.insert(o, v)
+		case json.Number:
+			n, perr := strconv.ParseInt(string(v), 10, 64)
+			if perr != nil {
+				return nil, fmt.Errorf("invalid operation count: %s", v)
+			}
+			if n >= 0 {
+				err = l.retain(o, uint64(n))
+			} else {
+				err = l.delete(o, uint64(-n))
+			}
+		default:
+			return nil, fmt.Errorf("invalid operation type: %T", tok)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	if err := endJSON(dec); err != nil {
+		return nil, err
+	}
+	if debug {
+		assert("DecodeJSON", checkOp(o))
+	}
+	return o, nil
+}
+
+// endJSON returns an error if anything but whitespace follows the value dec
+// has read, as json.Unmarshal would.
+func endJSON(dec *json.Decoder) error {
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("invalid operation sequence: data after the array")
+	}
+	return nil
+}
+
+// nextValue returns the first byte of the next value in data, which starts
+// between two values of an array.
+func nextValue(data []byte) byte {
+	for _, c := range data {
+		switch c {
+		case ' ', '\t', '\n', '\r', ',':
+		default:
+			return c
+		}
+	}
+	return 0
+}
+
+// DecodeBinary parses the binary encoding, enforcing the limits.
+func (l DecodeLimits) DecodeBinary(data []byte) (*OperationSeq, error) {
+	o := NewOperationSeq()
+	if err := l.decodeBinary(o, data); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// DecodeString parses the base64 form produced by EncodeString, enforcing the limits.
+func (l DecodeLimits) DecodeString(s string) (*OperationSeq, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return l.DecodeBinary(data)
+}
+
+// maxLen returns the longest base or target length l allows. Without
+// MaxLen, lengths are still bounded by int, so hostile counts cannot
+// overflow them.
+func (l DecodeLimits) maxLen() int {
+	if l.MaxLen > 0 {
+		return l.MaxLen
+	}
+	return math.MaxInt
+}
+
+func (l DecodeLimits) retain(o *OperationSeq, n uint64) error {
+	if n > uint64(l.maxLen()-o.baseLen) || n > uint64(l.maxLen()-o.targetLen) {
+		return ErrLengthTooLarge
+	}
+	o.Retain(n)
+	return nil
+}
+
+func (l DecodeLimits) delete(o *OperationSeq, n uint64) error {
+	if n > uint64(l.maxLen()-o.baseLen) {
+		return ErrLengthTooLarge
+	}
+	o.Delete(n)
+	return nil
+}
+
+func (l DecodeLimits) insert(o *OperationSeq, s string) error {
+	n := charCount(s)
+	if l.MaxInsertLen > 0 && n > l.MaxInsertLen {
+		return ErrOpTooLarge
+	}
+	if n > l.maxLen()-o.targetLen {
+		return ErrLengthTooLarge
+	}
+	o.Insert(s)
+	return nil
+}
@@ -0,0 +1,100 @@
+package ot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodeLimitsJSON(t *testing.T) {
+	limits := DecodeLimits{MaxOps: 4, MaxInsertLen: 5, MaxLen: 100}
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{"within limits", `[5,"hello",-3]`, nil},
+		{"null", `null`, nil},
+		{"too many ops", `[1,"a",1,"b",1]`, ErrTooManyOps},
+		{"insert too large", `["toolong"]`, ErrOpTooLarge},
+		{"retain too large", `[101]`, ErrLengthTooLarge},
+		{"delete too large", `[60,-60]`, ErrLengthTooLarge},
+		{"target too large", `[100,"a"]`, ErrLengthTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := limits.DecodeJSON([]byte(tt.input))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDecodeLimitsJSONMalformed(t *testing.T) {
+	inputs := []string{`[1e18]`, `[1.5]`, `[true]`, `[[1]]`, `{}`, `[1,`, `[1] 0`, `null 0`}
+
+	for _, input := range inputs {
+		if _, err := (DecodeLimits{}).DecodeJSON([]byte(input)); err == nil {
+			t.Errorf("%s: expected error", input)
+		}
+	}
+}
+
+func TestDecodeOverflow(t *testing.T) {
+	// Counts that would wrap the lengths are rejected even without limits.
+	for _, input := range []string{`[4611686018427387904,4611686018427387904]`, `[1,-4611686018427387904,"x",-4611686018427387904]`} {
+		if _, err := (DecodeLimits{}).DecodeJSON([]byte(input)); !errors.Is(err, ErrLengthTooLarge) {
+			t.Errorf("%s: expected ErrLengthTooLarge from DecodeJSON, got %v", input, err)
+		}
+		if err := NewOperationSeq().UnmarshalJSON([]byte(input)); !errors.Is(err, ErrLengthTooLarge) {
+			t.Errorf("%s: expected ErrLengthTooLarge from UnmarshalJSON, got %v", input, err)
+		}
+	}
+	if err := NewOperationSeq().UnmarshalJSON([]byte(`[1e30]`)); err == nil {
+		t.Errorf("expected UnmarshalJSON to reject a count out of range")
+	}
+}
+
+func TestDecodeLimitsZeroMatchesUnmarshal(t *testing.T) {
+	input := `[3,"héllo",-2,"x",7]`
+
+	var want OperationSeq
+	if err := want.UnmarshalJSON([]byte(input)); err != nil {
+		t.Fatalf("UnmarshalJSON failed: %v", err)
+	}
+
+	got, err := DecodeLimits{}.DecodeJSON([]byte(input))
+	if err != nil {
+		t.Fatalf("DecodeJSON failed: %v", err)
+	}
+	if got.String() != want.String() || got.baseLen != want.baseLen || got.targetLen != want.targetLen {
+		t.Errorf("expected %s, got %s", &want, got)
+	}
+}
+
+func TestDecodeLimitsBinary(t *testing.T) {
+	o := NewOperationSeq()
+	o.Retain(10)
+	o.Insert(strings.Repeat("x", 20))
+
+	s, err := o.EncodeString()
+	if err != nil {
+		t.Fatalf("EncodeString failed: %v", err)
+	}
+
+	if _, err := (DecodeLimits{MaxInsertLen: 20}).DecodeString(s); err != nil {
+		t.Errorf("expected success, got %v", err)
+	}
+	if _, err := (DecodeLimits{MaxInsertLen: 19}).DecodeString(s); !errors.Is(err, ErrOpTooLarge) {
+		t.Errorf("expected ErrOpTooLarge, got %v", err)
+	}
+	if _, err := (DecodeLimits{MaxOps: 1}).DecodeString(s); !errors.Is(err, ErrTooManyOps) {
+		t.Errorf("expected ErrTooManyOps, got %v", err)
+	}
+	if _, err := (DecodeLimits{MaxLen: 29}).DecodeString(s); !errors.Is(err, ErrLengthTooLarge) {
+		t.Errorf("expected ErrLengthTooLarge, got %v", err)
+	}
+}
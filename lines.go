@@ -0,0 +1,195 @@
+package ot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrLinesNotTracked is returned by Server.OffsetToPosition and
+// Server.PositionToOffset when the server is not keeping a line index.
+var ErrLinesNotTracked = errors.New("line index not tracked")
+
+// Position is a zero-based line and column in a document. Columns count
+// characters, like every other offset in this package.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// LineIndex converts between character offsets and line/column positions in
+// a document. It is kept in step with the document by applying the same
+// operations to both, so converting after each edit does not rescan the
+// text. A LineIndex is not safe for concurrent use; even conversions update
+// a cache. A Server's index, kept by TrackLines, is guarded by the server.
+type LineIndex struct {
+	lines  []int // characters in each line, including its newline
+	len    int
+	starts []int // offset of each line, rebuilt on demand after Apply
+}
+
+// NewLineIndex returns the line index of doc.
+func NewLineIndex(doc string) *LineIndex {
+	l := &LineIndex{len: charCount(doc)}
+	for {
+		i := strings.IndexByte(doc, '\n')
+		if i < 0 {
+			l.lines = append(l.lines, utf8.RuneCountInString(doc))
+			return l
+		}
+		l.lines = append(l.lines, utf8.RuneCountInString(doc[:i])+1)
+		doc = doc[i+1:]
+	}
+}
+
+// Len returns the length of the indexed document in characters.
+func (l *LineIndex) Len() int {
+	return l.len
+}
+
+// Lines returns the number of lines in the indexed document. An empty
+// document, and one ending in a newline, has an empty last line.
+func (l *LineIndex) Lines() int {
+	return len(l.lines)
+}
+
+// Apply updates the index for op, which must apply to the indexed document.
+func (l *LineIndex) Apply(op *OperationSeq) error {
+	if op.baseLen != l.len {
+		return lengthMismatch(op.baseLen, l.len)
+	}
+
+	out := make([]int, 0, len(l.lines))
+	i, off := 0, 0 // position in l.lines
+	cur := 0       // length of the line being built
+	last := len(l.lines) - 1
+	take := func(n int, keep bool) {
+		for n > 0 {
+			k := min(n, l.lines[i]-off)
+			if keep {
+				cur += k
+			}
+			n -= k
+			if off += k; off == l.lines[i] && i < last {
+				// Passed the line's newline.
+				if keep {
+					out = append(out, cur)
+					cur = 0
+				}
+				i, off = i+1, 0
+			}
+		}
+	}
+	for _, v := range op.ops {
+		switch v.Kind {
+		case KindRetain:
+			take(int(v.N), true)
+		case KindDelete:
+			take(int(v.N), false)
+		case KindInsert:
+			text := v.Text
+			for {
+				j := strings.IndexByte(text, '\n')
+				if j < 0 {
+					cur += utf8.RuneCountInString(text)
+					break
+				}
+				out = append(out, cur+utf8.RuneCountInString(text[:j])+1)
+				cur = 0
+				text = text[j+1:]
+			}
+		}
+	}
+	l.lines = append(out, cur)
+	l.len = op.targetLen
+	l.starts = nil
+	return nil
+}
+
+// OffsetToPosition returns the position of the character at offset. The
+// offset just past a newline is the start of the next line, and the length
+// of the document is the end of the last line.
+func (l *LineIndex) OffsetToPosition(offset int) (Position, error) {
+	if offset < 0 || offset > l.len {
+		return Position{}, fmt.Errorf("offset %d out of range for document of length %d", offset, l.len)
+	}
+	starts := l.lineStarts()
+	line := sort.Search(len(starts), func(i int) bool { return starts[i] > offset }) - 1
+	return Position{Line: line, Column: offset - starts[line]}, nil
+}
+
+// PositionToOffset returns the offset of pos. The column may be at most the
+// length of the line, not counting its newline.
+func (l *LineIndex) PositionToOffset(pos Position) (int, error) {
+	if pos.Line < 0 || pos.Line >= len(l.lines) {
+		return 0, fmt.Errorf("line %d out of range for document of %d lines", pos.Line, len(l.lines))
+	}
+	width := l.lines[pos.Line]
+	if pos.Line < len(l.lines)-1 {
+		width-- // the newline
+	}
+	if pos.Column < 0 || pos.Column > width {
+		return 0, fmt.Errorf("column %d out of range for line %d of length %d", pos.Column, pos.Line, width)
+	}
+	return l.lineStarts()[pos.Line] + pos.Column, nil
+}
+
+func (l *LineIndex) lineStarts() []int {
+	if l.starts == nil {
+		l.starts = make([]int, len(l.lines))
+		for i := 1; i < len(l.lines); i++ {
+			l.starts[i] = l.starts[i-1] + l.lines[i-1]
+		}
+	}
+	return l.starts
+}
+
+// TrackLines starts keeping a line index of the document, for
+// OffsetToPosition and PositionToOffset. It is kept in memory only.
+func (s *Server) TrackLines() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lines == nil {
+		s.lines = NewLineIndex(s.doc)
+	}
+}
+
+// indexLines updates the line index, if kept, for op. Callers hold s.mu.
+func (s *Server) indexLines(op *OperationSeq) {
+	if s.lines == nil {
+		return
+	}
+	if err := s.lines.Apply(op); err != nil {
+		// Only possible if the index was already out of step.
+		s.logger.Error("line index out of step; rebuilt", "revision", s.revision(), "err", err)
+		s.lines = NewLineIndex(s.doc)
+	}
+}
+
+// OffsetToPosition converts an offset in the current document to a line and
+// column, together with the revision it refers to. Returns
+// ErrLinesNotTracked unless TrackLines was called.
+func (s *Server) OffsetToPosition(offset int) (Position, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lines == nil {
+		return Position{}, 0, ErrLinesNotTracked
+	}
+	pos, err := s.lines.OffsetToPosition(offset)
+	return pos, s.revision(), err
+}
+
+// PositionToOffset converts a line and column in the current document to an
+// offset, together with the revision it refers to. Returns
+// ErrLinesNotTracked unless TrackLines was called.
+func (s *Server) PositionToOffset(pos Position) (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lines == nil {
+		return 0, 0, ErrLinesNotTracked
+	}
+	offset, err := s.lines.PositionToOffset(pos)
+	return offset, s.revision(), err
+}
@@ -0,0 +1,128 @@
+package ot
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLineIndexApply(t *testing.T) {
+	cases := []struct {
+		name string
+		doc  string
+		op   func() *OperationSeq
+	}{
+		{"insert newline", "hello world", func() *OperationSeq {
+			op := NewOperationSeq()
+			op.Retain(5)
+			op.Insert("\n")
+			op.Retain(6)
+			return op
+		}},
+		{"join lines", "ab\ncd\nef", func() *OperationSeq {
+			op := NewOperationSeq()
+			op.Retain(2)
+			op.Delete(1)
+			op.Retain(5)
+			return op
+		}},
+		{"delete across lines", "ab\ncd\nef", func() *OperationSeq {
+			op := NewOperationSeq()
+			op.Retain(1)
+			op.Delete(6)
+			op.Retain(1)
+			return op
+		}},
+		{"multi-line paste", "aé\n🌍b", func() *OperationSeq {
+			op := NewOperationSeq()
+			op.Retain(4)
+			op.Insert("x\n\ny\n")
+			op.Retain(1)
+			return op
+		}},
+		{"trailing newline", "ab", func() *OperationSeq {
+			op := NewOperationSeq()
+			op.Retain(2)
+			op.Insert("\n")
+			return op
+		}},
+		{"clear", "a\nb\n", func() *OperationSeq {
+			op := NewOperationSeq()
+			op.Delete(4)
+			return op
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			op := c.op()
+			l := NewLineIndex(c.doc)
+			if err := l.Apply(op); err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			doc, err := op.Apply(c.doc)
+			if err != nil {
+				t.Fatalf("applying to the document failed: %v", err)
+			}
+			want := NewLineIndex(doc)
+			if !reflect.DeepEqual(l.lines, want.lines) || l.Len() != want.Len() {
+				t.Errorf("expected lines %v, got %v", want.lines, l.lines)
+			}
+		})
+	}
+
+	if err := NewLineIndex("abc").Apply(NewOperationSeq()); !errors.Is(err, ErrIncompatibleLengths) {
+		t.Errorf("expected ErrIncompatibleLengths, got %v", err)
+	}
+}
+
+func TestLineIndexConversions(t *testing.T) {
+	l := NewLineIndex("ab\n🌍\n")
+	if l.Lines() != 3 {
+		t.Errorf("expected 3 lines, got %d", l.Lines())
+	}
+	for offset, want := range []Position{{0, 0}, {0, 1}, {0, 2}, {1, 0}, {1, 1}, {2, 0}} {
+		pos, err := l.OffsetToPosition(offset)
+		if err != nil || pos != want {
+			t.Errorf("offset %d: expected %v, got %v (%v)", offset, want, pos, err)
+		}
+		back, err := l.PositionToOffset(want)
+		if err != nil || back != offset {
+			t.Errorf("position %v: expected %d, got %d (%v)", want, offset, back, err)
+		}
+	}
+
+	if _, err := l.OffsetToPosition(6); err == nil {
+		t.Error("expected an error past the end")
+	}
+	if _, err := l.PositionToOffset(Position{Line: 0, Column: 3}); err == nil {
+		t.Error("expected an error past the end of the line")
+	}
+	if _, err := l.PositionToOffset(Position{Line: 3}); err == nil {
+		t.Error("expected an error past the last line")
+	}
+}
+
+func TestServerTrackLines(t *testing.T) {
+	s := NewServer("one\ntwo")
+	if _, _, err := s.OffsetToPosition(0); !errors.Is(err, ErrLinesNotTracked) {
+		t.Errorf("expected ErrLinesNotTracked, got %v", err)
+	}
+	s.TrackLines()
+
+	op := NewOperationSeq()
+	op.Insert("zero\n")
+	op.Retain(7)
+	if _, err := s.ReceiveOperation(context.Background(), 0, op); err != nil {
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+
+	pos, rev, err := s.OffsetToPosition(10)
+	if err != nil || rev != 1 || pos != (Position{Line: 2, Column: 1}) {
+		t.Errorf("expected {2 1} at revision 1, got %v at %d (%v)", pos, rev, err)
+	}
+	offset, _, err := s.PositionToOffset(Position{Line: 1, Column: 3})
+	if err != nil || offset != 8 {
+		t.Errorf("expected offset 8, got %d (%v)", offset, err)
+	}
+}
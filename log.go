@@ -0,0 +1,34 @@
+package ot
+
+import (
+	"context"
+	"log/slog"
+)
+
+// discard is the logger used when none is configured.
+var discard = slog.New(discardHandler{})
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// SetLogger sets where the server logs rejected operations, transform
+// failures, and Store errors. The server does not add attributes naming the
+// document; pass logger.With("doc", name) for that. A nil logger disables
+// logging.
+//
+// Rejections caused by the client, such as a stale revision or an operation
+// over the limits, are logged at Info; failed transforms at Warn, since they
+// mean the client's document has diverged; and Store failures at Error,
+// except ErrConflict, which is routine with several nodes and logged at Debug.
+func (s *Server) SetLogger(logger *slog.Logger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if logger == nil {
+		logger = discard
+	}
+	s.logger = logger
+}
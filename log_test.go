@@ -0,0 +1,82 @@
+package ot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestServerLogger(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewServer("hello")
+	s.SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	if _, err := s.ReceiveOperation(context.Background(), 3, NewOperationSeq()); !errors.Is(err, ErrInvalidRevision) {
+		t.Fatalf("expected ErrInvalidRevision, got %v", err)
+	}
+	if !strings.Contains(buf.String(), `level=INFO msg="operation rejected" revision=3 err="invalid revision"`) {
+		t.Errorf("expected rejection to be logged, got %q", buf.String())
+	}
+
+	op := NewOperationSeq()
+	op.Retain(5)
+	op.Insert("!")
+	if _, err := s.ReceiveOperation(context.Background(), 0, op); err != nil {
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+	buf.Reset()
+	bad := NewOperationSeq()
+	bad.Retain(2)
+	if _, err := s.ReceiveOperation(context.Background(), 0, bad); !errors.Is(err, ErrIncompatibleLengths) {
+		t.Fatalf("expected ErrIncompatibleLengths, got %v", err)
+	}
+	if !strings.Contains(buf.String(), `level=WARN msg="transform failed" revision=0 against=0`) {
+		t.Errorf("expected transform failure to be logged, got %q", buf.String())
+	}
+
+	s.SetLogger(nil)
+	if _, err := s.ReceiveOperation(context.Background(), -1, op); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestHubLogger(t *testing.T) {
+	var buf bytes.Buffer
+	h := &Hub{
+		Authorizer: testAuthorizer{},
+		Logger:     slog.New(slog.NewTextHandler(&buf, nil)),
+	}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+
+	sub, err := h.Subscribe(ctx, "notes", "viewer")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if _, err := h.Subscribe(ctx, "notes", "stranger"); err == nil {
+		t.Fatal("expected stranger to be refused")
+	}
+	op := NewOperationSeq()
+	op.Insert("x")
+	if _, _, err := h.Submit(ctx, "notes", "viewer", 0, op); err == nil {
+		t.Fatal("expected viewer to be refused")
+	}
+	sub.Close()
+
+	for _, want := range []string{
+		`msg="client joined" doc=notes client=viewer revision=0 readOnly=true`,
+		`msg="subscription refused" doc=notes client=stranger`,
+		`msg="operation refused" doc=notes client=viewer`,
+		`msg="client left" doc=notes client=viewer`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected log to contain %q, got %q", want, buf.String())
+		}
+	}
+	// Refused by the hub, so the server never saw it.
+	if strings.Contains(buf.String(), "operation rejected") {
+		t.Errorf("expected no server rejection, got %q", buf.String())
+	}
+}
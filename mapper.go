@@ -0,0 +1,90 @@
+package ot
+
+import "sort"
+
+// PositionMapper maps character offsets between the documents before and
+// after an operation, in time logarithmic in the number of its components.
+// It is built once per operation, so remapping many positions, such as
+// bookmarks, diagnostics, or breakpoints, does not walk the operation for
+// each one. A PositionMapper is safe for concurrent use.
+type PositionMapper struct {
+	forward  []mapSpan // by offset in the document before
+	backward []mapSpan // by offset in the document after
+}
+
+// mapSpan maps offsets from start onwards to offsets from to onwards, one
+// for one if keep is set and all to to otherwise. gap counts the characters
+// inserted at start, just before to.
+type mapSpan struct {
+	start, to int
+	keep      bool
+	gap       int
+}
+
+// NewPositionMapper returns a mapper for o.
+func NewPositionMapper(o *OperationSeq) *PositionMapper {
+	m := &PositionMapper{
+		forward:  make([]mapSpan, 0, len(o.ops)+1),
+		backward: make([]mapSpan, 0, len(o.ops)+1),
+	}
+	before, after, gap := 0, 0, 0
+	for _, v := range o.ops {
+		switch v.Kind {
+		case KindRetain:
+			m.forward = append(m.forward, mapSpan{before, after, true, gap})
+			m.backward = append(m.backward, mapSpan{after, before, true, 0})
+			before += int(v.N)
+			after += int(v.N)
+			gap = 0
+		case KindDelete:
+			m.forward = append(m.forward, mapSpan{before, after, false, gap})
+			before += int(v.N)
+			gap = 0
+		case KindInsert:
+			m.backward = append(m.backward, mapSpan{after, before, false, 0})
+			after += int(v.N)
+			gap += int(v.N)
+		}
+	}
+	// Offsets past the end move with it.
+	m.forward = append(m.forward, mapSpan{before, after, true, gap})
+	m.backward = append(m.backward, mapSpan{after, before, true, 0})
+	return m
+}
+
+// MapForward returns where an offset in the document before the operation
+// ends up in the document after it, as TransformIndex does: text inserted at
+// the offset pushes it forward, and an offset inside deleted text moves to
+// the start of the deletion.
+func (m *PositionMapper) MapForward(offset int) int {
+	return mapOffset(m.forward, offset, true)
+}
+
+// mapForward is MapForward, except that if after is false, text inserted at
+// the offset is left after it.
+func (m *PositionMapper) mapForward(offset int, after bool) int {
+	return mapOffset(m.forward, offset, after)
+}
+
+// MapBackward returns where an offset in the document after the operation
+// was in the document before it. An offset inside inserted text moves to
+// where the text was inserted, and one just after deleted text stays after
+// it.
+func (m *PositionMapper) MapBackward(offset int) int {
+	return mapOffset(m.backward, offset, true)
+}
+
+func mapOffset(spans []mapSpan, offset int, after bool) int {
+	i := sort.Search(len(spans), func(i int) bool { return spans[i].start > offset }) - 1
+	if i < 0 {
+		return offset
+	}
+	s := spans[i]
+	if !after && offset == s.start {
+		return s.to - s.gap
+	}
+	if !s.keep {
+		return s.to
+	}
+	return s.to + offset - s.start
+}
@@ -0,0 +1,40 @@
+package ot
+
+import "testing"
+
+func TestPositionMapperForward(t *testing.T) {
+	a, b := benchmarkEdits()
+	for _, op := range []*OperationSeq{a, b} {
+		m := NewPositionMapper(op)
+		for i := 0; i <= op.BaseLen()+1; i++ {
+			if got, want := m.MapForward(i), op.TransformIndex(i); got != want {
+				t.Fatalf("offset %d: expected %d, got %d", i, want, got)
+			}
+			if got, want := m.mapForward(i, false), op.transformIndex(i, false); got != want {
+				t.Fatalf("offset %d: expected %d sticking left, got %d", i, want, got)
+			}
+		}
+	}
+}
+
+func TestPositionMapperBackward(t *testing.T) {
+	// "hello world" → "hi, world!"
+	op := NewOperationSeq()
+	op.Retain(1)
+	op.Insert("i,")
+	op.Delete(4)
+	op.Retain(6)
+	op.Insert("!")
+	m := NewPositionMapper(op)
+
+	for after, want := range []int{0, 1, 1, 5, 6, 7, 8, 9, 10, 11, 11} {
+		if got := m.MapBackward(after); got != want {
+			t.Errorf("offset %d: expected %d, got %d", after, want, got)
+		}
+	}
+	for before, want := range []int{0, 3, 3, 3, 3, 3, 4, 5, 6, 7, 8, 10} {
+		if got := m.MapForward(before); got != want {
+			t.Errorf("offset %d: expected %d forward, got %d", before, want, got)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package ot
+
+import "maps"
+
+// Stickiness decides which way a marker endpoint goes when text is inserted
+// exactly where it is.
+type Stickiness int
+
+const (
+	// StickRight keeps the endpoint with the text after it, so text
+	// inserted there ends up before it, as with TransformIndex.
+	StickRight Stickiness = iota
+
+	// StickLeft keeps the endpoint with the text before it, so text
+	// inserted there ends up after it.
+	StickLeft
+)
+
+// Marker is a point or range in a document, such as a search result, a lint
+// diagnostic, or a remote cursor, in character offsets. A point marker has
+// Start == End. Each endpoint has its own Stickiness; the zero values keep
+// text inserted at the start outside a range and text inserted at the end
+// inside it.
+type Marker struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+
+	StartStickiness Stickiness `json:"startStickiness,omitempty"`
+	EndStickiness   Stickiness `json:"endStickiness,omitempty"`
+}
+
+// Transform returns the marker moved through o. End never moves before
+// Start; a point marker whose endpoints stick apart stays a point at Start.
+func (m Marker) Transform(o *OperationSeq) Marker {
+	m.Start = o.transformIndex(m.Start, m.StartStickiness != StickLeft)
+	m.End = max(m.Start, o.transformIndex(m.End, m.EndStickiness != StickLeft))
+	return m
+}
+
+func (m Marker) transform(pm *PositionMapper) Marker {
+	m.Start = pm.mapForward(m.Start, m.StartStickiness != StickLeft)
+	m.End = max(m.Start, pm.mapForward(m.End, m.EndStickiness != StickLeft))
+	return m
+}
+
+// MarkerSet holds markers by ID and moves them in bulk: every operation
+// applied to the document must be passed to Transform, which maps all the
+// markers through a PositionMapper built once for the operation.
+//
+// The zero value is ready to use. A MarkerSet is not safe for concurrent
+// use.
+type MarkerSet struct {
+	markers map[string]Marker
+}
+
+// Set places the marker id at m, which must refer to the current document.
+func (s *MarkerSet) Set(id string, m Marker) {
+	if s.markers == nil {
+		s.markers = make(map[string]Marker)
+	}
+	s.markers[id] = m
+}
+
+// Get returns the marker id.
+func (s *MarkerSet) Get(id string) (Marker, bool) {
+	m, ok := s.markers[id]
+	return m, ok
+}
+
+// Delete removes the marker id.
+func (s *MarkerSet) Delete(id string) {
+	delete(s.markers, id)
+}
+
+// Clear removes every marker, as when a search is cleared.
+func (s *MarkerSet) Clear() {
+	clear(s.markers)
+}
+
+// Len returns the number of markers.
+func (s *MarkerSet) Len() int {
+	return len(s.markers)
+}
+
+// Markers returns a copy of the markers by ID.
+func (s *MarkerSet) Markers() map[string]Marker {
+	return maps.Clone(s.markers)
+}
+
+// Transform moves every marker through o, an operation applied to the
+// current document.
+func (s *MarkerSet) Transform(o *OperationSeq) {
+	if len(s.markers) == 0 {
+		return
+	}
+	pm := NewPositionMapper(o)
+	for id, m := range s.markers {
+		s.markers[id] = m.transform(pm)
+	}
+}
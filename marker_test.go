@@ -0,0 +1,65 @@
+package ot
+
+import "testing"
+
+func TestMarkerTransform(t *testing.T) {
+	// "hello world" with "xx" inserted at 6 and "!" at the end.
+	op := NewOperationSeq()
+	op.Retain(6)
+	op.Insert("xx")
+	op.Retain(5)
+	op.Insert("!")
+
+	tests := []struct {
+		name string
+		m    Marker
+		want Marker
+	}{
+		{"point sticking right", Marker{Start: 6, End: 6}, Marker{Start: 8, End: 8}},
+		{"point sticking left", Marker{Start: 6, End: 6, StartStickiness: StickLeft, EndStickiness: StickLeft},
+			Marker{Start: 6, End: 6, StartStickiness: StickLeft, EndStickiness: StickLeft}},
+		{"point sticking apart", Marker{Start: 6, End: 6, EndStickiness: StickLeft},
+			Marker{Start: 8, End: 8, EndStickiness: StickLeft}},
+		{"range excluding both", Marker{Start: 6, End: 11, EndStickiness: StickLeft},
+			Marker{Start: 8, End: 13, EndStickiness: StickLeft}},
+		{"range including both", Marker{Start: 6, End: 11, StartStickiness: StickLeft},
+			Marker{Start: 6, End: 14, StartStickiness: StickLeft}},
+	}
+	for _, tt := range tests {
+		if got := tt.m.Transform(op); got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+		s := &MarkerSet{}
+		s.Set("m", tt.m)
+		s.Transform(op)
+		if got, _ := s.Get("m"); got != tt.want {
+			t.Errorf("%s: expected %+v from the set, got %+v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestMarkerSet(t *testing.T) {
+	s := &MarkerSet{}
+	s.Set("a", Marker{Start: 1, End: 3})
+	s.Set("b", Marker{Start: 4, End: 4})
+
+	op := NewOperationSeq()
+	op.Delete(2)
+	op.Retain(3)
+	s.Transform(op)
+	if m, _ := s.Get("a"); m != (Marker{Start: 0, End: 1}) {
+		t.Errorf("expected a at 0-1, got %+v", m)
+	}
+	if m, _ := s.Get("b"); m != (Marker{Start: 2, End: 2}) {
+		t.Errorf("expected b at 2, got %+v", m)
+	}
+
+	s.Delete("a")
+	if _, ok := s.Get("a"); ok || s.Len() != 1 {
+		t.Errorf("expected only b, got %v", s.Markers())
+	}
+	s.Clear()
+	if s.Len() != 0 {
+		t.Errorf("expected no markers, got %v", s.Markers())
+	}
+}
@@ -0,0 +1,53 @@
+package ot
+
+import "fmt"
+
+// Merge3Result is the outcome of Merge3.
+type Merge3Result struct {
+	// Doc is the merged document.
+	Doc string
+
+	// Mine brings the mine side to Doc: the theirs changes, transformed
+	// past the mine ones. Theirs likewise brings the theirs side to Doc.
+	Mine   *OperationSeq
+	Theirs *OperationSeq
+}
+
+// Merge3 merges two chains of operations that diverged from base, such as
+// an offline fork and the document it was copied from, or external edits
+// imported against an old version. Each chain is composed and the two are
+// transformed against each other. Where both insert at the same place the
+// texts are ordered as Transform orders them, by the texts and not by side,
+// so swapping mine and theirs gives the same Doc; see Guarantees.
+//
+// A chain that does not apply to base is reported as a *ReplayError, with
+// the operation's index in its chain as the Revision, wrapped with the side
+// it came from.
+func Merge3(base string, mine, theirs []*OperationSeq) (Merge3Result, error) {
+	mineDoc, err := ReplayLog(base, mine)
+	if err != nil {
+		return Merge3Result{}, fmt.Errorf("mine: %w", err)
+	}
+	if _, err := ReplayLog(base, theirs); err != nil {
+		return Merge3Result{}, fmt.Errorf("theirs: %w", err)
+	}
+
+	n := charCount(base)
+	a, err := composeAll(n, mine)
+	if err != nil {
+		return Merge3Result{}, err
+	}
+	b, err := composeAll(n, theirs)
+	if err != nil {
+		return Merge3Result{}, err
+	}
+	aPrime, bPrime, err := a.Transform(b)
+	if err != nil {
+		return Merge3Result{}, err
+	}
+	doc, err := bPrime.Apply(mineDoc)
+	if err != nil {
+		return Merge3Result{}, err
+	}
+	return Merge3Result{Doc: doc, Mine: bPrime, Theirs: aPrime}, nil
+}
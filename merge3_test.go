@@ -0,0 +1,52 @@
+package ot
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+	base := "the cat sat"
+	mine := []*OperationSeq{NewOperationSeq(), NewOperationSeq()}
+	mine[0].Retain(4)
+	mine[0].Insert("fat ")
+	mine[0].Retain(7) // "the fat cat sat"
+	mine[1].Retain(15)
+	mine[1].Insert(" down") // "the fat cat sat down"
+	theirs := []*OperationSeq{NewOperationSeq()}
+	theirs[0].Insert("Yes, ")
+	theirs[0].Delete(3)
+	theirs[0].Insert("a")
+	theirs[0].Retain(8) // "Yes, a cat sat"
+
+	res, err := Merge3(base, mine, theirs)
+	if err != nil {
+		t.Fatalf("Merge3 failed: %v", err)
+	}
+	if want := "Yes, a fat cat sat down"; res.Doc != want {
+		t.Errorf("expected %q, got %q", want, res.Doc)
+	}
+	if got, err := res.Mine.Apply("the fat cat sat down"); err != nil || got != res.Doc {
+		t.Errorf("expected mine to reach %q, got %q (%v)", res.Doc, got, err)
+	}
+	if got, err := res.Theirs.Apply("Yes, a cat sat"); err != nil || got != res.Doc {
+		t.Errorf("expected theirs to reach %q, got %q (%v)", res.Doc, got, err)
+	}
+
+	// Concurrent inserts at the same place are ordered by their text, not
+	// by which side made them.
+	a, b := NewOperationSeq(), NewOperationSeq()
+	a.Insert("theirs")
+	b.Insert("mine")
+	for _, sides := range [][2]*OperationSeq{{a, b}, {b, a}} {
+		res, err := Merge3("", []*OperationSeq{sides[0]}, []*OperationSeq{sides[1]})
+		if err != nil || res.Doc != "minetheirs" {
+			t.Errorf("expected %q, got %q (%v)", "minetheirs", res.Doc, err)
+		}
+	}
+
+	var replayErr *ReplayError
+	if _, err := Merge3("abc", mine, theirs); !errors.As(err, &replayErr) || replayErr.Revision != 0 {
+		t.Errorf("expected a *ReplayError at 0, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package ot
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Meta is metadata attached to an operation: who made it, when, and on
+// which device. It travels with the operation through Transform, which keeps
+// each operation's metadata on its transformed counterpart, and Compose,
+// which merges it field by field with the later operation winning. The JSON
+// and binary encodings carry it too. Operations built from scratch, such as
+// an Invert, have none.
+type Meta struct {
+	Author string
+	Time   time.Time
+	Origin string
+}
+
+// IsZero reports whether m has no fields set.
+func (m Meta) IsZero() bool {
+	return m.Author == "" && m.Time.IsZero() && m.Origin == ""
+}
+
+// Meta returns the metadata attached to o, or the zero Meta if there is none.
+func (o *OperationSeq) Meta() Meta {
+	if o.meta == nil {
+		return Meta{}
+	}
+	return *o.meta
+}
+
+// SetMeta attaches m to o, replacing any metadata it had. A zero m removes
+// it.
+func (o *OperationSeq) SetMeta(m Meta) {
+	if m.IsZero() {
+		o.meta = nil
+		return
+	}
+	o.meta = &m
+}
+
+// composeMeta returns the metadata of the composition of operations carrying
+// a and b, in that order: b's fields, with those it leaves unset taken from
+// a. The pointers are shared, as metadata is never modified in place.
+func composeMeta(a, b *Meta) *Meta {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	m := *b
+	if m.Author == "" {
+		m.Author = a.Author
+	}
+	if m.Time.IsZero() {
+		m.Time = a.Time
+	}
+	if m.Origin == "" {
+		m.Origin = a.Origin
+	}
+	return &m
+}
+
+// metaJSON is the wire form of Meta, leaving out unset fields.
+type metaJSON struct {
+	Author string     `json:"author,omitempty"`
+	Time   *time.Time `json:"time,omitempty"`
+	Origin string     `json:"origin,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler for Meta, leaving out unset fields.
+func (m Meta) MarshalJSON() ([]byte, error) {
+	w := metaJSON{Author: m.Author, Origin: m.Origin}
+	if !m.Time.IsZero() {
+		w.Time = &m.Time
+	}
+	return json.Marshal(w)
+}
+
+// UnmarshalJSON implements json.Unmarshaler for Meta.
+func (m *Meta) UnmarshalJSON(data []byte) error {
+	var w metaJSON
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+	*m = Meta{Author: w.Author, Origin: w.Origin}
+	if w.Time != nil {
+		m.Time = *w.Time
+	}
+	return nil
+}
+
+// unmarshalMeta decodes metadata for an OperationSeq, which keeps nil for
+// none.
+func unmarshalMeta(data []byte) (*Meta, error) {
+	var m Meta
+	if err := m.UnmarshalJSON(data); err != nil || m.IsZero() {
+		return nil, err
+	}
+	return &m, nil
+}
@@ -0,0 +1,86 @@
+package ot
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestMetaTransformAndCompose(t *testing.T) {
+	at := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	a := NewOperationSeq()
+	a.Insert("a")
+	a.SetMeta(Meta{Author: "alice", Time: at, Origin: "laptop"})
+	b := NewOperationSeq()
+	b.Insert("b")
+	b.SetMeta(Meta{Author: "bob"})
+
+	aPrime, bPrime, err := a.Transform(b)
+	if err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+	if aPrime.Meta() != a.Meta() || bPrime.Meta() != b.Meta() {
+		t.Errorf("expected metadata kept, got %+v and %+v", aPrime.Meta(), bPrime.Meta())
+	}
+
+	composed, err := a.Compose(bPrime)
+	if err != nil {
+		t.Fatalf("Compose failed: %v", err)
+	}
+	want := Meta{Author: "bob", Time: at, Origin: "laptop"}
+	if got := composed.Meta(); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	plain := NewOperationSeq()
+	plain.Retain(2)
+	if composed, _ = composed.Compose(plain); composed.Meta() != want {
+		t.Errorf("expected %+v after composing without metadata, got %+v", want, composed.Meta())
+	}
+
+	a.SetMeta(Meta{})
+	if !a.Meta().IsZero() {
+		t.Errorf("expected no metadata, got %+v", a.Meta())
+	}
+}
+
+func TestMetaEncoding(t *testing.T) {
+	op := NewOperationSeq()
+	op.Retain(2)
+	op.Insert("hi")
+	op.SetMeta(Meta{Author: "alice", Time: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), Origin: "phone"})
+
+	data, err := json.Marshal(op)
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+	if want := `[2,"hi",{"author":"alice","time":"2024-01-02T15:04:05Z","origin":"phone"}]`; string(data) != want {
+		t.Errorf("expected %s, got %s", want, data)
+	}
+	var decoded OperationSeq
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Meta() != op.Meta() || decoded.String() != op.String() {
+		t.Errorf("expected %s from UnmarshalJSON, got %s (%v)", data, decoded.String(), err)
+	}
+	limited, err := DecodeLimits{}.DecodeJSON(data)
+	if err != nil || limited.Meta() != op.Meta() || limited.TargetLen() != 4 {
+		t.Errorf("expected %s from DecodeJSON, got %v (%v)", data, limited, err)
+	}
+
+	bin, err := op.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	var fromBinary OperationSeq
+	if err := fromBinary.UnmarshalBinary(bin); err != nil || fromBinary.Meta() != op.Meta() || fromBinary.String() != op.String() {
+		t.Errorf("expected %s from UnmarshalBinary, got %s (%v)", data, fromBinary.String(), err)
+	}
+
+	for _, bad := range []string{`[{"author":"alice"},2]`, `[2,{"author":"a"},{"author":"b"}]`} {
+		if err := json.Unmarshal([]byte(bad), &decoded); err == nil {
+			t.Errorf("expected UnmarshalJSON to reject %s", bad)
+		}
+		if _, err := (DecodeLimits{}).DecodeJSON([]byte(bad)); err == nil {
+			t.Errorf("expected DecodeJSON to reject %s", bad)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package ot
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Metrics receives measurements from a Server and a Hub. Implementations
+// must be safe for concurrent use and should return quickly, since they are
+// called with document locks held. Measurements are not labelled by
+// document, to keep the number of series bounded.
+//
+// The otprom package provides an implementation that serves the Prometheus
+// text format.
+type Metrics interface {
+	// OpAccepted is called for each operation a Server accepts.
+	OpAccepted()
+
+	// Transformed is called when a Server transforms an incoming operation
+	// against the count operations accepted since its revision, with the
+	// time taken. It is not called for operations made against the current
+	// revision.
+	Transformed(count int, elapsed time.Duration)
+
+	// Broadcast is called each time a Hub delivers an accepted operation,
+	// with the number of subscribers it reached.
+	Broadcast(subscribers int)
+
+	// ClientJoined and ClientLeft are called as Hub subscriptions start and
+	// end, so their difference is the number of active clients.
+	ClientJoined()
+	ClientLeft()
+}
+
+// TransformOutcome classifies how transformation changed an incoming
+// operation, as a measure of how contended a document is.
+type TransformOutcome int
+
+const (
+	// TransformUnchanged means the concurrent operations did not affect it:
+	// it makes the same changes at the same positions, and only the length
+	// of the text it retains at the end may differ.
+	TransformUnchanged TransformOutcome = iota
+
+	// TransformShifted means its positions moved, but it still inserts and
+	// deletes everything it did.
+	TransformShifted
+
+	// TransformDropped means some of the text it deleted had already been
+	// deleted concurrently, so that part of it was dropped.
+	TransformDropped
+)
+
+var transformOutcomeNames = [...]string{"unchanged", "shifted", "dropped"}
+
+// String returns the outcome's name, as used for labels.
+func (o TransformOutcome) String() string {
+	if o < 0 || int(o) >= len(transformOutcomeNames) {
+		return fmt.Sprintf("TransformOutcome(%d)", int(o))
+	}
+	return transformOutcomeNames[o]
+}
+
+// ConflictMetrics is implemented by Metrics that also count how incoming
+// operations fared when transformed. A Server checks for it and, after each
+// call to Transformed, reports the outcome for that operation.
+type ConflictMetrics interface {
+	TransformOutcome(outcome TransformOutcome)
+}
+
+// transformOutcome classifies transformed, the result of transforming
+// original against concurrent operations.
+func transformOutcome(original, transformed *OperationSeq) TransformOutcome {
+	if slices.Equal(trimRetain(original.ops), trimRetain(transformed.ops)) {
+		return TransformUnchanged
+	}
+	if deleted(transformed) < deleted(original) {
+		return TransformDropped
+	}
+	return TransformShifted
+}
+
+// trimRetain returns ops without a final retain.
+func trimRetain(ops []Component) []Component {
+	if n := len(ops); n > 0 && ops[n-1].Kind == KindRetain {
+		return ops[:n-1]
+	}
+	return ops
+}
+
+// deleted returns the number of characters op deletes.
+func deleted(op *OperationSeq) int {
+	n := 0
+	for _, c := range op.ops {
+		if c.Kind == KindDelete {
+			n += int(c.N)
+		}
+	}
+	return n
+}
+
+// NopMetrics is a Metrics that discards every measurement. It is used when
+// no Metrics is configured.
+type NopMetrics struct{}
+
+func (NopMetrics) OpAccepted()                    {}
+func (NopMetrics) Transformed(int, time.Duration) {}
+func (NopMetrics) Broadcast(int)                  {}
+func (NopMetrics) ClientJoined()                  {}
+func (NopMetrics) ClientLeft()                    {}
+
+// SetMetrics sets where the server reports measurements. A nil m disables
+// reporting.
+func (s *Server) SetMetrics(m Metrics) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m == nil {
+		m = NopMetrics{}
+	}
+	s.metrics = m
+}
@@ -0,0 +1,131 @@
+package ot
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingMetrics struct {
+	mu         sync.Mutex
+	accepted   int
+	transforms int
+	broadcasts []int
+	clients    int
+	outcomes   []TransformOutcome
+}
+
+func (m *recordingMetrics) OpAccepted() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.accepted++
+}
+
+func (m *recordingMetrics) Transformed(count int, _ time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.transforms += count
+}
+
+func (m *recordingMetrics) TransformOutcome(outcome TransformOutcome) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.outcomes = append(m.outcomes, outcome)
+}
+
+func (m *recordingMetrics) Broadcast(subscribers int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.broadcasts = append(m.broadcasts, subscribers)
+}
+
+func (m *recordingMetrics) ClientJoined() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clients++
+}
+
+func (m *recordingMetrics) ClientLeft() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clients--
+}
+
+func TestHubMetrics(t *testing.T) {
+	m := &recordingMetrics{}
+	h := &Hub{Metrics: m}
+	ctx := context.Background()
+
+	a, err := h.Subscribe(ctx, "doc", "a")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	b, err := h.Subscribe(ctx, "doc", "b")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	op := NewOperationSeq()
+	op.Insert("x")
+	if _, _, err := h.Submit(ctx, "doc", "a", 0, op); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	// Made against revision 0, so it is transformed against the first.
+	if _, _, err := h.Submit(ctx, "doc", "b", 0, op); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	b.Close()
+	a.Close()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.accepted != 2 {
+		t.Errorf("expected 2 accepted ops, got %d", m.accepted)
+	}
+	if m.transforms != 1 {
+		t.Errorf("expected 1 transform, got %d", m.transforms)
+	}
+	if len(m.broadcasts) != 2 || m.broadcasts[0] != 2 || m.broadcasts[1] != 2 {
+		t.Errorf("expected two broadcasts to 2 subscribers, got %v", m.broadcasts)
+	}
+	if m.clients != 0 {
+		t.Errorf("expected no active clients, got %d", m.clients)
+	}
+}
+
+func TestTransformOutcomes(t *testing.T) {
+	s := NewServer("hello world")
+	m := &recordingMetrics{}
+	s.SetMetrics(m)
+	ctx := context.Background()
+
+	// Revision 1 deletes "hello ".
+	del := NewOperationSeq()
+	del.Delete(6)
+	del.Retain(5)
+	if _, err := s.ReceiveOperation(ctx, 0, del); err != nil {
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+
+	ops := []func(op *OperationSeq){
+		func(op *OperationSeq) { op.Insert("> "); op.Retain(11) },           // at the start
+		func(op *OperationSeq) { op.Retain(11); op.Insert("!") },            // after the deletion
+		func(op *OperationSeq) { op.Retain(2); op.Delete(2); op.Retain(7) }, // inside it
+	}
+	for _, build := range ops {
+		op := NewOperationSeq()
+		build(op)
+		if _, err := s.ReceiveOperation(ctx, 0, op); err != nil {
+			t.Fatalf("ReceiveOperation failed: %v", err)
+		}
+	}
+
+	want := []TransformOutcome{TransformUnchanged, TransformShifted, TransformDropped}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !slices.Equal(m.outcomes, want) {
+		t.Errorf("expected %v, got %v", want, m.outcomes)
+	}
+}
@@ -0,0 +1,86 @@
+package ot
+
+import "unicode/utf8"
+
+// Minimize returns an operation with the same effect on doc as o, with
+// every insert trimmed against the text it replaces: characters deleted and
+// inserted again unchanged become retains, so replacing "hello" with "help"
+// retains "hel" and replaces only "lo" with "p". Components are merged as
+// the result is built, as they are in any OperationSeq.
+//
+// The result is meant for storing or sending operations that have already
+// been applied. It has the same effect on doc but not the same intent, so it
+// may transform differently against concurrent operations.
+//
+// Returns ErrIncompatibleLengths if o does not apply to doc.
+func (o *OperationSeq) Minimize(doc string) (*OperationSeq, error) {
+	if n := charCount(doc); n != o.baseLen {
+		return nil, lengthMismatch(o.baseLen, n)
+	}
+
+	out := WithCapacity(len(o.ops))
+	pos := 0 // byte offset in doc
+	var (
+		ins      string // text inserted since the last retain
+		delStart = -1   // byte offset where deleted text starts, if any
+	)
+	flush := func() {
+		del := ""
+		if delStart >= 0 {
+			del = doc[delStart:pos]
+		}
+		p := commonPrefix(ins, del)
+		s := commonSuffix(ins[p:], del[p:])
+		out.Retain(uint64(charCount(ins[:p])))
+		out.Insert(ins[p : len(ins)-s])
+		out.Delete(uint64(charCount(del[p : len(del)-s])))
+		out.Retain(uint64(charCount(ins[len(ins)-s:])))
+		ins, delStart = "", -1
+	}
+	for _, v := range o.ops {
+		switch v.Kind {
+		case KindRetain:
+			flush()
+			out.Retain(v.N)
+			pos = skipRunes(doc, pos, v.N)
+		case KindInsert:
+			ins += v.Text
+		case KindDelete:
+			if delStart < 0 {
+				delStart = pos
+			}
+			pos = skipRunes(doc, pos, v.N)
+		}
+	}
+	flush()
+	out.meta = o.meta
+	return out, nil
+}
+
+// commonPrefix returns the length in bytes of the longest common prefix of a
+// and b that ends between characters.
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) {
+		r, size := utf8.DecodeRuneInString(a[n:])
+		if q, qsize := utf8.DecodeRuneInString(b[n:]); r != q || size != qsize {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+// commonSuffix returns the length in bytes of the longest common suffix of a
+// and b that starts between characters.
+func commonSuffix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) {
+		r, size := utf8.DecodeLastRuneInString(a[:len(a)-n])
+		if q, qsize := utf8.DecodeLastRuneInString(b[:len(b)-n]); r != q || size != qsize {
+			break
+		}
+		n += size
+	}
+	return n
+}
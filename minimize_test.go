@@ -0,0 +1,115 @@
+package ot
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMinimize(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		op   func() *OperationSeq
+		want func() *OperationSeq
+	}{
+		{
+			name: "replace with shared prefix",
+			doc:  "say hello",
+			op: func() *OperationSeq {
+				op := NewOperationSeq()
+				op.Retain(4)
+				op.Insert("help")
+				op.Delete(5)
+				return op
+			},
+			want: func() *OperationSeq {
+				op := NewOperationSeq()
+				op.Retain(7)
+				op.Insert("p")
+				op.Delete(2)
+				return op
+			},
+		},
+		{
+			name: "identical text",
+			doc:  "héllo wörld",
+			op: func() *OperationSeq {
+				op := NewOperationSeq()
+				op.Insert("héllo")
+				op.Delete(5)
+				op.Retain(1)
+				op.Insert("wörld")
+				op.Delete(5)
+				return op
+			},
+			want: func() *OperationSeq {
+				op := NewOperationSeq()
+				op.Retain(11)
+				return op
+			},
+		},
+		{
+			name: "shared suffix",
+			doc:  "🌍b",
+			op: func() *OperationSeq {
+				op := NewOperationSeq()
+				op.Insert("a🌍")
+				op.Delete(1)
+				op.Retain(1)
+				return op
+			},
+			want: func() *OperationSeq {
+				op := NewOperationSeq()
+				op.Insert("a")
+				op.Retain(2)
+				return op
+			},
+		},
+		{
+			name: "nothing shared",
+			doc:  "abc",
+			op: func() *OperationSeq {
+				op := NewOperationSeq()
+				op.Retain(1)
+				op.Insert("x")
+				op.Delete(1)
+				op.Retain(1)
+				return op
+			},
+			want: func() *OperationSeq {
+				op := NewOperationSeq()
+				op.Retain(1)
+				op.Insert("x")
+				op.Delete(1)
+				op.Retain(1)
+				return op
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := tt.op()
+			got, err := op.Minimize(tt.doc)
+			if err != nil {
+				t.Fatalf("Minimize failed: %v", err)
+			}
+			want := tt.want()
+			if !reflect.DeepEqual(got.ops, want.ops) || got.baseLen != want.baseLen || got.targetLen != want.targetLen {
+				t.Errorf("expected %v, got %v", want.ops, got.ops)
+			}
+			before, err := op.Apply(tt.doc)
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			after, err := got.Apply(tt.doc)
+			if err != nil || after != before {
+				t.Errorf("expected %q, got %q (%v)", before, after, err)
+			}
+		})
+	}
+
+	if _, err := NewOperationSeq().Minimize("abc"); !errors.Is(err, ErrIncompatibleLengths) {
+		t.Errorf("expected ErrIncompatibleLengths, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package ot
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrReadOnly is returned when an operation is refused because of the
+// document's Mode. The client should stop editing until the mode changes.
+var ErrReadOnly = errors.New("document is read-only")
+
+// Mode controls which operations a Server accepts. The zero value,
+// ModeEditable, accepts everything.
+type Mode int
+
+const (
+	// ModeEditable accepts every operation.
+	ModeEditable Mode = iota
+
+	// ModeReadOnly refuses every operation.
+	ModeReadOnly
+
+	// ModeCommentOnly refuses operations that change the text. Operations
+	// that leave it untouched are accepted, so annotations carried alongside
+	// the text keep working.
+	ModeCommentOnly
+
+	// ModeFrozen refuses every operation once the document reaches the
+	// revision it was frozen at. Unlike ModeReadOnly, it records that
+	// revision, so the document can be frozen ahead of time.
+	ModeFrozen
+)
+
+var modeNames = [...]string{"editable", "readOnly", "commentOnly", "frozen"}
+
+// String returns the name used for m in the JSON wire format.
+func (m Mode) String() string {
+	if m < 0 || int(m) >= len(modeNames) {
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+	return modeNames[m]
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (m Mode) MarshalText() ([]byte, error) {
+	if m < 0 || int(m) >= len(modeNames) {
+		return nil, fmt.Errorf("invalid mode: %d", int(m))
+	}
+	return []byte(modeNames[m]), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (m *Mode) UnmarshalText(text []byte) error {
+	for i, name := range modeNames {
+		if string(text) == name {
+			*m = Mode(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid mode: %q", text)
+}
+
+// SetMode changes the operations the server accepts from now on. ModeFrozen
+// freezes the document at its current revision; use FreezeAt to freeze it
+// later. Operations from Sync were accepted elsewhere and are always applied.
+func (s *Server) SetMode(mode Mode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mode = mode
+	s.frozenAt = s.revision()
+}
+
+// FreezeAt keeps the document editable until it reaches revision and freezes
+// it there. It returns ErrInvalidRevision if the document is already past
+// revision.
+func (s *Server) FreezeAt(revision int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if revision < s.revision() {
+		return ErrInvalidRevision
+	}
+	s.mode = ModeFrozen
+	s.frozenAt = revision
+	return nil
+}
+
+// Mode returns the mode in effect. A document frozen at a revision it has
+// not reached yet is still ModeEditable.
+func (s *Server) Mode() Mode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.currentMode()
+}
+
+// FrozenAt returns the revision the document is, or will be, frozen at, and
+// whether it is set to freeze at all.
+func (s *Server) FrozenAt() (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.frozenAt, s.mode == ModeFrozen
+}
+
+func (s *Server) currentMode() Mode {
+	if s.mode == ModeFrozen && s.revision() < s.frozenAt {
+		return ModeEditable
+	}
+	return s.mode
+}
+
+// checkMode refuses op if the current mode does not allow it.
+func (s *Server) checkMode(op *OperationSeq) error {
+	switch s.currentMode() {
+	case ModeReadOnly, ModeFrozen:
+		return ErrReadOnly
+	case ModeCommentOnly:
+		if !op.IsNoop() {
+			return ErrReadOnly
+		}
+	}
+	return nil
+}
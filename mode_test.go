@@ -0,0 +1,130 @@
+package ot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestServerMode(t *testing.T) {
+	s := NewServer("hello")
+	edit := NewOperationSeq()
+	edit.Retain(5)
+	edit.Insert("!")
+	noop := NewOperationSeq()
+	noop.Retain(5)
+
+	s.SetMode(ModeReadOnly)
+	if _, err := s.ReceiveOperation(context.Background(), 0, noop); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("read-only: expected ErrReadOnly, got %v", err)
+	}
+
+	s.SetMode(ModeCommentOnly)
+	if _, err := s.ReceiveOperation(context.Background(), 0, edit); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("comment-only: expected ErrReadOnly, got %v", err)
+	}
+	if _, err := s.ReceiveOperation(context.Background(), 0, noop); err != nil {
+		t.Errorf("comment-only: expected no-op accepted, got %v", err)
+	}
+
+	s.SetMode(ModeEditable)
+	if _, err := s.ReceiveOperation(context.Background(), 1, edit); err != nil {
+		t.Fatalf("editable: ReceiveOperation failed: %v", err)
+	}
+	if s.Document() != "hello!" {
+		t.Errorf("expected %q, got %q", "hello!", s.Document())
+	}
+}
+
+func TestServerFreezeAt(t *testing.T) {
+	s := NewServer("")
+	if err := s.FreezeAt(1); err != nil {
+		t.Fatalf("FreezeAt failed: %v", err)
+	}
+	if s.Mode() != ModeEditable {
+		t.Errorf("expected editable before revision 1, got %v", s.Mode())
+	}
+
+	op := NewOperationSeq()
+	op.Insert("a")
+	if _, err := s.ReceiveOperation(context.Background(), 0, op); err != nil {
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+	if s.Mode() != ModeFrozen {
+		t.Errorf("expected frozen at revision 1, got %v", s.Mode())
+	}
+	if at, ok := s.FrozenAt(); !ok || at != 1 {
+		t.Errorf("expected frozen at 1, got %d, %t", at, ok)
+	}
+	if _, err := s.ReceiveOperation(context.Background(), 0, op); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+	if err := s.FreezeAt(0); !errors.Is(err, ErrInvalidRevision) {
+		t.Errorf("expected ErrInvalidRevision, got %v", err)
+	}
+}
+
+func TestModeJSON(t *testing.T) {
+	data, err := json.Marshal(map[string]Mode{"mode": ModeCommentOnly})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(data) != `{"mode":"commentOnly"}` {
+		t.Errorf("expected %s, got %s", `{"mode":"commentOnly"}`, data)
+	}
+	var m map[string]Mode
+	if err := json.Unmarshal(data, &m); err != nil || m["mode"] != ModeCommentOnly {
+		t.Errorf("expected commentOnly, got %v (%v)", m["mode"], err)
+	}
+	if err := json.Unmarshal([]byte(`{"mode":"locked"}`), &m); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestHubMode(t *testing.T) {
+	h := NewHub(func(context.Context, string) (*Server, error) { return NewServer(""), nil })
+	ctx := context.Background()
+
+	sub, err := h.Subscribe(ctx, "doc", "alice")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Close()
+	<-sub.C // own join
+
+	if err := h.SetMode(ctx, "doc", ModeReadOnly); err != nil {
+		t.Fatalf("SetMode failed: %v", err)
+	}
+	if ev := <-sub.C; ev.Kind != EventMode || ev.Mode != ModeReadOnly {
+		t.Errorf("expected read-only mode event, got %+v", ev)
+	}
+	if err := h.SetMode(ctx, "doc", ModeEditable); err != nil {
+		t.Fatalf("SetMode failed: %v", err)
+	}
+	<-sub.C
+
+	if err := h.FreezeAt(ctx, "doc", 1); err != nil {
+		t.Fatalf("FreezeAt failed: %v", err)
+	}
+	op := NewOperationSeq()
+	op.Insert("a")
+	if _, _, err := h.Submit(ctx, "doc", "alice", 0, op); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if ev := <-sub.C; ev.Kind != EventOp {
+		t.Errorf("expected op event, got %+v", ev)
+	}
+	if ev := <-sub.C; ev.Kind != EventMode || ev.Mode != ModeFrozen || ev.Revision != 1 {
+		t.Errorf("expected frozen mode event at revision 1, got %+v", ev)
+	}
+
+	late, err := h.Subscribe(ctx, "doc", "bob")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer late.Close()
+	if late.Mode != ModeFrozen {
+		t.Errorf("expected subscription in frozen mode, got %v", late.Mode)
+	}
+}
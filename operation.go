@@ -21,10 +21,37 @@ import (
 var (
 	// ErrIncompatibleLengths is returned when operations have incompatible lengths
 	ErrIncompatibleLengths = errors.New("incompatible lengths")
+
+	// ErrNotSequential is returned by Compose when the second operation does
+	// not apply to the result of the first. A peer that sends it has lost
+	// track of the document and should resync.
+	ErrNotSequential = errors.New("operations are not sequential")
+
+	// ErrNotConcurrent is returned by Transform when the operations do not
+	// apply to the same document. A peer that sends it has lost track of
+	// the document and should resync.
+	ErrNotConcurrent = errors.New("operations are not concurrent")
+
+	// ErrMalformedOp is returned by Compose and Transform when an
+	// operation's components do not add up to its lengths. It is never
+	// returned for operations built with the builder methods or decoded, so
+	// a peer should be rejected rather than resynced.
+	ErrMalformedOp = errors.New("malformed operation")
+
+	// ErrTooManyOps is returned when decoded input has more components than allowed
+	ErrTooManyOps = errors.New("too many operations")
+
+	// ErrOpTooLarge is returned when a single component exceeds its size limit
+	ErrOpTooLarge = errors.New("operation too large")
+
+	// ErrLengthTooLarge is returned when a decoded sequence's base or target length exceeds its limit
+	ErrLengthTooLarge = errors.New("operation length too large")
 )
 
 // Operation represents a single operation in a document.
 // This is modeled as an interface to match Go idioms, but has three concrete types.
+// OperationSeq stores its operations as Components, which need no allocation;
+// Ops converts them to this form.
 type Operation interface {
 	isOperation()
 }
@@ -50,6 +77,42 @@ type Insert struct {
 
 func (Insert) isOperation() {}
 
+// Kind identifies what a Component does.
+type Kind uint8
+
+// Component kinds. The zero Kind marks the absence of a component.
+const (
+	KindRetain Kind = iota + 1
+	KindDelete
+	KindInsert
+)
+
+// Component is a single operation stored by value: a Retain or Delete of N
+// characters, or an Insert of Text. Unlike the Operation types, it is not
+// boxed in an interface, so a sequence of them is one flat allocation.
+//
+// For an Insert, N holds the length of Text in characters, counted once when
+// the component is built, so that transforming and composing it does not
+// count it again.
+type Component struct {
+	Kind Kind
+	N    uint64 // characters retained, deleted, or in Text
+	Text string // for KindInsert
+}
+
+// Operation returns c as a Retain, Delete, or Insert, or nil if c has no Kind.
+func (c Component) Operation() Operation {
+	switch c.Kind {
+	case KindRetain:
+		return Retain{N: c.N}
+	case KindDelete:
+		return Delete{N: c.N}
+	case KindInsert:
+		return Insert{Text: c.Text}
+	}
+	return nil
+}
+
 // charCount returns the number of UTF-8 characters (runes) in a string.
 // This is critical for compatibility - we count Unicode codepoints, not bytes.
 func charCount(s string) int {
@@ -58,16 +121,24 @@ func charCount(s string) int {
 
 // OperationSeq is a sequence of operations on text.
 // It tracks both the required input length (baseLen) and the resulting output length (targetLen).
+//
+// An OperationSeq is not safe for concurrent use while it is being built.
+// Apply, Transform, Compose and the other methods that return a result only
+// read their operands, so a finished sequence may be shared between
+// goroutines as long as none of them modifies or releases it. To edit a
+// document from several goroutines, use a Server, which transforms and
+// applies each operation under a lock.
 type OperationSeq struct {
-	ops       []Operation
+	ops       []Component
 	baseLen   int // Required length of input string
 	targetLen int // Length of string after applying operations
+	meta      *Meta
 }
 
 // NewOperationSeq creates a new empty operation sequence.
 func NewOperationSeq() *OperationSeq {
 	return &OperationSeq{
-		ops:       make([]Operation, 0),
+		ops:       make([]Component, 0),
 		baseLen:   0,
 		targetLen: 0,
 	}
@@ -76,7 +147,7 @@ func NewOperationSeq() *OperationSeq {
 // WithCapacity creates a new operation sequence with pre-allocated capacity.
 func WithCapacity(capacity int) *OperationSeq {
 	return &OperationSeq{
-		ops:       make([]Operation, 0, capacity),
+		ops:       make([]Component, 0, capacity),
 		baseLen:   0,
 		targetLen: 0,
 	}
@@ -92,8 +163,19 @@ func (o *OperationSeq) TargetLen() int {
 	return o.targetLen
 }
 
-// Ops returns the underlying slice of operations.
+// Ops returns the operations in the sequence. The slice is built on each
+// call; Components avoids that.
 func (o *OperationSeq) Ops() []Operation {
+	ops := make([]Operation, len(o.ops))
+	for i, c := range o.ops {
+		ops[i] = c.Operation()
+	}
+	return ops
+}
+
+// Components returns the underlying slice of components. Callers must not
+// modify it.
+func (o *OperationSeq) Components() []Component {
 	return o.ops
 }
 
@@ -102,10 +184,8 @@ func (o *OperationSeq) IsNoop() bool {
 	if len(o.ops) == 0 {
 		return true
 	}
-	if len(o.ops) == 1 {
-		if _, ok := o.ops[0].(Retain); ok {
-			return true
-		}
+	if len(o.ops) == 1 && o.ops[0].Kind == KindRetain {
+		return true
 	}
 	return false
 }
@@ -116,40 +196,43 @@ func (o *OperationSeq) Insert(s string) {
 	if s == "" {
 		return
 	}
+	o.insert(s, uint64(charCount(s)))
+}
 
-	o.targetLen += charCount(s)
+// insert is Insert for text whose length in characters, chars, is already
+// known.
+func (o *OperationSeq) insert(s string, chars uint64) {
+	o.targetLen += int(chars)
 
 	n := len(o.ops)
 	if n == 0 {
-		o.ops = append(o.ops, Insert{Text: s})
+		o.ops = append(o.ops, Component{Kind: KindInsert, N: chars, Text: s})
 		return
 	}
 
 	// Try to merge with last operation
-	if insert, ok := o.ops[n-1].(Insert); ok {
-		o.ops[n-1] = Insert{Text: insert.Text + s}
+	if o.ops[n-1].Kind == KindInsert {
+		o.ops[n-1].Text += s
+		o.ops[n-1].N += chars
 		return
 	}
 
 	// Check if we need to swap with Delete and merge with previous Insert
-	if n >= 2 {
-		if _, ok := o.ops[n-1].(Delete); ok {
-			if insert, ok := o.ops[n-2].(Insert); ok {
-				o.ops[n-2] = Insert{Text: insert.Text + s}
-				return
-			}
-		}
+	if n >= 2 && o.ops[n-1].Kind == KindDelete && o.ops[n-2].Kind == KindInsert {
+		o.ops[n-2].Text += s
+		o.ops[n-2].N += chars
+		return
 	}
 
 	// If last operation is Delete, we need to insert the Insert before it
-	if del, ok := o.ops[n-1].(Delete); ok {
-		o.ops[n-1] = Insert{Text: s}
+	if del := o.ops[n-1]; del.Kind == KindDelete {
+		o.ops[n-1] = Component{Kind: KindInsert, N: chars, Text: s}
 		o.ops = append(o.ops, del)
 		return
 	}
 
 	// Default: just append
-	o.ops = append(o.ops, Insert{Text: s})
+	o.ops = append(o.ops, Component{Kind: KindInsert, N: chars, Text: s})
 }
 
 // Delete removes n characters at the current cursor position.
@@ -161,14 +244,12 @@ func (o *OperationSeq) Delete(n uint64) {
 
 	o.baseLen += int(n)
 
-	if len(o.ops) > 0 {
-		if del, ok := o.ops[len(o.ops)-1].(Delete); ok {
-			o.ops[len(o.ops)-1] = Delete{N: del.N + n}
-			return
-		}
+	if last := len(o.ops) - 1; last >= 0 && o.ops[last].Kind == KindDelete {
+		o.ops[last].N += n
+		return
 	}
 
-	o.ops = append(o.ops, Delete{N: n})
+	o.ops = append(o.ops, Component{Kind: KindDelete, N: n})
 }
 
 // Retain moves the cursor n positions forward.
@@ -181,12 +262,10 @@ func (o *OperationSeq) Retain(n uint64) {
 	o.baseLen += int(n)
 	o.targetLen += int(n)
 
-	if len(o.ops) > 0 {
-		if ret, ok := o.ops[len(o.ops)-1].(Retain); ok {
-			o.ops[len(o.ops)-1] = Retain{N: ret.N + n}
-			return
-		}
+	if last := len(o.ops) - 1; last >= 0 && o.ops[last].Kind == KindRetain {
+		o.ops[last].N += n
+		return
 	}
 
-	o.ops = append(o.ops, Retain{N: n})
+	o.ops = append(o.ops, Component{Kind: KindRetain, N: n})
 }
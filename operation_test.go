@@ -2,6 +2,8 @@ package ot
 
 import (
 	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
 )
 
@@ -114,7 +116,7 @@ func TestOpsMerging(t *testing.T) {
 	if len(o.ops) != 1 {
 		t.Errorf("expected 1 op, got %d", len(o.ops))
 	}
-	if ret, ok := o.ops[0].(Retain); !ok || ret.N != 2 {
+	if ret, ok := o.Ops()[0].(Retain); !ok || ret.N != 2 {
 		t.Errorf("expected Retain(2), got %v", o.ops[0])
 	}
 
@@ -122,7 +124,7 @@ func TestOpsMerging(t *testing.T) {
 	if len(o.ops) != 1 {
 		t.Errorf("expected 1 op (merged), got %d", len(o.ops))
 	}
-	if ret, ok := o.ops[0].(Retain); !ok || ret.N != 5 {
+	if ret, ok := o.Ops()[0].(Retain); !ok || ret.N != 5 {
 		t.Errorf("expected Retain(5), got %v", o.ops[0])
 	}
 
@@ -130,7 +132,7 @@ func TestOpsMerging(t *testing.T) {
 	if len(o.ops) != 2 {
 		t.Errorf("expected 2 ops, got %d", len(o.ops))
 	}
-	if ins, ok := o.ops[1].(Insert); !ok || ins.Text != "abc" {
+	if ins, ok := o.Ops()[1].(Insert); !ok || ins.Text != "abc" {
 		t.Errorf("expected Insert(abc), got %v", o.ops[1])
 	}
 
@@ -138,7 +140,7 @@ func TestOpsMerging(t *testing.T) {
 	if len(o.ops) != 2 {
 		t.Errorf("expected 2 ops (merged), got %d", len(o.ops))
 	}
-	if ins, ok := o.ops[1].(Insert); !ok || ins.Text != "abcxyz" {
+	if ins, ok := o.Ops()[1].(Insert); !ok || ins.Text != "abcxyz" {
 		t.Errorf("expected Insert(abcxyz), got %v", o.ops[1])
 	}
 
@@ -146,7 +148,7 @@ func TestOpsMerging(t *testing.T) {
 	if len(o.ops) != 3 {
 		t.Errorf("expected 3 ops, got %d", len(o.ops))
 	}
-	if del, ok := o.ops[2].(Delete); !ok || del.N != 1 {
+	if del, ok := o.Ops()[2].(Delete); !ok || del.N != 1 {
 		t.Errorf("expected Delete(1), got %v", o.ops[2])
 	}
 
@@ -154,11 +156,36 @@ func TestOpsMerging(t *testing.T) {
 	if len(o.ops) != 3 {
 		t.Errorf("expected 3 ops (merged), got %d", len(o.ops))
 	}
-	if del, ok := o.ops[2].(Delete); !ok || del.N != 2 {
+	if del, ok := o.Ops()[2].(Delete); !ok || del.N != 2 {
 		t.Errorf("expected Delete(2), got %v", o.ops[2])
 	}
 }
 
+func TestComponents(t *testing.T) {
+	o := NewOperationSeq()
+	o.Retain(2)
+	o.Insert("hé")
+	o.Delete(1)
+
+	want := []Component{{Kind: KindRetain, N: 2}, {Kind: KindInsert, N: 2, Text: "hé"}, {Kind: KindDelete, N: 1}}
+	got := o.Components()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d components, got %d", len(want), len(got))
+	}
+	ops := o.Ops()
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("component %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+		if ops[i] != want[i].Operation() {
+			t.Errorf("op %d: expected %v, got %v", i, want[i].Operation(), ops[i])
+		}
+	}
+	if (Component{}).Operation() != nil {
+		t.Error("expected nil for the zero Component")
+	}
+}
+
 func TestIsNoop(t *testing.T) {
 	o := NewOperationSeq()
 	if !o.IsNoop() {
@@ -233,6 +260,22 @@ func TestApply(t *testing.T) {
 			},
 			expect: "henlo",
 		},
+		{
+			name: "multi-byte characters",
+			s:    "héllo 🌍!",
+			ops: func() *OperationSeq {
+				o := NewOperationSeq()
+				o.Retain(1)
+				o.Delete(1)
+				o.Insert("e")
+				o.Retain(4)
+				o.Delete(1)
+				o.Insert("world")
+				o.Retain(1)
+				return o
+			},
+			expect: "hello world!",
+		},
 	}
 
 	for _, tt := range tests {
@@ -248,6 +291,23 @@ func TestApply(t *testing.T) {
 	}
 }
 
+func BenchmarkApplyLargeDocument(b *testing.B) {
+	doc := strings.Repeat("héllo wörld\n", 1<<16) // about 1MB
+	n := uint64(charCount(doc))
+	o := NewOperationSeq()
+	o.Retain(n / 2)
+	o.Insert("x")
+	o.Retain(n - n/2)
+
+	b.SetBytes(int64(len(doc)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := o.Apply(doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func TestInvert(t *testing.T) {
 	tests := []struct {
 		name string
@@ -318,6 +378,31 @@ func TestInvert(t *testing.T) {
 	}
 }
 
+func TestInvertChecked(t *testing.T) {
+	o := NewOperationSeq()
+	o.Retain(2)
+	o.Delete(2)
+
+	inverse, err := o.InvertChecked("abc")
+	var mismatch *LengthMismatchError
+	if inverse != nil || !errors.As(err, &mismatch) || mismatch.Want != 4 || mismatch.Got != 3 {
+		t.Errorf("expected a mismatch of 4 and 3, got %v, %v", inverse, err)
+	}
+	if inverse, err := o.InvertChecked("héyo"); err != nil || inverse.String() != `[2,"yo"]` {
+		t.Errorf("expected [2,\"yo\"], got %v, %v", inverse, err)
+	}
+
+	// Invert keeps its old, unchecked behaviour.
+	if got := o.Invert(""); got.String() != "[2]" {
+		t.Errorf("expected [2], got %v", got)
+	}
+	retain := NewOperationSeq()
+	retain.Retain(2)
+	if got := retain.Invert("abcd"); got.String() != "[2]" {
+		t.Errorf("expected [2], got %v", got)
+	}
+}
+
 func TestSerde(t *testing.T) {
 	// Test simple case
 	jsonStr := `[1,-1,"abc"]`
@@ -350,3 +435,40 @@ func TestSerde(t *testing.T) {
 		t.Errorf("round-trip: expected %d ops, got %d", len(o.ops), len(o2.ops))
 	}
 }
+
+func TestInsertLengths(t *testing.T) {
+	check := func(name string, o *OperationSeq) {
+		t.Helper()
+		for _, c := range o.Components() {
+			if c.Kind == KindInsert && int(c.N) != charCount(c.Text) {
+				t.Errorf("%s: expected N of %q to be %d, got %d", name, c.Text, charCount(c.Text), c.N)
+			}
+		}
+	}
+
+	a := NewOperationSeq()
+	a.Insert("é")
+	a.Delete(2)
+	a.Insert("🌍x") // merged before the delete
+	check("merged", a)
+
+	b := NewOperationSeq()
+	b.Retain(1)
+	b.Delete(1)
+	b.Retain(1)
+	composed, err := a.Compose(b)
+	if err != nil {
+		t.Fatalf("Compose failed: %v", err)
+	}
+	check("composed", composed)
+
+	c := NewOperationSeq()
+	c.Insert("ab")
+	c.Retain(2)
+	aPrime, cPrime, err := a.Transform(c)
+	if err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+	check("transformed", aPrime)
+	check("transformed", cPrime)
+}
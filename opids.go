@@ -0,0 +1,87 @@
+package ot
+
+import "context"
+
+// recentOps is how many submitted operation IDs a Server remembers.
+const recentOps = 1024
+
+// opKey identifies an operation by the client that submitted it and the ID
+// the client gave it. IDs only need to be unique per client.
+type opKey struct {
+	client, id string
+}
+
+// opAck is what SubmitOp returned for an operation, and whether the
+// operation was clipped to the protected ranges.
+type opAck struct {
+	op       *OperationSeq
+	revision int
+	clipped  bool
+}
+
+// opIDs remembers the results of recently submitted operations, forgetting
+// the oldest once full.
+type opIDs struct {
+	acks  map[opKey]opAck
+	order []opKey // oldest first
+}
+
+func (r *opIDs) add(key opKey, ack opAck) {
+	if r.acks == nil {
+		r.acks = make(map[opKey]opAck)
+	}
+	if len(r.order) == recentOps {
+		delete(r.acks, r.order[0])
+		r.order = r.order[1:]
+	}
+	r.acks[key] = ack
+	r.order = append(r.order, key)
+}
+
+// SubmitOp is Submit for an operation the client identified with id. The
+// server remembers the result for its most recent 1024 identified
+// operations: submitting one of them again, say because the acknowledgement
+// was lost, returns the original result without applying it twice. An empty
+// id is not recorded.
+func (s *Server) SubmitOp(ctx context.Context, client, id string, clientRevision int, op *OperationSeq) (*OperationSeq, int, error) {
+	ack, _, err := s.submitOp(ctx, client, id, clientRevision, op)
+	return ack.op, ack.revision, err
+}
+
+// submitOp implements SubmitOp and also reports whether op was a duplicate.
+func (s *Server) submitOp(ctx context.Context, client, id string, clientRevision int, op *OperationSeq) (opAck, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := opKey{client, id}
+	if ack, ok := s.opIDs.acks[key]; ok && id != "" {
+		return ack, true, nil
+	}
+	before := s.doc
+	op, clipped, err := s.receive(ctx, client, clientRevision, op, false)
+	if err != nil {
+		return opAck{}, false, err
+	}
+	if client != "" {
+		// The operation is applied already, so a failure here only costs
+		// the client its undo step.
+		if err := s.pushUndo(client, before); err != nil {
+			s.logger.Error("recording undo failed", "client", client, "revision", s.revision(), "err", err)
+		}
+	}
+	ack := opAck{op, s.revision(), clipped}
+	if id != "" {
+		s.opIDs.add(key, ack)
+	}
+	return ack, false, nil
+}
+
+// OpRevision returns the revision produced by the operation client submitted
+// with id through SubmitOp, and whether the server remembers it. IDs are kept
+// in memory only, so a restarted server or one on another node does not know
+// them.
+func (s *Server) OpRevision(client, id string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ack, ok := s.opIDs.acks[opKey{client, id}]
+	return ack.revision, ok
+}
@@ -0,0 +1,97 @@
+package ot
+
+import (
+	"context"
+	"strconv"
+	"testing"
+)
+
+func TestServerSubmitOpDuplicate(t *testing.T) {
+	s := NewServer("")
+	op := NewOperationSeq()
+	op.Insert("a")
+
+	applied, rev, err := s.SubmitOp(context.Background(), "alice", "1", 0, op)
+	if err != nil {
+		t.Fatalf("SubmitOp failed: %v", err)
+	}
+	again, againRev, err := s.SubmitOp(context.Background(), "alice", "1", 0, op)
+	if err != nil {
+		t.Fatalf("SubmitOp retry failed: %v", err)
+	}
+	if again != applied || againRev != rev {
+		t.Errorf("expected original ack at revision %d, got %d", rev, againRev)
+	}
+	if s.Document() != "a" {
+		t.Errorf("expected %q, got %q", "a", s.Document())
+	}
+
+	// IDs are per client, and unidentified operations are never deduplicated.
+	if _, rev, err := s.SubmitOp(context.Background(), "bob", "1", 0, op); err != nil || rev != 2 {
+		t.Errorf("expected bob's op at revision 2, got %d (%v)", rev, err)
+	}
+	if _, rev, err := s.SubmitOp(context.Background(), "alice", "", 0, op); err != nil || rev != 3 {
+		t.Errorf("expected unidentified op at revision 3, got %d (%v)", rev, err)
+	}
+	if got, ok := s.OpRevision("alice", "1"); !ok || got != 1 {
+		t.Errorf("expected revision 1, got %d, %t", got, ok)
+	}
+}
+
+func TestOpIDsForgetOldest(t *testing.T) {
+	var ids opIDs
+	for i := 0; i <= recentOps; i++ {
+		ids.add(opKey{"alice", strconv.Itoa(i)}, opAck{revision: i + 1})
+	}
+	if len(ids.acks) != recentOps || len(ids.order) != recentOps {
+		t.Errorf("expected %d remembered, got %d and %d", recentOps, len(ids.acks), len(ids.order))
+	}
+	if _, ok := ids.acks[opKey{"alice", "0"}]; ok {
+		t.Error("expected the oldest ID to be forgotten")
+	}
+	if _, ok := ids.acks[opKey{"alice", "1"}]; !ok {
+		t.Error("expected the second oldest ID to be remembered")
+	}
+}
+
+func TestHubSubmitOpDuplicate(t *testing.T) {
+	h := NewHub(func(context.Context, string) (*Server, error) { return NewServer(""), nil })
+	ctx := context.Background()
+
+	alice, err := h.Subscribe(ctx, "doc", "alice")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer alice.Close()
+	bob, err := h.Subscribe(ctx, "doc", "bob")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer bob.Close()
+	<-alice.C // own join
+	<-alice.C // bob's join
+	<-bob.C   // own join
+
+	op := NewOperationSeq()
+	op.Insert("a")
+	for i := 0; i < 2; i++ {
+		if _, _, err := h.SubmitOp(ctx, "doc", "alice", "1", 0, op); err != nil {
+			t.Fatalf("SubmitOp failed: %v", err)
+		}
+	}
+
+	if ev := <-alice.C; ev.Kind != EventOp || ev.Revision != 1 {
+		t.Errorf("expected op at revision 1, got %+v", ev)
+	}
+	if ev := <-alice.C; ev.Kind != EventAck || ev.Revision != 1 {
+		t.Errorf("expected repeated ack at revision 1, got %+v", ev)
+	}
+	if ev := <-bob.C; ev.Kind != EventOp || ev.Revision != 1 {
+		t.Errorf("expected op at revision 1, got %+v", ev)
+	}
+	select {
+	case ev := <-bob.C:
+		t.Errorf("expected no further events for bob, got %+v", ev)
+	default:
+	}
+}
@@ -0,0 +1,98 @@
+package ot
+
+// OpReader reads the components of an operation sequence in order, and can
+// split them: Take returns the first n characters of the current component
+// and leaves the rest to be read next. This is how Transform and Compose walk
+// two operations side by side, and the same pattern serves any walker that
+// lines up one operation against another or against a document.
+//
+// The zero OpReader is exhausted. An OpReader is a small value; declaring one
+// as a local variable does not allocate.
+type OpReader struct {
+	ops []Component
+	i   int       // index of the next component
+	cur Component // what is left of the current component
+}
+
+// NewOpReader returns a reader positioned at the first component of o. o
+// must not be modified while the reader is in use.
+func NewOpReader(o *OperationSeq) OpReader {
+	r := OpReader{ops: o.ops}
+	r.advance()
+	return r
+}
+
+// Peek returns what is left of the current component without consuming it,
+// or the zero Component once the reader is exhausted.
+func (r *OpReader) Peek() Component {
+	return r.cur
+}
+
+// Done reports whether every component has been read.
+func (r *OpReader) Done() bool {
+	return r.cur.Kind == 0
+}
+
+// Next consumes and returns what is left of the current component, or the
+// zero Component once the reader is exhausted.
+func (r *OpReader) Next() Component {
+	c := r.cur
+	r.advance()
+	return c
+}
+
+// Take consumes and returns up to n characters of the current component:
+// retained, deleted, or inserted. If n covers the rest of the component Take
+// is the same as Next; otherwise the remainder stays current. n must be
+// positive.
+func (r *OpReader) Take(n uint64) Component {
+	if n < r.cur.N {
+		return r.split(n)
+	}
+	return r.Next()
+}
+
+// split takes the first n characters of the current component, which has
+// more than that left.
+func (r *OpReader) split(n uint64) Component {
+	c := Component{Kind: r.cur.Kind, N: n}
+	r.cur.N -= n
+	if c.Kind == KindInsert {
+		cut := skipRunes(r.cur.Text, 0, n)
+		c.Text, r.cur.Text = r.cur.Text[:cut], r.cur.Text[cut:]
+	}
+	return c
+}
+
+// index returns the index of the current component, or the number of
+// components once the reader is exhausted.
+func (r *OpReader) index() int {
+	if r.Done() {
+		return len(r.ops)
+	}
+	return r.i - 1
+}
+
+// remaining returns the characters left in the current component and the
+// ones after it, leaving out components of kind skip.
+func (r *OpReader) remaining(skip Kind) int {
+	n := 0
+	if r.cur.Kind != 0 && r.cur.Kind != skip {
+		n += int(r.cur.N)
+	}
+	for _, c := range r.ops[r.i:] {
+		if c.Kind != skip {
+			n += int(c.N)
+		}
+	}
+	return n
+}
+
+func (r *OpReader) advance() {
+	if r.i < len(r.ops) {
+		r.cur = r.ops[r.i]
+		r.i++
+	} else {
+		r.cur = Component{}
+	}
+}
@@ -0,0 +1,62 @@
+package ot
+
+import "testing"
+
+func TestOpReader(t *testing.T) {
+	o := NewOperationSeq()
+	o.Retain(5)
+	o.Insert("hé🌍!")
+	o.Delete(3)
+
+	r := NewOpReader(o)
+	steps := []struct {
+		n    uint64 // 0 for Next
+		want Component
+	}{
+		{2, Component{Kind: KindRetain, N: 2}},
+		{0, Component{Kind: KindRetain, N: 3}},
+		{2, Component{Kind: KindInsert, N: 2, Text: "hé"}},
+		{1, Component{Kind: KindInsert, N: 1, Text: "🌍"}},
+		{5, Component{Kind: KindInsert, N: 1, Text: "!"}},
+		{3, Component{Kind: KindDelete, N: 3}},
+	}
+	for i, s := range steps {
+		if r.Done() {
+			t.Fatalf("step %d: expected more components", i)
+		}
+		var got Component
+		if s.n == 0 {
+			got = r.Next()
+		} else {
+			got = r.Take(s.n)
+		}
+		if got != s.want {
+			t.Errorf("step %d: expected %+v, got %+v", i, s.want, got)
+		}
+	}
+	if !r.Done() {
+		t.Errorf("expected the reader to be done, got %+v", r.Peek())
+	}
+	if c := r.Next(); c.Kind != 0 {
+		t.Errorf("expected the zero Component, got %+v", c)
+	}
+
+	var zero OpReader
+	if !zero.Done() || zero.Take(1).Kind != 0 {
+		t.Error("expected the zero OpReader to be exhausted")
+	}
+}
+
+func TestOpReaderPeek(t *testing.T) {
+	o := NewOperationSeq()
+	o.Insert("abc")
+	r := NewOpReader(o)
+	r.Take(1)
+	want := Component{Kind: KindInsert, N: 2, Text: "bc"}
+	if got := r.Peek(); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if got := r.Peek(); got != want {
+		t.Errorf("expected Peek not to consume, got %+v", got)
+	}
+}
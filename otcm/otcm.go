@@ -0,0 +1,275 @@
+// Package otcm adapts the documents of an ot.Hub to the protocol of
+// CodeMirror 6's @codemirror/collab extension, so a CodeMirror editor can
+// sync against a Go backend.
+//
+// In that protocol the server keeps a list of updates and accepts new ones
+// only from a client that has seen them all; the client rebases its own
+// changes when it falls behind. An Authority provides the three calls a
+// client makes, each of which maps onto one request of whatever transport
+// carries them:
+//
+//	getDocument()            Authority.Document
+//	pullUpdates(version)     Authority.Pull
+//	pushUpdates(version, u)  Authority.Push
+//
+// An update's changes travel as ChangeSet.toJSON() produces them, and are
+// passed to ChangeSet.fromJSON on the other side. CodeMirror counts UTF-16
+// code units where this package counts characters, so converting them
+// needs the document they apply to; FromChangeSet and ToChangeSet take it.
+package otcm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+// Update is an update in the form @codemirror/collab sends and receives.
+type Update struct {
+	// Changes is the update's ChangeSet in its JSON form.
+	Changes json.RawMessage `json:"changes"`
+
+	// ClientID identifies the editor that made the update, so it can
+	// recognize its own updates when it pulls them.
+	ClientID string `json:"clientID"`
+}
+
+// FromChangeSet returns the operation for a CodeMirror ChangeSet, given in
+// its JSON form, that applies to doc.
+//
+// Returns an *ot.LengthMismatchError, in UTF-16 code units, if the changes
+// do not cover doc exactly, and an error if they split a character that
+// takes two code units.
+func FromChangeSet(doc string, changes json.RawMessage) (*ot.OperationSeq, error) {
+	var sections []json.RawMessage
+	if err := json.Unmarshal(changes, &sections); err != nil {
+		return nil, fmt.Errorf("otcm: invalid changes: %w", err)
+	}
+
+	op := ot.NewOperationSeq()
+	pos, units := 0, 0 // byte and UTF-16 offsets in doc
+	docUnits := utf16Len(doc)
+	// take advances past n code units of doc, the extent of section i, and
+	// returns the number of characters they hold.
+	take := func(i, n int) (uint64, error) {
+		if n > docUnits-units {
+			return 0, &ot.LengthMismatchError{Want: units + n, Got: docUnits, OpIndex: i}
+		}
+		chars, end := 0, units+n
+		for units < end {
+			r, size := utf8.DecodeRuneInString(doc[pos:])
+			pos += size
+			units += runeUnits(r)
+			chars++
+		}
+		if units != end {
+			return 0, fmt.Errorf("otcm: section %d splits a character at code unit %d", i, end)
+		}
+		return uint64(chars), nil
+	}
+
+	for i, raw := range sections {
+		var n int
+		if json.Unmarshal(raw, &n) == nil {
+			if n < 0 {
+				return nil, fmt.Errorf("otcm: invalid changes: negative length %d", n)
+			}
+			chars, err := take(i, n)
+			if err != nil {
+				return nil, err
+			}
+			op.Retain(chars)
+			continue
+		}
+
+		var replace []json.RawMessage
+		if err := json.Unmarshal(raw, &replace); err != nil || len(replace) == 0 {
+			return nil, fmt.Errorf("otcm: invalid changes: section %d is %s", i, raw)
+		}
+		if err := json.Unmarshal(replace[0], &n); err != nil || n < 0 {
+			return nil, fmt.Errorf("otcm: invalid changes: section %d deletes %s", i, replace[0])
+		}
+		chars, err := take(i, n)
+		if err != nil {
+			return nil, err
+		}
+		if len(replace) > 1 {
+			lines := make([]string, len(replace)-1)
+			for j, line := range replace[1:] {
+				if err := json.Unmarshal(line, &lines[j]); err != nil {
+					return nil, fmt.Errorf("otcm: invalid changes: section %d inserts %s", i, line)
+				}
+			}
+			op.Insert(strings.Join(lines, "\n"))
+		}
+		op.Delete(chars)
+	}
+	if units != docUnits {
+		return nil, &ot.LengthMismatchError{Want: units, Got: docUnits, OpIndex: len(sections)}
+	}
+	return op, nil
+}
+
+// ToChangeSet returns the JSON form of the CodeMirror ChangeSet for op,
+// which applies to doc.
+//
+// Returns an *ot.LengthMismatchError if op does not apply to doc.
+func ToChangeSet(doc string, op *ot.OperationSeq) (json.RawMessage, error) {
+	if n := utf8.RuneCountInString(doc); n != op.BaseLen() {
+		return nil, &ot.LengthMismatchError{Want: op.BaseLen(), Got: n, OpIndex: -1}
+	}
+
+	sections := []any{}
+	pos := 0 // byte offset in doc
+	// skip advances past n characters of doc and returns the code units
+	// they take.
+	skip := func(n uint64) int {
+		units := 0
+		for ; n > 0 && pos < len(doc); n-- {
+			r, size := utf8.DecodeRuneInString(doc[pos:])
+			pos += size
+			units += runeUnits(r)
+		}
+		return units
+	}
+	// An insert and the delete after it, if any, are one replacement.
+	var insert []any
+	for _, c := range op.Components() {
+		switch c.Kind {
+		case ot.KindRetain:
+			if insert != nil {
+				sections = append(sections, insert)
+				insert = nil
+			}
+			sections = append(sections, skip(c.N))
+		case ot.KindInsert:
+			insert = []any{0}
+			for _, line := range strings.Split(c.Text, "\n") {
+				insert = append(insert, line)
+			}
+		case ot.KindDelete:
+			if insert == nil {
+				insert = []any{0}
+			}
+			insert[0] = skip(c.N)
+			sections = append(sections, insert)
+			insert = nil
+		}
+	}
+	if insert != nil {
+		sections = append(sections, insert)
+	}
+	return json.Marshal(sections)
+}
+
+// utf16Len returns the length of s in UTF-16 code units.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeUnits(r)
+	}
+	return n
+}
+
+// runeUnits returns the number of UTF-16 code units r takes.
+func runeUnits(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// Authority serves the documents of a Hub to @codemirror/collab clients.
+type Authority struct {
+	Hub *ot.Hub
+}
+
+// NewAuthority returns an Authority serving the documents of hub.
+func NewAuthority(hub *ot.Hub) *Authority {
+	return &Authority{Hub: hub}
+}
+
+// Document returns the content of doc and its version, the number of
+// updates applied to it, for a client to start from.
+func (a *Authority) Document(ctx context.Context, doc string) (string, int, error) {
+	server, err := a.Hub.Server(ctx, doc)
+	if err != nil {
+		return "", 0, err
+	}
+	content, version := server.State()
+	return content, version, nil
+}
+
+// Pull returns the updates applied to doc since version, which may be none.
+// It does not wait for new ones; a transport that long-polls, as the
+// collab example does, can retry or subscribe to the Hub meanwhile.
+//
+// Updates pushed through the Authority carry the client ID they were
+// pushed with; others carry their operation's Meta.Author.
+func (a *Authority) Pull(ctx context.Context, doc string, version int) ([]Update, error) {
+	server, err := a.Hub.Server(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	content, err := server.DocumentAt(version)
+	if err != nil {
+		return nil, err
+	}
+	ops, _, err := server.OperationsSince(version)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make([]Update, len(ops))
+	for i, op := range ops {
+		changes, err := ToChangeSet(content, op)
+		if err != nil {
+			return nil, fmt.Errorf("otcm: version %d: %w", version+i, err)
+		}
+		if content, err = op.Apply(content); err != nil {
+			return nil, fmt.Errorf("otcm: version %d: %w", version+i, err)
+		}
+		updates[i] = Update{Changes: changes, ClientID: op.Meta().Author}
+	}
+	return updates, nil
+}
+
+// Push applies updates a client made against version, in order, through
+// Hub.ApplyAt, so they reach the Hub's other subscribers as well. It
+// reports false, without an error, if doc has moved on from version; the
+// client then pulls, rebases its updates, and pushes again. If it moves on
+// part way through, the updates applied so far stay applied, and the client
+// recognizes them by their client ID when it pulls.
+func (a *Authority) Push(ctx context.Context, doc string, version int, updates []Update) (bool, error) {
+	content, current, err := a.Document(ctx, doc)
+	if err != nil {
+		return false, err
+	}
+	if version != current {
+		return false, nil
+	}
+
+	for i, u := range updates {
+		op, err := FromChangeSet(content, u.Changes)
+		if err != nil {
+			return false, err
+		}
+		op.SetMeta(ot.Meta{Author: u.ClientID})
+		applied, err := a.Hub.ApplyAt(ctx, doc, u.ClientID, version+i, op)
+		if errors.Is(err, ot.ErrStaleRevision) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if content, err = applied.Apply(content); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,154 @@
+package otcm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"testing"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+func TestChangeSet(t *testing.T) {
+	tests := []struct {
+		doc     string
+		op      string // JSON wire format
+		changes string
+	}{
+		{"hello", `[5," world"]`, `[5,[0," world"]]`},
+		{"hello", `[1,-3,1]`, `[1,[3],1]`},
+		{"hello", `[1,"EL",-2,2]`, `[1,[2,"EL"],2]`},
+		{"a🌍b", `[1,-1,"x\ny",1]`, `[1,[2,"x","y"],1]`},
+		{"🌍🌍", `[1,"é",1]`, `[2,[0,"é"],2]`},
+		{"", `[]`, `[]`},
+	}
+	for _, tt := range tests {
+		op := ot.NewOperationSeq()
+		if err := json.Unmarshal([]byte(tt.op), op); err != nil {
+			t.Fatal(err)
+		}
+		changes, err := ToChangeSet(tt.doc, op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(changes) != tt.changes {
+			t.Errorf("%q %s: expected %s, got %s", tt.doc, tt.op, tt.changes, changes)
+		}
+		back, err := FromChangeSet(tt.doc, json.RawMessage(tt.changes))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if back.String() != op.String() {
+			t.Errorf("%q %s: expected %s back, got %s", tt.doc, tt.changes, op, back)
+		}
+	}
+}
+
+func TestChangeSetRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		doc := ot.RandomString(r, r.Intn(30))
+		op := ot.RandomOp(r, len([]rune(doc)), ot.RandomOptions{})
+		changes, err := ToChangeSet(doc, op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		back, err := FromChangeSet(doc, changes)
+		if err != nil {
+			t.Fatalf("%q %s: %v", doc, changes, err)
+		}
+		if back.String() != op.String() {
+			t.Fatalf("%q: expected %s back from %s, got %s", doc, op, changes, back)
+		}
+	}
+}
+
+func TestFromChangeSetErrors(t *testing.T) {
+	tests := []struct {
+		doc     string
+		changes string
+	}{
+		{"hello", `[4]`},
+		{"hello", `[6]`},
+		{"hello", `[3,[3]]`},
+		{"a🌍", `[2,1]`},
+		{"hello", `{}`},
+		{"hello", `[5,"x"]`},
+		{"hello", `[5,[]]`},
+		{"hello", `[-1,6]`},
+		{"hello", `[5,[0,1]]`},
+	}
+	for _, tt := range tests {
+		if op, err := FromChangeSet(tt.doc, json.RawMessage(tt.changes)); err == nil {
+			t.Errorf("%q %s: expected an error, got %s", tt.doc, tt.changes, op)
+		}
+	}
+
+	var mismatch *ot.LengthMismatchError
+	if _, err := FromChangeSet("a🌍", json.RawMessage(`[1]`)); !errors.As(err, &mismatch) || mismatch.Want != 1 || mismatch.Got != 3 {
+		t.Errorf("expected a mismatch of 1 and 3 code units, got %v", err)
+	}
+}
+
+func TestAuthority(t *testing.T) {
+	ctx := context.Background()
+	hub := ot.NewHub(func(context.Context, string) (*ot.Server, error) {
+		return ot.NewServer("a🌍"), nil
+	})
+	a := NewAuthority(hub)
+
+	doc, version, err := a.Document(ctx, "d")
+	if err != nil || doc != "a🌍" || version != 0 {
+		t.Fatalf("expected %q at 0, got %q at %d, %v", "a🌍", doc, version, err)
+	}
+
+	sub, err := hub.Subscribe(ctx, "d", "watcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	ok, err := a.Push(ctx, "d", 0, []Update{
+		{Changes: json.RawMessage(`[3,[0,"!"]]`), ClientID: "cm1"},
+		{Changes: json.RawMessage(`[[1],3]`), ClientID: "cm1"},
+	})
+	if err != nil || !ok {
+		t.Fatalf("expected the push to be accepted, got %v, %v", ok, err)
+	}
+	if doc, version, _ = a.Document(ctx, "d"); doc != "🌍!" || version != 2 {
+		t.Errorf("expected %q at 2, got %q at %d", "🌍!", doc, version)
+	}
+
+	// The others subscribed to the Hub see the updates.
+	for ev := range sub.C {
+		if ev.Kind == ot.EventOp && ev.Revision == 2 {
+			break
+		}
+	}
+
+	ok, err = a.Push(ctx, "d", 1, []Update{{Changes: json.RawMessage(`[[2],2]`), ClientID: "cm2"}})
+	if err != nil || ok {
+		t.Errorf("expected a push at an old version to be refused, got %v, %v", ok, err)
+	}
+
+	updates, err := a.Pull(ctx, "d", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Update{
+		{Changes: json.RawMessage(`[3,[0,"!"]]`), ClientID: "cm1"},
+		{Changes: json.RawMessage(`[[1],3]`), ClientID: "cm1"},
+	}
+	if len(updates) != len(want) {
+		t.Fatalf("expected %d updates, got %d", len(want), len(updates))
+	}
+	for i := range want {
+		if string(updates[i].Changes) != string(want[i].Changes) || updates[i].ClientID != want[i].ClientID {
+			t.Errorf("update %d: expected %s from %s, got %s from %s", i, want[i].Changes, want[i].ClientID, updates[i].Changes, updates[i].ClientID)
+		}
+	}
+	if updates, err = a.Pull(ctx, "d", 2); err != nil || len(updates) != 0 {
+		t.Errorf("expected no updates, got %v, %v", updates, err)
+	}
+}
@@ -0,0 +1,158 @@
+// Package otgolden holds golden results for Transform and Compose: pairs of
+// operations in the JSON wire format with the results this implementation
+// gave when they were recorded, so a change that alters them is caught.
+//
+// They are not conformance vectors. Every case was recorded from this
+// implementation and none has been checked against another: in particular,
+// concurrent inserts at the same position are ordered by their text here,
+// which ot.js and operational-transform-rs do not do.
+//
+// Cases are stored as a JSON array of Case objects:
+//
+//	[{"name": "insert vs insert", "kind": "transform", "doc": "abc",
+//	  "a": ["x", 3], "b": ["y", 3],
+//	  "aPrime": ["x", 4], "bPrime": [1, "y", 3], "result": "xyabc"}]
+//
+// Default returns the cases shipped with this package.
+package otgolden
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+// Kinds of Case.
+const (
+	// KindTransform cases hold concurrent operations A and B on Doc, and
+	// the A' and B' that Transform gives.
+	KindTransform = "transform"
+
+	// KindCompose cases hold A on Doc and B on its result, and the
+	// operation Compose gives.
+	KindCompose = "compose"
+)
+
+// Case is one golden test case. Expected results that are absent
+// are not checked.
+type Case struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	Doc  string `json:"doc"`
+
+	A *ot.OperationSeq `json:"a"`
+	B *ot.OperationSeq `json:"b"`
+
+	// APrime and BPrime are the results of a transform case.
+	APrime *ot.OperationSeq `json:"aPrime,omitempty"`
+	BPrime *ot.OperationSeq `json:"bPrime,omitempty"`
+
+	// Compose is the result of a compose case.
+	Compose *ot.OperationSeq `json:"compose,omitempty"`
+
+	// Result is the document after both operations: A then B' for a
+	// transform case, A then B for a compose one.
+	Result *string `json:"result,omitempty"`
+
+	// Error is set if the operations must be rejected with
+	// ot.ErrIncompatibleLengths.
+	Error bool `json:"error,omitempty"`
+}
+
+//go:embed golden.json
+var golden []byte
+
+// Default returns the cases shipped with this package.
+func Default() []Case {
+	v, err := Load(bytes.NewReader(golden))
+	if err != nil {
+		panic("otgolden: " + err.Error())
+	}
+	return v
+}
+
+// Load reads a JSON array of cases from r.
+func Load(r io.Reader) ([]Case, error) {
+	var v []Case
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return nil, fmt.Errorf("loading cases: %w", err)
+	}
+	for i, vec := range v {
+		if vec.A == nil || vec.B == nil {
+			return nil, fmt.Errorf("loading cases: %d (%s): missing operation", i, vec.Name)
+		}
+		if vec.Kind != KindTransform && vec.Kind != KindCompose {
+			return nil, fmt.Errorf("loading cases: %d (%s): unknown kind %q", i, vec.Name, vec.Kind)
+		}
+	}
+	return v, nil
+}
+
+// Check runs v against this implementation and returns an error describing
+// the first result that differs from the expected one. Operations are
+// compared in their wire form, so a result with the same effect but
+// differently split components is a failure.
+func (v Case) Check() error {
+	switch v.Kind {
+	case KindTransform:
+		aPrime, bPrime, err := v.A.Transform(v.B)
+		if v.Error || err != nil {
+			return checkErr(v, err)
+		}
+		if err := same("A'", v.APrime, aPrime); err != nil {
+			return err
+		}
+		if err := same("B'", v.BPrime, bPrime); err != nil {
+			return err
+		}
+		return v.checkResult(v.A, bPrime)
+	case KindCompose:
+		composed, err := v.A.Compose(v.B)
+		if v.Error || err != nil {
+			return checkErr(v, err)
+		}
+		if err := same("compose", v.Compose, composed); err != nil {
+			return err
+		}
+		return v.checkResult(composed)
+	}
+	return fmt.Errorf("unknown kind %q", v.Kind)
+}
+
+func (v Case) checkResult(ops ...*ot.OperationSeq) error {
+	if v.Result == nil {
+		return nil
+	}
+	doc, err := ot.ReplayLog(v.Doc, ops)
+	if err != nil {
+		return err
+	}
+	if doc != *v.Result {
+		return fmt.Errorf("result: expected %q, got %q", *v.Result, doc)
+	}
+	return nil
+}
+
+func checkErr(v Case, err error) error {
+	switch {
+	case v.Error && err == nil:
+		return errors.New("expected ErrIncompatibleLengths, got success")
+	case v.Error && !errors.Is(err, ot.ErrIncompatibleLengths):
+		return fmt.Errorf("expected ErrIncompatibleLengths, got %w", err)
+	case !v.Error:
+		return fmt.Errorf("unexpected error: %w", err)
+	}
+	return nil
+}
+
+func same(what string, want, got *ot.OperationSeq) error {
+	if want != nil && want.String() != got.String() {
+		return fmt.Errorf("%s: expected %v, got %v", what, want, got)
+	}
+	return nil
+}
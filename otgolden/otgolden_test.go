@@ -0,0 +1,48 @@
+package otgolden
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	cases := Default()
+	if len(cases) == 0 {
+		t.Fatal("expected shipped cases")
+	}
+	for _, v := range cases {
+		if err := v.Check(); err != nil {
+			t.Errorf("%s: %v", v.Name, err)
+		}
+	}
+}
+
+func TestCheckFails(t *testing.T) {
+	cases, err := Load(strings.NewReader(`[
+		{"name": "wrong order", "kind": "transform", "doc": "abc", "a": ["x", 3], "b": ["y", 3], "aPrime": [1, "x", 3]},
+		{"name": "wrong result", "kind": "compose", "doc": "abc", "a": [3, "d"], "b": [4, "e"], "result": "abced"},
+		{"name": "missing error", "kind": "compose", "doc": "abc", "a": [3], "b": [3], "error": true},
+		{"name": "unexpected error", "kind": "transform", "doc": "abc", "a": [3], "b": [2]}
+	]`))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	for _, v := range cases {
+		if err := v.Check(); err == nil {
+			t.Errorf("%s: expected Check to fail", v.Name)
+		}
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for _, input := range []string{
+		`{}`,
+		`[{"name": "no b", "kind": "transform", "a": [1]}]`,
+		`[{"name": "bad kind", "kind": "invert", "a": [1], "b": [1]}]`,
+		`[{"name": "bad op", "kind": "transform", "a": [true], "b": [1]}]`,
+	} {
+		if _, err := Load(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected an error", input)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+// Package othttp exposes documents over plain HTTP for integrations that do
+// not hold a realtime connection, such as bots, importers, and CI jobs.
+//
+// # Endpoints
+//
+//	GET  /docs/{id}
+//	    {"revision":3,"document":"...","mode":"readOnly"}
+//	    The response carries an ETag of the revision, e.g. "3". "mode" is the
+//	    document's ot.Mode, omitted while it is editable.
+//
+//	GET  /docs/{id}/ops?since=N
+//	    {"revision":5,"ops":[[...],[...]]}
+//	    The operations from revision N up to the current revision. If N has
+//	    been compacted away the response is 410 Gone, and the client should
+//	    refetch GET /docs/{id}.
+//
+//	POST /docs/{id}/ops
+//	    Request:  {"revision":3,"op":[...]}
+//	    Response: {"revision":6,"op":[...]}
+//	    Submits an operation made against revision 3. The server transforms it
+//	    past any operations accepted since then and returns the form that was
+//	    applied. To refuse rebasing instead, send If-Match with the ETag from
+//	    GET /docs/{id}; the request then fails with 412 Precondition Failed
+//	    unless the document is still at that revision. An optional
+//	    X-Client-ID header names the submitter in the events other clients
+//	    see. Unless the Hub has an Authorizer to vouch for it, the name is
+//	    prefixed with "http:", so that a request cannot pass itself off as
+//	    a realtime client whose ID it saw in presence.
+//	    An optional Idempotency-Key header, unique among the submitter's
+//	    operations, makes retries safe: resubmitting with the same
+//	    X-Client-ID and key returns the original response instead of
+//	    applying the operation again. It is ignored with If-Match, where a
+//	    retry fails the precondition instead.
+//
+//	GET  /docs/{id}/stream
+//	    The document's events as Server-Sent Events, for read-mostly clients
+//	    such as live previews and dashboards. Each event's data is a message
+//	    of the otws protocol: first "joined", with the document, then "op",
+//	    "presence", "cursor", "meta", and "mode" as they happen, and
+//	    "shutdown" if the Hub shuts down. Events carry their revision as the
+//	    event ID, so an EventSource that reconnects with Last-Event-ID gets a
+//	    "joined" whose "ops" are the operations it missed, unless they have
+//	    been compacted away. "joined" never carries a resume secret. If the
+//	    Hub has an Authorizer, an X-Client-ID header it accepts names the
+//	    subscriber, and operations POSTed under the same ID arrive on the
+//	    stream as "ack". Otherwise the header is ignored, and the stream
+//	    watches the document as a viewer (see ot.Hub.Watch) under an ID of
+//	    its own, and may receive operations in batches.
+//
+// If the Hub has an Authorizer, GET requests are checked with
+// AuthorizeSubscribe and POST requests with Authorize, using the request
+// context and the X-Client-ID header. Errors wrapping ot.ErrForbidden are
+// reported as 403 Forbidden. Submitters over the Hub's RateLimit get
+// 429 Too Many Requests with a Retry-After header. Operations over the
+// server's ServerLimits get 413 Request Entity Too Large, or 409 Conflict if
+// made against a revision too old to transform. Operations the document's
+// mode refuses get 423 Locked.
+//
+// Every request runs with its own context, so a deadline set by middleware
+// such as http.TimeoutHandler also bounds the Store calls it makes. Requests
+// that run out of time get 503 Service Unavailable.
+//
+// Errors are reported as {"error":"..."} with a matching status code.
+package othttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+// Handler serves the endpoints described in the package documentation.
+//
+// Submitted operations go through the Hub, so clients subscribed to the same
+// Hub (for example over otws) receive them.
+type Handler struct {
+	// Hub holds the documents. Unknown documents are reported as 404 when
+	// Hub.Open returns ot.ErrDocumentNotFound.
+	Hub *ot.Hub
+
+	// Limits are applied when decoding submitted operations.
+	Limits ot.DecodeLimits
+
+	// MaxBodyBytes bounds the size of a request body; zero means no limit.
+	MaxBodyBytes int64
+
+	// StreamKeepAlive is how often an idle event stream sends a comment, so
+	// proxies do not close it; zero disables keep-alives.
+	StreamKeepAlive time.Duration
+
+	// Logger receives failed requests, at Error for server errors and at
+	// Debug otherwise. Nil means the Hub's Logger.
+	Logger *slog.Logger
+}
+
+// discard is the logger used when neither the Handler nor the Hub has one.
+var discard = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.Level(math.MaxInt)}))
+
+func (h *Handler) logger() *slog.Logger {
+	switch {
+	case h.Logger != nil:
+		return h.Logger
+	case h.Hub.Logger != nil:
+		return h.Hub.Logger
+	}
+	return discard
+}
+
+// NewHandler returns a Handler serving the documents of hub.
+func NewHandler(hub *ot.Hub) *Handler {
+	return &Handler{Hub: hub}
+}
+
+type snapshotResponse struct {
+	Revision int     `json:"revision"`
+	Document string  `json:"document"`
+	Mode     ot.Mode `json:"mode,omitempty"`
+}
+
+type opsResponse struct {
+	Revision int                `json:"revision"`
+	Ops      []*ot.OperationSeq `json:"ops"`
+}
+
+type submitRequest struct {
+	Revision int             `json:"revision"`
+	Op       json.RawMessage `json:"op"`
+}
+
+type submitResponse struct {
+	Revision int              `json:"revision"`
+	Op       *ot.OperationSeq `json:"op"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest, ok := strings.CutPrefix(r.URL.Path, "/docs/")
+	if !ok || rest == "" {
+		h.writeError(w, r, http.StatusNotFound, ot.ErrDocumentNotFound)
+		return
+	}
+	id, sub, _ := strings.Cut(rest, "/")
+
+	switch {
+	case sub == "" && r.Method == http.MethodGet:
+		h.getDocument(w, r, id)
+	case sub == "ops" && r.Method == http.MethodGet:
+		h.getOps(w, r, id)
+	case sub == "ops" && r.Method == http.MethodPost:
+		h.postOp(w, r, id)
+	case sub == "stream" && r.Method == http.MethodGet:
+		h.stream(w, r, id)
+	case sub == "" || sub == "ops" || sub == "stream":
+		h.writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	default:
+		h.writeError(w, r, http.StatusNotFound, fmt.Errorf("unknown resource %q", sub))
+	}
+}
+
+// lookup returns the server for a document the client may read, or writes an
+// error response and returns nil. Reads are authorized like subscriptions.
+func (h *Handler) lookup(w http.ResponseWriter, r *http.Request, id string) *ot.Server {
+	if a := h.Hub.Authorizer; a != nil {
+		if _, err := a.AuthorizeSubscribe(r.Context(), r.Header.Get("X-Client-ID"), id); err != nil {
+			h.writeError(w, r, statusFor(err), err)
+			return nil
+		}
+	}
+	server, err := h.Hub.Server(r.Context(), id)
+	if err != nil {
+		h.writeError(w, r, statusFor(err), err)
+		return nil
+	}
+	return server
+}
+
+func (h *Handler) getDocument(w http.ResponseWriter, r *http.Request, id string) {
+	server := h.lookup(w, r, id)
+	if server == nil {
+		return
+	}
+
+	doc, revision := server.State()
+	w.Header().Set("ETag", etag(revision))
+	writeJSON(w, http.StatusOK, snapshotResponse{Revision: revision, Document: doc, Mode: server.Mode()})
+}
+
+func (h *Handler) getOps(w http.ResponseWriter, r *http.Request, id string) {
+	since, err := strconv.Atoi(r.URL.Query().Get("since"))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, fmt.Errorf("invalid since parameter: %w", err))
+		return
+	}
+
+	server := h.lookup(w, r, id)
+	if server == nil {
+		return
+	}
+
+	ops, revision, err := server.OperationsSince(since)
+	if err != nil {
+		h.writeError(w, r, statusFor(err), err)
+		return
+	}
+	w.Header().Set("ETag", etag(revision))
+	writeJSON(w, http.StatusOK, opsResponse{Revision: revision, Ops: ops})
+}
+
+func (h *Handler) postOp(w http.ResponseWriter, r *http.Request, id string) {
+	body := r.Body
+	if h.MaxBodyBytes > 0 {
+		body = http.MaxBytesReader(w, body, h.MaxBodyBytes)
+	}
+
+	var req submitRequest
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		h.writeError(w, r, status, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+	op, err := h.Limits.DecodeJSON(req.Op)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, fmt.Errorf("invalid operation: %w", err))
+		return
+	}
+
+	client := h.submitter(r)
+	var applied *ot.OperationSeq
+	revision := req.Revision + 1
+	if match := r.Header.Get("If-Match"); match != "" {
+		if match != etag(req.Revision) {
+			h.writeError(w, r, http.StatusPreconditionFailed, errors.New("If-Match does not match the submitted revision"))
+			return
+		}
+		applied, err = h.Hub.ApplyAt(r.Context(), id, client, req.Revision, op)
+	} else {
+		applied, revision, err = h.Hub.SubmitOp(r.Context(), id, client, r.Header.Get("Idempotency-Key"), req.Revision, op)
+	}
+	if err != nil {
+		h.writeError(w, r, statusFor(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, submitResponse{Revision: revision, Op: applied})
+}
+
+// submitter returns the client a POST request submits for: X-Client-ID if
+// the Hub's Authorizer will check it, and otherwise the header in a
+// namespace no realtime client's ID is in. Without it, anyone could submit
+// as a connected client, whose session would then take the "ack" for an
+// operation it never sent as that of its own pending one.
+func (h *Handler) submitter(r *http.Request) string {
+	client := r.Header.Get("X-Client-ID")
+	if client == "" || h.Hub.Authorizer != nil {
+		return client
+	}
+	return "http:" + client
+}
+
+func etag(revision int) string {
+	return strconv.Quote(strconv.Itoa(revision))
+}
+
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, ot.ErrDocumentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ot.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ot.ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ot.ErrInvalidRevision):
+		return http.StatusConflict
+	case errors.Is(err, ot.ErrRevisionCompacted):
+		return http.StatusGone
+	case errors.Is(err, ot.ErrStaleRevision):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, ot.ErrIncompatibleLengths):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ot.ErrOpTooLarge), errors.Is(err, ot.ErrDocumentTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ot.ErrTooFarBehind):
+		return http.StatusConflict
+	case errors.Is(err, ot.ErrReadOnly):
+		return http.StatusLocked
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, ot.ErrHubClosed):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	level := slog.LevelDebug
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	h.logger().Log(r.Context(), level, "request failed", "method", r.Method, "path", r.URL.Path, "client", r.Header.Get("X-Client-ID"), "status", status, "err", err)
+
+	var limited *ot.RateLimitError
+	if errors.As(err, &limited) {
+		// Retry-After is in whole seconds; round up so an early retry is not
+		// rejected again.
+		secs := (limited.RetryAfter + time.Second - 1) / time.Second
+		w.Header().Set("Retry-After", strconv.FormatInt(int64(secs), 10))
+	}
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	// Headers are already sent; an encoding failure can only truncate the body.
+	json.NewEncoder(w).Encode(v) //nolint:errcheck // nothing useful to do once the status is written
+}
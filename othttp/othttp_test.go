@@ -0,0 +1,333 @@
+package othttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+func newTestHandler() (*Handler, *ot.Server) {
+	server := ot.NewServer("hello")
+	h := NewHandler(ot.NewHub(func(_ context.Context, doc string) (*ot.Server, error) {
+		if doc != "notes" {
+			return nil, ot.ErrDocumentNotFound
+		}
+		return server, nil
+	}))
+	return h, server
+}
+
+func do(t *testing.T, h http.Handler, method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %q failed: %v", rec.Body.String(), err)
+	}
+}
+
+func TestGetDocument(t *testing.T) {
+	h, _ := newTestHandler()
+
+	rec := do(t, h, http.MethodGet, "/docs/notes", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp snapshotResponse
+	decode(t, rec, &resp)
+	if resp.Document != "hello" || resp.Revision != 0 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if rec.Header().Get("ETag") != `"0"` {
+		t.Errorf("expected ETag \"0\", got %q", rec.Header().Get("ETag"))
+	}
+
+	if rec := do(t, h, http.MethodGet, "/docs/missing", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+	if rec := do(t, h, http.MethodDelete, "/docs/notes", "", nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+}
+
+func TestPostAndListOps(t *testing.T) {
+	h, server := newTestHandler()
+
+	rec := do(t, h, http.MethodPost, "/docs/notes/ops", `{"revision":0,"op":[5,"!"]}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// A second edit against revision 0 is rebased past the first.
+	rec = do(t, h, http.MethodPost, "/docs/notes/ops", `{"revision":0,"op":["*",5]}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var submitted struct {
+		Revision int             `json:"revision"`
+		Op       json.RawMessage `json:"op"`
+	}
+	decode(t, rec, &submitted)
+	if submitted.Revision != 2 || string(submitted.Op) != `["*",6]` {
+		t.Errorf("unexpected response: revision %d, op %s", submitted.Revision, submitted.Op)
+	}
+	if server.Document() != "*hello!" {
+		t.Errorf("expected %q, got %q", "*hello!", server.Document())
+	}
+
+	rec = do(t, h, http.MethodGet, "/docs/notes/ops?since=1", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var listed struct {
+		Revision int               `json:"revision"`
+		Ops      []json.RawMessage `json:"ops"`
+	}
+	decode(t, rec, &listed)
+	if listed.Revision != 2 || len(listed.Ops) != 1 || string(listed.Ops[0]) != `["*",6]` {
+		t.Errorf("unexpected listing: %+v", listed)
+	}
+}
+
+func TestPostReachesSubscribers(t *testing.T) {
+	h, _ := newTestHandler()
+	sub, err := h.Hub.Subscribe(context.Background(), "notes", "watcher")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Close()
+	<-sub.C // own join
+
+	rec := do(t, h, http.MethodPost, "/docs/notes/ops", `{"revision":0,"op":[5,"!"]}`, map[string]string{"X-Client-ID": "bot"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	ev := <-sub.C
+	if ev.Kind != ot.EventOp || ev.Client != "http:bot" || ev.Revision != 1 {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+}
+
+func TestPostUnauthenticatedClientID(t *testing.T) {
+	h, _ := newTestHandler()
+	victim, err := h.Hub.Subscribe(context.Background(), "notes", "victim")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer victim.Close()
+	<-victim.C // own join
+
+	// Without an Authorizer nothing vouches for X-Client-ID, so the
+	// operation must not pass for one the victim submitted.
+	rec := do(t, h, http.MethodPost, "/docs/notes/ops", `{"revision":0,"op":[5,"!"]}`, map[string]string{"X-Client-ID": "victim"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if ev := <-victim.C; ev.Client == "victim" {
+		t.Errorf("expected an operation from another client, got %+v", ev)
+	}
+
+	h.Hub.Authorizer = trustClients{}
+	rec = do(t, h, http.MethodPost, "/docs/notes/ops", `{"revision":1,"op":[6,"?"]}`, map[string]string{"X-Client-ID": "victim"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if ev := <-victim.C; ev.Client != "victim" {
+		t.Errorf("expected the vouched-for client, got %+v", ev)
+	}
+}
+
+func TestPostIdempotencyKey(t *testing.T) {
+	h, server := newTestHandler()
+	header := map[string]string{"X-Client-ID": "bot", "Idempotency-Key": "k1"}
+
+	first := do(t, h, http.MethodPost, "/docs/notes/ops", `{"revision":0,"op":[5,"!"]}`, header)
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", first.Code, first.Body)
+	}
+	retry := do(t, h, http.MethodPost, "/docs/notes/ops", `{"revision":0,"op":[5,"!"]}`, header)
+	if retry.Code != http.StatusOK || retry.Body.String() != first.Body.String() {
+		t.Errorf("expected original response %s, got %d: %s", first.Body, retry.Code, retry.Body)
+	}
+	if server.Document() != "hello!" {
+		t.Errorf("expected %q, got %q", "hello!", server.Document())
+	}
+}
+
+func TestPostIfMatch(t *testing.T) {
+	h, _ := newTestHandler()
+
+	ok := do(t, h, http.MethodPost, "/docs/notes/ops", `{"revision":0,"op":[5,"!"]}`, map[string]string{"If-Match": `"0"`})
+	if ok.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", ok.Code, ok.Body)
+	}
+
+	stale := do(t, h, http.MethodPost, "/docs/notes/ops", `{"revision":0,"op":[5,"?"]}`, map[string]string{"If-Match": `"0"`})
+	if stale.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected 412, got %d", stale.Code)
+	}
+}
+
+func TestPostIfMatchClipped(t *testing.T) {
+	h, server := newTestHandler()
+	server.SetProtectPolicy(ot.ProtectClip)
+	if err := server.Protect("head", ot.Range{Start: 0, End: 3}); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := h.Hub.Subscribe(context.Background(), "notes", "watcher")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Close()
+	<-sub.C // own join
+
+	rec := do(t, h, http.MethodPost, "/docs/notes/ops", `{"revision":0,"op":[-5]}`, map[string]string{"If-Match": `"0"`})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp submitResponse
+	decode(t, rec, &resp)
+	if ev := <-sub.C; ev.Op.String() != resp.Op.String() {
+		t.Errorf("expected subscribers to get %v, got %v", resp.Op, ev.Op)
+	}
+	replica, err := resp.Op.Apply("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replica != "hel" || server.Document() != "hel" {
+		t.Errorf("expected %q on both, got %q and %q", "hel", replica, server.Document())
+	}
+}
+
+func TestPostErrors(t *testing.T) {
+	h, _ := newTestHandler()
+	h.MaxBodyBytes = 64
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"malformed body", "/docs/notes/ops", `{`, http.StatusBadRequest},
+		{"malformed op", "/docs/notes/ops", `{"revision":0,"op":[true]}`, http.StatusBadRequest},
+		{"future revision", "/docs/notes/ops", `{"revision":9,"op":[5]}`, http.StatusConflict},
+		{"wrong length", "/docs/notes/ops", `{"revision":0,"op":[3]}`, http.StatusUnprocessableEntity},
+		{"too large", "/docs/notes/ops", `{"revision":0,"op":[5,"` + strings.Repeat("x", 100) + `"]}`, http.StatusRequestEntityTooLarge},
+		{"unknown doc", "/docs/missing/ops", `{"revision":0,"op":[5]}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(t, h, http.MethodPost, tt.path, tt.body, nil)
+			if rec.Code != tt.status {
+				t.Errorf("expected %d, got %d: %s", tt.status, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	if rec := do(t, h, http.MethodGet, "/docs/notes/ops?since=x", "", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	h, server := newTestHandler()
+	server.SetMode(ot.ModeReadOnly)
+
+	rec := do(t, h, http.MethodPost, "/docs/notes/ops", `{"revision":0,"op":[5,"!"]}`, nil)
+	if rec.Code != http.StatusLocked {
+		t.Errorf("expected 423, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var snap snapshotResponse
+	decode(t, do(t, h, http.MethodGet, "/docs/notes", "", nil), &snap)
+	if snap.Mode != ot.ModeReadOnly {
+		t.Errorf("expected readOnly, got %v", snap.Mode)
+	}
+}
+
+func TestCompactedRevision(t *testing.T) {
+	h, server := newTestHandler()
+
+	op := ot.NewOperationSeq()
+	op.Retain(5)
+	op.Insert("!")
+	if _, err := server.ReceiveOperation(context.Background(), 0, op); err != nil {
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+	if err := server.Compact(context.Background(), 1); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+
+	if rec := do(t, h, http.MethodGet, "/docs/notes/ops?since=0", "", nil); rec.Code != http.StatusGone {
+		t.Errorf("expected 410, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(t, h, http.MethodGet, "/docs/notes/ops?since=1", "", nil); rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+type denyWrites struct{}
+
+func (denyWrites) Authorize(_ context.Context, client, _ string, _ *ot.OperationSeq) error {
+	return fmt.Errorf("%w: %s is read-only", ot.ErrForbidden, client)
+}
+
+func (denyWrites) AuthorizeSubscribe(_ context.Context, client, _ string) (bool, error) {
+	if client == "" {
+		return false, fmt.Errorf("%w: anonymous", ot.ErrForbidden)
+	}
+	return true, nil
+}
+
+func TestAuthorizer(t *testing.T) {
+	h, _ := newTestHandler()
+	h.Hub.Authorizer = denyWrites{}
+	reader := map[string]string{"X-Client-ID": "reader"}
+
+	if rec := do(t, h, http.MethodGet, "/docs/notes", "", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for anonymous read, got %d", rec.Code)
+	}
+	if rec := do(t, h, http.MethodGet, "/docs/notes", "", reader); rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(t, h, http.MethodGet, "/docs/notes/ops?since=0", "", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for anonymous read, got %d", rec.Code)
+	}
+	if rec := do(t, h, http.MethodPost, "/docs/notes/ops", `{"revision":0,"op":[5]}`, reader); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for write, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	h, _ := newTestHandler()
+	h.Hub.RateLimit = ot.RateLimit{OpsPerSecond: 0.5}
+
+	if rec := do(t, h, http.MethodPost, "/docs/notes/ops", `{"revision":0,"op":[5,"!"]}`, nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	rec := do(t, h, http.MethodPost, "/docs/notes/ops", `{"revision":1,"op":[6,"!"]}`, nil)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d: %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("expected Retry-After 2, got %q", got)
+	}
+}
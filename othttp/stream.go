@@ -0,0 +1,141 @@
+package othttp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	ot "github.com/shiv248/operational-transformation-go"
+	"github.com/shiv248/operational-transformation-go/otws"
+)
+
+// stream serves GET /docs/{id}/stream: the document's events as Server-Sent
+// Events, encoded as otws messages.
+func (h *Handler) stream(w http.ResponseWriter, r *http.Request, id string) {
+	ctx := r.Context()
+	rc := http.NewResponseController(w)
+
+	// A stream takes X-Client-ID as its identity only if the Authorizer
+	// vouched for it: otherwise anyone could read the acknowledgements of a
+	// client whose ID they saw in presence. Other streams cannot submit
+	// operations, so they watch the document as viewers.
+	client := r.Header.Get("X-Client-ID")
+	watch := client == "" || h.Hub.Authorizer == nil
+	if watch {
+		var b [9]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			h.writeError(w, r, http.StatusInternalServerError, fmt.Errorf("generating client ID: %w", err))
+			return
+		}
+		client = base64.RawURLEncoding.EncodeToString(b[:])
+	}
+
+	var sub *ot.Subscription
+	var err error
+	if last := r.Header.Get("Last-Event-ID"); last != "" {
+		revision, perr := strconv.Atoi(last)
+		if perr != nil {
+			h.writeError(w, r, http.StatusBadRequest, fmt.Errorf("invalid Last-Event-ID: %w", perr))
+			return
+		}
+		// A stream has no pending operation to claim, so it catches up by
+		// revision alone, without a resume secret.
+		sub, err = h.Hub.SubscribeSince(ctx, id, client, revision, watch)
+		if errors.Is(err, ot.ErrRevisionCompacted) || errors.Is(err, ot.ErrInvalidRevision) {
+			// Too far behind to catch up; start again from the current document.
+			sub, err = h.subscribe(ctx, id, client, watch)
+		}
+	} else {
+		sub, err = h.subscribe(ctx, id, client, watch)
+	}
+	if err != nil {
+		h.writeError(w, r, statusFor(err), err)
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if !h.writeEvent(w, r, sub.Revision, otws.JoinedMessage(sub)) {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		h.logger().ErrorContext(ctx, "streaming not supported", "path", r.URL.Path, "err", err)
+		return
+	}
+
+	var keepAlive <-chan time.Time
+	if h.StreamKeepAlive > 0 {
+		ticker := time.NewTicker(h.StreamKeepAlive)
+		defer ticker.Stop()
+		keepAlive = ticker.C
+	}
+	for {
+		select {
+		case ev, ok := <-sub.C:
+			if !ok {
+				if errors.Is(sub.Err(), ot.ErrHubClosed) {
+					h.writeEvent(w, r, 0, otws.Message{Type: otws.TypeShutdown})
+					rc.Flush() //nolint:errcheck // the stream is ending either way
+				}
+				return
+			}
+			msg, ok := otws.EventMessage(ev, client)
+			if !ok {
+				continue
+			}
+			if !h.writeEvent(w, r, ev.Revision, msg) {
+				return
+			}
+		case <-keepAlive:
+			if _, err := w.Write([]byte(": keep-alive\n\n")); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (h *Handler) subscribe(ctx context.Context, id, client string, watch bool) (*ot.Subscription, error) {
+	if watch {
+		return h.Hub.Watch(ctx, id, client)
+	}
+	return h.Hub.Subscribe(ctx, id, client)
+}
+
+// writeEvent writes msg as an event whose ID is revision, so a reconnecting
+// EventSource resumes from it with Last-Event-ID. A zero revision is left
+// out, keeping the last ID the client saw. It returns false if the client is
+// gone.
+func (h *Handler) writeEvent(w http.ResponseWriter, r *http.Request, revision int, msg otws.Message) bool {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		h.logger().ErrorContext(r.Context(), "encoding message failed", "type", msg.Type, "err", err)
+		return true
+	}
+	buf := make([]byte, 0, len(data)+32)
+	if revision > 0 {
+		buf = append(buf, "id: "...)
+		buf = strconv.AppendInt(buf, int64(revision), 10)
+		buf = append(buf, '\n')
+	}
+	buf = append(buf, "data: "...)
+	buf = append(buf, data...)
+	buf = append(buf, "\n\n"...)
+	if _, err := w.Write(buf); err != nil {
+		h.logger().DebugContext(r.Context(), "stream write failed", "path", r.URL.Path, "err", err)
+		return false
+	}
+	return true
+}
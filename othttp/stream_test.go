@@ -0,0 +1,195 @@
+package othttp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ot "github.com/shiv248/operational-transformation-go"
+	"github.com/shiv248/operational-transformation-go/otws"
+)
+
+// sseEvent is one parsed Server-Sent Event.
+type sseEvent struct {
+	id  string
+	msg otws.Message
+}
+
+func openStream(t *testing.T, url string, header map[string]string) (*bufio.Reader, func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("NewRequest failed: %v", err)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected text/event-stream, got %q", ct)
+	}
+	return bufio.NewReader(resp.Body), func() {
+		cancel()
+		resp.Body.Close() //nolint:errcheck // test cleanup
+	}
+}
+
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var ev sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream failed: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return ev
+		case strings.HasPrefix(line, "id: "):
+			ev.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev.msg); err != nil {
+				t.Fatalf("decoding %q failed: %v", line, err)
+			}
+		}
+	}
+}
+
+// trustClients accepts every client that names itself.
+type trustClients struct{}
+
+func (trustClients) Authorize(context.Context, string, string, *ot.OperationSeq) error {
+	return nil
+}
+
+func (trustClients) AuthorizeSubscribe(_ context.Context, client, _ string) (bool, error) {
+	if client == "" {
+		return false, fmt.Errorf("%w: anonymous", ot.ErrForbidden)
+	}
+	return false, nil
+}
+
+func TestStream(t *testing.T) {
+	h, _ := newTestHandler()
+	h.Hub.Authorizer = trustClients{}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	r, stop := openStream(t, srv.URL+"/docs/notes/stream", map[string]string{"X-Client-ID": "viewer"})
+	defer stop()
+
+	ev := readEvent(t, r)
+	if ev.msg.Type != otws.TypeJoined || ev.msg.Document != "hello" || ev.msg.Client != "viewer" {
+		t.Fatalf("expected joined with the document, got %+v", ev.msg)
+	}
+	if ev.msg.Secret != "" {
+		t.Errorf("expected no resume secret on a stream, got %q", ev.msg.Secret)
+	}
+	if ev := readEvent(t, r); ev.msg.Type != otws.TypePresence {
+		t.Fatalf("expected presence, got %+v", ev.msg)
+	}
+
+	if rec := do(t, h, http.MethodPost, "/docs/notes/ops", `{"revision":0,"op":[5,"!"]}`, map[string]string{"X-Client-ID": "bot"}); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	ev = readEvent(t, r)
+	if ev.msg.Type != otws.TypeOp || ev.msg.Client != "bot" || string(ev.msg.Op) != `[5,"!"]` || ev.id != "1" {
+		t.Errorf("expected op from bot with id 1, got id %q %+v", ev.id, ev.msg)
+	}
+
+	// The subscriber's own submissions arrive as acknowledgements.
+	if rec := do(t, h, http.MethodPost, "/docs/notes/ops", `{"revision":1,"op":[6,"?"]}`, map[string]string{"X-Client-ID": "viewer"}); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if ev := readEvent(t, r); ev.msg.Type != otws.TypeAck || ev.msg.Revision != 2 {
+		t.Errorf("expected ack of revision 2, got %+v", ev.msg)
+	}
+
+	if rec := do(t, h, http.MethodPost, "/docs/notes/stream", "", nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+	if rec := do(t, h, http.MethodGet, "/docs/missing/stream", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestStreamUnauthenticatedClientID(t *testing.T) {
+	h, _ := newTestHandler()
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	// Without an Authorizer nothing vouches for X-Client-ID, so the stream
+	// must not take over the identity of the client it names.
+	r, stop := openStream(t, srv.URL+"/docs/notes/stream", map[string]string{"X-Client-ID": "victim"})
+	defer stop()
+	ev := readEvent(t, r)
+	if ev.msg.Type != otws.TypeJoined || ev.msg.Client == "victim" || !ev.msg.ReadOnly || ev.msg.Secret != "" {
+		t.Fatalf("expected an anonymous read-only joined without a secret, got %+v", ev.msg)
+	}
+	readEvent(t, r) // presence
+
+	if rec := do(t, h, http.MethodPost, "/docs/notes/ops", `{"revision":0,"op":[5,"!"]}`, map[string]string{"X-Client-ID": "victim"}); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if ev := readEvent(t, r); ev.msg.Type != otws.TypeOp || ev.msg.Client != "http:victim" {
+		t.Errorf("expected an op from http:victim, not an ack, got %+v", ev.msg)
+	}
+}
+
+func TestStreamLastEventID(t *testing.T) {
+	h, server := newTestHandler()
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	op := ot.NewOperationSeq()
+	op.Retain(5)
+	op.Insert("!")
+	if _, err := server.ReceiveOperation(context.Background(), 0, op); err != nil {
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+
+	r, stop := openStream(t, srv.URL+"/docs/notes/stream", map[string]string{"Last-Event-ID": "0"})
+	defer stop()
+	ev := readEvent(t, r)
+	if ev.msg.Type != otws.TypeJoined || ev.msg.Revision != 1 || len(ev.msg.Ops) != 1 || ev.id != "1" {
+		t.Errorf("expected joined at revision 1 with the missed op, got id %q %+v", ev.id, ev.msg)
+	}
+
+	// A revision the server does not have starts afresh.
+	r2, stop2 := openStream(t, srv.URL+"/docs/notes/stream", map[string]string{"Last-Event-ID": "9"})
+	defer stop2()
+	if ev := readEvent(t, r2); ev.msg.Type != otws.TypeJoined || ev.msg.Document != "hello!" || len(ev.msg.Ops) != 0 {
+		t.Errorf("expected a fresh joined, got %+v", ev.msg)
+	}
+}
+
+func TestStreamShutdown(t *testing.T) {
+	h, _ := newTestHandler()
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	r, stop := openStream(t, srv.URL+"/docs/notes/stream", nil)
+	defer stop()
+	readEvent(t, r) // joined
+	readEvent(t, r) // presence
+
+	if err := h.Hub.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if ev := readEvent(t, r); ev.msg.Type != otws.TypeShutdown {
+		t.Errorf("expected shutdown, got %+v", ev.msg)
+	}
+}
@@ -0,0 +1,239 @@
+// Package otlog implements an append-only, checksummed log of operations.
+//
+// A log is a flat sequence of records. Each record is framed as:
+//
+//	length   uint32 (little-endian) - size of the payload in bytes
+//	checksum uint32 (little-endian) - CRC-32C of the payload
+//	payload:
+//	  revision uint64 (little-endian)
+//	  op       binary encoding of the OperationSeq
+//
+// Every record is written with a single Write call, so a crash can only leave
+// a torn record at the very end of the file. OpenFile detects and discards
+// such a tail before accepting new appends.
+package otlog
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+var (
+	// ErrCorrupt is returned when a record's checksum does not match its payload
+	ErrCorrupt = errors.New("otlog: corrupt record")
+
+	// ErrTruncated is returned when the input ends in the middle of a record
+	ErrTruncated = errors.New("otlog: truncated record")
+
+	// ErrRevisionOrder is returned when appending a revision that does not follow the previous one
+	ErrRevisionOrder = errors.New("otlog: revision out of order")
+)
+
+const (
+	headerSize = 8
+
+	// maxPayload bounds the payload length read from a header. Payloads
+	// are read as they arrive, so a larger length in a corrupt header costs
+	// no more than the bytes that follow it.
+	maxPayload = 1 << 30
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Record is a single logged operation.
+type Record struct {
+	// Revision is the document revision the operation applies to.
+	Revision int
+	Op       *ot.OperationSeq
+}
+
+// Writer appends records to an underlying io.Writer.
+type Writer struct {
+	w    io.Writer
+	last int
+}
+
+// NewWriter returns a Writer appending to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, last: -1}
+}
+
+// Append writes a record. Revisions must be strictly increasing.
+func (w *Writer) Append(revision int, op *ot.OperationSeq) error {
+	if revision <= w.last {
+		return fmt.Errorf("%w: %d after %d", ErrRevisionOrder, revision, w.last)
+	}
+
+	payload, err := op.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, headerSize+8, headerSize+8+len(payload))
+	binary.LittleEndian.PutUint64(buf[headerSize:], uint64(revision))
+	buf = append(buf, payload...)
+	binary.LittleEndian.PutUint32(buf[0:], uint32(len(buf)-headerSize))
+	binary.LittleEndian.PutUint32(buf[4:], crc32.Checksum(buf[headerSize:], crcTable))
+
+	if _, err := w.w.Write(buf); err != nil {
+		return err
+	}
+	w.last = revision
+	return nil
+}
+
+// LastRevision returns the revision of the most recently appended record,
+// or -1 if nothing has been appended.
+func (w *Writer) LastRevision() int {
+	return w.last
+}
+
+// Reader iterates over the records of a log.
+type Reader struct {
+	r      io.Reader
+	offset int64
+}
+
+// NewReader returns a Reader reading records from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// Next returns the next record. It returns io.EOF at a clean end of input,
+// ErrTruncated if the input ends mid-record, and ErrCorrupt if a checksum
+// does not match.
+func (r *Reader) Next() (Record, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r.r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return Record{}, ErrTruncated
+		}
+		return Record{}, err
+	}
+
+	size := binary.LittleEndian.Uint32(header[0:])
+	sum := binary.LittleEndian.Uint32(header[4:])
+	if size < 8 || size > maxPayload {
+		return Record{}, ErrCorrupt
+	}
+
+	// The buffer grows with the bytes actually present, so a corrupt length
+	// at the end of a short file does not allocate the full amount.
+	payload, err := io.ReadAll(io.LimitReader(r.r, int64(size)))
+	if err != nil {
+		return Record{}, err
+	}
+	if len(payload) < int(size) {
+		return Record{}, ErrTruncated
+	}
+	if crc32.Checksum(payload, crcTable) != sum {
+		return Record{}, ErrCorrupt
+	}
+
+	op := ot.NewOperationSeq()
+	if err := op.UnmarshalBinary(payload[8:]); err != nil {
+		return Record{}, fmt.Errorf("%w: %w", ErrCorrupt, err)
+	}
+
+	r.offset += headerSize + int64(size)
+	return Record{
+		Revision: int(binary.LittleEndian.Uint64(payload)),
+		Op:       op,
+	}, nil
+}
+
+// Offset returns the number of bytes consumed by successfully read records.
+func (r *Reader) Offset() int64 {
+	return r.offset
+}
+
+// File is a log stored in a file on disk.
+type File struct {
+	f *os.File
+	*Writer
+}
+
+// OpenFile opens or creates the log at path for appending.
+//
+// Existing records are scanned to recover the last revision. A torn record at
+// the end of the file, left by a crash during Append, is truncated away.
+// Corruption anywhere else is reported as ErrCorrupt.
+func OpenFile(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	last, end, err := recoverTail(f)
+	if err == nil {
+		err = f.Truncate(end)
+	}
+	if err == nil {
+		_, err = f.Seek(end, io.SeekStart)
+	}
+	if err != nil {
+		return nil, errors.Join(err, f.Close())
+	}
+
+	w := NewWriter(f)
+	w.last = last
+	return &File{f: f, Writer: w}, nil
+}
+
+// recoverTail scans f and returns the last good revision and the offset just
+// past the last good record.
+func recoverTail(f *os.File) (int, int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	r := NewReader(f)
+	last := -1
+	for {
+		rec, err := r.Next()
+		switch {
+		case err == nil:
+			last = rec.Revision
+			continue
+		case errors.Is(err, io.EOF), errors.Is(err, ErrTruncated):
+			return last, r.Offset(), nil
+		case errors.Is(err, ErrCorrupt):
+			// A corrupt record is only a torn write if nothing follows it.
+			if tornTail(f, r.Offset(), info.Size()) {
+				return last, r.Offset(), nil
+			}
+			return 0, 0, fmt.Errorf("%w at offset %d", ErrCorrupt, r.Offset())
+		default:
+			return 0, 0, err
+		}
+	}
+}
+
+// tornTail reports whether the record starting at offset ends exactly at the
+// end of a file of the given size, as a final write whose data never reached
+// the disk would.
+func tornTail(f *os.File, offset, size int64) bool {
+	var header [headerSize]byte
+	if _, err := f.ReadAt(header[:], offset); err != nil {
+		return false
+	}
+	n := int64(binary.LittleEndian.Uint32(header[0:]))
+	return offset+headerSize+n == size
+}
+
+// Sync commits appended records to stable storage.
+func (f *File) Sync() error {
+	return f.f.Sync()
+}
+
+// Close closes the underlying file.
+func (f *File) Close() error {
+	return f.f.Close()
+}
@@ -0,0 +1,177 @@
+package otlog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+func testOp(text string) *ot.OperationSeq {
+	o := ot.NewOperationSeq()
+	o.Retain(3)
+	o.Insert(text)
+	o.Delete(1)
+	return o
+}
+
+func TestWriterReader(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for i, text := range []string{"a", "héllo", "🌍"} {
+		if err := w.Append(i, testOp(text)); err != nil {
+			t.Fatalf("Append %d failed: %v", i, err)
+		}
+	}
+
+	r := NewReader(&buf)
+	for i, text := range []string{"a", "héllo", "🌍"} {
+		rec, err := r.Next()
+		if err != nil {
+			t.Fatalf("Next %d failed: %v", i, err)
+		}
+		if rec.Revision != i {
+			t.Errorf("expected revision %d, got %d", i, rec.Revision)
+		}
+		if rec.Op.String() != testOp(text).String() {
+			t.Errorf("expected %s, got %s", testOp(text), rec.Op)
+		}
+	}
+	if _, err := r.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestWriterRevisionOrder(t *testing.T) {
+	w := NewWriter(io.Discard)
+	if err := w.Append(5, testOp("a")); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if err := w.Append(5, testOp("b")); !errors.Is(err, ErrRevisionOrder) {
+		t.Errorf("expected ErrRevisionOrder, got %v", err)
+	}
+	if w.LastRevision() != 5 {
+		t.Errorf("expected last revision 5, got %d", w.LastRevision())
+	}
+}
+
+func TestReaderCorrupt(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Append(0, testOp("abc")); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	data := buf.Bytes()
+
+	flipped := append([]byte(nil), data...)
+	flipped[len(flipped)-1] ^= 0xff
+	if _, err := NewReader(bytes.NewReader(flipped)).Next(); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt, got %v", err)
+	}
+
+	for _, n := range []int{3, headerSize, len(data) - 1} {
+		if _, err := NewReader(bytes.NewReader(data[:n])).Next(); !errors.Is(err, ErrTruncated) {
+			t.Errorf("prefix %d: expected ErrTruncated, got %v", n, err)
+		}
+	}
+}
+
+func TestReaderCorruptLength(t *testing.T) {
+	// A header claiming the largest payload, followed by a few bytes.
+	data := []byte{0, 0, 0, 0x40, 0, 0, 0, 0, 1, 2, 3}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := NewReader(bytes.NewReader(data)).Next(); !errors.Is(err, ErrTruncated) {
+		t.Errorf("expected ErrTruncated, got %v", err)
+	}
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Errorf("expected a small allocation, got %d bytes", n)
+	}
+}
+
+func TestOpenFileRecoversTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.log")
+
+	f, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := f.Append(i, testOp("x")); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Simulate a crash midway through writing the last record.
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if err := os.Truncate(path, info.Size()-2); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+
+	f, err = OpenFile(path)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	if f.LastRevision() != 1 {
+		t.Errorf("expected last revision 1, got %d", f.LastRevision())
+	}
+	if err := f.Append(2, testOp("y")); err != nil {
+		t.Fatalf("Append after recovery failed: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	r := NewReader(bytes.NewReader(data))
+	var revs []int
+	for {
+		rec, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		revs = append(revs, rec.Revision)
+	}
+	if len(revs) != 3 || revs[2] != 2 {
+		t.Errorf("expected revisions [0 1 2], got %v", revs)
+	}
+}
+
+func TestOpenFileRejectsMidFileCorruption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.log")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for i := 0; i < 2; i++ {
+		if err := w.Append(i, testOp("x")); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	data := buf.Bytes()
+	data[headerSize+8] ^= 0xff
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	if _, err := OpenFile(path); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt, got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package otlog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Snapshot format:
+//
+//	magic    [4]byte "OTS1"
+//	revision uint64 (little-endian)
+//	length   uint64 (little-endian) - size of the document in bytes
+//	document [length]byte
+//	checksum [32]byte - SHA-256 of everything above
+
+var snapshotMagic = [4]byte{'O', 'T', 'S', '1'}
+
+// ErrBadSnapshot is returned when a snapshot is malformed or fails its checksum
+var ErrBadSnapshot = errors.New("otlog: bad snapshot")
+
+// SaveSnapshot writes doc at the given revision to w.
+func SaveSnapshot(w io.Writer, doc string, revision int) error {
+	h := sha256.New()
+	mw := io.MultiWriter(w, h)
+
+	var header [20]byte
+	copy(header[:4], snapshotMagic[:])
+	binary.LittleEndian.PutUint64(header[4:], uint64(revision))
+	binary.LittleEndian.PutUint64(header[12:], uint64(len(doc)))
+	if _, err := mw.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mw, doc); err != nil {
+		return err
+	}
+	_, err := w.Write(h.Sum(nil))
+	return err
+}
+
+// LoadSnapshot reads a snapshot written by SaveSnapshot, verifying its checksum.
+func LoadSnapshot(r io.Reader) (string, int, error) {
+	h := sha256.New()
+	tr := io.TeeReader(r, h)
+
+	var header [20]byte
+	if _, err := io.ReadFull(tr, header[:]); err != nil {
+		return "", 0, fmt.Errorf("%w: %w", ErrBadSnapshot, err)
+	}
+	if !bytes.Equal(header[:4], snapshotMagic[:]) {
+		return "", 0, fmt.Errorf("%w: bad magic", ErrBadSnapshot)
+	}
+	revision := binary.LittleEndian.Uint64(header[4:])
+	size := binary.LittleEndian.Uint64(header[12:])
+
+	var doc bytes.Buffer
+	if n, err := io.CopyN(&doc, tr, int64(size)); err != nil || uint64(n) != size {
+		return "", 0, fmt.Errorf("%w: truncated document", ErrBadSnapshot)
+	}
+
+	var sum [sha256.Size]byte
+	if _, err := io.ReadFull(r, sum[:]); err != nil {
+		return "", 0, fmt.Errorf("%w: truncated checksum", ErrBadSnapshot)
+	}
+	if !bytes.Equal(sum[:], h.Sum(nil)) {
+		return "", 0, fmt.Errorf("%w: checksum mismatch", ErrBadSnapshot)
+	}
+
+	return doc.String(), int(revision), nil
+}
+
+// Compact folds the log at logPath into the snapshot at snapshotPath and
+// empties the log. It returns the revision of the new snapshot.
+//
+// A missing snapshot is treated as an empty document at revision 0. Log
+// records older than the snapshot are skipped, so a crash between writing the
+// snapshot and truncating the log is harmless. The log must not be open for
+// appending while Compact runs.
+func Compact(snapshotPath, logPath string) (int, error) {
+	doc, revision, err := readSnapshotFile(snapshotPath)
+	if err != nil {
+		return 0, err
+	}
+
+	logFile, err := os.Open(logPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, err
+	}
+	if logFile != nil {
+		doc, revision, err = replay(doc, revision, NewReader(logFile))
+		if cerr := logFile.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := writeSnapshotFile(snapshotPath, doc, revision); err != nil {
+		return 0, err
+	}
+	if err := os.Truncate(logPath, 0); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, err
+	}
+	return revision, nil
+}
+
+// replay applies every record at or after revision to doc.
+func replay(doc string, revision int, r *Reader) (string, int, error) {
+	for {
+		rec, err := r.Next()
+		if errors.Is(err, io.EOF) || errors.Is(err, ErrTruncated) {
+			return doc, revision, nil
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		if rec.Revision < revision {
+			continue
+		}
+		if rec.Revision != revision {
+			return "", 0, fmt.Errorf("otlog: missing revisions %d to %d", revision, rec.Revision-1)
+		}
+
+		doc, err = rec.Op.Apply(doc)
+		if err != nil {
+			return "", 0, fmt.Errorf("otlog: replaying revision %d: %w", rec.Revision, err)
+		}
+		revision++
+	}
+}
+
+func readSnapshotFile(path string) (string, int, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	doc, revision, err := LoadSnapshot(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return doc, revision, err
+}
+
+// writeSnapshotFile replaces the snapshot at path atomically.
+func writeSnapshotFile(path, doc string, revision int) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	err = SaveSnapshot(tmp, doc, revision)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return errors.Join(err, os.Remove(tmp.Name()))
+	}
+	return nil
+}
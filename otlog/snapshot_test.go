@@ -0,0 +1,154 @@
+package otlog
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SaveSnapshot(&buf, "héllo wörld", 42); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	doc, rev, err := LoadSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	if doc != "héllo wörld" || rev != 42 {
+		t.Errorf("expected (%q, 42), got (%q, %d)", "héllo wörld", doc, rev)
+	}
+}
+
+func TestSnapshotCorrupt(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SaveSnapshot(&buf, "hello", 1); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	data := buf.Bytes()
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"bad magic", append([]byte("XXXX"), data[4:]...)},
+		{"truncated", data[:len(data)-1]},
+		{"flipped content", func() []byte {
+			d := append([]byte(nil), data...)
+			d[20] ^= 0xff
+			return d
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := LoadSnapshot(bytes.NewReader(tt.data)); !errors.Is(err, ErrBadSnapshot) {
+				t.Errorf("expected ErrBadSnapshot, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCompact(t *testing.T) {
+	dir := t.TempDir()
+	snapPath := filepath.Join(dir, "doc.snap")
+	logPath := filepath.Join(dir, "doc.log")
+
+	f, err := OpenFile(logPath)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	doc := ""
+	for i, text := range []string{"hello", " world", "!"} {
+		op := ot.NewOperationSeq()
+		op.Retain(uint64(len(doc)))
+		op.Insert(text)
+		if err := f.Append(i, op); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+		doc += text
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	rev, err := Compact(snapPath, logPath)
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if rev != 3 {
+		t.Errorf("expected revision 3, got %d", rev)
+	}
+
+	info, err := os.Stat(logPath)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("expected empty log after compaction, got %d bytes", info.Size())
+	}
+
+	got, gotRev, err := readSnapshotFile(snapPath)
+	if err != nil {
+		t.Fatalf("reading snapshot failed: %v", err)
+	}
+	if got != doc || gotRev != 3 {
+		t.Errorf("expected (%q, 3), got (%q, %d)", doc, got, gotRev)
+	}
+
+	// Appending continues from the snapshot revision and compacts again.
+	f, err = OpenFile(logPath)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	op := ot.NewOperationSeq()
+	op.Delete(5)
+	op.Retain(7)
+	if err := f.Append(3, op); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if _, err := Compact(snapPath, logPath); err != nil {
+		t.Fatalf("second Compact failed: %v", err)
+	}
+	got, gotRev, err = readSnapshotFile(snapPath)
+	if err != nil {
+		t.Fatalf("reading snapshot failed: %v", err)
+	}
+	if got != " world!" || gotRev != 4 {
+		t.Errorf("expected (%q, 4), got (%q, %d)", " world!", got, gotRev)
+	}
+}
+
+func TestCompactSkipsRecordsBeforeSnapshot(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	first := ot.NewOperationSeq()
+	first.Insert("a")
+	second := ot.NewOperationSeq()
+	second.Retain(1)
+	second.Insert("b")
+	if err := w.Append(0, first); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if err := w.Append(1, second); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	doc, rev, err := replay("a", 1, NewReader(&buf))
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if doc != "ab" || rev != 2 {
+		t.Errorf("expected (%q, 2), got (%q, %d)", "ab", doc, rev)
+	}
+}
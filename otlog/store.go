@@ -0,0 +1,347 @@
+package otlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+// FileStore is an ot.Store that keeps each document in its own directory:
+//
+//	<root>/<escaped name>/ops.log    operations, in the otlog record format
+//	<root>/<escaped name>/snapshot   latest snapshot, in the snapshot format
+//
+// Every saved operation is synced to disk before SaveOp returns. File I/O
+// cannot be interrupted, so contexts are only checked before it starts,
+// after waiting for other calls on the same document.
+type FileStore struct {
+	root string
+
+	mu   sync.Mutex
+	docs map[string]*fileDoc
+}
+
+type fileDoc struct {
+	dir string
+
+	mu  sync.Mutex
+	log *File
+}
+
+var (
+	_ ot.Store          = (*FileStore)(nil)
+	_ ot.Trimmer        = (*FileStore)(nil)
+	_ ot.HistoryQuerier = (*FileStore)(nil)
+)
+
+// NewFileStore returns a FileStore rooted at dir, creating it if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{root: dir, docs: make(map[string]*fileDoc)}, nil
+}
+
+// docDir maps a document name to a directory name that is safe on every
+// platform and cannot escape the root.
+func docDir(name string) string {
+	escaped := url.PathEscape(name)
+	if strings.HasPrefix(escaped, ".") {
+		escaped = "%2E" + escaped[1:]
+	}
+	return escaped
+}
+
+func (s *FileStore) doc(name string) (*fileDoc, error) {
+	if name == "" {
+		return nil, errors.New("otlog: empty document name")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d, ok := s.docs[name]; ok {
+		return d, nil
+	}
+	dir := filepath.Join(s.root, docDir(name))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	d := &fileDoc{dir: dir}
+	s.docs[name] = d
+	return d, nil
+}
+
+func (d *fileDoc) logPath() string      { return filepath.Join(d.dir, "ops.log") }
+func (d *fileDoc) snapshotPath() string { return filepath.Join(d.dir, "snapshot") }
+
+// SaveOp implements ot.Store. It fails with an error wrapping ot.ErrConflict
+// if the revision is already saved or covered by the latest snapshot, and
+// with an error if it would leave a gap after the last saved revision.
+func (s *FileStore) SaveOp(ctx context.Context, doc string, revision int, op *ot.OperationSeq) error {
+	d, err := s.doc(doc)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if d.log == nil {
+		if d.log, err = OpenFile(d.logPath()); err != nil {
+			return err
+		}
+	}
+	last := d.log.LastRevision()
+	if last < 0 {
+		// The log is empty, either new or trimmed by a compaction, so the
+		// next revision is the one the latest snapshot left off at.
+		_, base, err := readSnapshotFile(d.snapshotPath())
+		if err != nil {
+			return err
+		}
+		last = base - 1
+	}
+	switch {
+	case revision <= last:
+		return fmt.Errorf("%w: revision %d already saved", ot.ErrConflict, revision)
+	case revision != last+1:
+		return fmt.Errorf("otlog: saving revision %d after %d", revision, last)
+	}
+	if err := d.log.Append(revision, op); err != nil {
+		return err
+	}
+	return d.log.Sync()
+}
+
+// LoadOpsSince implements ot.Store.
+func (s *FileStore) LoadOpsSince(ctx context.Context, doc string, revision int) ([]*ot.OperationSeq, error) {
+	d, err := s.doc(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(d.logPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return []*ot.OperationSeq{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ops, err := readOpsSince(NewReader(f), revision)
+	return ops, errors.Join(err, f.Close())
+}
+
+func readOpsSince(r *Reader, revision int) ([]*ot.OperationSeq, error) {
+	recs, err := readRecordsSince(r, revision)
+	if err != nil {
+		return nil, err
+	}
+	ops := make([]*ot.OperationSeq, 0, len(recs))
+	next := revision
+	for _, rec := range recs {
+		if rec.Revision != next {
+			return nil, fmt.Errorf("otlog: missing revisions %d to %d", next, rec.Revision-1)
+		}
+		ops = append(ops, rec.Op)
+		next++
+	}
+	return ops, nil
+}
+
+// readRecordsSince reads the records from revision onwards, stopping at a
+// torn tail.
+func readRecordsSince(r *Reader, revision int) ([]Record, error) {
+	var recs []Record
+	for {
+		rec, err := r.Next()
+		if errors.Is(err, io.EOF) || errors.Is(err, ErrTruncated) {
+			return recs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if rec.Revision >= revision {
+			recs = append(recs, rec)
+		}
+	}
+}
+
+// OpsBetween implements ot.HistoryQuerier.
+func (s *FileStore) OpsBetween(ctx context.Context, doc string, from, to time.Time) ([]ot.HistoryEntry, error) {
+	return s.query(ctx, doc, func(m ot.Meta) bool {
+		return !m.Time.IsZero() && !m.Time.Before(from) && m.Time.Before(to)
+	})
+}
+
+// OpsByAuthor implements ot.HistoryQuerier.
+func (s *FileStore) OpsByAuthor(ctx context.Context, doc, author string) ([]ot.HistoryEntry, error) {
+	return s.query(ctx, doc, func(m ot.Meta) bool { return m.Author == author })
+}
+
+// query scans the log for the operations, from its first record onwards,
+// whose metadata matches.
+func (s *FileStore) query(ctx context.Context, doc string, match func(ot.Meta) bool) ([]ot.HistoryEntry, error) {
+	d, err := s.doc(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(d.logPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	recs, err := readRecordsSince(NewReader(f), 0)
+	if err := errors.Join(err, f.Close()); err != nil {
+		return nil, err
+	}
+	var entries []ot.HistoryEntry
+	for _, rec := range recs {
+		if match(rec.Op.Meta()) {
+			entries = append(entries, ot.HistoryEntry{Revision: rec.Revision, Op: rec.Op})
+		}
+	}
+	return entries, nil
+}
+
+// TrimOps implements ot.Trimmer by rewriting the log without the operations
+// before revision. The new log replaces the old one atomically.
+func (s *FileStore) TrimOps(ctx context.Context, doc string, revision int) error {
+	d, err := s.doc(doc)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if d.log != nil {
+		err := d.log.Close()
+		d.log = nil
+		if err != nil {
+			return err
+		}
+	}
+
+	path := d.logPath()
+	src, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	recs, err := readRecordsSince(NewReader(src), revision)
+	if err := errors.Join(err, src.Close()); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(d.dir, "ops.log.tmp*")
+	if err != nil {
+		return err
+	}
+	w := NewWriter(tmp)
+	for _, rec := range recs {
+		if err = w.Append(rec.Revision, rec.Op); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return errors.Join(err, os.Remove(tmp.Name()))
+	}
+	return nil
+}
+
+// SaveSnapshot implements ot.Store.
+func (s *FileStore) SaveSnapshot(ctx context.Context, doc string, revision int, content string) error {
+	d, err := s.doc(doc)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return writeSnapshotFile(d.snapshotPath(), content, revision)
+}
+
+// LoadLatestSnapshot implements ot.Store.
+func (s *FileStore) LoadLatestSnapshot(ctx context.Context, doc string) (string, int, error) {
+	d, err := s.doc(doc)
+	if err != nil {
+		return "", 0, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return "", 0, err
+	}
+	return readSnapshotFile(d.snapshotPath())
+}
+
+// Close closes the open log files.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for _, d := range s.docs {
+		d.mu.Lock()
+		if d.log != nil {
+			errs = append(errs, d.log.Close())
+			d.log = nil
+		}
+		d.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
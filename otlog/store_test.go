@@ -0,0 +1,294 @@
+package otlog
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+func TestFileStoreRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	server, err := ot.OpenServer(context.Background(), store, "notes/today")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	for i, text := range []string{"hello", " world"} {
+		op := ot.NewOperationSeq()
+		op.Retain(uint64(len(server.Document())))
+		op.Insert(text)
+		if _, err := server.ReceiveOperation(context.Background(), i, op); err != nil {
+			t.Fatalf("ReceiveOperation failed: %v", err)
+		}
+	}
+	if err := store.SaveSnapshot(context.Background(), "notes/today", 1, "hello"); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// A fresh store sees the snapshot plus the operation after it.
+	store, err = NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+	}()
+	restored, err := ot.OpenServer(context.Background(), store, "notes/today")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	doc, rev := restored.State()
+	if doc != "hello world" || rev != 2 {
+		t.Errorf("expected (%q, 2), got (%q, %d)", "hello world", doc, rev)
+	}
+
+	// Editing continues at the restored revision.
+	op := ot.NewOperationSeq()
+	op.Retain(11)
+	op.Insert("!")
+	if _, err := restored.ReceiveOperation(context.Background(), 2, op); err != nil {
+		t.Fatalf("ReceiveOperation after restart failed: %v", err)
+	}
+	ops, err := store.LoadOpsSince(context.Background(), "notes/today", 0)
+	if err != nil {
+		t.Fatalf("LoadOpsSince failed: %v", err)
+	}
+	if len(ops) != 3 {
+		t.Errorf("expected 3 stored ops, got %d", len(ops))
+	}
+}
+
+func TestFileStoreEmptyDocument(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+
+	doc, rev, err := store.LoadLatestSnapshot(context.Background(), "new")
+	if err != nil || doc != "" || rev != 0 {
+		t.Errorf("expected empty snapshot, got (%q, %d, %v)", doc, rev, err)
+	}
+	ops, err := store.LoadOpsSince(context.Background(), "new", 0)
+	if err != nil || len(ops) != 0 {
+		t.Errorf("expected no ops, got (%d, %v)", len(ops), err)
+	}
+	if err := store.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
+
+func TestFileStoreSaveOpOrder(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+	}()
+	ctx := context.Background()
+	op := ot.NewOperationSeq()
+	op.Insert("a")
+
+	if err := store.SaveOp(ctx, "doc", 0, op); err != nil {
+		t.Fatalf("SaveOp failed: %v", err)
+	}
+	if err := store.SaveOp(ctx, "doc", 0, op); !errors.Is(err, ot.ErrConflict) {
+		t.Errorf("expected ot.ErrConflict for a saved revision, got %v", err)
+	}
+	if err := store.SaveOp(ctx, "doc", 2, op); err == nil || errors.Is(err, ot.ErrConflict) {
+		t.Errorf("expected a gap error, got %v", err)
+	}
+	ops, err := store.LoadOpsSince(ctx, "doc", 0)
+	if err != nil || len(ops) != 1 {
+		t.Errorf("expected 1 stored op, got (%d, %v)", len(ops), err)
+	}
+}
+
+func TestFileStoreSaveOpAfterTrim(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+	}()
+	ctx := context.Background()
+	server, err := ot.OpenServer(ctx, store, "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	for i, text := range []string{"a", "b"} {
+		op := ot.NewOperationSeq()
+		op.Retain(uint64(i))
+		op.Insert(text)
+		if _, err := server.ReceiveOperation(ctx, i, op); err != nil {
+			t.Fatalf("ReceiveOperation failed: %v", err)
+		}
+	}
+	// Compacting through the current revision leaves the log empty.
+	if err := server.Compact(ctx, 2); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+
+	op := ot.NewOperationSeq()
+	op.Retain(1)
+	op.Insert("x")
+	if err := store.SaveOp(ctx, "doc", 1, op); !errors.Is(err, ot.ErrConflict) {
+		t.Errorf("expected ot.ErrConflict for a compacted revision, got %v", err)
+	}
+	if err := store.SaveOp(ctx, "doc", 3, op); err == nil || errors.Is(err, ot.ErrConflict) {
+		t.Errorf("expected a gap error, got %v", err)
+	}
+	next := ot.NewOperationSeq()
+	next.Retain(2)
+	next.Insert("c")
+	if err := store.SaveOp(ctx, "doc", 2, next); err != nil {
+		t.Errorf("SaveOp after the snapshot failed: %v", err)
+	}
+}
+
+func TestFileStoreDirectoryNames(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+
+	for _, name := range []string{"..", "../escape", "a/b", ".hidden"} {
+		if err := store.SaveSnapshot(context.Background(), name, 0, name); err != nil {
+			t.Fatalf("SaveSnapshot(%q) failed: %v", name, err)
+		}
+		got, _, err := store.LoadLatestSnapshot(context.Background(), name)
+		if err != nil || got != name {
+			t.Errorf("%q: expected round-trip, got (%q, %v)", name, got, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escape")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("document escaped the store root: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Errorf("expected 4 document directories, got %d", len(entries))
+	}
+
+	if err := store.SaveSnapshot(context.Background(), "", 0, ""); err == nil {
+		t.Error("expected error for empty document name")
+	}
+}
+
+func TestFileStoreCompact(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	server, err := ot.OpenServer(context.Background(), store, "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	for i, text := range []string{"a", "b", "c"} {
+		op := ot.NewOperationSeq()
+		op.Retain(uint64(i))
+		op.Insert(text)
+		if _, err := server.ReceiveOperation(context.Background(), i, op); err != nil {
+			t.Fatalf("ReceiveOperation failed: %v", err)
+		}
+	}
+
+	if err := server.Compact(context.Background(), 2); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	ops, err := store.LoadOpsSince(context.Background(), "doc", 0)
+	if err == nil {
+		t.Errorf("expected trimmed revisions to be missing, got %d ops", len(ops))
+	}
+	if ops, err = store.LoadOpsSince(context.Background(), "doc", 2); err != nil || len(ops) != 1 {
+		t.Errorf("expected 1 op after revision 2, got (%d, %v)", len(ops), err)
+	}
+
+	// Appending continues after the rewrite.
+	op := ot.NewOperationSeq()
+	op.Retain(3)
+	op.Insert("d")
+	if _, err := server.ReceiveOperation(context.Background(), 3, op); err != nil {
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	store, err = NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+	}()
+	restored, err := ot.OpenServer(context.Background(), store, "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	if doc, rev := restored.State(); doc != "abcd" || rev != 4 {
+		t.Errorf("expected (%q, 4), got (%q, %d)", "abcd", doc, rev)
+	}
+}
+
+func TestFileStoreHistoryQueries(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+	}()
+
+	at := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+	for i, author := range []string{"alice", "bob", "alice"} {
+		op := ot.NewOperationSeq()
+		op.Retain(uint64(i))
+		op.Insert("x")
+		op.SetMeta(ot.Meta{Author: author, Time: at.Add(time.Duration(i) * time.Hour)})
+		if err := store.SaveOp(ctx, "doc", i, op); err != nil {
+			t.Fatalf("SaveOp failed: %v", err)
+		}
+	}
+	if err := store.TrimOps(ctx, "doc", 1); err != nil {
+		t.Fatalf("TrimOps failed: %v", err)
+	}
+
+	got, err := ot.OpsByAuthor(ctx, store, "doc", "alice")
+	if err != nil || len(got) != 1 || got[0].Revision != 2 {
+		t.Errorf("expected revision 2 by alice after trimming, got %+v (%v)", got, err)
+	}
+	got, err = ot.OpsBetween(ctx, store, "doc", at, at.Add(2*time.Hour))
+	if err != nil || len(got) != 1 || got[0].Revision != 1 || got[0].Op.Meta().Author != "bob" {
+		t.Errorf("expected revision 1 by bob, got %+v (%v)", got, err)
+	}
+}
@@ -0,0 +1,222 @@
+// Package otpg implements ot.Store on PostgreSQL using database/sql.
+//
+// The package does not import a driver; open the *sql.DB with the one your
+// application already uses (lib/pq, pgx's stdlib adapter, ...). Create the
+// tables with Schema or CreateSchema:
+//
+//	ot_ops        (doc_id, revision) -> op        binary encoding of the operation
+//	ot_snapshots  (doc_id, revision) -> content   full document text
+//
+// The primary key on ot_ops doubles as a fencing mechanism. If two servers
+// believe they own the same document, only one of them can save a given
+// revision; the other gets ot.ErrConflict and must not apply the operation.
+//
+// Every query runs with the context passed to the Store method, so the
+// deadline of the request that submitted an operation also bounds how long
+// saving it may take.
+package otpg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+// Schema creates the tables used by Store. It is safe to run more than once.
+const Schema = `
+CREATE TABLE IF NOT EXISTS ot_ops (
+	doc_id   text   NOT NULL,
+	revision bigint NOT NULL,
+	op       bytea  NOT NULL,
+	PRIMARY KEY (doc_id, revision)
+);
+CREATE TABLE IF NOT EXISTS ot_snapshots (
+	doc_id   text   NOT NULL,
+	revision bigint NOT NULL,
+	content  text   NOT NULL,
+	PRIMARY KEY (doc_id, revision)
+);`
+
+const (
+	queryLastRevision     = `SELECT max(revision) FROM ot_ops WHERE doc_id = $1`
+	querySnapshotRevision = `SELECT max(revision) FROM ot_snapshots WHERE doc_id = $1`
+	queryInsertOp         = `INSERT INTO ot_ops (doc_id, revision, op) VALUES ($1, $2, $3)`
+	queryOpsSince         = `SELECT revision, op FROM ot_ops WHERE doc_id = $1 AND revision >= $2 ORDER BY revision`
+	queryPutSnapshot      = `INSERT INTO ot_snapshots (doc_id, revision, content) VALUES ($1, $2, $3) ` +
+		`ON CONFLICT (doc_id, revision) DO UPDATE SET content = EXCLUDED.content`
+	queryLatestSnapshot = `SELECT content, revision FROM ot_snapshots WHERE doc_id = $1 ORDER BY revision DESC LIMIT 1`
+	queryTrimOps        = `DELETE FROM ot_ops WHERE doc_id = $1 AND revision < $2 ` +
+		`AND revision < (SELECT max(revision) FROM ot_ops WHERE doc_id = $1)`
+	queryTrimSnapshots = `DELETE FROM ot_snapshots WHERE doc_id = $1 AND revision < $2`
+)
+
+// uniqueViolation is the PostgreSQL SQLSTATE for a unique constraint violation.
+const uniqueViolation = "23505"
+
+// Store is an ot.Store backed by PostgreSQL.
+type Store struct {
+	db *sql.DB
+}
+
+var (
+	_ ot.Store   = (*Store)(nil)
+	_ ot.Trimmer = (*Store)(nil)
+)
+
+// NewStore returns a Store using db.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// CreateSchema runs Schema against the database.
+func (s *Store) CreateSchema(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, Schema)
+	return err
+}
+
+// SaveOp implements ot.Store. Each operation is saved in its own transaction,
+// which fails with ot.ErrConflict if the revision is already taken and with an
+// error if it would leave a gap after the last saved revision. A document
+// without operations continues from its latest snapshot, or from 0.
+func (s *Store) SaveOp(ctx context.Context, doc string, revision int, op *ot.OperationSeq) (err error) {
+	data, err := op.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, rollback(tx))
+		}
+	}()
+
+	var last sql.NullInt64
+	if err := tx.QueryRowContext(ctx, queryLastRevision, doc).Scan(&last); err != nil {
+		return err
+	}
+	if !last.Valid {
+		// No operations are saved, so the next revision is the one the
+		// latest snapshot left off at, or 0 without one.
+		var base sql.NullInt64
+		if err := tx.QueryRowContext(ctx, querySnapshotRevision, doc).Scan(&base); err != nil {
+			return err
+		}
+		last.Int64 = base.Int64 - 1
+	}
+	switch {
+	case last.Int64 >= int64(revision):
+		return ot.ErrConflict
+	case last.Int64 != int64(revision)-1:
+		return fmt.Errorf("otpg: saving revision %d after %d", revision, last.Int64)
+	}
+
+	if _, err := tx.ExecContext(ctx, queryInsertOp, doc, int64(revision), data); err != nil {
+		if isUniqueViolation(err) {
+			return ot.ErrConflict
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// rollback rolls tx back, ignoring the error if it has already finished.
+func rollback(tx *sql.Tx) error {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		return err
+	}
+	return nil
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation.
+// Both lib/pq and pgx expose the SQLSTATE through a SQLState method.
+func isUniqueViolation(err error) bool {
+	var state interface{ SQLState() string }
+	return errors.As(err, &state) && state.SQLState() == uniqueViolation
+}
+
+// LoadOpsSince implements ot.Store.
+func (s *Store) LoadOpsSince(ctx context.Context, doc string, revision int) (ops []*ot.OperationSeq, err error) {
+	rows, err := s.db.QueryContext(ctx, queryOpsSince, doc, int64(revision))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, rows.Close())
+	}()
+
+	ops = make([]*ot.OperationSeq, 0)
+	next := int64(revision)
+	for rows.Next() {
+		var rev int64
+		var data []byte
+		if err := rows.Scan(&rev, &data); err != nil {
+			return nil, err
+		}
+		if rev != next {
+			return nil, fmt.Errorf("otpg: missing revisions %d to %d", next, rev-1)
+		}
+
+		op := ot.NewOperationSeq()
+		if err := op.UnmarshalBinary(data); err != nil {
+			return nil, fmt.Errorf("otpg: revision %d: %w", rev, err)
+		}
+		ops = append(ops, op)
+		next++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+// TrimOps implements ot.Trimmer. It deletes the operations before revision,
+// except for the newest one, which SaveOp needs to keep detecting conflicts.
+// Snapshots before revision are deleted too, since Server.Compact saves one
+// at revision first.
+func (s *Store) TrimOps(ctx context.Context, doc string, revision int) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, rollback(tx))
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, queryTrimOps, doc, int64(revision)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, queryTrimSnapshots, doc, int64(revision)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SaveSnapshot implements ot.Store. Saving the same revision twice replaces
+// the content.
+func (s *Store) SaveSnapshot(ctx context.Context, doc string, revision int, content string) error {
+	_, err := s.db.ExecContext(ctx, queryPutSnapshot, doc, int64(revision), content)
+	return err
+}
+
+// LoadLatestSnapshot implements ot.Store.
+func (s *Store) LoadLatestSnapshot(ctx context.Context, doc string) (string, int, error) {
+	var content string
+	var revision int64
+	err := s.db.QueryRowContext(ctx, queryLatestSnapshot, doc).Scan(&content, &revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	return content, int(revision), nil
+}
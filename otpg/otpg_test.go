@@ -0,0 +1,414 @@
+package otpg
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+// fakeDB is an in-memory stand-in for PostgreSQL that understands exactly the
+// queries issued by Store, so the tests run without a database server.
+type fakeDB struct {
+	mu        sync.Mutex
+	ops       map[string]map[int64][]byte
+	snapshots map[string]map[int64]string
+	commits   int
+	rollbacks int
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{
+		ops:       make(map[string]map[int64][]byte),
+		snapshots: make(map[string]map[int64]string),
+	}
+}
+
+type pgError struct{ code string }
+
+func (e *pgError) Error() string    { return "pq: SQLSTATE " + e.code }
+func (e *pgError) SQLState() string { return e.code }
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: db}, nil }
+func (db *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct {
+	db   *fakeDB
+	undo []func()
+	inTx bool
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{c, query}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.inTx = true
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.commits++
+	c.undo, c.inTx = nil, false
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.rollbacks++
+	for i := len(c.undo) - 1; i >= 0; i-- {
+		c.undo[i]()
+	}
+	c.undo, c.inTx = nil, false
+	return nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+// fakeArgs are the arguments of a Store query, which always come in the
+// order doc, revision, value.
+type fakeArgs struct {
+	doc      string
+	revision int64
+	bytes    []byte
+	text     string
+}
+
+func parseArgs(args []driver.Value) (fakeArgs, error) {
+	var a fakeArgs
+	ok := len(args) >= 1
+	if ok {
+		a.doc, ok = args[0].(string)
+	}
+	if ok && len(args) >= 2 {
+		a.revision, ok = args[1].(int64)
+	}
+	if ok && len(args) >= 3 {
+		switch v := args[2].(type) {
+		case []byte:
+			a.bytes = v
+		case string:
+			a.text = v
+		default:
+			ok = false
+		}
+	}
+	if !ok {
+		return a, fmt.Errorf("unexpected arguments: %v", args)
+	}
+	return a, nil
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	db := s.conn.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if s.query == Schema {
+		return driver.RowsAffected(0), nil
+	}
+	a, err := parseArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	switch s.query {
+	case queryInsertOp:
+		if db.ops[a.doc] == nil {
+			db.ops[a.doc] = make(map[int64][]byte)
+		}
+		if _, ok := db.ops[a.doc][a.revision]; ok {
+			return nil, &pgError{uniqueViolation}
+		}
+		db.ops[a.doc][a.revision] = a.bytes
+		if s.conn.inTx {
+			s.conn.undo = append(s.conn.undo, func() { delete(db.ops[a.doc], a.revision) })
+		}
+	case queryTrimOps:
+		revs := sortedRevisions(db.ops[a.doc])
+		for _, rev := range revs {
+			if rev < a.revision && rev < revs[len(revs)-1] {
+				delete(db.ops[a.doc], rev)
+			}
+		}
+	case queryTrimSnapshots:
+		for rev := range db.snapshots[a.doc] {
+			if rev < a.revision {
+				delete(db.snapshots[a.doc], rev)
+			}
+		}
+	case queryPutSnapshot:
+		if db.snapshots[a.doc] == nil {
+			db.snapshots[a.doc] = make(map[int64]string)
+		}
+		db.snapshots[a.doc][a.revision] = a.text
+	default:
+		return nil, fmt.Errorf("unexpected exec: %s", s.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	db := s.conn.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	a, err := parseArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := &fakeRows{}
+	switch s.query {
+	case queryLastRevision:
+		var last driver.Value
+		if revs := sortedRevisions(db.ops[a.doc]); len(revs) > 0 {
+			last = revs[len(revs)-1]
+		}
+		rows.columns = []string{"max"}
+		rows.values = [][]driver.Value{{last}}
+	case querySnapshotRevision:
+		var last driver.Value
+		if revs := sortedRevisions(db.snapshots[a.doc]); len(revs) > 0 {
+			last = revs[len(revs)-1]
+		}
+		rows.columns = []string{"max"}
+		rows.values = [][]driver.Value{{last}}
+	case queryOpsSince:
+		rows.columns = []string{"revision", "op"}
+		for _, rev := range sortedRevisions(db.ops[a.doc]) {
+			if rev >= a.revision {
+				rows.values = append(rows.values, []driver.Value{rev, db.ops[a.doc][rev]})
+			}
+		}
+	case queryLatestSnapshot:
+		rows.columns = []string{"content", "revision"}
+		if revs := sortedRevisions(db.snapshots[a.doc]); len(revs) > 0 {
+			rev := revs[len(revs)-1]
+			rows.values = [][]driver.Value{{db.snapshots[a.doc][rev], rev}}
+		}
+	default:
+		return nil, fmt.Errorf("unexpected query: %s", s.query)
+	}
+	return rows, nil
+}
+
+func sortedRevisions[V any](m map[int64]V) []int64 {
+	revs := make([]int64, 0, len(m))
+	for rev := range m {
+		revs = append(revs, rev)
+	}
+	sort.Slice(revs, func(i, j int) bool { return revs[i] < revs[j] })
+	return revs
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func newTestStore(t *testing.T, db *fakeDB) *Store {
+	t.Helper()
+	conn := sql.OpenDB(db)
+	t.Cleanup(func() {
+		if err := conn.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+	})
+	store := NewStore(conn)
+	if err := store.CreateSchema(context.Background()); err != nil {
+		t.Fatalf("CreateSchema failed: %v", err)
+	}
+	return store
+}
+
+func insert(server *ot.Server, text string) error {
+	doc, rev := server.State()
+	op := ot.NewOperationSeq()
+	op.Retain(uint64(len([]rune(doc))))
+	op.Insert(text)
+	_, err := server.ReceiveOperation(context.Background(), rev, op)
+	return err
+}
+
+func TestStoreRestart(t *testing.T) {
+	db := newFakeDB()
+	store := newTestStore(t, db)
+
+	server, err := ot.OpenServer(context.Background(), store, "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	for _, text := range []string{"a", "b", "c"} {
+		if err := insert(server, text); err != nil {
+			t.Fatalf("insert failed: %v", err)
+		}
+	}
+	if db.commits != 3 {
+		t.Errorf("expected one transaction per op, got %d commits", db.commits)
+	}
+	if err := store.SaveSnapshot(context.Background(), "doc", 2, "ab"); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	restored, err := ot.OpenServer(context.Background(), newTestStore(t, db), "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	if doc, rev := restored.State(); doc != "abc" || rev != 3 {
+		t.Errorf("expected (%q, 3), got (%q, %d)", "abc", doc, rev)
+	}
+}
+
+func TestStoreEmptyDocument(t *testing.T) {
+	store := newTestStore(t, newFakeDB())
+
+	doc, rev, err := store.LoadLatestSnapshot(context.Background(), "missing")
+	if err != nil || doc != "" || rev != 0 {
+		t.Errorf("expected empty snapshot, got (%q, %d, %v)", doc, rev, err)
+	}
+	ops, err := store.LoadOpsSince(context.Background(), "missing", 0)
+	if err != nil || len(ops) != 0 {
+		t.Errorf("expected no ops, got (%d, %v)", len(ops), err)
+	}
+}
+
+func TestStoreSplitBrain(t *testing.T) {
+	db := newFakeDB()
+
+	// Two servers believe they own the same document.
+	a, err := ot.OpenServer(context.Background(), newTestStore(t, db), "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	b, err := ot.OpenServer(context.Background(), newTestStore(t, db), "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+
+	if err := insert(a, "a"); err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	if err := insert(b, "b"); !errors.Is(err, ot.ErrConflict) {
+		t.Errorf("expected ot.ErrConflict, got %v", err)
+	}
+	if doc := b.Document(); doc != "" {
+		t.Errorf("expected rejected op not to be applied, got %q", doc)
+	}
+	if db.rollbacks != 1 {
+		t.Errorf("expected 1 rollback, got %d", db.rollbacks)
+	}
+}
+
+func TestStoreRevisionGap(t *testing.T) {
+	store := newTestStore(t, newFakeDB())
+
+	op := ot.NewOperationSeq()
+	op.Insert("a")
+	if err := store.SaveOp(context.Background(), "doc", 0, op); err != nil {
+		t.Fatalf("SaveOp failed: %v", err)
+	}
+	if err := store.SaveOp(context.Background(), "doc", 2, op); err == nil || errors.Is(err, ot.ErrConflict) {
+		t.Errorf("expected gap error, got %v", err)
+	}
+}
+
+func TestStoreSaveOpWithoutOps(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t, newFakeDB())
+	op := ot.NewOperationSeq()
+	op.Insert("a")
+
+	if err := store.SaveOp(ctx, "new", 2, op); err == nil || errors.Is(err, ot.ErrConflict) {
+		t.Errorf("expected gap error for a new document, got %v", err)
+	}
+
+	// A document with only a snapshot continues from its revision.
+	if err := store.SaveSnapshot(ctx, "doc", 5, "hello"); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	if err := store.SaveOp(ctx, "doc", 3, op); !errors.Is(err, ot.ErrConflict) {
+		t.Errorf("expected ot.ErrConflict for a revision before the snapshot, got %v", err)
+	}
+	if err := store.SaveOp(ctx, "doc", 6, op); err == nil || errors.Is(err, ot.ErrConflict) {
+		t.Errorf("expected gap error, got %v", err)
+	}
+	if err := store.SaveOp(ctx, "doc", 5, op); err != nil {
+		t.Errorf("SaveOp failed: %v", err)
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	if !isUniqueViolation(fmt.Errorf("insert: %w", &pgError{uniqueViolation})) {
+		t.Error("expected wrapped 23505 to be a unique violation")
+	}
+	if isUniqueViolation(&pgError{"40001"}) {
+		t.Error("expected serialization failure not to be a unique violation")
+	}
+	if isUniqueViolation(errors.New("boom")) {
+		t.Error("expected plain error not to be a unique violation")
+	}
+}
+
+func TestStoreCompact(t *testing.T) {
+	db := newFakeDB()
+	server, err := ot.OpenServer(context.Background(), newTestStore(t, db), "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	for _, text := range []string{"a", "b", "c"} {
+		if err := insert(server, text); err != nil {
+			t.Fatalf("insert failed: %v", err)
+		}
+	}
+
+	if err := server.Compact(context.Background(), 3); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	// The newest operation survives as the conflict fence.
+	if revs := sortedRevisions(db.ops["doc"]); len(revs) != 1 || revs[0] != 2 {
+		t.Errorf("expected only revision 2 kept, got %v", revs)
+	}
+
+	restored, err := ot.OpenServer(context.Background(), newTestStore(t, db), "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	if doc, rev := restored.State(); doc != "abc" || rev != 3 {
+		t.Errorf("expected (%q, 3), got (%q, %d)", "abc", doc, rev)
+	}
+
+	// A stale writer is still fenced off.
+	op := ot.NewOperationSeq()
+	op.Insert("x")
+	if err := newTestStore(t, db).SaveOp(context.Background(), "doc", 2, op); !errors.Is(err, ot.ErrConflict) {
+		t.Errorf("expected ot.ErrConflict, got %v", err)
+	}
+}
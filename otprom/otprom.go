@@ -0,0 +1,196 @@
+// Package otprom collects ot.Metrics and serves them in the Prometheus text
+// exposition format, without depending on the Prometheus client library.
+// Install it on a Hub and mount it where Prometheus scrapes:
+//
+//	m := otprom.New()
+//	hub.Metrics = m
+//	http.Handle("/metrics", m)
+//
+// The exported series are:
+//
+//	ot_ops_accepted_total              counter
+//	ot_transforms_total                counter, operations transformed against
+//	ot_transform_duration_seconds      histogram, per incoming operation
+//	ot_transform_outcomes_total        counter, by outcome: unchanged, shifted, dropped
+//	ot_broadcast_subscribers           histogram, subscribers per operation
+//	ot_active_clients                  gauge
+//
+// To serve them alongside metrics from another registry, call WriteTo from
+// that registry's handler or mount the two on different paths.
+package otprom
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+var (
+	// DurationBuckets are the upper bounds, in seconds, of the transform
+	// duration histogram.
+	DurationBuckets = []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1, .5, 1}
+
+	// FanoutBuckets are the upper bounds of the broadcast histogram.
+	FanoutBuckets = []float64{0, 1, 2, 5, 10, 20, 50, 100, 200, 500, 1000}
+)
+
+// Metrics is an ot.Metrics that keeps its measurements in memory and writes
+// them in the Prometheus text format. It is safe for concurrent use.
+type Metrics struct {
+	mu         sync.Mutex
+	accepted   uint64
+	transforms uint64
+	outcomes   [3]uint64 // by ot.TransformOutcome
+	duration   histogram
+	fanout     histogram
+	clients    int64
+}
+
+var (
+	_ ot.Metrics         = (*Metrics)(nil)
+	_ ot.ConflictMetrics = (*Metrics)(nil)
+)
+
+// New returns empty Metrics using DurationBuckets and FanoutBuckets.
+func New() *Metrics {
+	return &Metrics{
+		duration: newHistogram(DurationBuckets),
+		fanout:   newHistogram(FanoutBuckets),
+	}
+}
+
+// OpAccepted implements ot.Metrics.
+func (m *Metrics) OpAccepted() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.accepted++
+}
+
+// Transformed implements ot.Metrics.
+func (m *Metrics) Transformed(count int, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.transforms += uint64(count)
+	m.duration.observe(elapsed.Seconds())
+}
+
+// TransformOutcome implements ot.ConflictMetrics.
+func (m *Metrics) TransformOutcome(outcome ot.TransformOutcome) {
+	if outcome < 0 || int(outcome) >= len(m.outcomes) {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.outcomes[outcome]++
+}
+
+// Broadcast implements ot.Metrics.
+func (m *Metrics) Broadcast(subscribers int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fanout.observe(float64(subscribers))
+}
+
+// ClientJoined implements ot.Metrics.
+func (m *Metrics) ClientJoined() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clients++
+}
+
+// ClientLeft implements ot.Metrics.
+func (m *Metrics) ClientLeft() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clients--
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	// The status is already sent; a write failure means the scraper went away.
+	m.WriteTo(w) //nolint:errcheck // nothing useful to do once the status is written
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	accepted, transforms, outcomes, clients := m.accepted, m.transforms, m.outcomes, m.clients
+	duration, fanout := m.duration.clone(), m.fanout.clone()
+	m.mu.Unlock()
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "ot_ops_accepted_total", "counter", "Operations accepted by servers.")
+	fmt.Fprintf(&buf, "ot_ops_accepted_total %d\n", accepted)
+	writeHeader(&buf, "ot_transforms_total", "counter", "Concurrent operations that incoming operations were transformed against.")
+	fmt.Fprintf(&buf, "ot_transforms_total %d\n", transforms)
+	writeHeader(&buf, "ot_transform_outcomes_total", "counter", "Incoming operations transformed, by how transformation changed them.")
+	for i, n := range outcomes {
+		fmt.Fprintf(&buf, "ot_transform_outcomes_total{outcome=%q} %d\n", ot.TransformOutcome(i), n)
+	}
+	duration.write(&buf, "ot_transform_duration_seconds", "Time spent transforming an incoming operation.")
+	fanout.write(&buf, "ot_broadcast_subscribers", "Subscribers reached by each broadcast operation.")
+	writeHeader(&buf, "ot_active_clients", "gauge", "Clients subscribed to documents.")
+	fmt.Fprintf(&buf, "ot_active_clients %d\n", clients)
+	return buf.WriteTo(w)
+}
+
+func writeHeader(buf *bytes.Buffer, name, typ, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(buf, "# TYPE %s %s\n", name, typ)
+}
+
+// histogram counts observations into cumulative buckets.
+type histogram struct {
+	bounds []float64
+	counts []uint64 // counts[i] is observations <= bounds[i], not cumulative
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(bounds []float64) histogram {
+	return histogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range h.bounds {
+		if v <= b {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+func (h *histogram) clone() histogram {
+	c := *h
+	c.counts = append([]uint64(nil), h.counts...)
+	return c
+}
+
+func (h *histogram) write(buf *bytes.Buffer, name, help string) {
+	writeHeader(buf, name, "histogram", help)
+	var cumulative uint64
+	for i, b := range h.bounds {
+		cumulative += h.counts[i]
+		fmt.Fprintf(buf, "%s_bucket{le=%q} %d\n", name, formatFloat(b), cumulative)
+	}
+	fmt.Fprintf(buf, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	fmt.Fprintf(buf, "%s_sum %s\n", name, formatFloat(h.sum))
+	fmt.Fprintf(buf, "%s_count %d\n", name, h.count)
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
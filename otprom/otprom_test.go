@@ -0,0 +1,59 @@
+package otprom
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New()
+	m.Transformed(3, 2*time.Millisecond)
+	m.TransformOutcome(ot.TransformDropped)
+	m.Broadcast(4)
+	m.Broadcast(0)
+
+	hub := &ot.Hub{Metrics: m}
+	sub, err := hub.Subscribe(context.Background(), "doc", "a")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Close()
+	op := ot.NewOperationSeq()
+	op.Insert("x")
+	if _, _, err := hub.Submit(context.Background(), "doc", "a", 0, op); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE ot_ops_accepted_total counter\not_ops_accepted_total 1\n",
+		"ot_transforms_total 3\n",
+		"ot_transform_outcomes_total{outcome=\"unchanged\"} 0\n",
+		"ot_transform_outcomes_total{outcome=\"dropped\"} 1\n",
+		"ot_transform_duration_seconds_bucket{le=\"0.001\"} 0\n",
+		"ot_transform_duration_seconds_bucket{le=\"0.005\"} 1\n",
+		"ot_transform_duration_seconds_count 1\n",
+		"ot_broadcast_subscribers_bucket{le=\"0\"} 1\n",
+		"ot_broadcast_subscribers_bucket{le=\"1\"} 2\n",
+		"ot_broadcast_subscribers_bucket{le=\"5\"} 3\n",
+		"ot_broadcast_subscribers_bucket{le=\"+Inf\"} 3\n",
+		"ot_broadcast_subscribers_sum 5\n",
+		"# TYPE ot_active_clients gauge\not_active_clients 1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Package resp is a minimal client for the Redis serialization protocol
+// (RESP2), covering what otredis needs: sending commands as arrays of bulk
+// strings and reading replies and pushed pub/sub messages.
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxBulk bounds the size of a bulk string read from the server so a corrupt
+// length cannot trigger a huge allocation.
+const maxBulk = 1 << 20
+
+// DialTimeout bounds how long Dial waits for the TCP connection.
+const DialTimeout = 5 * time.Second
+
+var errProtocol = errors.New("resp: protocol error")
+
+// Error is an error reply from the server, such as "ERR unknown command".
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Conn is a connection to a Redis server.
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	writeMu sync.Mutex
+}
+
+// Dial connects to the server at addr.
+func Dial(addr string) (*Conn, error) {
+	c, err := net.DialTimeout("tcp", addr, DialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(c), nil
+}
+
+// NewConn wraps an established connection. Either end of a connection can
+// use it, since commands and replies share the same encoding.
+func NewConn(c net.Conn) *Conn {
+	return &Conn{conn: c, br: bufio.NewReader(c)}
+}
+
+// Do sends a command and returns its reply. Error replies are returned as
+// an Error. Do must not be used on a connection in pub/sub mode.
+func (c *Conn) Do(args ...string) (any, error) {
+	if err := c.Send(args...); err != nil {
+		return nil, err
+	}
+	reply, err := c.Receive()
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := reply.(Error); ok {
+		return nil, e
+	}
+	return reply, nil
+}
+
+// Send writes a command as an array of bulk strings.
+func (c *Conn) Send(args ...string) error {
+	var sb strings.Builder
+	sb.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		sb.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := io.WriteString(c.conn, sb.String())
+	return err
+}
+
+// Receive reads one value. Simple strings and bulk strings are returned as
+// string, integers as int64, arrays as []any, error replies as Error, and
+// null bulk strings and arrays as nil.
+func (c *Conn) Receive() (any, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, fmt.Errorf("%w: empty line", errProtocol)
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return Error(line[1:]), nil
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid integer %q", errProtocol, line)
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < -1 || n > maxBulk {
+			return nil, fmt.Errorf("%w: invalid bulk length %q", errProtocol, line)
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.br, buf); err != nil {
+			return nil, err
+		}
+		if string(buf[n:]) != "\r\n" {
+			return nil, fmt.Errorf("%w: bulk string not terminated", errProtocol)
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < -1 || n > maxBulk {
+			return nil, fmt.Errorf("%w: invalid array length %q", errProtocol, line)
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		values := make([]any, n)
+		for i := range values {
+			if values[i], err = c.Receive(); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", errProtocol, line[0])
+	}
+}
+
+func (c *Conn) readLine() (string, error) {
+	line, err := c.br.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(line, "\r\n") {
+		return "", fmt.Errorf("%w: line not terminated by CRLF", errProtocol)
+	}
+	return line[:len(line)-2], nil
+}
+
+// WriteReply writes a reply in the encoding Receive reads, for servers and
+// tests. It accepts the same types Receive returns.
+func (c *Conn) WriteReply(v any) error {
+	var sb strings.Builder
+	if err := appendValue(&sb, v); err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := io.WriteString(c.conn, sb.String())
+	return err
+}
+
+func appendValue(sb *strings.Builder, v any) error {
+	switch v := v.(type) {
+	case nil:
+		sb.WriteString("$-1\r\n")
+	case string:
+		sb.WriteString("$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n")
+	case int64:
+		sb.WriteString(":" + strconv.FormatInt(v, 10) + "\r\n")
+	case Error:
+		sb.WriteString("-" + string(v) + "\r\n")
+	case []any:
+		sb.WriteString("*" + strconv.Itoa(len(v)) + "\r\n")
+		for _, e := range v {
+			if err := appendValue(sb, e); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("resp: cannot encode %T", v)
+	}
+	return nil
+}
+
+// Close closes the connection, unblocking any pending Receive.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
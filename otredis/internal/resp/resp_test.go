@@ -0,0 +1,116 @@
+package resp
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func pipe(t *testing.T) (*Conn, *Conn) {
+	t.Helper()
+	a, b := net.Pipe()
+	client, server := NewConn(a), NewConn(b)
+	t.Cleanup(func() {
+		if err := client.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+		if err := server.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+	})
+	return client, server
+}
+
+func TestRoundTrip(t *testing.T) {
+	client, server := pipe(t)
+
+	values := []any{
+		"OK",
+		int64(-42),
+		nil,
+		Error("ERR boom"),
+		[]any{"pmessage", "ot:*", "ot:doc", "1"},
+		[]any{},
+		"with\r\nnewlines",
+	}
+	go func() {
+		for _, v := range values {
+			if err := server.WriteReply(v); err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, want := range values {
+		got, err := client.Receive()
+		if err != nil {
+			t.Fatalf("Receive failed: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %#v, got %#v", want, got)
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	client, server := pipe(t)
+
+	go func() {
+		for {
+			cmd, err := server.Receive()
+			if err != nil {
+				return
+			}
+			args, ok := cmd.([]any)
+			if !ok || len(args) != 2 {
+				return
+			}
+			var reply any = args[1]
+			if args[0] == "FAIL" {
+				reply = Error("ERR failed")
+			}
+			if err := server.WriteReply(reply); err != nil {
+				return
+			}
+		}
+	}()
+
+	reply, err := client.Do("ECHO", "hello")
+	if err != nil || reply != "hello" {
+		t.Errorf("expected hello, got (%v, %v)", reply, err)
+	}
+
+	var e Error
+	if _, err := client.Do("FAIL", "x"); !errors.As(err, &e) || e != "ERR failed" {
+		t.Errorf("expected Error reply, got %v", err)
+	}
+}
+
+func TestReceiveInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"unknown type", "?x\r\n"},
+		{"bare newline", "+OK\n"},
+		{"bad integer", ":abc\r\n"},
+		{"huge bulk", "$99999999999\r\n"},
+		{"unterminated bulk", "$2\r\nabcd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := pipe(t)
+			go func() {
+				// The write fails once the client gives up and the pipe closes.
+				if _, err := server.conn.Write([]byte(tt.input)); err != nil {
+					return
+				}
+			}()
+			if v, err := client.Receive(); err == nil {
+				t.Errorf("expected error, got %#v", v)
+			}
+		})
+	}
+}
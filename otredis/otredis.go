@@ -0,0 +1,341 @@
+// Package otredis connects the Hubs of several server nodes through Redis
+// pub/sub, so clients of the same document can be spread across nodes.
+//
+// Every node must use the same shared Store, one that reports ot.ErrConflict
+// when two nodes save the same revision (such as otpg). The Store decides
+// the order of operations; Redis only tells the other nodes to look. When a
+// node accepts an operation it publishes
+//
+//	channel: <prefix><document>
+//	message: <node id> <revision>
+//
+// and every other node subscribed to <prefix>* calls Hub.Sync for that
+// document, delivering the new operations to its local subscribers. A node
+// that misses a message catches up at its next conflict or notification, and
+// all open documents are synced after reconnecting to Redis.
+//
+// Only operations are relayed. Hub.Broadcast payloads such as cursors stay
+// on the node where they were sent.
+package otredis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ot "github.com/shiv248/operational-transformation-go"
+	"github.com/shiv248/operational-transformation-go/otredis/internal/resp"
+)
+
+// DefaultPrefix is the channel prefix used when Config.Prefix is empty.
+const DefaultPrefix = "ot:"
+
+const (
+	// queueSize is the number of notifications buffered for publishing.
+	// Beyond that they are dropped, which only delays other nodes.
+	queueSize = 1024
+
+	minBackoff = 100 * time.Millisecond
+	maxBackoff = 5 * time.Second
+)
+
+// Config describes the Redis server and channels to use.
+type Config struct {
+	// Addr is the host:port of the Redis server.
+	Addr string
+
+	// Password is sent with AUTH if set.
+	Password string
+
+	// Prefix is prepended to document names to form channel names. Nodes
+	// only see each other if they use the same prefix. Empty means DefaultPrefix.
+	Prefix string
+
+	// Logger receives connection failures and reconnects. Nil means the
+	// hub's Logger.
+	Logger *slog.Logger
+}
+
+// Relay is an ot.Relay that publishes to Redis and syncs a Hub from the
+// notifications of other nodes. Install it with:
+//
+//	relay, err := otredis.Dial(cfg, hub)
+//	if err != nil { ... }
+//	hub.Relay = relay
+type Relay struct {
+	cfg  Config
+	hub  *ot.Hub
+	node string
+	log  *slog.Logger
+
+	queue chan notice
+	wg    sync.WaitGroup
+
+	// ctx is canceled by Close, which also interrupts syncs in progress.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu  sync.Mutex
+	sub *resp.Conn
+	err error
+}
+
+type notice struct {
+	doc      string
+	revision int
+}
+
+var _ ot.Relay = (*Relay)(nil)
+
+// Dial connects to Redis, subscribes to the other nodes' notifications, and
+// returns a relay that syncs hub from them. It fails if Redis cannot be
+// reached; later connection failures are retried in the background.
+func Dial(cfg Config, hub *ot.Hub) (*Relay, error) {
+	if cfg.Prefix == "" {
+		cfg.Prefix = DefaultPrefix
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	node := hex.EncodeToString(id)
+	log := cfg.Logger
+	if log == nil {
+		log = hub.Logger
+	}
+	if log == nil {
+		log = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.Level(math.MaxInt)}))
+	}
+
+	r := &Relay{
+		cfg:   cfg,
+		hub:   hub,
+		node:  node,
+		log:   log.With("node", node),
+		queue: make(chan notice, queueSize),
+	}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+
+	sub, err := r.subscribe()
+	if err != nil {
+		r.cancel()
+		return nil, err
+	}
+	r.sub = sub
+
+	r.wg.Add(2)
+	go r.publishLoop()
+	go r.subscribeLoop(sub)
+	return r, nil
+}
+
+// Publish implements ot.Relay. It queues the notification and returns
+// immediately.
+func (r *Relay) Publish(doc string, revision int) {
+	select {
+	case r.queue <- notice{doc, revision}:
+	default:
+	}
+}
+
+// Err returns the most recent error talking to Redis or syncing the hub, or
+// nil if the last attempt succeeded. It is meant for health checks.
+func (r *Relay) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *Relay) setErr(err error) {
+	if err != nil {
+		r.log.Warn("redis relay failed", "addr", r.cfg.Addr, "err", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+}
+
+// Close stops the relay and closes its connections. Queued notifications
+// that have not been published are dropped.
+func (r *Relay) Close() error {
+	r.mu.Lock()
+	select {
+	case <-r.ctx.Done():
+		r.mu.Unlock()
+		return nil
+	default:
+	}
+	r.cancel()
+	var err error
+	if r.sub != nil {
+		err = r.sub.Close()
+	}
+	r.mu.Unlock()
+
+	r.wg.Wait()
+	return err
+}
+
+func (r *Relay) closed() bool {
+	select {
+	case <-r.ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// dial opens an authenticated connection.
+func (r *Relay) dial() (*resp.Conn, error) {
+	c, err := resp.Dial(r.cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if r.cfg.Password != "" {
+		if _, err := c.Do("AUTH", r.cfg.Password); err != nil {
+			return nil, errors.Join(err, c.Close())
+		}
+	}
+	return c, nil
+}
+
+// subscribe opens a connection in pub/sub mode.
+func (r *Relay) subscribe() (*resp.Conn, error) {
+	c, err := r.dial()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Send("PSUBSCRIBE", r.cfg.Prefix+"*"); err != nil {
+		return nil, errors.Join(err, c.Close())
+	}
+	reply, err := c.Receive()
+	if err != nil {
+		return nil, errors.Join(err, c.Close())
+	}
+	if e, ok := reply.(resp.Error); ok {
+		return nil, errors.Join(e, c.Close())
+	}
+	return c, nil
+}
+
+func (r *Relay) publishLoop() {
+	defer r.wg.Done()
+
+	var c *resp.Conn
+	defer func() {
+		if c != nil {
+			c.Close() //nolint:errcheck // shutting down
+		}
+	}()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case n := <-r.queue:
+			if c == nil {
+				var err error
+				if c, err = r.dial(); err != nil {
+					r.setErr(err)
+					continue
+				}
+			}
+			msg := r.node + " " + strconv.Itoa(n.revision)
+			if _, err := c.Do("PUBLISH", r.cfg.Prefix+n.doc, msg); err != nil {
+				r.setErr(err)
+				c.Close() //nolint:errcheck // replaced on the next notification
+				c = nil
+				continue
+			}
+			r.setErr(nil)
+		}
+	}
+}
+
+func (r *Relay) subscribeLoop(c *resp.Conn) {
+	defer r.wg.Done()
+
+	backoff := minBackoff
+	for {
+		if err := r.receive(c); err != nil && !r.closed() {
+			r.setErr(err)
+		}
+		r.mu.Lock()
+		r.sub = nil
+		r.mu.Unlock()
+		c.Close() //nolint:errcheck // the connection already failed
+
+		for {
+			select {
+			case <-r.ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+
+			var err error
+			if c, err = r.subscribe(); err == nil {
+				break
+			}
+			r.setErr(err)
+			backoff = min(backoff*2, maxBackoff)
+		}
+		backoff = minBackoff
+
+		r.mu.Lock()
+		if r.closed() {
+			r.mu.Unlock()
+			c.Close() //nolint:errcheck // shutting down
+			return
+		}
+		r.sub = c
+		r.mu.Unlock()
+		r.log.Info("redis relay reconnected", "addr", r.cfg.Addr)
+
+		// Notifications sent while disconnected were lost.
+		for _, doc := range r.hub.Documents() {
+			if err := r.hub.Sync(r.ctx, doc); err != nil {
+				r.setErr(err)
+			}
+		}
+	}
+}
+
+// receive handles pushed messages until the connection fails.
+func (r *Relay) receive(c *resp.Conn) error {
+	for {
+		reply, err := c.Receive()
+		if err != nil {
+			return err
+		}
+
+		// ["pmessage", pattern, channel, message]
+		msg, ok := reply.([]any)
+		if !ok || len(msg) != 4 || msg[0] != "pmessage" {
+			continue
+		}
+		channel, ok1 := msg[2].(string)
+		payload, ok2 := msg[3].(string)
+		if !ok1 || !ok2 {
+			continue
+		}
+
+		node, _, _ := strings.Cut(payload, " ")
+		doc, ok := strings.CutPrefix(channel, r.cfg.Prefix)
+		if !ok || node == r.node {
+			continue
+		}
+		if err := r.hub.Sync(r.ctx, doc); err != nil {
+			r.setErr(err)
+		}
+	}
+}
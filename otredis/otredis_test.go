@@ -0,0 +1,276 @@
+package otredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ot "github.com/shiv248/operational-transformation-go"
+	"github.com/shiv248/operational-transformation-go/otredis/internal/resp"
+)
+
+// fakeRedis implements the handful of commands the relay uses.
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+
+	mu    sync.Mutex
+	conns map[*resp.Conn]string // connection -> subscribed pattern
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	s := &fakeRedis{ln: ln, password: password, conns: make(map[*resp.Conn]string)}
+	go s.serve()
+	t.Cleanup(func() {
+		if err := ln.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+		s.dropAll()
+	})
+	return s
+}
+
+func (s *fakeRedis) serve() {
+	for {
+		nc, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		c := resp.NewConn(nc)
+		s.mu.Lock()
+		s.conns[c] = ""
+		s.mu.Unlock()
+		go s.handle(c)
+	}
+}
+
+// dropAll closes every client connection, as a Redis restart would.
+func (s *fakeRedis) dropAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.Close() //nolint:errcheck // simulating a crash
+		delete(s.conns, c)
+	}
+}
+
+func (s *fakeRedis) handle(c *resp.Conn) {
+	authed := s.password == ""
+	for {
+		v, err := c.Receive()
+		if err != nil {
+			return
+		}
+		cmd, ok := v.([]any)
+		if !ok || len(cmd) == 0 {
+			return
+		}
+		args := make([]string, len(cmd))
+		for i := range cmd {
+			if args[i], ok = cmd[i].(string); !ok {
+				return
+			}
+		}
+
+		var reply any
+		switch {
+		case args[0] == "AUTH" && len(args) == 2:
+			if args[1] != s.password {
+				reply = resp.Error("WRONGPASS invalid password")
+			} else {
+				authed, reply = true, "OK"
+			}
+		case !authed:
+			reply = resp.Error("NOAUTH Authentication required.")
+		case args[0] == "PSUBSCRIBE" && len(args) == 2:
+			s.mu.Lock()
+			s.conns[c] = args[1]
+			s.mu.Unlock()
+			reply = []any{"psubscribe", args[1], int64(1)}
+		case args[0] == "PUBLISH" && len(args) == 3:
+			reply = s.publish(args[1], args[2])
+		default:
+			reply = resp.Error("ERR unknown command")
+		}
+		if err := c.WriteReply(reply); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeRedis) publish(channel, msg string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for c, pattern := range s.conns {
+		if pattern != "" && strings.HasPrefix(channel, strings.TrimSuffix(pattern, "*")) {
+			if c.WriteReply([]any{"pmessage", pattern, channel, msg}) == nil {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// sharedStore is an ot.Store shared by every node in a test, standing in
+// for a database.
+type sharedStore struct {
+	mu  sync.Mutex
+	ops map[string][]*ot.OperationSeq
+}
+
+func (s *sharedStore) SaveOp(_ context.Context, doc string, revision int, op *ot.OperationSeq) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if revision < len(s.ops[doc]) {
+		return ot.ErrConflict
+	}
+	if revision > len(s.ops[doc]) {
+		return fmt.Errorf("gap before revision %d", revision)
+	}
+	s.ops[doc] = append(s.ops[doc], op)
+	return nil
+}
+
+func (s *sharedStore) LoadOpsSince(_ context.Context, doc string, revision int) ([]*ot.OperationSeq, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*ot.OperationSeq{}, s.ops[doc][revision:]...), nil
+}
+
+func (s *sharedStore) SaveSnapshot(context.Context, string, int, string) error {
+	return errors.New("not supported")
+}
+
+func (s *sharedStore) LoadLatestSnapshot(context.Context, string) (string, int, error) {
+	return "", 0, nil
+}
+
+// newNode returns a hub on store connected to the Redis at addr.
+func newNode(t *testing.T, store ot.Store, addr string) *ot.Hub {
+	t.Helper()
+	hub := &ot.Hub{Store: store}
+	relay, err := Dial(Config{Addr: addr, Password: "secret"}, hub)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := relay.Close(); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+	})
+	hub.Relay = relay
+	return hub
+}
+
+func insert(t *testing.T, hub *ot.Hub, doc, text string) {
+	t.Helper()
+	server, err := hub.Server(context.Background(), doc)
+	if err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+	content, rev := server.State()
+	op := ot.NewOperationSeq()
+	op.Retain(uint64(len([]rune(content))))
+	op.Insert(text)
+	if _, _, err := hub.Submit(context.Background(), doc, "client", rev, op); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+}
+
+// waitForRevision reads events from sub until it sees an operation
+// producing revision.
+func waitForRevision(t *testing.T, sub *ot.Subscription, revision int) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-sub.C:
+			if ev.Kind == ot.EventOp && ev.Revision == revision {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for revision %d", revision)
+		}
+	}
+}
+
+func TestRelayDeliversRemoteOps(t *testing.T) {
+	redis := newFakeRedis(t, "secret")
+	store := &sharedStore{ops: make(map[string][]*ot.OperationSeq)}
+	a := newNode(t, store, redis.ln.Addr().String())
+	b := newNode(t, store, redis.ln.Addr().String())
+
+	sub, err := b.Subscribe(context.Background(), "doc", "bob")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Close()
+
+	insert(t, a, "doc", "hello")
+	waitForRevision(t, sub, 1)
+
+	insert(t, b, "doc", " world")
+	insert(t, a, "doc", "!")
+	waitForRevision(t, sub, 3)
+
+	server, err := b.Server(context.Background(), "doc")
+	if err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+	if got := server.Document(); got != "hello world!" {
+		t.Errorf("expected %q, got %q", "hello world!", got)
+	}
+}
+
+func TestRelayResyncsAfterReconnect(t *testing.T) {
+	redis := newFakeRedis(t, "secret")
+	store := &sharedStore{ops: make(map[string][]*ot.OperationSeq)}
+	a := newNode(t, store, redis.ln.Addr().String())
+	b := newNode(t, store, redis.ln.Addr().String())
+
+	sub, err := b.Subscribe(context.Background(), "doc", "bob")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Close()
+
+	// Whether or not the notification gets through, b catches up once it
+	// has reconnected.
+	redis.dropAll()
+	insert(t, a, "doc", "x")
+	waitForRevision(t, sub, 1)
+}
+
+func TestDialErrors(t *testing.T) {
+	redis := newFakeRedis(t, "secret")
+
+	var e resp.Error
+	_, err := Dial(Config{Addr: redis.ln.Addr().String(), Password: "wrong"}, &ot.Hub{})
+	if !errors.As(err, &e) || !strings.HasPrefix(string(e), "WRONGPASS") {
+		t.Errorf("expected WRONGPASS, got %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	addr := ln.Addr().String()
+	if err := ln.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := Dial(Config{Addr: addr}, &ot.Hub{}); err == nil {
+		t.Error("expected error dialing a closed port")
+	}
+}
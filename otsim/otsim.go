@@ -0,0 +1,438 @@
+// Package otsim simulates many clients editing one document over an
+// unreliable network, to catch protocol-level bugs that unit tests of
+// Transform cannot: clients that apply operations out of order, mishandle
+// acknowledgements, or transform buffered edits wrongly.
+//
+// Each simulated client follows the usual OT client loop: at most one
+// operation in flight, further edits composed into a buffer, and incoming
+// operations transformed past both. Messages are delivered after a random
+// latency and may overtake each other; clients put them back in order by
+// revision, as a real client must. The simulation runs on a virtual clock,
+// so it is fast and, for a given Seed, deterministic.
+//
+//	server := ot.NewServer("")
+//	res, err := otsim.Run(ctx, server, otsim.Config{Clients: 8, Edits: 200, Seed: 1})
+//
+// Run fails with ErrDiverged if any client ends with a different document
+// than the server. Its errors are a *Failure, holding the seed and a trace
+// of the run to reproduce it with:
+//
+//	if err := otsim.RunSeed(seed, 4, 100); err != nil {
+//		t.Fatal(err) // prints the seed and the trace as JSON
+//	}
+//
+// Shrink cuts a failing run down to a smaller one that fails the same way.
+package otsim
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+// ErrDiverged is returned by Run when a client's document does not match the
+// server's once every message has been delivered.
+var ErrDiverged = errors.New("clients diverged")
+
+// Workload produces a client's next edit, an operation that applies to doc.
+// It must only use r for randomness, so runs can be replayed.
+type Workload func(r *rand.Rand, doc string) *ot.OperationSeq
+
+// Config describes a simulation. Zero durations and probabilities disable
+// the corresponding behavior.
+type Config struct {
+	// Clients is the number of simulated clients.
+	Clients int
+
+	// Edits is the number of edits each client makes.
+	Edits int
+
+	// Latency is the minimum one-way delay of every message, and Jitter the
+	// maximum random delay added to it.
+	Latency time.Duration
+	Jitter  time.Duration
+
+	// Reorder is the probability that a message is held back by a further
+	// 2*(Latency+Jitter), so that later messages overtake it.
+	Reorder float64
+
+	// Think is the mean time between a client's edits; each gap is drawn
+	// uniformly from [0, 2*Think).
+	Think time.Duration
+
+	// Workload produces the edits. Nil means RandomEdits.
+	Workload Workload
+
+	// Seed seeds the random source driving the whole simulation.
+	Seed int64
+}
+
+// Result summarizes a simulation that converged.
+type Result struct {
+	// Document and Revision are the final state shared by every client.
+	Document string
+	Revision int
+
+	// Messages is the number of messages delivered, and Elapsed the virtual
+	// time until the last one.
+	Messages int
+	Elapsed  time.Duration
+}
+
+// Step kinds in a Trace.
+const (
+	StepEdit   = "edit"   // Client made the edit Op
+	StepSubmit = "submit" // Op, made at Revision, reached the server
+	StepAck    = "ack"    // the server's acknowledgement of Revision reached Client
+	StepRemote = "remote" // another client's Op, producing Revision, reached Client
+)
+
+// Step is one event of a simulation, in the order they happened.
+type Step struct {
+	At       time.Duration    `json:"at"`
+	Kind     string           `json:"kind"`
+	Client   int              `json:"client"`
+	Revision int              `json:"revision,omitempty"`
+	Op       *ot.OperationSeq `json:"op,omitempty"`
+}
+
+// Trace records a simulation: the state it started from and every step
+// until it ended.
+type Trace struct {
+	Seed     int64  `json:"seed"`
+	Clients  int    `json:"clients"`
+	Doc      string `json:"doc"`
+	Revision int    `json:"revision"`
+	Steps    []Step `json:"steps"`
+}
+
+// Failure is the error Run returns when a simulation fails, for any reason
+// but the context ending. Err is the cause, and Trace the run up to it.
+type Failure struct {
+	Trace Trace
+	Err   error
+}
+
+// Error reports the seed and the trace as JSON, so the failure can be
+// reproduced from a test log.
+func (f *Failure) Error() string {
+	trace, err := json.Marshal(f.Trace)
+	if err != nil {
+		trace = []byte(err.Error())
+	}
+	return fmt.Sprintf("%v (seed %d)\ntrace: %s", f.Err, f.Trace.Seed, trace)
+}
+
+func (f *Failure) Unwrap() error { return f.Err }
+
+// RunSeed runs a simulation of clients clients making edits edits each, with
+// the given seed, on an empty document, over a network with latency and
+// reordering. It returns a *Failure if the run fails, and is the quickest
+// way to turn a seed from a bug report back into a failing test.
+func RunSeed(seed int64, clients, edits int) error {
+	_, err := Run(context.Background(), ot.NewServer(""), Config{
+		Clients: clients,
+		Edits:   edits,
+		Latency: 10 * time.Millisecond,
+		Jitter:  20 * time.Millisecond,
+		Reorder: 0.2,
+		Think:   10 * time.Millisecond,
+		Seed:    seed,
+	})
+	return err
+}
+
+// Run simulates cfg.Clients clients editing the document held by server,
+// each starting from its current state, until every edit has been made and
+// every message delivered. Edits are submitted with server.Submit, so a
+// Server with a Store, limits, or a Mode can be exercised too; an error
+// from it ends the run.
+func Run(ctx context.Context, server *ot.Server, cfg Config) (*Result, error) {
+	return run(ctx, server, cfg, nil)
+}
+
+// run is Run, with the edits of each client taken from script instead of
+// the workload if it is not nil.
+func run(ctx context.Context, server *ot.Server, cfg Config, script [][]*ot.OperationSeq) (*Result, error) {
+	if cfg.Workload == nil {
+		cfg.Workload = RandomEdits
+	}
+	if script != nil {
+		cfg.Clients = len(script)
+	}
+	sim := &simulation{
+		ctx:    ctx,
+		cfg:    cfg,
+		rand:   rand.New(rand.NewSource(cfg.Seed)),
+		server: server,
+		script: script,
+	}
+	doc, revision := server.State()
+	sim.trace = Trace{Seed: cfg.Seed, Clients: cfg.Clients, Doc: doc, Revision: revision}
+	for i := 0; i < cfg.Clients; i++ {
+		sim.clients = append(sim.clients, &client{
+			id:       i,
+			doc:      doc,
+			revision: revision,
+			early:    make(map[int]event),
+		})
+		if sim.edits(i) > 0 {
+			sim.schedule(event{kind: evEdit, to: i}, sim.think())
+		}
+	}
+
+	for sim.queue.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ev, ok := heap.Pop(&sim.queue).(event)
+		if !ok {
+			return nil, errors.New("otsim: corrupt event queue")
+		}
+		sim.now = ev.at
+		if err := sim.handle(ev); err != nil {
+			return nil, sim.fail(err)
+		}
+	}
+
+	doc, revision = server.State()
+	for _, c := range sim.clients {
+		if c.inflight != nil || c.buffer != nil || len(c.early) > 0 {
+			return nil, sim.fail(fmt.Errorf("%w: client %d has unacknowledged edits", ErrDiverged, c.id))
+		}
+		if c.doc != doc || c.revision != revision {
+			return nil, sim.fail(fmt.Errorf("%w: client %d has %q at revision %d, server has %q at revision %d",
+				ErrDiverged, c.id, c.doc, c.revision, doc, revision))
+		}
+	}
+	return &Result{Document: doc, Revision: revision, Messages: sim.messages, Elapsed: sim.now}, nil
+}
+
+type eventKind int
+
+const (
+	evEdit   eventKind = iota // client makes an edit
+	evSubmit                  // operation reaches the server
+	evAck                     // acknowledgement reaches a client
+	evRemote                  // another client's operation reaches a client
+)
+
+type event struct {
+	kind     eventKind
+	at       time.Duration
+	seq      int // breaks ties in at, in scheduling order
+	to       int // client the event concerns
+	revision int
+	op       *ot.OperationSeq
+}
+
+type simulation struct {
+	ctx      context.Context
+	cfg      Config
+	rand     *rand.Rand
+	server   *ot.Server
+	clients  []*client
+	queue    eventQueue
+	now      time.Duration
+	seq      int
+	messages int
+	trace    Trace
+	script   [][]*ot.OperationSeq // edits by client, if not from the workload
+}
+
+// edits returns the number of edits client makes.
+func (s *simulation) edits(client int) int {
+	if s.script != nil {
+		return len(s.script[client])
+	}
+	return s.cfg.Edits
+}
+
+// nextEdit returns c's next edit.
+func (s *simulation) nextEdit(c *client) *ot.OperationSeq {
+	if s.script != nil {
+		return fit(s.script[c.id][c.edits], c.doc)
+	}
+	return s.cfg.Workload(s.rand, c.doc)
+}
+
+func (s *simulation) step(kind string, ev event) {
+	s.trace.Steps = append(s.trace.Steps, Step{At: s.now, Kind: kind, Client: ev.to, Revision: ev.revision, Op: ev.op})
+}
+
+func (s *simulation) fail(err error) error {
+	if s.ctx.Err() != nil {
+		return err
+	}
+	return &Failure{Trace: s.trace, Err: err}
+}
+
+func (s *simulation) schedule(ev event, delay time.Duration) {
+	ev.at = s.now + delay
+	ev.seq = s.seq
+	s.seq++
+	heap.Push(&s.queue, ev)
+}
+
+// send schedules the delivery of a message after a simulated network delay.
+func (s *simulation) send(ev event) {
+	delay := s.cfg.Latency
+	if s.cfg.Jitter > 0 {
+		delay += time.Duration(s.rand.Int63n(int64(s.cfg.Jitter)))
+	}
+	if s.cfg.Reorder > 0 && s.rand.Float64() < s.cfg.Reorder {
+		delay += 2 * (s.cfg.Latency + s.cfg.Jitter)
+	}
+	s.schedule(ev, delay)
+}
+
+func (s *simulation) think() time.Duration {
+	if s.cfg.Think <= 0 {
+		return 0
+	}
+	return time.Duration(s.rand.Int63n(int64(2 * s.cfg.Think)))
+}
+
+func (s *simulation) handle(ev event) error {
+	switch ev.kind {
+	case evEdit:
+		c := s.clients[ev.to]
+		op := s.nextEdit(c)
+		s.step(StepEdit, event{to: c.id, op: op})
+		if err := c.edit(s, op); err != nil {
+			return err
+		}
+		if c.edits++; c.edits < s.edits(c.id) {
+			s.schedule(event{kind: evEdit, to: c.id}, s.think())
+		}
+	case evSubmit:
+		s.messages++
+		s.step(StepSubmit, ev)
+		applied, revision, err := s.server.Submit(s.ctx, ev.revision, ev.op)
+		if err != nil {
+			return fmt.Errorf("otsim: client %d: %w", ev.to, err)
+		}
+		for _, c := range s.clients {
+			if c.id == ev.to {
+				s.send(event{kind: evAck, to: c.id, revision: revision})
+			} else {
+				s.send(event{kind: evRemote, to: c.id, revision: revision, op: applied})
+			}
+		}
+	case evAck:
+		s.messages++
+		s.step(StepAck, ev)
+		return s.clients[ev.to].receive(s, ev)
+	case evRemote:
+		s.messages++
+		s.step(StepRemote, ev)
+		return s.clients[ev.to].receive(s, ev)
+	}
+	return nil
+}
+
+// client is the state of one simulated editor.
+type client struct {
+	id       int
+	doc      string
+	revision int
+	inflight *ot.OperationSeq // sent, not yet acknowledged
+	buffer   *ot.OperationSeq // made while inflight was pending
+	early    map[int]event    // messages that overtook earlier ones, by revision
+	edits    int
+}
+
+func (c *client) edit(s *simulation, op *ot.OperationSeq) error {
+	doc, err := op.Apply(c.doc)
+	if err != nil {
+		return fmt.Errorf("otsim: client %d: workload produced an invalid edit: %w", c.id, err)
+	}
+	c.doc = doc
+	switch {
+	case c.inflight == nil:
+		c.inflight = op
+		s.send(event{kind: evSubmit, to: c.id, revision: c.revision, op: op})
+	case c.buffer == nil:
+		c.buffer = op
+	default:
+		if c.buffer, err = c.buffer.Compose(op); err != nil {
+			return fmt.Errorf("otsim: client %d: %w", c.id, err)
+		}
+	}
+	return nil
+}
+
+// receive handles a message from the server, and any held back messages it
+// unblocks, in revision order.
+func (c *client) receive(s *simulation, ev event) error {
+	c.early[ev.revision] = ev
+	for {
+		next, ok := c.early[c.revision+1]
+		if !ok {
+			return nil
+		}
+		delete(c.early, next.revision)
+		if err := c.apply(s, next); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *client) apply(s *simulation, ev event) error {
+	c.revision = ev.revision
+	if ev.kind == evAck {
+		c.inflight, c.buffer = c.buffer, nil
+		if c.inflight != nil {
+			s.send(event{kind: evSubmit, to: c.id, revision: c.revision, op: c.inflight})
+		}
+		return nil
+	}
+
+	op := ev.op
+	var err error
+	if c.inflight != nil {
+		if c.inflight, op, err = c.inflight.Transform(op); err != nil {
+			return fmt.Errorf("otsim: client %d: %w", c.id, err)
+		}
+	}
+	if c.buffer != nil {
+		if c.buffer, op, err = c.buffer.Transform(op); err != nil {
+			return fmt.Errorf("otsim: client %d: %w", c.id, err)
+		}
+	}
+	if c.doc, err = op.Apply(c.doc); err != nil {
+		return fmt.Errorf("otsim: client %d: %w", c.id, err)
+	}
+	return nil
+}
+
+// eventQueue orders events by time, then by scheduling order.
+type eventQueue []event
+
+func (q eventQueue) Len() int { return len(q) }
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	return q[i].seq < q[j].seq
+}
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *eventQueue) Push(x any) {
+	if ev, ok := x.(event); ok {
+		*q = append(*q, ev)
+	}
+}
+
+func (q *eventQueue) Pop() any {
+	old := *q
+	ev := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return ev
+}
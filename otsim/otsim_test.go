@@ -0,0 +1,123 @@
+package otsim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+func TestConvergence(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"lockstep", Config{Clients: 3, Edits: 50}},
+		{"latency", Config{Clients: 5, Edits: 100, Latency: 40 * time.Millisecond, Jitter: 20 * time.Millisecond, Think: 30 * time.Millisecond}},
+		{"reordering", Config{Clients: 8, Edits: 100, Latency: 10 * time.Millisecond, Jitter: 50 * time.Millisecond, Reorder: 0.3, Think: 5 * time.Millisecond}},
+		{"typing", Config{Clients: 4, Edits: 200, Latency: 25 * time.Millisecond, Think: 10 * time.Millisecond, Workload: Typing}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for seed := int64(1); seed <= 5; seed++ {
+				cfg := tt.cfg
+				cfg.Seed = seed
+				res, err := Run(context.Background(), ot.NewServer("hello"), cfg)
+				if err != nil {
+					t.Fatalf("seed %d: %v", seed, err)
+				}
+				// Edits made while one is in flight are composed, so there
+				// can be fewer revisions than edits, but never more.
+				if res.Revision < cfg.Clients || res.Revision > cfg.Clients*cfg.Edits {
+					t.Errorf("seed %d: expected %d to %d revisions, got %d", seed, cfg.Clients, cfg.Clients*cfg.Edits, res.Revision)
+				}
+			}
+		})
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	cfg := Config{Clients: 4, Edits: 50, Latency: time.Millisecond, Jitter: 10 * time.Millisecond, Reorder: 0.2, Think: time.Millisecond, Seed: 42}
+	a, err := Run(context.Background(), ot.NewServer(""), cfg)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	b, err := Run(context.Background(), ot.NewServer(""), cfg)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if *a != *b {
+		t.Errorf("expected identical runs, got %+v and %+v", a, b)
+	}
+}
+
+func TestServerErrorEndsRun(t *testing.T) {
+	server := ot.NewServer("")
+	server.SetMode(ot.ModeReadOnly)
+	if _, err := Run(context.Background(), server, Config{Clients: 2, Edits: 1}); !errors.Is(err, ot.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestDivergenceDetected(t *testing.T) {
+	// A workload whose edits do not fit the document is reported, not
+	// silently dropped.
+	bad := func(*rand.Rand, string) *ot.OperationSeq {
+		op := ot.NewOperationSeq()
+		op.Retain(100)
+		return op
+	}
+	if _, err := Run(context.Background(), ot.NewServer(""), Config{Clients: 1, Edits: 1, Workload: bad}); err == nil {
+		t.Error("expected error for an invalid workload edit")
+	}
+}
+
+func TestRunSeed(t *testing.T) {
+	for seed := int64(1); seed <= 3; seed++ {
+		if err := RunSeed(seed, 4, 50); err != nil {
+			t.Errorf("seed %d: %v", seed, err)
+		}
+	}
+}
+
+func TestFailureTrace(t *testing.T) {
+	// The second edit of each client does not fit the document.
+	bad := func(r *rand.Rand, doc string) *ot.OperationSeq {
+		if doc != "" {
+			op := ot.NewOperationSeq()
+			op.Retain(100)
+			return op
+		}
+		return Typing(r, doc)
+	}
+	_, err := Run(context.Background(), ot.NewServer(""), Config{Clients: 2, Edits: 2, Workload: bad, Seed: 7})
+	var failure *Failure
+	if !errors.As(err, &failure) {
+		t.Fatalf("expected a *Failure, got %v", err)
+	}
+	if failure.Trace.Seed != 7 || failure.Trace.Clients != 2 || len(failure.Trace.Steps) == 0 {
+		t.Errorf("expected a trace of seed 7 with 2 clients, got %+v", failure.Trace)
+	}
+	last := failure.Trace.Steps[len(failure.Trace.Steps)-1]
+	if last.Kind != StepEdit || last.Op.BaseLen() != 100 {
+		t.Errorf("expected the trace to end with the bad edit, got %+v", last)
+	}
+
+	msg := err.Error()
+	i := strings.Index(msg, "trace: ")
+	if !strings.Contains(msg, "(seed 7)") || i < 0 {
+		t.Fatalf("expected the seed and trace in %q", msg)
+	}
+	var trace Trace
+	if err := json.Unmarshal([]byte(msg[i+len("trace: "):]), &trace); err != nil {
+		t.Fatalf("expected the trace as JSON: %v", err)
+	}
+	if len(trace.Steps) != len(failure.Trace.Steps) {
+		t.Errorf("expected %d steps, got %d", len(failure.Trace.Steps), len(trace.Steps))
+	}
+}
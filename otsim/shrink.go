@@ -0,0 +1,159 @@
+package otsim
+
+import (
+	"context"
+	"errors"
+	"unicode/utf8"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+// Shrink searches for a smaller simulation that fails like failure did,
+// which Run returned for cfg, and returns the failure of the smallest one
+// found, or failure itself if none is smaller. Each attempt runs on a
+// server from newServer, which must start in the same state as the first.
+//
+// The edits recorded in the trace are replayed, each fitted to the
+// document the client has by then, while passes remove clients, remove
+// edits, shorten inserts and deletes, and quieten the network, keeping
+// every change after which the run still fails. A run still fails if it
+// returns a *Failure that, when failure was a divergence, is a divergence
+// too. The search is greedy, so the result is small rather than the
+// smallest possible.
+//
+// Failures found by go test -fuzz need no Shrink: the fuzzing engine
+// minimizes its inputs itself.
+func Shrink(ctx context.Context, newServer func() *ot.Server, cfg Config, failure *Failure) (*Failure, error) {
+	sh := &shrinker{ctx: ctx, newServer: newServer, diverged: errors.Is(failure.Err, ErrDiverged), best: failure}
+	script := make([][]*ot.OperationSeq, failure.Trace.Clients)
+	for _, st := range failure.Trace.Steps {
+		if st.Kind == StepEdit {
+			script[st.Client] = append(script[st.Client], st.Op)
+		}
+	}
+	// The scripted run differs from the original in how it draws random
+	// numbers, so it has to fail on its own before anything is removed.
+	if !sh.try(cfg, script) {
+		return failure, ctx.Err()
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, quiet := range []func(*Config) bool{
+			func(c *Config) bool { old := c.Reorder; c.Reorder = 0; return old != 0 },
+			func(c *Config) bool { old := c.Jitter; c.Jitter = 0; return old != 0 },
+			func(c *Config) bool { old := c.Latency; c.Latency = 0; return old != 0 },
+			func(c *Config) bool { old := c.Think; c.Think = 0; return old != 0 },
+		} {
+			next := cfg
+			if quiet(&next) && sh.try(next, script) {
+				cfg, changed = next, true
+			}
+		}
+		for i := 0; i < len(script) && len(script) > 1; i++ {
+			if next := remove(script, i); sh.try(cfg, next) {
+				script, changed = next, true
+				i--
+			}
+		}
+		for c := range script {
+			for i := 0; i < len(script[c]); i++ {
+				next := replace(script, c, remove(script[c], i))
+				if sh.try(cfg, next) {
+					script, changed = next, true
+					i--
+				}
+			}
+		}
+		for c := range script {
+			for i, op := range script[c] {
+				if small := shorten(op); small.String() != op.String() {
+					if next := replace(script, c, replace(script[c], i, small)); sh.try(cfg, next) {
+						script, changed = next, true
+					}
+				}
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return sh.best, err
+		}
+	}
+	return sh.best, nil
+}
+
+type shrinker struct {
+	ctx       context.Context
+	newServer func() *ot.Server
+	diverged  bool
+	best      *Failure
+}
+
+// try runs cfg with script and reports whether it still fails, keeping its
+// failure if so.
+func (sh *shrinker) try(cfg Config, script [][]*ot.OperationSeq) bool {
+	if sh.ctx.Err() != nil {
+		return false
+	}
+	_, err := run(sh.ctx, sh.newServer(), cfg, script)
+	var failure *Failure
+	if !errors.As(err, &failure) || (sh.diverged && !errors.Is(err, ErrDiverged)) {
+		return false
+	}
+	sh.best = failure
+	return true
+}
+
+// fit returns op adjusted to apply to doc: retains and deletes are cut off
+// at the end of the document and any rest of it retained, so an edit still
+// applies after the edits before it were removed or shortened.
+func fit(op *ot.OperationSeq, doc string) *ot.OperationSeq {
+	left := uint64(utf8.RuneCountInString(doc))
+	out := ot.NewOperationSeq()
+	for _, c := range op.Components() {
+		switch c.Kind {
+		case ot.KindInsert:
+			out.Insert(c.Text)
+		case ot.KindRetain, ot.KindDelete:
+			n := min(c.N, left)
+			left -= n
+			if c.Kind == ot.KindRetain {
+				out.Retain(n)
+			} else {
+				out.Delete(n)
+			}
+		}
+	}
+	out.Retain(left)
+	return out
+}
+
+// shorten returns op with each insert cut to its first character and each
+// delete to one character.
+func shorten(op *ot.OperationSeq) *ot.OperationSeq {
+	out := ot.NewOperationSeq()
+	for _, c := range op.Components() {
+		switch c.Kind {
+		case ot.KindInsert:
+			_, size := utf8.DecodeRuneInString(c.Text)
+			out.Insert(c.Text[:size])
+		case ot.KindDelete:
+			out.Delete(1)
+			out.Retain(c.N - 1)
+		case ot.KindRetain:
+			out.Retain(c.N)
+		}
+	}
+	return out
+}
+
+// remove returns s without its element i, leaving s as it was.
+func remove[T any](s []T, i int) []T {
+	return append(append([]T(nil), s[:i]...), s[i+1:]...)
+}
+
+// replace returns s with element i set to v, leaving s as it was.
+func replace[T any](s []T, i int, v T) []T {
+	out := append([]T(nil), s...)
+	out[i] = v
+	return out
+}
@@ -0,0 +1,73 @@
+package otsim
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+func TestShrink(t *testing.T) {
+	ctx := context.Background()
+	const limit = 40
+	newServer := func() *ot.Server {
+		s := ot.NewServer("")
+		s.SetLimits(ot.ServerLimits{MaxDocLen: limit})
+		return s
+	}
+	cfg := Config{Clients: 4, Edits: 20, Latency: 5 * time.Millisecond, Jitter: 10 * time.Millisecond, Reorder: 0.3, Think: 5 * time.Millisecond, Seed: 3}
+	_, err := Run(ctx, newServer(), cfg)
+	var failure *Failure
+	if !errors.As(err, &failure) || !errors.Is(err, ot.ErrDocumentTooLarge) {
+		t.Fatalf("expected a *Failure for ErrDocumentTooLarge, got %v", err)
+	}
+
+	shrunk, err := Shrink(ctx, newServer, cfg, failure)
+	if err != nil {
+		t.Fatalf("Shrink failed: %v", err)
+	}
+	if !errors.Is(shrunk, ot.ErrDocumentTooLarge) {
+		t.Errorf("expected the shrunk run to fail the same way, got %v", shrunk.Err)
+	}
+	if len(shrunk.Trace.Steps) >= len(failure.Trace.Steps)/2 {
+		t.Errorf("expected far fewer than %d steps, got %d", len(failure.Trace.Steps), len(shrunk.Trace.Steps))
+	}
+	// Inserts are shortened until the limit is only just passed: by at most
+	// the longest insert RandomEdits makes.
+	inserted := 0
+	for _, st := range shrunk.Trace.Steps {
+		if st.At != 0 {
+			t.Errorf("expected the network quietened, got a step at %v", st.At)
+		}
+		if st.Kind == StepEdit {
+			inserted += st.Op.TargetLen() - st.Op.BaseLen()
+		}
+	}
+	if inserted <= limit || inserted > limit+8 {
+		t.Errorf("expected %d to %d characters inserted, got %d in %v", limit+1, limit+8, inserted, shrunk)
+	}
+}
+
+func TestFit(t *testing.T) {
+	op := ot.NewOperationSeq()
+	op.Retain(3)
+	op.Insert("x")
+	op.Delete(4)
+	op.Retain(2)
+	tests := []struct {
+		doc  string
+		want string
+	}{
+		{"abcdefghi", `[3,"x",-4,2]`},
+		{"abcde", `[3,"x",-2]`},
+		{"ab", `[2,"x"]`},
+		{"abcdefghijkl", `[3,"x",-4,5]`},
+	}
+	for _, tt := range tests {
+		if got := fit(op, tt.doc); got.String() != tt.want {
+			t.Errorf("%q: expected %s, got %s", tt.doc, tt.want, got)
+		}
+	}
+}
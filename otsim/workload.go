@@ -0,0 +1,40 @@
+package otsim
+
+import (
+	"math/rand"
+	"unicode/utf8"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+// RandomEdits inserts up to 8 random characters at a random position, or,
+// one time in three on a non-empty document, deletes up to 8 characters
+// there, possibly replacing them.
+func RandomEdits(r *rand.Rand, doc string) *ot.OperationSeq {
+	n := utf8.RuneCountInString(doc)
+	pos := r.Intn(n + 1)
+	op := ot.NewOperationSeq()
+	op.Retain(uint64(pos))
+	if n > pos && r.Intn(3) == 0 {
+		op.Delete(uint64(1 + r.Intn(min(8, n-pos))))
+		if r.Intn(2) == 0 {
+			op.Insert(ot.RandomString(r, 1+r.Intn(8)))
+		}
+	} else {
+		op.Insert(ot.RandomString(r, 1+r.Intn(8)))
+	}
+	op.Retain(uint64(n - op.BaseLen()))
+	return op
+}
+
+// Typing inserts a single character at a random position, like several
+// people typing in the same paragraph.
+func Typing(r *rand.Rand, doc string) *ot.OperationSeq {
+	n := utf8.RuneCountInString(doc)
+	pos := r.Intn(n + 1)
+	op := ot.NewOperationSeq()
+	op.Retain(uint64(pos))
+	op.Insert(ot.RandomString(r, 1))
+	op.Retain(uint64(n - pos))
+	return op
+}
@@ -0,0 +1,76 @@
+// Package ottest provides assertions for testing code built on ot, checking
+// the properties that operations must have to converge:
+//
+//	func TestEdits(t *testing.T) {
+//		ottest.AssertTP1(t, "hello", mine, theirs)
+//	}
+//
+// Each assertion marks itself as a helper, reports a failed property with
+// Errorf, and stops the test with Fatalf if an operation does not apply at
+// all.
+package ottest
+
+import (
+	"testing"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+// AssertTP1 checks that concurrent operations a and b on s converge when
+// transformed: applying a then b' gives the same document as applying b
+// then a'. It returns that document.
+func AssertTP1(t testing.TB, s string, a, b *ot.OperationSeq) string {
+	t.Helper()
+	aPrime, bPrime, err := a.Transform(b)
+	if err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+	afterAB := apply(t, s, a, bPrime)
+	afterBA := apply(t, s, b, aPrime)
+	if afterAB != afterBA {
+		t.Errorf("TP1 failed for %v and %v on %q: a, b' gave %q, b, a' gave %q", a, b, s, afterAB, afterBA)
+	}
+	return afterAB
+}
+
+// AssertComposeApply checks that composing a with b, which applies to the
+// result of a, has the same effect on s as applying them in turn. It
+// returns the resulting document.
+func AssertComposeApply(t testing.TB, s string, a, b *ot.OperationSeq) string {
+	t.Helper()
+	ab, err := a.Compose(b)
+	if err != nil {
+		t.Fatalf("Compose failed: %v", err)
+	}
+	want := apply(t, s, a, b)
+	if got := apply(t, s, ab); got != want {
+		t.Errorf("compose failed for %v and %v on %q: expected %q, got %q", a, b, s, want, got)
+	}
+	return want
+}
+
+// AssertInvert checks that the inverse of a restores s after a is applied
+// to it.
+func AssertInvert(t testing.TB, s string, a *ot.OperationSeq) {
+	t.Helper()
+	after := apply(t, s, a)
+	inverse, err := a.InvertChecked(s)
+	if err != nil {
+		t.Fatalf("Invert failed: %v", err)
+	}
+	if got := apply(t, after, inverse); got != s {
+		t.Errorf("invert failed for %v on %q: expected %q, got %q", a, s, s, got)
+	}
+}
+
+// apply applies ops to s in turn.
+func apply(t testing.TB, s string, ops ...*ot.OperationSeq) string {
+	t.Helper()
+	for _, op := range ops {
+		var err error
+		if s, err = op.Apply(s); err != nil {
+			t.Fatalf("Apply %v to %q failed: %v", op, s, err)
+		}
+	}
+	return s
+}
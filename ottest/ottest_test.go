@@ -0,0 +1,69 @@
+package ottest
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"unicode/utf8"
+
+	ot "github.com/shiv248/operational-transformation-go"
+)
+
+func TestAssertions(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		s := ot.RandomString(r, r.Intn(30))
+		n := utf8.RuneCountInString(s)
+		a := ot.RandomOp(r, n, ot.RandomOptions{})
+		AssertTP1(t, s, a, ot.RandomOp(r, n, ot.RandomOptions{}))
+		AssertComposeApply(t, s, a, ot.RandomOp(r, a.TargetLen(), ot.RandomOptions{}))
+		AssertInvert(t, s, a)
+	}
+}
+
+// recorder is a testing.TB that records failures instead of reporting them.
+type recorder struct {
+	testing.TB
+	errors []string
+	fatal  bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	r.fatal = true
+	panic(r)
+}
+
+func TestAssertionsFail(t *testing.T) {
+	run := func(f func(testing.TB)) (rec *recorder) {
+		rec = &recorder{}
+		defer func() {
+			if p := recover(); p != nil && p != rec {
+				panic(p)
+			}
+		}()
+		f(rec)
+		return rec
+	}
+
+	a := ot.NewOperationSeq()
+	a.Insert("x")
+	a.Retain(3)
+	rec := run(func(t testing.TB) { AssertTP1(t, "toolong", a, a) })
+	if !rec.fatal || len(rec.errors) != 1 {
+		t.Errorf("expected a fatal failure for an op that does not apply, got %+v", rec)
+	}
+
+	b := ot.NewOperationSeq()
+	b.Retain(4)
+	rec = run(func(t testing.TB) { AssertComposeApply(t, "abc", a, b) })
+	if rec.fatal || len(rec.errors) != 0 {
+		t.Errorf("expected no failures, got %+v", rec)
+	}
+}
@@ -0,0 +1,425 @@
+// Package websocket is a minimal implementation of RFC 6455, covering what
+// otws needs: the opening handshake, frame masking, fragmented messages,
+// ping/pong, and the closing handshake. Extensions and subprotocols are not
+// supported. The client side exists for tests and tools.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Opcodes from RFC 6455 section 5.2.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// Close codes from RFC 6455 section 7.4.1.
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseInvalidPayload  = 1007
+	CloseMessageTooBig   = 1009
+	CloseInternalFailure = 1011
+)
+
+var (
+	// ErrClosed is returned when the peer has closed the connection
+	ErrClosed = errors.New("websocket: connection closed")
+
+	// ErrMessageTooBig is returned when a message exceeds the read limit
+	ErrMessageTooBig = errors.New("websocket: message too big")
+
+	// ErrInvalidUTF8 is returned when a text message is not valid UTF-8
+	ErrInvalidUTF8 = errors.New("websocket: invalid UTF-8 in text message")
+
+	errProtocol = errors.New("websocket: protocol error")
+)
+
+// DefaultWriteTimeout bounds how long a write may block on a peer that has
+// stopped reading, for connections from Upgrade and Dial.
+const DefaultWriteTimeout = 10 * time.Second
+
+// Conn is a WebSocket connection.
+type Conn struct {
+	conn     net.Conn
+	br       *bufio.Reader
+	maxBytes int64
+	client   bool // clients mask outgoing frames and expect unmasked ones
+
+	writeMu      sync.Mutex
+	writeTimeout time.Duration
+	closed       bool
+}
+
+// SetWriteTimeout sets how long each write may block before the connection
+// is given up on; zero or less means no limit.
+func (c *Conn) SetWriteTimeout(d time.Duration) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.writeTimeout = d
+}
+
+// Upgrade performs the opening handshake and hijacks the HTTP connection.
+// maxBytes bounds the size of a single incoming message; zero means no limit.
+// checkOrigin decides whether to accept the request's Origin; nil means
+// SameOrigin.
+func Upgrade(w http.ResponseWriter, r *http.Request, maxBytes int64, checkOrigin func(*http.Request) bool) (*Conn, error) {
+	if checkOrigin == nil {
+		checkOrigin = SameOrigin
+	}
+	if !checkOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return nil, fmt.Errorf("%w: origin %q not allowed", errProtocol, r.Header.Get("Origin"))
+	}
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "websocket upgrade required", http.StatusUpgradeRequired)
+		return nil, fmt.Errorf("%w: not a websocket handshake", errProtocol)
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusBadRequest)
+		return nil, fmt.Errorf("%w: unsupported version", errProtocol)
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, fmt.Errorf("%w: missing key", errProtocol)
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, errors.New("websocket: response does not support hijacking")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + AcceptKey(key) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(response)); err != nil {
+		return nil, errors.Join(err, conn.Close())
+	}
+
+	return &Conn{conn: conn, br: rw.Reader, maxBytes: maxBytes, writeTimeout: DefaultWriteTimeout}, nil
+}
+
+// Dial opens a client connection to a ws:// URL.
+func Dial(rawURL string) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "ws" {
+		return nil, fmt.Errorf("websocket: unsupported scheme %q", u.Scheme)
+	}
+
+	conn, err := net.Dial("tcp", u.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, errors.Join(err, conn.Close())
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+
+	request := "GET " + u.RequestURI() + " HTTP/1.1\r\n" +
+		"Host: " + u.Host + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(request)); err != nil {
+		return nil, errors.Join(err, conn.Close())
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		return nil, errors.Join(err, conn.Close())
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != AcceptKey(key) {
+		return nil, errors.Join(fmt.Errorf("websocket: handshake failed: %s", resp.Status), conn.Close())
+	}
+
+	return &Conn{conn: conn, br: br, client: true, writeTimeout: DefaultWriteTimeout}, nil
+}
+
+// SameOrigin reports whether the request's Origin header names the host it
+// was sent to, as it does for a page served by the same server. Requests
+// without an Origin come from clients other than browsers and are accepted;
+// browsers always send one, so a page on another site cannot open a
+// connection with the user's cookies.
+func SameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// AcceptKey computes the Sec-WebSocket-Accept value for a client key.
+func AcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage returns the payload of the next text or binary message.
+// Control frames are handled internally. When the peer closes the
+// connection, the close is acknowledged and ErrClosed is returned. A text
+// message that is not valid UTF-8 fails the connection (section 8.1).
+func (c *Conn) ReadMessage() ([]byte, error) {
+	var msg []byte
+	inMessage, text := false, false
+
+	for {
+		limit := int64(-1)
+		if c.maxBytes > 0 {
+			limit = c.maxBytes - int64(len(msg))
+		}
+		fin, opcode, payload, err := c.readFrame(limit)
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			code := CloseNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			return nil, errors.Join(ErrClosed, c.Close(code))
+		case opText, opBinary:
+			if inMessage {
+				return nil, c.fail(CloseProtocolError, errProtocol)
+			}
+			inMessage, text = true, opcode == opText
+			msg = payload
+		case opContinuation:
+			if !inMessage {
+				return nil, c.fail(CloseProtocolError, errProtocol)
+			}
+			msg = append(msg, payload...)
+		default:
+			return nil, c.fail(CloseProtocolError, errProtocol)
+		}
+
+		if fin {
+			if text && !utf8.Valid(msg) {
+				return nil, c.fail(CloseInvalidPayload, ErrInvalidUTF8)
+			}
+			return msg, nil
+		}
+	}
+}
+
+// readFrame reads the next frame. Data frames with a payload over limit
+// bytes fail the connection; a negative limit means no limit.
+func (c *Conn) readFrame(limit int64) (bool, byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := head[0]&0x80 != 0
+	opcode := head[0] & 0x0f
+	masked := head[1]&0x80 != 0
+	if head[0]&0x70 != 0 || masked == c.client {
+		// Reserved bits need an extension; only clients mask (section 5.1).
+		return false, 0, nil, c.fail(CloseProtocolError, errProtocol)
+	}
+
+	length := uint64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+		if length>>63 != 0 {
+			// The most significant bit must be 0 (section 5.2).
+			return false, 0, nil, c.fail(CloseProtocolError, errProtocol)
+		}
+	}
+	if opcode >= opClose && (length > 125 || !fin) {
+		return false, 0, nil, c.fail(CloseProtocolError, errProtocol)
+	}
+	if opcode < opClose && limit >= 0 && length > uint64(limit) {
+		return false, 0, nil, c.fail(CloseMessageTooBig, ErrMessageTooBig)
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	// The buffer grows with the bytes that arrive, not with the length the
+	// peer claims, so a header alone cannot make it allocate.
+	payload, err := io.ReadAll(io.LimitReader(c.br, int64(length)))
+	if err != nil {
+		return false, 0, nil, err
+	}
+	if uint64(len(payload)) < length {
+		return false, 0, nil, io.ErrUnexpectedEOF
+	}
+	if masked {
+		maskBytes(mask, payload)
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// WriteText sends a text message. It is safe to call concurrently.
+func (c *Conn) WriteText(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	return c.writeRawFrame(true, opcode, payload)
+}
+
+func (c *Conn) writeRawFrame(fin bool, opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+
+	head := opcode
+	if fin {
+		head |= 0x80
+	}
+
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, head)
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		maskBytes(mask, frame[start:])
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	if c.writeTimeout > 0 {
+		if err := c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
+			return err
+		}
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		// A frame may have been cut short, so nothing more can be sent.
+		// Closing also unblocks the reader.
+		c.closed = true
+		return errors.Join(err, c.conn.Close())
+	}
+	return nil
+}
+
+func maskBytes(mask [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= mask[i%4]
+	}
+}
+
+// Close sends a close frame with the given code and closes the connection.
+func (c *Conn) Close(code int) error {
+	var payload [2]byte
+	binary.BigEndian.PutUint16(payload[:], uint16(code))
+	werr := c.writeFrame(opClose, payload[:])
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if errors.Is(werr, ErrClosed) {
+		werr = nil
+	}
+	return errors.Join(werr, c.conn.Close())
+}
+
+// fail closes the connection with code and returns err.
+func (c *Conn) fail(code int, err error) error {
+	return errors.Join(err, c.Close(code))
+}
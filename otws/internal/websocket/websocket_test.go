@@ -0,0 +1,243 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// Example from RFC 6455 section 1.3.
+	if got := AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("expected RFC example accept key, got %q", got)
+	}
+}
+
+func echoServer(t *testing.T, maxBytes int64) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, maxBytes, nil)
+		if err != nil {
+			return
+		}
+		for {
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteText(msg); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func dial(t *testing.T, url string) *Conn {
+	t.Helper()
+	conn, err := Dial(url)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := conn.Close(CloseNormal); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+	})
+	return conn
+}
+
+func TestEcho(t *testing.T) {
+	conn := dial(t, echoServer(t, 0))
+
+	for _, size := range []int{0, 5, 125, 126, 70000} {
+		msg := bytes.Repeat([]byte("x"), size)
+		if err := conn.WriteText(msg); err != nil {
+			t.Fatalf("WriteText(%d) failed: %v", size, err)
+		}
+		got, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage(%d) failed: %v", size, err)
+		}
+		if !bytes.Equal(got, msg) {
+			t.Errorf("size %d: echo mismatch", size)
+		}
+	}
+}
+
+func TestFragmentsAndPing(t *testing.T) {
+	conn := dial(t, echoServer(t, 0))
+
+	// A ping between fragments must be answered without disturbing the message.
+	frames := []struct {
+		fin    bool
+		opcode byte
+		data   string
+	}{
+		{false, opText, "hel"},
+		{true, opPing, "p"},
+		{true, opContinuation, "lo"},
+	}
+	for _, f := range frames {
+		if err := conn.writeRawFrame(f.fin, f.opcode, []byte(f.data)); err != nil {
+			t.Fatalf("write frame failed: %v", err)
+		}
+	}
+
+	// The pong is consumed by ReadMessage; the echo follows.
+	got, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage failed: %v", err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("expected %q, got %q", "hello", got)
+	}
+}
+
+func TestMessageTooBig(t *testing.T) {
+	conn := dial(t, echoServer(t, 10))
+
+	if err := conn.WriteText(bytes.Repeat([]byte("x"), 11)); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	if _, err := conn.ReadMessage(); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestUpgradeRejectsPlainHTTP(t *testing.T) {
+	url := echoServer(t, 0)
+	resp, err := http.Get("http" + strings.TrimPrefix(url, "ws"))
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		t.Fatalf("closing body failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusUpgradeRequired {
+		t.Errorf("expected 426, got %d", resp.StatusCode)
+	}
+}
+
+func TestUpgradeChecksOrigin(t *testing.T) {
+	url := "http" + strings.TrimPrefix(echoServer(t, 0), "ws")
+	for _, tc := range []struct {
+		origin string
+		want   int
+	}{
+		{"", http.StatusSwitchingProtocols},
+		{url, http.StatusSwitchingProtocols},
+		{"http://evil.example", http.StatusForbidden},
+	} {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatalf("NewRequest failed: %v", err)
+		}
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		if tc.origin != "" {
+			req.Header.Set("Origin", tc.origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Do failed: %v", err)
+		}
+		if err := resp.Body.Close(); err != nil {
+			t.Fatalf("closing body failed: %v", err)
+		}
+		if resp.StatusCode != tc.want {
+			t.Errorf("origin %q: expected %d, got %d", tc.origin, tc.want, resp.StatusCode)
+		}
+	}
+}
+
+// readRaw feeds frame to a server-side Conn and returns the result of
+// ReadMessage once the peer has hung up.
+func readRaw(t *testing.T, frame []byte, maxBytes int64) ([]byte, error) {
+	t.Helper()
+	server, client := net.Pipe()
+	go func() {
+		client.Write(frame)            //nolint:errcheck // the server may fail first
+		go io.Copy(io.Discard, client) //nolint:errcheck // drains the close frame
+		client.Close()                 //nolint:errcheck // ends the stream
+	}()
+	conn := &Conn{conn: server, br: bufio.NewReader(server), maxBytes: maxBytes}
+	t.Cleanup(func() { server.Close() }) //nolint:errcheck // may already be closed
+	return conn.ReadMessage()
+}
+
+func TestReadFrameLength(t *testing.T) {
+	// A masked text frame header with a 64-bit length, followed by a mask
+	// key and no payload.
+	header := func(length uint64) []byte {
+		frame := []byte{0x81, 0x80 | 127}
+		frame = binary.BigEndian.AppendUint64(frame, length)
+		return append(frame, 0, 0, 0, 0)
+	}
+
+	if _, err := readRaw(t, header(1<<63), 0); !errors.Is(err, errProtocol) {
+		t.Errorf("expected protocol error for high bit, got %v", err)
+	}
+	if _, err := readRaw(t, header(1<<40), 1<<20); !errors.Is(err, ErrMessageTooBig) {
+		t.Errorf("expected ErrMessageTooBig, got %v", err)
+	}
+	// Without a limit, a claimed length the peer never sends ends in EOF
+	// rather than an up-front allocation.
+	if _, err := readRaw(t, header(1<<40), 0); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close() //nolint:errcheck // test cleanup
+	conn := &Conn{conn: server, br: bufio.NewReader(server)}
+
+	// A masked text frame whose payload is not UTF-8; a zero mask leaves
+	// it as is.
+	go client.Write([]byte{0x81, 0x80 | 2, 0, 0, 0, 0, 0xff, 0xfe}) //nolint:errcheck // the read below reports failures
+	closeFrame := make(chan []byte, 1)
+	go func() {
+		frame := make([]byte, 4)
+		_, err := io.ReadFull(client, frame)
+		if err != nil {
+			frame = nil
+		}
+		closeFrame <- frame
+	}()
+
+	if _, err := conn.ReadMessage(); !errors.Is(err, ErrInvalidUTF8) {
+		t.Errorf("expected ErrInvalidUTF8, got %v", err)
+	}
+	frame := <-closeFrame
+	if len(frame) != 4 || frame[0] != 0x88 || int(binary.BigEndian.Uint16(frame[2:])) != CloseInvalidPayload {
+		t.Errorf("expected a close frame with code %d, got %x", CloseInvalidPayload, frame)
+	}
+}
+
+func TestWriteTimeout(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close() //nolint:errcheck // test cleanup
+	conn := &Conn{conn: server, br: bufio.NewReader(server)}
+	conn.SetWriteTimeout(20 * time.Millisecond)
+
+	// The peer never reads, so the write cannot complete.
+	if err := conn.WriteText([]byte("stuck")); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("expected os.ErrDeadlineExceeded, got %v", err)
+	}
+	if err := conn.WriteText([]byte("next")); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed after a timed-out write, got %v", err)
+	}
+}
@@ -0,0 +1,625 @@
+// Package otws serves collaborative editing sessions over WebSocket.
+//
+// # Protocol
+//
+// Every message is a JSON text frame holding an object with a "type" field.
+// A frame that is not valid UTF-8 closes the connection with code 1007.
+// Revisions count the operations the server has accepted for a document.
+//
+// A connection can join several documents, each with its own revisions.
+// Messages about a document carry its name in "doc"; a client that has
+// joined only one document may leave it out of the messages it sends.
+// Events for different documents interleave, but those for one document
+// arrive in order.
+//
+// Client to server:
+//
+//	{"type":"join","doc":"notes"}
+//	    Subscribes to a document; answered with "joined". A connection must
+//	    join a document before sending anything else about it.
+//	{"type":"leave","doc":"notes"}
+//	    Unsubscribes from a document; answered with "left" once every
+//	    event for it has been sent.
+//	{"type":"join","doc":"notes","resume":{"client":"Zk3v","secret":"q8Xc...","revision":7,"pending":"42"}}
+//	    Continues a session after a lost connection or a "shutdown", on this
+//	    node or any other sharing the Store and ResumeKey; see
+//	    ot.ResumeToken. "client" and "secret" are from the earlier "joined",
+//	    "revision" the last revision the client applied, and "pending" the
+//	    ID of its unacknowledged "op". A token whose secret does not match
+//	    is rejected with code "forbidden".
+//	    Only the first join on a connection may adopt a client ID; later
+//	    ones must resume with the ID the connection already has.
+//	{"type":"op","revision":3,"op":[5,"x"],"id":"42"}
+//	    An operation made against revision 3, in the JSON wire format.
+//	    The sender receives "ack"; every other client receives "op". The
+//	    optional "id", unique among the sender's operations, lets a resumed
+//	    session tell whether the operation was accepted, and makes resending
+//	    it safe: a repeat is acknowledged again instead of applied twice.
+//	{"type":"undo","doc":"notes"}
+//	    Reverts the sender's most recent operation that has not been undone,
+//	    keeping everyone else's edits; see ot.Server.Undo. Every client,
+//	    including the sender, receives the result as an "op" with
+//	    "undo":true.
+//	{"type":"cursor","revision":3,"cursor":{"anchor":2,"head":4}}
+//	    The sender's selection as of revision 3. The server keeps it in step
+//	    with later operations and relays it to every other client.
+//	{"type":"meta","meta":{"name":"Ada","color":"#f80","idle":false}}
+//	    Updates the sender's metadata. Keys are merged into what was sent
+//	    before, and a null value deletes a key. Metadata is discarded when
+//	    the client disconnects.
+//
+// Server to client:
+//
+//	{"type":"joined","doc":"notes","client":"c1","secret":"q8Xc...","revision":3,"document":"...",
+//	 "cursors":{"c2":{"anchor":0,"head":0}},"awareness":{"c2":{"name":"Ada"}}}
+//	    The client's ID, the secret it needs to resume the session, the
+//	    document content at the given revision, and the other clients'
+//	    selections at that revision and metadata. The secret is sent to no
+//	    one else, and the client keeps it to itself. If the
+//	    Hub's Authorizer made the subscription read-only, "readOnly":true is
+//	    included and "op" messages are rejected. If the document is not
+//	    editable, "mode" gives its ot.Mode: "readOnly", "commentOnly", or
+//	    "frozen".
+//	    A resumed session also gets "ops", the operations accepted since the
+//	    token's revision, and "pendingRevision" if its pending operation is
+//	    known to be among them, as the revision it produced. The client
+//	    transforms its pending and buffered operations against the others,
+//	    as for incoming "op" messages, and treats that one as an "ack"
+//	    carrying it as "op", in case the server clipped it. If
+//	    "pendingRevision" is absent, it resends the pending operation with
+//	    the same "id".
+//	{"type":"left","doc":"notes"}
+//	    No more events will arrive for the document.
+//	{"type":"ack","revision":4}
+//	    The client's pending operation was accepted and produced revision 4.
+//	{"type":"ack","revision":4,"op":[...]}
+//	    The pending operation was accepted, but the server clipped it to the
+//	    document's protected ranges (see ot.ProtectClip), so it applied "op"
+//	    instead, transformed like the pending one. The client undoes its
+//	    pending operation, applies "op" in its place, and transforms its
+//	    buffered operations to follow.
+//	{"type":"op","client":"c2","revision":4,"op":[...]}
+//	    Another client's operation, already transformed by the server, which
+//	    produced revision 4. With "undo":true, it undoes an operation of
+//	    "client", which may be the receiver itself. A read-only client may
+//	    receive several operations composed into one, without "client", if
+//	    the Hub batches them for viewers; its revision then advances by more
+//	    than one.
+//	{"type":"ack","revision":8,"checksum":"9f86d0..."}
+//	{"type":"op","client":"c2","revision":8,"op":[...],"checksum":"9f86d0..."}
+//	    If the Hub has a ChecksumEvery, "ack" and "op" messages for some
+//	    revisions carry "checksum", the hex SHA-256 of the UTF-8 document
+//	    at that revision (see ot.ContentHash). After applying the message,
+//	    the client hashes its document without its in-flight and buffered
+//	    operations; if the hashes differ, it has diverged and must discard
+//	    its state and join again rather than carry on editing a different
+//	    document. Checksums are optional: a client may ignore them.
+//	{"type":"cursor","client":"c2","revision":5,"cursor":{"anchor":2,"head":4},"meta":{...}}
+//	    Another client's selection, transformed by the server to revision 5,
+//	    together with its current metadata.
+//	    A client that has seen revision 5 transforms it through its own
+//	    in-flight and buffered operations before drawing it. Selections sent
+//	    earlier move with every "op" the same way.
+//	{"type":"meta","client":"c2","meta":{"name":"Ada","color":"#f80"}}
+//	    Another client's metadata after an update.
+//	{"type":"presence","clients":["c1","c2"]}
+//	    The clients connected to the document, sent whenever it changes.
+//	{"type":"mode","revision":9,"mode":"readOnly"}
+//	    The document's mode changed at revision 9; "mode" is absent when it
+//	    became editable again. Operations the new mode refuses are rejected
+//	    with code "read_only", so the client stops sending them.
+//	{"type":"error","doc":"notes","id":"42","error":"...","code":"rate_limited","retryAfter":250}
+//	    A message was rejected. The connection stays open. "doc" names the
+//	    document the rejected message was about, if any, and "id" is that of
+//	    a rejected "op" that had one. "code" is set for
+//	    rejections a client may want to handle:
+//	      forbidden           the Hub's Authorizer refused the operation
+//	      rate_limited        the client is over the Hub's RateLimit; retry
+//	                          after "retryAfter" milliseconds
+//	      op_too_large        an insert or the operation as a whole is over
+//	                          the server's or the handler's limits
+//	      document_too_large  the operation would grow the document past the
+//	                          server's limit
+//	      too_far_behind      the operation's revision is too old to be
+//	                          transformed; rejoin and rebase local changes
+//	      read_only           the document's mode refuses the operation
+//	      shutting_down       the server is shutting down; resume elsewhere
+//	    A rejected "op" was not applied.
+//	{"type":"shutdown"}
+//	    The server is shutting down and closes the connection after this
+//	    message. Every operation acknowledged before it is durable; the
+//	    client resumes its session on another node.
+//
+// A client follows the usual OT client loop: it keeps at most one operation
+// in flight, buffers (composes) further local edits until the ack arrives,
+// and transforms in-flight and buffered operations against every incoming
+// "op" before applying it.
+package otws
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	ot "github.com/shiv248/operational-transformation-go"
+	"github.com/shiv248/operational-transformation-go/otws/internal/websocket"
+)
+
+// Message types.
+const (
+	TypeJoin     = "join"
+	TypeJoined   = "joined"
+	TypeLeave    = "leave"
+	TypeLeft     = "left"
+	TypeUndo     = "undo"
+	TypeOp       = "op"
+	TypeAck      = "ack"
+	TypeCursor   = "cursor"
+	TypeMeta     = "meta"
+	TypePresence = "presence"
+	TypeError    = "error"
+	TypeMode     = "mode"
+	TypeShutdown = "shutdown"
+)
+
+// Message is the envelope for every protocol message. Fields that do not
+// apply to a message type are omitted.
+type Message struct {
+	Type       string                    `json:"type"`
+	Doc        string                    `json:"doc,omitempty"`
+	Client     string                    `json:"client,omitempty"`
+	Secret     string                    `json:"secret,omitempty"`
+	Revision   int                       `json:"revision"`
+	Document   string                    `json:"document,omitempty"`
+	Op         json.RawMessage           `json:"op,omitempty"`
+	ID         string                    `json:"id,omitempty"`
+	Undo       bool                      `json:"undo,omitempty"`
+	Resume     *ot.ResumeToken           `json:"resume,omitempty"`
+	Ops        []*ot.OperationSeq        `json:"ops,omitempty"`
+	Pending    int                       `json:"pendingRevision,omitempty"`
+	Cursor     *Cursor                   `json:"cursor,omitempty"`
+	Cursors    map[string]Cursor         `json:"cursors,omitempty"`
+	Meta       map[string]any            `json:"meta,omitempty"`
+	Awareness  map[string]map[string]any `json:"awareness,omitempty"`
+	ReadOnly   bool                      `json:"readOnly,omitempty"`
+	Mode       ot.Mode                   `json:"mode,omitempty"`
+	Clients    []string                  `json:"clients,omitempty"`
+	Error      string                    `json:"error,omitempty"`
+	Code       string                    `json:"code,omitempty"`
+	RetryAfter int64                     `json:"retryAfter,omitempty"`
+	Checksum   string                    `json:"checksum,omitempty"`
+}
+
+// Error codes.
+const (
+	CodeForbidden        = "forbidden"
+	CodeRateLimited      = "rate_limited"
+	CodeOpTooLarge       = "op_too_large"
+	CodeDocumentTooLarge = "document_too_large"
+	CodeTooFarBehind     = "too_far_behind"
+	CodeReadOnly         = "read_only"
+	CodeShuttingDown     = "shutting_down"
+)
+
+// Cursor is a selection in character offsets. A caret has Anchor == Head.
+type Cursor = ot.Selection
+
+// Handler is an http.Handler that upgrades requests to WebSocket and runs
+// the protocol against the documents of a Hub.
+type Handler struct {
+	// Hub holds the documents. Operations submitted through other paths,
+	// such as othttp, reach WebSocket clients as long as they go through
+	// the same Hub.
+	Hub *ot.Hub
+
+	// Limits are applied when decoding operations from clients.
+	Limits ot.DecodeLimits
+
+	// MaxMessageBytes bounds the size of an incoming message. Zero means
+	// DefaultMaxMessageBytes, and a negative value no limit.
+	MaxMessageBytes int64
+
+	// WriteTimeout bounds how long sending a message may block on a client
+	// that has stopped reading, after which the connection is closed so it
+	// cannot hold up the document's other clients. Zero means
+	// DefaultWriteTimeout, and a negative value no limit.
+	WriteTimeout time.Duration
+
+	// CheckOrigin decides whether to accept a connection from the page
+	// named by the request's Origin header. Nil accepts only the page's own
+	// host, and clients other than browsers, which send no Origin; without
+	// it, any site a user visits could connect as them.
+	CheckOrigin func(r *http.Request) bool
+
+	// Logger receives connection lifecycle events and rejected messages at
+	// Debug, with "client" and "remote" attributes. Nil means the Hub's
+	// Logger, which also logs joins, leaves, and refused operations.
+	Logger *slog.Logger
+}
+
+// DefaultMaxMessageBytes is the size limit on incoming messages when
+// Handler.MaxMessageBytes is zero.
+const DefaultMaxMessageBytes = 1 << 20
+
+// DefaultWriteTimeout is the write timeout when Handler.WriteTimeout is zero.
+const DefaultWriteTimeout = websocket.DefaultWriteTimeout
+
+// NewHandler returns a Handler serving the documents of hub, accepting
+// messages of up to DefaultMaxMessageBytes from pages on the same host, and
+// giving up on clients that block a write for DefaultWriteTimeout.
+func NewHandler(hub *ot.Hub) *Handler {
+	return &Handler{Hub: hub}
+}
+
+// discard is the logger used when neither the Handler nor the Hub has one.
+var discard = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.Level(math.MaxInt)}))
+
+func (h *Handler) logger() *slog.Logger {
+	switch {
+	case h.Logger != nil:
+		return h.Logger
+	case h.Hub.Logger != nil:
+		return h.Hub.Logger
+	}
+	return discard
+}
+
+type session struct {
+	ctx  context.Context
+	id   string
+	conn *websocket.Conn
+	log  *slog.Logger
+
+	mu     sync.Mutex
+	feeds  map[string]*feed // by document
+	closed bool
+}
+
+// feed is a joined document: its subscription and the forwarder draining it.
+type feed struct {
+	sub  *ot.Subscription
+	done chan struct{} // closed when the forwarder returns
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	maxBytes := h.MaxMessageBytes
+	switch {
+	case maxBytes == 0:
+		maxBytes = DefaultMaxMessageBytes
+	case maxBytes < 0:
+		maxBytes = 0
+	}
+	conn, err := websocket.Upgrade(w, r, maxBytes, h.CheckOrigin)
+	if err != nil {
+		h.logger().DebugContext(r.Context(), "websocket upgrade failed", "remote", r.RemoteAddr, "err", err)
+		return
+	}
+	if h.WriteTimeout != 0 {
+		conn.SetWriteTimeout(h.WriteTimeout)
+	}
+
+	// IDs are random so that a session resumed from another node cannot
+	// collide with one started here.
+	var b [9]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		h.logger().ErrorContext(r.Context(), "generating client ID failed", "err", err)
+		conn.Close(websocket.CloseInternalFailure) //nolint:errcheck // already failing the connection
+		return
+	}
+	id := base64.RawURLEncoding.EncodeToString(b[:])
+	s := &session{
+		ctx:   r.Context(),
+		id:    id,
+		conn:  conn,
+		log:   h.logger().With("client", id, "remote", r.RemoteAddr),
+		feeds: make(map[string]*feed),
+	}
+	s.log.DebugContext(s.ctx, "connection opened")
+	defer s.close(websocket.CloseNormal)
+
+	for {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			s.log.DebugContext(s.ctx, "connection closed", "err", err)
+			return
+		}
+
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			s.sendError("", "", fmt.Errorf("invalid message: %w", err))
+			continue
+		}
+
+		if msg.Type == TypeJoin {
+			h.join(s, msg)
+			continue
+		}
+		f, err := s.feed(msg.Doc)
+		if err != nil {
+			s.sendError(msg.Doc, msg.ID, err)
+			continue
+		}
+		sub := f.sub
+		switch msg.Type {
+		case TypeLeave:
+			s.leave(f)
+		case TypeOp:
+			h.receiveOp(s, sub, msg)
+		case TypeUndo:
+			if _, _, err := h.Hub.Undo(s.ctx, sub.Doc, s.id); err != nil {
+				s.sendError(sub.Doc, "", err)
+			}
+		case TypeCursor:
+			h.relayCursor(s, sub, msg)
+		case TypeMeta:
+			if err := h.Hub.SetMeta(s.ctx, sub.Doc, s.id, msg.Meta); err != nil {
+				s.sendError(sub.Doc, "", err)
+			}
+		default:
+			s.sendError(sub.Doc, "", fmt.Errorf("unexpected message type %q", msg.Type))
+		}
+	}
+}
+
+// feed returns the joined document doc. An empty doc names the only joined
+// document, so single-document clients can leave it out.
+func (s *session) feed(doc string) (*feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if doc == "" && len(s.feeds) == 1 {
+		for _, f := range s.feeds {
+			return f, nil
+		}
+	}
+	switch f, ok := s.feeds[doc]; {
+	case ok:
+		return f, nil
+	case doc == "" && len(s.feeds) > 1:
+		return nil, errors.New("doc is required when several documents are joined")
+	case doc == "":
+		return nil, errors.New("join a document first")
+	default:
+		return nil, fmt.Errorf("document %q is not joined", doc)
+	}
+}
+
+func (h *Handler) join(s *session, msg Message) {
+	doc := msg.Doc
+	s.mu.Lock()
+	_, joined := s.feeds[doc]
+	first := len(s.feeds) == 0
+	s.mu.Unlock()
+	if joined {
+		s.sendError(doc, "", fmt.Errorf("document %q is already joined", doc))
+		return
+	}
+
+	var sub *ot.Subscription
+	var err error
+	switch {
+	case msg.Resume != nil && !first && msg.Resume.Client != s.id:
+		// Every document on a connection belongs to the same client.
+		err = errors.New("resume token is for another client")
+	case msg.Resume != nil:
+		// Resume checks the token's secret, so the connection only takes
+		// over a client ID it was given.
+		token := *msg.Resume
+		token.Doc = doc
+		if sub, err = h.Hub.Resume(s.ctx, token); err == nil && first {
+			s.id = token.Client
+			s.log = s.log.With("resumed", s.id)
+		}
+	default:
+		sub, err = h.Hub.Subscribe(s.ctx, doc, s.id)
+	}
+	if err != nil {
+		s.sendError(doc, "", err)
+		return
+	}
+	f := &feed{sub: sub, done: make(chan struct{})}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		sub.Close()
+		return
+	}
+	s.feeds[doc] = f
+	s.mu.Unlock()
+
+	// The joined message must precede every event, so send it before the
+	// forwarder starts draining the subscription.
+	reply := JoinedMessage(sub)
+	reply.Secret = sub.ResumeSecret
+	s.sendMessage(reply)
+	go s.forward(f)
+}
+
+// JoinedMessage returns the "joined" message that starts a subscription. It
+// leaves Secret empty: only a transport that has authenticated the client as
+// sub.Client may hand it sub.ResumeSecret.
+func JoinedMessage(sub *ot.Subscription) Message {
+	return Message{
+		Type:      TypeJoined,
+		Doc:       sub.Doc,
+		Client:    sub.Client,
+		Revision:  sub.Revision,
+		Document:  sub.Document,
+		Cursors:   sub.Selections,
+		Awareness: sub.Meta,
+		ReadOnly:  sub.ReadOnly,
+		Mode:      sub.Mode,
+		Ops:       sub.Missed,
+		Pending:   sub.PendingRevision,
+	}
+}
+
+// leave ends the subscription and sends "left" once every event queued
+// before it has been forwarded.
+func (s *session) leave(f *feed) {
+	s.mu.Lock()
+	delete(s.feeds, f.sub.Doc)
+	s.mu.Unlock()
+	f.sub.Close()
+	<-f.done
+	s.sendMessage(Message{Type: TypeLeft, Doc: f.sub.Doc})
+}
+
+func (h *Handler) receiveOp(s *session, sub *ot.Subscription, msg Message) {
+	op, err := h.Limits.DecodeJSON(msg.Op)
+	if err != nil {
+		s.sendError(sub.Doc, msg.ID, fmt.Errorf("invalid operation: %w", err))
+		return
+	}
+
+	// The acknowledgement arrives through the subscription, in order with
+	// the other clients' operations.
+	if _, _, err := h.Hub.SubmitOp(s.ctx, sub.Doc, s.id, msg.ID, msg.Revision, op); err != nil {
+		s.sendError(sub.Doc, msg.ID, err)
+	}
+}
+
+func (h *Handler) relayCursor(s *session, sub *ot.Subscription, msg Message) {
+	if msg.Cursor == nil {
+		s.sendError(sub.Doc, "", errors.New("cursor message without cursor"))
+		return
+	}
+	if err := h.Hub.SetSelection(s.ctx, sub.Doc, s.id, msg.Revision, *msg.Cursor); err != nil {
+		s.sendError(sub.Doc, "", err)
+	}
+}
+
+// forward translates hub events into protocol messages until the
+// subscription ends.
+func (s *session) forward(f *feed) {
+	defer close(f.done)
+	sub := f.sub
+	for ev := range sub.C {
+		if msg, ok := EventMessage(ev, s.id); ok {
+			s.sendMessage(msg)
+		}
+	}
+
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return
+	}
+	// A hub shutdown or a dropped subscription ends the whole connection;
+	// the client resumes every document it had joined.
+	switch err := sub.Err(); {
+	case errors.Is(err, ot.ErrHubClosed):
+		s.sendMessage(Message{Type: TypeShutdown})
+		s.close(websocket.CloseGoingAway)
+	case err != nil:
+		s.close(websocket.CloseGoingAway)
+	}
+}
+
+// EventMessage translates a hub event into the message sent to client. It
+// returns false for events the protocol does not forward.
+func EventMessage(ev ot.Event, client string) (Message, bool) {
+	switch ev.Kind {
+	case ot.EventOp:
+		if ev.Client == client && !ev.Undo {
+			return ackMessage(ev)
+		}
+		data, err := json.Marshal(ev.Op)
+		if err != nil {
+			return Message{}, false
+		}
+		return Message{Type: TypeOp, Doc: ev.Doc, Client: ev.Client, Revision: ev.Revision, Op: data, Undo: ev.Undo, Checksum: ev.Checksum}, true
+	case ot.EventAck:
+		return ackMessage(ev)
+	case ot.EventJoin, ot.EventLeave:
+		return Message{Type: TypePresence, Doc: ev.Doc, Clients: ev.Clients}, true
+	case ot.EventSelection:
+		cursor := ev.Selection
+		return Message{Type: TypeCursor, Doc: ev.Doc, Client: ev.Client, Revision: ev.Revision, Cursor: &cursor, Meta: ev.Meta}, true
+	case ot.EventMeta:
+		return Message{Type: TypeMeta, Doc: ev.Doc, Client: ev.Client, Revision: ev.Revision, Meta: ev.Meta}, true
+	case ot.EventMode:
+		return Message{Type: TypeMode, Doc: ev.Doc, Revision: ev.Revision, Mode: ev.Mode}, true
+	}
+	return Message{}, false
+}
+
+// ackMessage acknowledges the operation of ev to its sender, with the
+// operation applied if the server clipped it.
+func ackMessage(ev ot.Event) (Message, bool) {
+	msg := Message{Type: TypeAck, Doc: ev.Doc, Revision: ev.Revision, Checksum: ev.Checksum}
+	if ev.Clipped {
+		data, err := json.Marshal(ev.Op)
+		if err != nil {
+			return Message{}, false
+		}
+		msg.Op = data
+	}
+	return msg, true
+}
+
+func (s *session) sendMessage(msg Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		s.log.ErrorContext(s.ctx, "encoding message failed", "type", msg.Type, "err", err)
+		return
+	}
+	if err := s.conn.WriteText(data); err != nil {
+		s.log.DebugContext(s.ctx, "write failed", "err", err)
+		s.close(websocket.CloseInternalFailure)
+	}
+}
+
+// sendError rejects a message about doc, if any, and for an operation
+// echoes the ID the client gave it, so that a client with several documents
+// joined knows which pending operation to roll back.
+func (s *session) sendError(doc, id string, err error) {
+	s.log.DebugContext(s.ctx, "message rejected", "doc", doc, "err", err)
+	msg := Message{Type: TypeError, Doc: doc, ID: id, Error: err.Error()}
+	var limited *ot.RateLimitError
+	switch {
+	case errors.As(err, &limited):
+		msg.Code = CodeRateLimited
+		msg.RetryAfter = limited.RetryAfter.Milliseconds()
+	case errors.Is(err, ot.ErrForbidden):
+		msg.Code = CodeForbidden
+	case errors.Is(err, ot.ErrOpTooLarge), errors.Is(err, ot.ErrTooManyOps), errors.Is(err, ot.ErrLengthTooLarge):
+		msg.Code = CodeOpTooLarge
+	case errors.Is(err, ot.ErrDocumentTooLarge):
+		msg.Code = CodeDocumentTooLarge
+	case errors.Is(err, ot.ErrTooFarBehind):
+		msg.Code = CodeTooFarBehind
+	case errors.Is(err, ot.ErrReadOnly):
+		msg.Code = CodeReadOnly
+	case errors.Is(err, ot.ErrHubClosed):
+		msg.Code = CodeShuttingDown
+	}
+	s.sendMessage(msg)
+}
+
+func (s *session) close(code int) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	feeds := s.feeds
+	s.feeds = nil
+	s.mu.Unlock()
+
+	for _, f := range feeds {
+		f.sub.Close()
+	}
+	s.conn.Close(code) //nolint:errcheck // the peer may already be gone; there is no one to report to
+}
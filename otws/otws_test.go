@@ -0,0 +1,543 @@
+package otws
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ot "github.com/shiv248/operational-transformation-go"
+	"github.com/shiv248/operational-transformation-go/otws/internal/websocket"
+)
+
+type testClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, *ot.Hub) {
+	t.Helper()
+	hub := ot.NewHub(func(_ context.Context, doc string) (*ot.Server, error) {
+		return ot.NewServer("hello"), nil
+	})
+	srv := httptest.NewServer(NewHandler(hub))
+	t.Cleanup(srv.Close)
+	return srv, hub
+}
+
+func document(t *testing.T, hub *ot.Hub, doc string) string {
+	t.Helper()
+	server, err := hub.Server(context.Background(), doc)
+	if err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+	return server.Document()
+}
+
+func connect(t *testing.T, srv *httptest.Server) *testClient {
+	t.Helper()
+	conn, err := websocket.Dial("ws" + strings.TrimPrefix(srv.URL, "http"))
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := conn.Close(websocket.CloseNormal); err != nil {
+			t.Errorf("Close failed: %v", err)
+		}
+	})
+	return &testClient{t: t, conn: conn}
+}
+
+func (c *testClient) send(msg Message) {
+	c.t.Helper()
+	data, err := json.Marshal(msg)
+	if err != nil {
+		c.t.Fatalf("Marshal failed: %v", err)
+	}
+	if err := c.conn.WriteText(data); err != nil {
+		c.t.Fatalf("WriteText failed: %v", err)
+	}
+}
+
+// expect reads messages until one of the given type arrives.
+func (c *testClient) expect(typ string) Message {
+	c.t.Helper()
+	for {
+		data, err := c.conn.ReadMessage()
+		if err != nil {
+			c.t.Fatalf("waiting for %q: %v", typ, err)
+		}
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if msg.Type == typ {
+			return msg
+		}
+	}
+}
+
+func (c *testClient) join(doc string) Message {
+	c.t.Helper()
+	c.send(Message{Type: TypeJoin, Doc: doc})
+	return c.expect(TypeJoined)
+}
+
+func TestJoinAndEdit(t *testing.T) {
+	srv, hub := newTestServer(t)
+
+	alice := connect(t, srv)
+	joined := alice.join("notes")
+	if joined.Document != "hello" || joined.Revision != 0 || joined.Client == "" {
+		t.Fatalf("unexpected joined message: %+v", joined)
+	}
+
+	bob := connect(t, srv)
+	bob.join("notes")
+	// Alice first sees only their own join, then both clients.
+	if p := alice.expect(TypePresence); len(p.Clients) != 1 {
+		t.Errorf("expected 1 client in presence, got %v", p.Clients)
+	}
+	if p := alice.expect(TypePresence); len(p.Clients) != 2 {
+		t.Errorf("expected 2 clients in presence, got %v", p.Clients)
+	}
+
+	alice.send(Message{Type: TypeOp, Revision: 0, Op: json.RawMessage(`[5," world"]`)})
+	if ack := alice.expect(TypeAck); ack.Revision != 1 || ack.Op != nil {
+		t.Errorf("expected ack at revision 1 without op, got %+v", ack)
+	}
+
+	op := bob.expect(TypeOp)
+	if op.Client != joined.Client || op.Revision != 1 || string(op.Op) != `[5," world"]` {
+		t.Errorf("unexpected op message: %+v", op)
+	}
+
+	if got := document(t, hub, "notes"); got != "hello world" {
+		t.Errorf("expected %q, got %q", "hello world", got)
+	}
+}
+
+func TestConcurrentOpsAreTransformed(t *testing.T) {
+	srv, hub := newTestServer(t)
+
+	alice := connect(t, srv)
+	alice.join("notes")
+	bob := connect(t, srv)
+	bob.join("notes")
+
+	// Both edit revision 0; bob's op arrives second and must be rebased.
+	alice.send(Message{Type: TypeOp, Revision: 0, Op: json.RawMessage(`[5,"!"]`)})
+	alice.expect(TypeAck)
+	bob.send(Message{Type: TypeOp, Revision: 0, Op: json.RawMessage(`["*",5]`)})
+	bob.expect(TypeAck)
+
+	if got := document(t, hub, "notes"); got != "*hello!" {
+		t.Errorf("expected %q, got %q", "*hello!", got)
+	}
+
+	rebased := alice.expect(TypeOp)
+	if string(rebased.Op) != `["*",6]` {
+		t.Errorf("expected rebased op [\"*\",6], got %s", rebased.Op)
+	}
+}
+
+func TestCursorRelay(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	alice := connect(t, srv)
+	alice.join("notes")
+	bob := connect(t, srv)
+	bob.join("notes")
+
+	alice.send(Message{Type: TypeCursor, Revision: 0, Cursor: &Cursor{Anchor: 1, Head: 3}})
+	msg := bob.expect(TypeCursor)
+	if msg.Cursor == nil || *msg.Cursor != (Cursor{Anchor: 1, Head: 3}) {
+		t.Errorf("unexpected cursor message: %+v", msg)
+	}
+
+	// bob inserts before alice's selection, which moves with the text.
+	bob.send(Message{Type: TypeOp, Revision: 0, Op: json.RawMessage(`["ab",5]`)})
+	bob.expect(TypeAck)
+
+	carol := connect(t, srv)
+	joined := carol.join("notes")
+	if got := joined.Cursors[msg.Client]; got != (Cursor{Anchor: 3, Head: 5}) {
+		t.Errorf("expected alice's cursor at 3-5 on join, got %+v", joined.Cursors)
+	}
+
+	// A cursor made against an old revision is transformed before relaying.
+	alice.send(Message{Type: TypeCursor, Revision: 0, Cursor: &Cursor{Anchor: 0, Head: 0}})
+	msg = carol.expect(TypeCursor)
+	if msg.Revision != 1 || *msg.Cursor != (Cursor{Anchor: 2, Head: 2}) {
+		t.Errorf("expected cursor 2-2 at revision 1, got %+v at %d", msg.Cursor, msg.Revision)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	srv, _ := newTestServer(t)
+	c := connect(t, srv)
+
+	c.send(Message{Type: TypeOp, Op: json.RawMessage(`[5]`)})
+	if msg := c.expect(TypeError); !strings.Contains(msg.Error, "join") {
+		t.Errorf("expected join error, got %q", msg.Error)
+	}
+
+	c.join("notes")
+	c.send(Message{Type: TypeOp, Revision: 7, Op: json.RawMessage(`[5]`)})
+	if msg := c.expect(TypeError); msg.Error != ot.ErrInvalidRevision.Error() {
+		t.Errorf("expected invalid revision error, got %q", msg.Error)
+	}
+
+	c.send(Message{Type: TypeOp, Revision: 0, Op: json.RawMessage(`[true]`)})
+	if msg := c.expect(TypeError); !strings.Contains(msg.Error, "invalid operation") {
+		t.Errorf("expected invalid operation error, got %q", msg.Error)
+	}
+}
+
+func TestAwareness(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	alice := connect(t, srv)
+	alice.join("notes")
+	bob := connect(t, srv)
+	bob.join("notes")
+
+	alice.send(Message{Type: TypeMeta, Meta: map[string]any{"name": "Alice", "color": "#f00"}})
+	msg := bob.expect(TypeMeta)
+	if msg.Meta["name"] != "Alice" || msg.Meta["color"] != "#f00" {
+		t.Errorf("unexpected meta message: %+v", msg)
+	}
+
+	// Updates merge with what was sent before; null deletes.
+	alice.send(Message{Type: TypeMeta, Meta: map[string]any{"idle": true, "color": nil}})
+	msg = bob.expect(TypeMeta)
+	if msg.Meta["name"] != "Alice" || msg.Meta["idle"] != true || msg.Meta["color"] != nil {
+		t.Errorf("unexpected merged meta: %+v", msg.Meta)
+	}
+
+	// Cursor updates carry the metadata along.
+	alice.send(Message{Type: TypeCursor, Cursor: &Cursor{Anchor: 1, Head: 1}})
+	if msg := bob.expect(TypeCursor); msg.Meta["name"] != "Alice" {
+		t.Errorf("expected cursor with name, got %+v", msg)
+	}
+
+	carol := connect(t, srv)
+	joined := carol.join("notes")
+	if len(joined.Awareness) != 1 {
+		t.Errorf("expected one client's metadata on join, got %+v", joined.Awareness)
+	}
+}
+
+type readOnlyAuthorizer struct{}
+
+func (readOnlyAuthorizer) Authorize(context.Context, string, string, *ot.OperationSeq) error {
+	return nil
+}
+
+func (readOnlyAuthorizer) AuthorizeSubscribe(context.Context, string, string) (bool, error) {
+	return true, nil
+}
+
+func TestReadOnlyJoin(t *testing.T) {
+	srv, hub := newTestServer(t)
+	hub.Authorizer = readOnlyAuthorizer{}
+
+	c := connect(t, srv)
+	if joined := c.join("notes"); !joined.ReadOnly {
+		t.Errorf("expected read-only join, got %+v", joined)
+	}
+	c.send(Message{Type: TypeOp, Revision: 0, Op: json.RawMessage(`[5,"!"]`)})
+	if msg := c.expect(TypeError); msg.Code != CodeForbidden {
+		t.Errorf("expected forbidden error, got %+v", msg)
+	}
+	if got := document(t, hub, "notes"); got != "hello" {
+		t.Errorf("expected unchanged document, got %q", got)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	srv, hub := newTestServer(t)
+	hub.RateLimit = ot.RateLimit{OpsPerSecond: 0.1}
+
+	c := connect(t, srv)
+	c.join("notes")
+	c.send(Message{Type: TypeOp, Revision: 0, Op: json.RawMessage(`[5,"!"]`)})
+	c.expect(TypeAck)
+	c.send(Message{Type: TypeOp, Revision: 1, Op: json.RawMessage(`[6,"!"]`)})
+	msg := c.expect(TypeError)
+	if msg.Code != CodeRateLimited || msg.RetryAfter <= 0 {
+		t.Errorf("expected rate limited error with retryAfter, got %+v", msg)
+	}
+	if got := document(t, hub, "notes"); got != "hello!" {
+		t.Errorf("expected %q, got %q", "hello!", got)
+	}
+}
+
+func TestLimitCodes(t *testing.T) {
+	srv, hub := newTestServer(t)
+	c := connect(t, srv)
+	c.join("notes")
+	server, err := hub.Server(context.Background(), "notes")
+	if err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+	server.SetLimits(ot.ServerLimits{MaxDocLen: 8, MaxInsertLen: 3, MaxPending: 1})
+	tests := []struct {
+		revision int
+		op       string
+		code     string
+	}{
+		{0, `[5,"!!!!"]`, CodeOpTooLarge},
+		{0, `[5,"!!!"]`, ""},
+		{1, `[8,"?"]`, CodeDocumentTooLarge},
+		{1, `[1,-1,6]`, ""},
+		{0, `[-1,4]`, CodeTooFarBehind},
+	}
+	for _, tt := range tests {
+		c.send(Message{Type: TypeOp, Revision: tt.revision, Op: json.RawMessage(tt.op)})
+		if tt.code == "" {
+			c.expect(TypeAck)
+			continue
+		}
+		if msg := c.expect(TypeError); msg.Code != tt.code {
+			t.Errorf("%s: expected code %q, got %+v", tt.op, tt.code, msg)
+		}
+	}
+}
+
+func TestShutdownAndResume(t *testing.T) {
+	srv, hub := newTestServer(t)
+	hub.ResumeKey = []byte("cluster key")
+
+	alice := connect(t, srv)
+	joined := alice.join("notes")
+	bob := connect(t, srv)
+	bob.join("notes")
+
+	alice.send(Message{Type: TypeOp, Revision: 0, Op: json.RawMessage(`[5,"!"]`), ID: "1"})
+	alice.expect(TypeAck)
+	bob.send(Message{Type: TypeOp, Revision: 1, Op: json.RawMessage(`[6,"?"]`)})
+	bob.expect(TypeAck)
+
+	server, err := hub.Server(context.Background(), "notes")
+	if err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+	if err := hub.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	alice.expect(TypeShutdown)
+
+	// The next node takes over the document; sharing the Server stands in
+	// for sharing a Store, and keeps the record of alice's operation ID.
+	next := ot.NewHub(func(context.Context, string) (*ot.Server, error) { return server, nil })
+	next.ResumeKey = hub.ResumeKey
+	srv2 := httptest.NewServer(NewHandler(next))
+	t.Cleanup(srv2.Close)
+
+	c := connect(t, srv2)
+	c.send(Message{Type: TypeJoin, Doc: "notes", Resume: &ot.ResumeToken{Client: joined.Client, Secret: joined.Secret, Pending: "1"}})
+	resumed := c.expect(TypeJoined)
+	if resumed.Client != joined.Client || len(resumed.Ops) != 2 || resumed.Pending != 1 {
+		t.Errorf("expected resumed session with 2 ops and pending revision 1, got %+v", resumed)
+	}
+	if resumed.Document != "hello!?" || resumed.Revision != 2 {
+		t.Errorf("expected %q at revision 2, got %q at %d", "hello!?", resumed.Document, resumed.Revision)
+	}
+}
+
+func TestResumeForged(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	alice := connect(t, srv)
+	joined := alice.join("notes")
+	if joined.Secret == "" {
+		t.Fatal("expected joined to carry a resume secret")
+	}
+
+	// mallory sees alice's ID in presence, but not her secret.
+	mallory := connect(t, srv)
+	mallory.send(Message{Type: TypeJoin, Doc: "notes", Resume: &ot.ResumeToken{Client: joined.Client}})
+	if msg := mallory.expect(TypeError); msg.Code != CodeForbidden {
+		t.Errorf("expected code %q, got %+v", CodeForbidden, msg)
+	}
+	if msg := mallory.join("notes"); msg.Client == joined.Client {
+		t.Errorf("expected a fresh client ID, got alice's %q", msg.Client)
+	}
+}
+
+func TestMode(t *testing.T) {
+	srv, hub := newTestServer(t)
+	c := connect(t, srv)
+	c.join("notes")
+
+	if err := hub.SetMode(context.Background(), "notes", ot.ModeCommentOnly); err != nil {
+		t.Fatalf("SetMode failed: %v", err)
+	}
+	if msg := c.expect(TypeMode); msg.Mode != ot.ModeCommentOnly {
+		t.Errorf("expected commentOnly, got %+v", msg)
+	}
+	c.send(Message{Type: TypeOp, Revision: 0, Op: json.RawMessage(`[5,"!"]`)})
+	if msg := c.expect(TypeError); msg.Code != CodeReadOnly {
+		t.Errorf("expected code %q, got %+v", CodeReadOnly, msg)
+	}
+
+	other := connect(t, srv)
+	if joined := other.join("notes"); joined.Mode != ot.ModeCommentOnly {
+		t.Errorf("expected joined in commentOnly mode, got %+v", joined)
+	}
+}
+
+func TestRetriedOp(t *testing.T) {
+	srv, hub := newTestServer(t)
+	c := connect(t, srv)
+	c.join("notes")
+
+	for i := 0; i < 2; i++ {
+		c.send(Message{Type: TypeOp, Revision: 0, Op: json.RawMessage(`[5,"!"]`), ID: "1"})
+		if ack := c.expect(TypeAck); ack.Revision != 1 {
+			t.Errorf("expected ack at revision 1, got %d", ack.Revision)
+		}
+	}
+	if got := document(t, hub, "notes"); got != "hello!" {
+		t.Errorf("expected %q, got %q", "hello!", got)
+	}
+}
+
+func TestMultipleDocuments(t *testing.T) {
+	srv, hub := newTestServer(t)
+	c := connect(t, srv)
+	a := c.join("a")
+	b := c.join("b")
+	if a.Client != b.Client {
+		t.Errorf("expected one client ID per connection, got %q and %q", a.Client, b.Client)
+	}
+
+	c.send(Message{Type: TypeOp, Revision: 0, Op: json.RawMessage(`[5,"!"]`)})
+	if msg := c.expect(TypeError); !strings.Contains(msg.Error, "doc is required") {
+		t.Errorf("expected doc required error, got %+v", msg)
+	}
+
+	c.send(Message{Type: TypeOp, Doc: "b", Revision: 0, Op: json.RawMessage(`[5,"!"]`)})
+	if ack := c.expect(TypeAck); ack.Doc != "b" || ack.Revision != 1 {
+		t.Errorf("expected ack for b at revision 1, got %+v", ack)
+	}
+	c.send(Message{Type: TypeOp, Doc: "a", Revision: 0, Op: json.RawMessage(`["?",5]`)})
+	if ack := c.expect(TypeAck); ack.Doc != "a" || ack.Revision != 1 {
+		t.Errorf("expected ack for a at revision 1, got %+v", ack)
+	}
+	// A rejection names the document and operation it is about.
+	c.send(Message{Type: TypeOp, Doc: "a", Revision: 1, Op: json.RawMessage(`[99]`), ID: "7"})
+	if msg := c.expect(TypeError); msg.Doc != "a" || msg.ID != "7" {
+		t.Errorf("expected an error for op 7 on a, got %+v", msg)
+	}
+	if got := document(t, hub, "a"); got != "?hello" {
+		t.Errorf("expected %q, got %q", "?hello", got)
+	}
+	if got := document(t, hub, "b"); got != "hello!" {
+		t.Errorf("expected %q, got %q", "hello!", got)
+	}
+
+	c.send(Message{Type: TypeLeave, Doc: "a"})
+	if msg := c.expect(TypeLeft); msg.Doc != "a" {
+		t.Errorf("expected left a, got %+v", msg)
+	}
+	if subs := hub.Subscribers("a"); len(subs) != 0 {
+		t.Errorf("expected no subscribers to a, got %v", subs)
+	}
+
+	// With only b joined, doc may be left out again.
+	c.send(Message{Type: TypeOp, Revision: 1, Op: json.RawMessage(`[6,"!"]`)})
+	if ack := c.expect(TypeAck); ack.Doc != "b" || ack.Revision != 2 {
+		t.Errorf("expected ack for b at revision 2, got %+v", ack)
+	}
+	c.send(Message{Type: TypeJoin, Doc: "b"})
+	if msg := c.expect(TypeError); !strings.Contains(msg.Error, "already joined") {
+		t.Errorf("expected already joined error, got %+v", msg)
+	}
+}
+
+func TestUndo(t *testing.T) {
+	srv, hub := newTestServer(t)
+	alice := connect(t, srv)
+	alice.join("notes")
+	bob := connect(t, srv)
+	bob.join("notes")
+
+	alice.send(Message{Type: TypeOp, Revision: 0, Op: json.RawMessage(`[5,"!"]`)})
+	alice.expect(TypeAck)
+	alice.send(Message{Type: TypeUndo})
+	if op := alice.expect(TypeOp); !op.Undo || op.Revision != 2 || string(op.Op) != `[5,-1]` {
+		t.Errorf("expected undo op at revision 2, got %+v", op)
+	}
+	bob.expect(TypeOp)
+	if op := bob.expect(TypeOp); !op.Undo {
+		t.Errorf("expected undo op, got %+v", op)
+	}
+	if got := document(t, hub, "notes"); got != "hello" {
+		t.Errorf("expected %q, got %q", "hello", got)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	hub := ot.NewHub(func(_ context.Context, doc string) (*ot.Server, error) {
+		return ot.NewServer("hello"), nil
+	})
+	hub.ChecksumEvery = 1
+	srv := httptest.NewServer(NewHandler(hub))
+	t.Cleanup(srv.Close)
+
+	alice := connect(t, srv)
+	alice.join("notes")
+	bob := connect(t, srv)
+	bob.join("notes")
+
+	alice.send(Message{Type: TypeOp, Revision: 0, Op: json.RawMessage(`[5,"!"]`)})
+	sum := ot.ContentHash("hello!")
+	want := hex.EncodeToString(sum[:])
+	if ack := alice.expect(TypeAck); ack.Checksum != want {
+		t.Errorf("expected ack checksum %q, got %q", want, ack.Checksum)
+	}
+	if op := bob.expect(TypeOp); op.Checksum != want {
+		t.Errorf("expected op checksum %q, got %q", want, op.Checksum)
+	}
+}
+
+// TestClippedOp checks that the sender of an operation the server clipped
+// gets the operation applied with its ack, so that it converges with the
+// server and the other clients.
+func TestClippedOp(t *testing.T) {
+	hub := ot.NewHub(func(_ context.Context, doc string) (*ot.Server, error) {
+		s := ot.NewServer("hello world")
+		s.SetProtectPolicy(ot.ProtectClip)
+		return s, s.Protect("greeting", ot.Range{Start: 0, End: 5})
+	})
+	srv := httptest.NewServer(NewHandler(hub))
+	t.Cleanup(srv.Close)
+
+	alice := connect(t, srv)
+	alice.join("notes")
+	bob := connect(t, srv)
+	bob.join("notes")
+
+	alice.send(Message{Type: TypeOp, Revision: 0, Op: json.RawMessage(`[-11]`)})
+	ack := alice.expect(TypeAck)
+	op := bob.expect(TypeOp)
+	if string(ack.Op) != `[5,-6]` || string(op.Op) != `[5,-6]` {
+		t.Fatalf(`expected [5,-6] for both clients, got %s and %s`, ack.Op, op.Op)
+	}
+
+	applied := ot.NewOperationSeq()
+	if err := json.Unmarshal(ack.Op, applied); err != nil {
+		t.Fatal(err)
+	}
+	replica, err := applied.Apply("hello world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := document(t, hub, "notes"); replica != got {
+		t.Errorf("expected %q, got %q", got, replica)
+	}
+}
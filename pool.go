@@ -0,0 +1,53 @@
+package ot
+
+import (
+	"slices"
+	"sync"
+)
+
+// maxPooledComponents bounds the capacity of the sequences kept in the pool,
+// so one huge operation does not pin its memory for good.
+const maxPooledComponents = 1024
+
+var seqPool = sync.Pool{
+	New: func() any { return NewOperationSeq() },
+}
+
+// AcquireOperationSeq returns an empty operation sequence, reusing one
+// returned with Release if possible. Transform and Compose take their results
+// from the same pool, so releasing those too, once they are no longer needed,
+// lets a busy server transform without allocating new sequences each time.
+func AcquireOperationSeq() *OperationSeq {
+	o, ok := seqPool.Get().(*OperationSeq)
+	if !ok {
+		return NewOperationSeq()
+	}
+	return o
+}
+
+// grow makes room for n more components, so that building a sequence of a
+// known size allocates once.
+func (o *OperationSeq) grow(n int) {
+	o.ops = slices.Grow(o.ops, n)
+}
+
+// Release empties o and returns it to the pool for AcquireOperationSeq. o must
+// not be used afterwards, including through other references to it; only
+// release sequences that were never shared, for instance through a Server's
+// history or a Hub event.
+func (o *OperationSeq) Release() {
+	if cap(o.ops) > maxPooledComponents {
+		return
+	}
+	o.reset()
+	seqPool.Put(o)
+}
+
+// reset empties o, keeping its capacity.
+func (o *OperationSeq) reset() {
+	clear(o.ops) // drop references to inserted text
+	o.ops = o.ops[:0]
+	o.baseLen = 0
+	o.targetLen = 0
+	o.meta = nil
+}
@@ -0,0 +1,50 @@
+package ot
+
+import "testing"
+
+func TestAcquireRelease(t *testing.T) {
+	o := AcquireOperationSeq()
+	o.Retain(3)
+	o.Insert("abc")
+	o.Release()
+
+	for i := 0; i < 10; i++ {
+		o := AcquireOperationSeq()
+		if len(o.ops) != 0 || o.baseLen != 0 || o.targetLen != 0 {
+			t.Fatalf("expected an empty sequence, got %+v", o)
+		}
+		o.Release()
+	}
+}
+
+func TestTransformAfterRelease(t *testing.T) {
+	a, b := benchmarkEdits()
+	want, _, err := a.Transform(b)
+	if err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		aPrime, bPrime, err := a.Transform(b)
+		if err != nil {
+			t.Fatalf("Transform failed: %v", err)
+		}
+		if len(aPrime.ops) != len(want.ops) || aPrime.targetLen != want.targetLen {
+			t.Fatalf("expected %v, got %v", want.ops, aPrime.ops)
+		}
+		aPrime.Release()
+		bPrime.Release()
+	}
+}
+
+func BenchmarkTransformRelease(b *testing.B) {
+	x, y := benchmarkEdits()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		xPrime, yPrime, err := x.Transform(y)
+		if err != nil {
+			b.Fatal(err)
+		}
+		xPrime.Release()
+		yPrime.Release()
+	}
+}
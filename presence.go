@@ -0,0 +1,181 @@
+package ot
+
+import (
+	"sync"
+	"time"
+)
+
+// Selection is a cursor or selection in character offsets. A caret has
+// Anchor == Head; Head is where the cursor is drawn.
+type Selection struct {
+	Anchor int `json:"anchor"`
+	Head   int `json:"head"`
+}
+
+// TransformIndex returns where a character offset in the document before o
+// ends up in the document after it. Text inserted at the offset pushes it
+// forward, and an offset inside deleted text moves to the start of the
+// deletion.
+func (o *OperationSeq) TransformIndex(index int) int {
+	return o.transformIndex(index, true)
+}
+
+// transformIndex implements TransformIndex. If after is false, text inserted
+// at the offset is left after it instead.
+func (o *OperationSeq) transformIndex(index int, after bool) int {
+	newIndex := index
+	remaining := index
+	for _, v := range o.ops {
+		switch v.Kind {
+		case KindRetain:
+			remaining -= int(v.N)
+		case KindInsert:
+			if remaining == 0 && !after {
+				return newIndex
+			}
+			newIndex += int(v.N)
+		case KindDelete:
+			newIndex -= min(remaining, int(v.N))
+			remaining -= int(v.N)
+		}
+		if remaining < 0 {
+			break
+		}
+	}
+	return newIndex
+}
+
+// Transform returns the selection moved through o.
+func (s Selection) Transform(o *OperationSeq) Selection {
+	return Selection{Anchor: o.TransformIndex(s.Anchor), Head: o.TransformIndex(s.Head)}
+}
+
+// Presence tracks the selections of the clients editing a document and keeps
+// them in step with it: every accepted operation must be passed to
+// Transform, so that remote cursors do not drift.
+//
+// Alongside its selection, each client can have arbitrary metadata such as a
+// display name, a color, or whether it is idle. Metadata is merged key by key
+// with last-write-wins semantics, so clients can update one field without
+// resending the rest.
+//
+// The zero value is ready to use. A Presence is safe for concurrent use.
+type Presence struct {
+	mu         sync.Mutex
+	selections map[string]Selection
+	meta       map[string]map[string]metaValue
+}
+
+type metaValue struct {
+	value any
+	at    time.Time
+}
+
+// Set records client's selection, which must refer to the current document.
+func (p *Presence) Set(client string, sel Selection) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.selections == nil {
+		p.selections = make(map[string]Selection)
+	}
+	p.selections[client] = sel
+}
+
+// Get returns client's selection.
+func (p *Presence) Get(client string) (Selection, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sel, ok := p.selections[client]
+	return sel, ok
+}
+
+// Merge updates client's metadata with the keys in meta, written at time at.
+// A key is only overwritten by a write at least as recent as the one that set
+// it, so updates that arrive out of order settle on the latest. A nil value
+// deletes the key.
+func (p *Presence) Merge(client string, meta map[string]any, at time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.meta == nil {
+		p.meta = make(map[string]map[string]metaValue)
+	}
+	fields := p.meta[client]
+	if fields == nil {
+		fields = make(map[string]metaValue)
+		p.meta[client] = fields
+	}
+	for key, value := range meta {
+		if old, ok := fields[key]; ok && at.Before(old.at) {
+			continue
+		}
+		fields[key] = metaValue{value: value, at: at}
+	}
+}
+
+// Meta returns a copy of client's metadata, or nil if it has none.
+func (p *Presence) Meta(client string) map[string]any {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.metaLocked(client)
+}
+
+func (p *Presence) metaLocked(client string) map[string]any {
+	var meta map[string]any
+	for key, v := range p.meta[client] {
+		if v.value == nil {
+			continue // deleted, kept only for its timestamp
+		}
+		if meta == nil {
+			meta = make(map[string]any)
+		}
+		meta[key] = v.value
+	}
+	return meta
+}
+
+// Remove forgets client's selection and metadata.
+func (p *Presence) Remove(client string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.selections, client)
+	delete(p.meta, client)
+}
+
+// Transform moves every selection through an accepted operation.
+func (p *Presence) Transform(op *OperationSeq) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for client, sel := range p.selections {
+		p.selections[client] = sel.Transform(op)
+	}
+}
+
+// State returns a copy of every client's selection, suitable for sending to
+// a client that has just joined.
+func (p *Presence) State() map[string]Selection {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state := make(map[string]Selection, len(p.selections))
+	for client, sel := range p.selections {
+		state[client] = sel
+	}
+	return state
+}
+
+// MetaState returns a copy of every client's metadata.
+func (p *Presence) MetaState() map[string]map[string]any {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state := make(map[string]map[string]any, len(p.meta))
+	for client := range p.meta {
+		if meta := p.metaLocked(client); meta != nil {
+			state[client] = meta
+		}
+	}
+	return state
+}
@@ -0,0 +1,219 @@
+package ot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTransformIndex(t *testing.T) {
+	// "hello world" -> "hello, big world": insert ", big" at 5, delete " " at 5.
+	op := NewOperationSeq()
+	op.Retain(5)
+	op.Insert(", big")
+	op.Delete(1)
+	op.Retain(5)
+
+	tests := []struct {
+		index, want int
+	}{
+		{0, 0},
+		{4, 4},
+		{5, 10}, // inserts at the cursor push it forward
+		{6, 10}, // the end of the deleted space
+		{7, 11},
+		{11, 15},
+	}
+	for _, tt := range tests {
+		if got := op.TransformIndex(tt.index); got != tt.want {
+			t.Errorf("TransformIndex(%d): expected %d, got %d", tt.index, tt.want, got)
+		}
+	}
+
+	del := NewOperationSeq()
+	del.Retain(2)
+	del.Delete(5)
+	del.Retain(1)
+	for index, want := range []int{0, 1, 2, 2, 2, 2, 2, 2, 3} {
+		if got := del.TransformIndex(index); got != want {
+			t.Errorf("delete: TransformIndex(%d): expected %d, got %d", index, want, got)
+		}
+	}
+}
+
+func TestPresence(t *testing.T) {
+	var p Presence
+	p.Set("alice", Selection{Anchor: 1, Head: 4})
+	p.Set("bob", Selection{Anchor: 6, Head: 6})
+
+	op := NewOperationSeq()
+	op.Insert(">>")
+	op.Retain(3)
+	op.Delete(2)
+	op.Retain(3)
+	p.Transform(op)
+
+	if sel, _ := p.Get("alice"); sel != (Selection{Anchor: 3, Head: 5}) {
+		t.Errorf("alice: expected {3 5}, got %+v", sel)
+	}
+	if sel, _ := p.Get("bob"); sel != (Selection{Anchor: 6, Head: 6}) {
+		t.Errorf("bob: expected {6 6}, got %+v", sel)
+	}
+
+	state := p.State()
+	p.Remove("bob")
+	if len(state) != 2 {
+		t.Errorf("expected State to be a copy, got %v", state)
+	}
+	if _, ok := p.Get("bob"); ok {
+		t.Error("expected bob to be removed")
+	}
+}
+
+func TestHubSelections(t *testing.T) {
+	h := NewHub(func(context.Context, string) (*Server, error) { return NewServer("hello"), nil })
+
+	alice, err := h.Subscribe(context.Background(), "doc", "alice")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	bob, err := h.Subscribe(context.Background(), "doc", "bob")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	<-alice.C // alice joined
+	<-alice.C // bob joined
+	<-bob.C   // bob joined
+
+	if err := h.SetSelection(context.Background(), "doc", "alice", 0, Selection{Anchor: 5, Head: 5}); err != nil {
+		t.Fatalf("SetSelection failed: %v", err)
+	}
+	if ev := <-bob.C; ev.Kind != EventSelection || ev.Client != "alice" || ev.Selection.Head != 5 {
+		t.Errorf("expected alice's selection, got %+v", ev)
+	}
+
+	op := NewOperationSeq()
+	op.Insert("oh, ")
+	op.Retain(5)
+	if _, _, err := h.Submit(context.Background(), "doc", "bob", 0, op); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	selections, rev, err := h.Selections(context.Background(), "doc")
+	if err != nil {
+		t.Fatalf("Selections failed: %v", err)
+	}
+	if rev != 1 || selections["alice"] != (Selection{Anchor: 9, Head: 9}) {
+		t.Errorf("expected alice at 9 at revision 1, got %v at %d", selections, rev)
+	}
+
+	// A selection made against revision 0 is transformed to revision 1.
+	if err := h.SetSelection(context.Background(), "doc", "bob", 0, Selection{Anchor: 0, Head: 5}); err != nil {
+		t.Fatalf("SetSelection failed: %v", err)
+	}
+	if sel := mustSelections(t, h)["bob"]; sel != (Selection{Anchor: 4, Head: 9}) {
+		t.Errorf("expected bob at 4-9, got %+v", sel)
+	}
+	if err := h.SetSelection(context.Background(), "doc", "bob", 7, Selection{}); !errors.Is(err, ErrInvalidRevision) {
+		t.Errorf("expected ErrInvalidRevision, got %v", err)
+	}
+
+	carol, err := h.Subscribe(context.Background(), "doc", "carol")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if len(carol.Selections) != 2 {
+		t.Errorf("expected carol to start with 2 selections, got %v", carol.Selections)
+	}
+
+	alice.Close()
+	if _, ok := mustSelections(t, h)["alice"]; ok {
+		t.Error("expected alice's selection to expire with the subscription")
+	}
+}
+
+func mustSelections(t *testing.T, h *Hub) map[string]Selection {
+	t.Helper()
+	selections, _, err := h.Selections(context.Background(), "doc")
+	if err != nil {
+		t.Fatalf("Selections failed: %v", err)
+	}
+	return selections
+}
+
+func TestPresenceMerge(t *testing.T) {
+	var p Presence
+	t0 := time.Unix(100, 0)
+
+	p.Merge("alice", map[string]any{"name": "Alice", "color": "red"}, t0)
+	p.Merge("alice", map[string]any{"color": "blue"}, t0.Add(time.Second))
+	// A stale write that arrives late loses.
+	p.Merge("alice", map[string]any{"color": "green", "idle": true}, t0.Add(-time.Second))
+
+	meta := p.Meta("alice")
+	if meta["name"] != "Alice" || meta["color"] != "blue" || meta["idle"] != true {
+		t.Errorf("unexpected merge result: %v", meta)
+	}
+
+	p.Merge("alice", map[string]any{"name": nil}, t0.Add(2*time.Second))
+	if _, ok := p.Meta("alice")["name"]; ok {
+		t.Error("expected nil to delete the key")
+	}
+	// The deletion wins over an older write.
+	p.Merge("alice", map[string]any{"name": "Old"}, t0.Add(time.Second))
+	if _, ok := p.Meta("alice")["name"]; ok {
+		t.Error("expected the deletion to win over an older write")
+	}
+
+	if state := p.MetaState(); len(state) != 1 {
+		t.Errorf("expected metadata for 1 client, got %v", state)
+	}
+	p.Remove("alice")
+	if p.Meta("alice") != nil {
+		t.Error("expected metadata to be removed")
+	}
+}
+
+func TestHubMeta(t *testing.T) {
+	var h Hub
+
+	alice, err := h.Subscribe(context.Background(), "doc", "alice")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	<-alice.C
+	if err := h.SetMeta(context.Background(), "doc", "alice", map[string]any{"name": "Alice"}); err != nil {
+		t.Fatalf("SetMeta failed: %v", err)
+	}
+	select {
+	case ev := <-alice.C:
+		t.Errorf("expected no echo to the sender, got %+v", ev)
+	default:
+	}
+
+	bob, err := h.Subscribe(context.Background(), "doc", "bob")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if bob.Meta["alice"]["name"] != "Alice" {
+		t.Errorf("expected alice's metadata on subscribe, got %v", bob.Meta)
+	}
+	<-bob.C
+
+	if err := h.SetMeta(context.Background(), "doc", "alice", map[string]any{"idle": true}); err != nil {
+		t.Fatalf("SetMeta failed: %v", err)
+	}
+	if ev := <-bob.C; ev.Kind != EventMeta || ev.Meta["name"] != "Alice" || ev.Meta["idle"] != true {
+		t.Errorf("expected merged metadata, got %+v", ev)
+	}
+
+	alice.Close()
+	carol, err := h.Subscribe(context.Background(), "doc", "carol")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if len(carol.Meta) != 0 {
+		t.Errorf("expected alice's metadata to expire, got %v", carol.Meta)
+	}
+}
@@ -0,0 +1,81 @@
+package ot
+
+// ChangeReport describes what an operation does to a document, as returned
+// by Preview.
+type ChangeReport struct {
+	// Deletions are the runs of characters the operation removes, at their
+	// positions in the original document.
+	Deletions []ChangeSpan `json:"deletions"`
+
+	// Insertions are the runs of characters the operation adds, at their
+	// positions in the resulting document.
+	Insertions []ChangeSpan `json:"insertions"`
+
+	// Deleted and Inserted are the total number of characters removed and
+	// added.
+	Deleted  int `json:"deleted"`
+	Inserted int `json:"inserted"`
+
+	// BaseLen and TargetLen are the lengths of the document before and after
+	// the operation, in characters.
+	BaseLen   int `json:"baseLen"`
+	TargetLen int `json:"targetLen"`
+}
+
+// ChangeSpan is a run of characters an operation inserts or deletes.
+type ChangeSpan struct {
+	// Pos is the character offset of the run.
+	Pos int `json:"pos"`
+
+	// Len is the length of the run in characters.
+	Len int `json:"len"`
+
+	// Text is the inserted or deleted text.
+	Text string `json:"text"`
+}
+
+// Preview checks that o applies to s and reports what it would change,
+// without building the resulting document. It fails exactly when Apply
+// would, so it can validate an operation before it is committed, and the
+// report can drive a summary of the change in a UI.
+//
+// Returns a *LengthMismatchError if the operation's base length doesn't match
+// the string length, or if its components retain or delete past the end of
+// the string or stop short of it.
+func (o *OperationSeq) Preview(s string) (ChangeReport, error) {
+	n := charCount(s)
+	if n != o.baseLen {
+		return ChangeReport{}, lengthMismatch(o.baseLen, n)
+	}
+
+	report := ChangeReport{BaseLen: o.baseLen, TargetLen: o.targetLen}
+	pos := 0 // byte offset in s
+	at := 0  // character offset in s
+	out := 0 // character offset in the result
+	for i, v := range o.ops {
+		if v.Kind != KindInsert && uint64(n-at) < v.N {
+			return ChangeReport{}, &LengthMismatchError{Want: at + int(v.N), Got: n, OpIndex: i}
+		}
+		switch v.Kind {
+		case KindRetain:
+			pos = skipRunes(s, pos, v.N)
+			at += int(v.N)
+			out += int(v.N)
+		case KindDelete:
+			end := skipRunes(s, pos, v.N)
+			report.Deletions = append(report.Deletions, ChangeSpan{Pos: at, Len: int(v.N), Text: s[pos:end]})
+			report.Deleted += int(v.N)
+			pos = end
+			at += int(v.N)
+		case KindInsert:
+			report.Insertions = append(report.Insertions, ChangeSpan{Pos: out, Len: int(v.N), Text: v.Text})
+			report.Inserted += int(v.N)
+			out += int(v.N)
+		}
+	}
+	if at != n {
+		return ChangeReport{}, &LengthMismatchError{Want: at, Got: n, OpIndex: len(o.ops)}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,77 @@
+package ot
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestPreview(t *testing.T) {
+	op := NewOperationSeq()
+	op.Retain(2)
+	op.Insert("XY")
+	op.Delete(3)
+	op.Retain(1)
+	op.Delete(1)
+	op.Insert("é")
+
+	report, err := op.Preview("ab€deéf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ChangeReport{
+		Deletions:  []ChangeSpan{{Pos: 2, Len: 3, Text: "€de"}, {Pos: 6, Len: 1, Text: "f"}},
+		Insertions: []ChangeSpan{{Pos: 2, Len: 2, Text: "XY"}, {Pos: 5, Len: 1, Text: "é"}},
+		Deleted:    4,
+		Inserted:   3,
+		BaseLen:    7,
+		TargetLen:  6,
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("expected %+v, got %+v", want, report)
+	}
+
+	result, err := op.Apply("ab€deéf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ins := range report.Insertions {
+		if got := string([]rune(result)[ins.Pos : ins.Pos+ins.Len]); got != ins.Text {
+			t.Errorf("expected %q at %d of the result, got %q", ins.Text, ins.Pos, got)
+		}
+	}
+}
+
+func TestPreviewMatchesApply(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		s := RandomString(r, 20)
+		op := RandomOp(r, charCount(s), RandomOptions{})
+		report, err := op.Preview(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := op.Apply(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := charCount(s) - report.Deleted + report.Inserted; got != charCount(result) {
+			t.Fatalf("expected the report of %v on %q to give %d characters, got %d", op, s, charCount(result), got)
+		}
+	}
+
+	bad := []*OperationSeq{
+		{ops: []Component{{Kind: KindRetain, N: 2}, {Kind: KindRetain, N: 4}}, baseLen: 3, targetLen: 3},
+		{ops: []Component{{Kind: KindRetain, N: 1}}, baseLen: 3, targetLen: 3},
+		{ops: []Component{{Kind: KindRetain, N: 4}}, baseLen: 4, targetLen: 4},
+	}
+	for i, op := range bad {
+		_, applyErr := op.Apply("abc")
+		_, err := op.Preview("abc")
+		var mismatch *LengthMismatchError
+		if !errors.As(err, &mismatch) || err.Error() != applyErr.Error() {
+			t.Errorf("test %d: expected %v, got %v", i, applyErr, err)
+		}
+	}
+}
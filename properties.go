@@ -0,0 +1,109 @@
+package ot
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTP2Violated is returned by CheckTP2 for operations that diverge.
+var ErrTP2Violated = errors.New("TP2 violated")
+
+// Property is a transformation property.
+type Property string
+
+const (
+	// TP1: for concurrent A and B, applying A then B' gives the same
+	// document as applying B then A'. Two sites that exchange operations
+	// converge; a central server ordering all operations, like Server,
+	// needs nothing more.
+	TP1 Property = "TP1"
+
+	// TP2: transforming C past A then B' gives the same operation as
+	// transforming it past B then A'. Without a central server, peers that
+	// receive three or more concurrent operations in different orders only
+	// converge if it holds.
+	TP2 Property = "TP2"
+)
+
+// Guarantee states whether Transform has a property under a tie-breaking
+// policy for concurrent inserts at the same position.
+type Guarantee struct {
+	TieBreak string   `json:"tieBreak"`
+	Property Property `json:"property"`
+	Holds    bool     `json:"holds"`
+	Detail   string   `json:"detail"`
+}
+
+// Guarantees reports which properties Transform has. It has one
+// tie-breaking policy, "text": of two inserts at the same position the one
+// with the lesser text goes first, and equal texts are both kept.
+//
+// TP2 does not hold, so a peer-to-peer topology must order operations some
+// other way, for example through one Server, or check its assumptions with
+// CheckTP2.
+func Guarantees() []Guarantee {
+	return []Guarantee{
+		{
+			TieBreak: "text",
+			Property: TP1,
+			Holds:    true,
+			Detail:   "for all concurrent operations",
+		},
+		{
+			TieBreak: "text",
+			Property: TP2,
+			Holds:    false,
+			Detail:   "fails when an insert is next to text a concurrent operation deletes; no counterexample is known for operations that only insert",
+		},
+	}
+}
+
+// CheckTP2 checks TP2 for concurrent operations a, b, and c on s: c
+// transformed past a and then b' must make the same change as c transformed
+// past b and then a'. It returns an error wrapping ErrTP2Violated with the
+// two results if not, and any error from transforming or applying the
+// operations.
+func CheckTP2(s string, a, b, c *OperationSeq) error {
+	bPrime, err := b.TransformAgainst(a)
+	if err != nil {
+		return err
+	}
+	aPrime, err := a.TransformAgainst(b)
+	if err != nil {
+		return err
+	}
+	viaA, err := transformSeq(c, a, bPrime)
+	if err != nil {
+		return err
+	}
+	viaB, err := transformSeq(c, b, aPrime)
+	if err != nil {
+		return err
+	}
+
+	doc, err := ReplayLog(s, []*OperationSeq{a, bPrime})
+	if err != nil {
+		return err
+	}
+	docA, err := viaA.Apply(doc)
+	if err != nil {
+		return err
+	}
+	docB, err := viaB.Apply(doc)
+	if err != nil {
+		return err
+	}
+	if docA != docB {
+		return fmt.Errorf("%w: c past a then b' gives %q, past b then a' gives %q", ErrTP2Violated, docA, docB)
+	}
+	return nil
+}
+
+// transformSeq transforms op past first and then second.
+func transformSeq(op, first, second *OperationSeq) (*OperationSeq, error) {
+	op, err := op.TransformAgainst(first)
+	if err != nil {
+		return nil, err
+	}
+	return op.TransformAgainst(second)
+}
@@ -0,0 +1,53 @@
+package ot
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestCheckTP2(t *testing.T) {
+	// Inserting before text another peer deletes while a third appends.
+	a, b, c := NewOperationSeq(), NewOperationSeq(), NewOperationSeq()
+	a.Insert("yxx")
+	a.Retain(3)
+	b.Delete(3)
+	c.Retain(3)
+	c.Insert("x")
+	if err := CheckTP2("btt", a, b, c); !errors.Is(err, ErrTP2Violated) {
+		t.Errorf("expected ErrTP2Violated, got %v", err)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		s := RandomString(r, 1+r.Intn(10))
+		n := charCount(s)
+		ops := make([]*OperationSeq, 3)
+		for j := range ops {
+			pos := r.Intn(n + 1)
+			ops[j] = NewOperationSeq()
+			ops[j].Retain(uint64(pos))
+			ops[j].Insert(RandomString(r, 1+r.Intn(2)))
+			ops[j].Retain(uint64(n - pos))
+		}
+		if err := CheckTP2(s, ops[0], ops[1], ops[2]); err != nil {
+			t.Fatalf("expected TP2 for inserts %v on %q, got %v", ops, s, err)
+		}
+	}
+
+	short := NewOperationSeq()
+	short.Retain(1)
+	if err := CheckTP2("btt", a, short, c); !errors.Is(err, ErrIncompatibleLengths) {
+		t.Errorf("expected ErrIncompatibleLengths, got %v", err)
+	}
+}
+
+func TestGuarantees(t *testing.T) {
+	holds := make(map[Property]bool)
+	for _, g := range Guarantees() {
+		holds[g.Property] = g.Holds
+	}
+	if !holds[TP1] || holds[TP2] {
+		t.Errorf("expected TP1 to hold and TP2 not to, got %v", holds)
+	}
+}
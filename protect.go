@@ -0,0 +1,146 @@
+package ot
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrProtected is returned when an operation would change a protected range
+// of the document and the server's ProtectPolicy is ProtectReject.
+var ErrProtected = errors.New("range is protected")
+
+// ProtectPolicy decides what a Server does with an operation that changes a
+// protected range. The zero value, ProtectReject, refuses it.
+type ProtectPolicy int
+
+const (
+	// ProtectReject refuses the whole operation with ErrProtected.
+	ProtectReject ProtectPolicy = iota
+
+	// ProtectClip applies the operation without the parts that change
+	// protected text: deletions inside a range are kept as retains and
+	// inserts inside one are dropped. The client learns of the difference
+	// from the operation returned to it.
+	ProtectClip
+)
+
+// Protect protects r, a range of the current document, under id, so that
+// operations may not insert into it or delete from it, such as a locked
+// header or a template region. Text may still be inserted at either edge,
+// outside the range. Protected ranges move with the document as it changes,
+// and are kept in memory only. Protecting an id again replaces its range.
+//
+// Operations from Sync were accepted elsewhere and are always applied.
+func (s *Server) Protect(id string, r Range) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Start < 0 || r.End < r.Start || r.End > charCount(s.doc) {
+		return fmt.Errorf("invalid range [%d, %d) for document of length %d", r.Start, r.End, charCount(s.doc))
+	}
+	s.protected.Set(id, r)
+	return nil
+}
+
+// Unprotect removes the protected range id.
+func (s *Server) Unprotect(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.protected.Delete(id)
+}
+
+// ProtectedRanges returns the protected ranges by id, in the current
+// document.
+func (s *Server) ProtectedRanges() map[string]Range {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.protected.Ranges()
+}
+
+// SetProtectPolicy sets what happens to operations that change a protected
+// range from now on.
+func (s *Server) SetProtectPolicy(p ProtectPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.protectPolicy = p
+}
+
+// checkProtected returns op, which applies to the current document, with
+// the changes it makes to protected ranges clipped, or ErrProtected if the
+// policy refuses them. Callers hold s.mu.
+func (s *Server) checkProtected(op *OperationSeq) (*OperationSeq, error) {
+	ranges := s.protectedSpans()
+	if len(ranges) == 0 {
+		return op, nil
+	}
+
+	out := WithCapacity(len(op.ops))
+	out.meta = op.meta
+	clipped := false
+	pos, i := 0, 0 // offset in the document, and first range not behind it
+	for _, c := range op.ops {
+		switch c.Kind {
+		case KindRetain:
+			out.Retain(c.N)
+			pos += int(c.N)
+		case KindInsert:
+			for i < len(ranges) && ranges[i].End <= pos {
+				i++
+			}
+			if i < len(ranges) && ranges[i].Start < pos {
+				clipped = true
+				continue
+			}
+			out.insert(c.Text, c.N)
+		case KindDelete:
+			for end := pos + int(c.N); pos < end; {
+				for i < len(ranges) && ranges[i].End <= pos {
+					i++
+				}
+				switch {
+				case i < len(ranges) && ranges[i].Start <= pos:
+					n := min(ranges[i].End, end) - pos
+					out.Retain(uint64(n))
+					pos += n
+					clipped = true
+				case i < len(ranges):
+					n := min(ranges[i].Start, end) - pos
+					out.Delete(uint64(n))
+					pos += n
+				default:
+					out.Delete(uint64(end - pos))
+					pos = end
+				}
+			}
+		}
+	}
+	if clipped && s.protectPolicy == ProtectReject {
+		return nil, ErrProtected
+	}
+	if !clipped {
+		return op, nil
+	}
+	return out, nil
+}
+
+// protectedSpans returns the non-empty protected ranges in document order,
+// overlapping ones merged. Callers hold s.mu.
+func (s *Server) protectedSpans() []Range {
+	var spans []Range
+	for _, r := range s.protected.ranges {
+		if r.Start < r.End {
+			spans = append(spans, r)
+		}
+	}
+	slices.SortFunc(spans, func(a, b Range) int { return a.Start - b.Start })
+	merged := spans[:0]
+	for _, r := range spans {
+		if n := len(merged); n > 0 && r.Start <= merged[n-1].End {
+			merged[n-1].End = max(merged[n-1].End, r.End)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
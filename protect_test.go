@@ -0,0 +1,80 @@
+package ot
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestServerProtectReject(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer("HEADER body")
+	if err := s.Protect("header", Range{0, 6}); err != nil {
+		t.Fatalf("Protect failed: %v", err)
+	}
+
+	inside := NewOperationSeq()
+	inside.Retain(3)
+	inside.Insert("x")
+	inside.Retain(8)
+	if _, err := s.ReceiveOperation(ctx, 0, inside); !errors.Is(err, ErrProtected) {
+		t.Errorf("expected ErrProtected for an insert inside, got %v", err)
+	}
+	overlap := NewOperationSeq()
+	overlap.Retain(5)
+	overlap.Delete(3)
+	overlap.Retain(3)
+	if _, err := s.ReceiveOperation(ctx, 0, overlap); !errors.Is(err, ErrProtected) {
+		t.Errorf("expected ErrProtected for a delete overlapping, got %v", err)
+	}
+
+	// Inserting at the edge is allowed and moves the range along.
+	before := NewOperationSeq()
+	before.Insert("> ")
+	before.Retain(11)
+	if _, err := s.ReceiveOperation(ctx, 0, before); err != nil {
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+	if got := s.ProtectedRanges()["header"]; got != (Range{2, 8}) {
+		t.Errorf("expected the range moved to {2 8}, got %v", got)
+	}
+
+	s.Unprotect("header")
+	if _, err := s.ReceiveOperation(ctx, 0, inside); err != nil {
+		t.Errorf("expected the insert accepted once unprotected, got %v", err)
+	}
+	if err := s.Protect("bad", Range{5, 100}); err == nil {
+		t.Error("expected an error for a range past the end")
+	}
+}
+
+func TestServerProtectClip(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer("HEADER body FOOTER")
+	s.SetProtectPolicy(ProtectClip)
+	if err := s.Protect("header", Range{0, 6}); err != nil {
+		t.Fatalf("Protect failed: %v", err)
+	}
+	if err := s.Protect("footer", Range{12, 18}); err != nil {
+		t.Fatalf("Protect failed: %v", err)
+	}
+
+	// Delete everything and insert inside the header.
+	op := NewOperationSeq()
+	op.Retain(2)
+	op.Insert("x")
+	op.Delete(16)
+	applied, err := s.ReceiveOperation(ctx, 0, op)
+	if err != nil {
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+	if got := s.Document(); got != "HEADERFOOTER" {
+		t.Errorf("expected %q, got %q", "HEADERFOOTER", got)
+	}
+	if got, err := applied.Apply("HEADER body FOOTER"); err != nil || got != "HEADERFOOTER" {
+		t.Errorf("expected the applied operation clipped, got %q (%v)", got, err)
+	}
+	if got := s.ProtectedRanges(); got["header"] != (Range{0, 6}) || got["footer"] != (Range{6, 12}) {
+		t.Errorf("expected the ranges at {0 6} and {6 12}, got %v", got)
+	}
+}
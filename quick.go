@@ -0,0 +1,46 @@
+package ot
+
+import (
+	"math/rand"
+	"reflect"
+	"testing/quick"
+)
+
+// Generate implements quick.Generator, returning RandomOp(r, size,
+// RandomOptions{}). testing/quick passes every argument of a call the same
+// size, so operations generated together are concurrent edits of one
+// document of size characters. QuickConcurrent and QuickSequential choose
+// the length instead.
+func (*OperationSeq) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(RandomOp(r, size, RandomOptions{}))
+}
+
+// QuickConcurrent returns a quick.Config for properties of concurrent
+// operations, such as TP1: every argument is a RandomOp on the same
+// document of baseLen characters. The property must take only
+// *OperationSeq arguments.
+func QuickConcurrent(baseLen int) *quick.Config {
+	return &quick.Config{Values: quickValues(baseLen, false)}
+}
+
+// QuickSequential returns a quick.Config for properties of operations
+// applied one after another, such as compose associativity or inversion:
+// the first argument is a RandomOp on a document of baseLen characters, and
+// each later one applies to the result of the one before. The property must
+// take only *OperationSeq arguments.
+func QuickSequential(baseLen int) *quick.Config {
+	return &quick.Config{Values: quickValues(baseLen, true)}
+}
+
+func quickValues(baseLen int, sequential bool) func([]reflect.Value, *rand.Rand) {
+	return func(args []reflect.Value, r *rand.Rand) {
+		n := baseLen
+		for i := range args {
+			op := RandomOp(r, n, RandomOptions{})
+			if sequential {
+				n = op.TargetLen()
+			}
+			args[i] = reflect.ValueOf(op)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package ot
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func TestQuickTP1(t *testing.T) {
+	tp1 := func(a, b *OperationSeq) bool {
+		doc := strings.Repeat("x", a.BaseLen())
+		aPrime, bPrime, err := a.Transform(b)
+		if err != nil {
+			return false
+		}
+		afterA, err1 := a.Apply(doc)
+		afterB, err2 := b.Apply(doc)
+		afterAB, err3 := bPrime.Apply(afterA)
+		afterBA, err4 := aPrime.Apply(afterB)
+		return err1 == nil && err2 == nil && err3 == nil && err4 == nil && afterAB == afterBA
+	}
+	if err := quick.Check(tp1, nil); err != nil {
+		t.Error(err)
+	}
+	if err := quick.Check(tp1, QuickConcurrent(3)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQuickSequential(t *testing.T) {
+	associative := func(a, b, c *OperationSeq) bool {
+		ab, err1 := a.Compose(b)
+		bc, err2 := b.Compose(c)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		left, err1 := ab.Compose(c)
+		right, err2 := a.Compose(bc)
+		return err1 == nil && err2 == nil && left.String() == right.String()
+	}
+	if err := quick.Check(associative, QuickSequential(20)); err != nil {
+		t.Error(err)
+	}
+
+	invert := func(a *OperationSeq) bool {
+		doc := RandomString(rand.New(rand.NewSource(int64(a.BaseLen()))), a.BaseLen())
+		after, err := a.Apply(doc)
+		if err != nil {
+			return false
+		}
+		inverse, err := a.InvertChecked(doc)
+		if err != nil {
+			return false
+		}
+		back, err := inverse.Apply(after)
+		return err == nil && back == doc
+	}
+	if err := quick.Check(invert, QuickSequential(10)); err != nil {
+		t.Error(err)
+	}
+}
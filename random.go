@@ -0,0 +1,63 @@
+package ot
+
+import "math/rand"
+
+// RandomOptions tunes RandomOp. The zero value gives the same mix of edits
+// as the Rust crate's test helper.
+type RandomOptions struct {
+	// MaxLen bounds the characters in each component. Zero means 20.
+	MaxLen int
+
+	// Alphabet holds the characters inserts are drawn from. Nil means
+	// RandomString's, which mixes ASCII with multi-byte characters.
+	Alphabet []rune
+}
+
+// randomAlphabet includes multi-byte characters so that generated text
+// exercises the difference between bytes and characters.
+var randomAlphabet = []rune("abcdefghijklmnopqrstuvwxyz \né🌍")
+
+// RandomString returns n characters drawn from r, mixing ASCII with
+// multi-byte characters, for tests.
+func RandomString(r *rand.Rand, n int) string {
+	return randomString(r, n, randomAlphabet)
+}
+
+func randomString(r *rand.Rand, n int, alphabet []rune) string {
+	text := make([]rune, n)
+	for i := range text {
+		text[i] = alphabet[r.Intn(len(alphabet))]
+	}
+	return string(text)
+}
+
+// RandomOp returns a random operation on a document of baseLen characters,
+// for property tests of code built on this package. It walks the document
+// retaining, deleting, and inserting spans of random length, and sometimes
+// inserts at the end. The same r gives the same operations.
+func RandomOp(r *rand.Rand, baseLen int, opts RandomOptions) *OperationSeq {
+	maxLen, alphabet := opts.MaxLen, opts.Alphabet
+	if maxLen <= 0 {
+		maxLen = 20
+	}
+	if len(alphabet) == 0 {
+		alphabet = randomAlphabet
+	}
+
+	op := NewOperationSeq()
+	for left := baseLen; left > 0; left = baseLen - op.BaseLen() {
+		n := 1 + r.Intn(min(left, maxLen))
+		switch f := r.Float64(); {
+		case f < 0.2:
+			op.Insert(randomString(r, n, alphabet))
+		case f < 0.4:
+			op.Delete(uint64(n))
+		default:
+			op.Retain(uint64(n))
+		}
+	}
+	if r.Float64() < 0.3 {
+		op.Insert(randomString(r, 1+r.Intn(maxLen), alphabet))
+	}
+	return op
+}
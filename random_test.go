@@ -0,0 +1,56 @@
+package ot
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRandomOp(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		doc := RandomString(r, r.Intn(50))
+		n := utf8.RuneCountInString(doc)
+		a := RandomOp(r, n, RandomOptions{})
+		b := RandomOp(r, n, RandomOptions{MaxLen: 3, Alphabet: []rune("xy")})
+		if a.BaseLen() != n || b.BaseLen() != n {
+			t.Fatalf("op %d: expected base length %d, got %d and %d", i, n, a.BaseLen(), b.BaseLen())
+		}
+		for _, c := range b.Components() {
+			if c.Kind == KindInsert && strings.Trim(c.Text, "xy") != "" {
+				t.Fatalf("op %d: expected inserts from the alphabet, got %q", i, c.Text)
+			}
+		}
+
+		aPrime, bPrime, err := a.Transform(b)
+		if err != nil {
+			t.Fatalf("op %d: Transform failed: %v", i, err)
+		}
+		ab, err := composeAll(n, []*OperationSeq{a, bPrime})
+		if err != nil {
+			t.Fatalf("op %d: Compose failed: %v", i, err)
+		}
+		ba, err := composeAll(n, []*OperationSeq{b, aPrime})
+		if err != nil {
+			t.Fatalf("op %d: Compose failed: %v", i, err)
+		}
+		afterAB, err := ab.Apply(doc)
+		if err != nil {
+			t.Fatalf("op %d: Apply failed: %v", i, err)
+		}
+		afterBA, err := ba.Apply(doc)
+		if err != nil {
+			t.Fatalf("op %d: Apply failed: %v", i, err)
+		}
+		if afterAB != afterBA {
+			t.Fatalf("op %d: expected convergence, got %q and %q", i, afterAB, afterBA)
+		}
+	}
+
+	x := RandomOp(rand.New(rand.NewSource(7)), 30, RandomOptions{})
+	y := RandomOp(rand.New(rand.NewSource(7)), 30, RandomOptions{})
+	if x.String() != y.String() {
+		t.Errorf("expected the same op from the same seed, got %v and %v", x, y)
+	}
+}
@@ -0,0 +1,184 @@
+package ot
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned, wrapped in a RateLimitError, when a client
+// submits operations faster than the Hub's RateLimit allows.
+var ErrRateLimited = errors.New("rate limited")
+
+// RateLimit configures per-client token buckets for operations submitted
+// through a Hub. Each client has its own buckets, shared across documents.
+// A zero rate disables that limit.
+type RateLimit struct {
+	// OpsPerSecond is the sustained number of operations a client may submit.
+	OpsPerSecond float64
+
+	// OpsBurst is how many operations may be submitted at once after a quiet
+	// period. Zero means one second's worth, and at least one.
+	OpsBurst int
+
+	// BytesPerSecond is the sustained number of bytes of inserted text a
+	// client may submit.
+	BytesPerSecond float64
+
+	// BytesBurst is how many bytes may be inserted at once after a quiet
+	// period. Zero means one second's worth. A single operation larger than
+	// the burst is accepted when the bucket is full, and the client then has
+	// to wait until it is paid back.
+	BytesBurst int
+}
+
+// RateLimitError is returned when a client exceeds its RateLimit. It matches
+// ErrRateLimited with errors.Is.
+type RateLimitError struct {
+	Client string
+
+	// Limit is "ops" or "bytes", whichever was exceeded.
+	Limit string
+
+	// RetryAfter is how long the client must wait before the operation
+	// would be accepted.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited: %s exceeded %s limit, retry after %v", e.Client, e.Limit, e.RetryAfter)
+}
+
+// Is reports whether target is ErrRateLimited.
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// idleSweep is how often buckets of clients that have gone quiet are dropped.
+const idleSweep = time.Minute
+
+// rateLimiter holds the buckets of every client.
+type rateLimiter struct {
+	mu        sync.Mutex
+	clients   map[string]*clientBuckets
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type clientBuckets struct {
+	ops, bytes bucket
+}
+
+// bucket is a token bucket that may go into debt. Tokens are refilled lazily.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (b *bucket) refill(now time.Time, rate, burst float64) {
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens = math.Min(burst, b.tokens+rate*now.Sub(b.last).Seconds())
+	}
+	b.last = now
+}
+
+// wait returns how long until n tokens can be taken, or zero if they can be
+// taken now.
+func (b *bucket) wait(n, rate, burst float64) time.Duration {
+	need := math.Min(n, burst)
+	if b.tokens >= need {
+		return 0
+	}
+	return time.Duration((need - b.tokens) / rate * float64(time.Second))
+}
+
+func burstOf(burst int, rate float64) float64 {
+	if burst > 0 {
+		return float64(burst)
+	}
+	return math.Max(1, math.Ceil(rate))
+}
+
+// allow takes one operation of size bytes from client's buckets, or returns
+// a RateLimitError without taking anything.
+func (l *rateLimiter) allow(cfg RateLimit, client string, size int) error {
+	if cfg.OpsPerSecond <= 0 && cfg.BytesPerSecond <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.now != nil {
+		now = l.now()
+	}
+	if l.clients == nil {
+		l.clients = make(map[string]*clientBuckets)
+		l.lastSweep = now
+	}
+	if now.Sub(l.lastSweep) >= idleSweep {
+		l.sweep(cfg, now)
+	}
+
+	c := l.clients[client]
+	if c == nil {
+		c = &clientBuckets{}
+		l.clients[client] = c
+	}
+
+	opsBurst := burstOf(cfg.OpsBurst, cfg.OpsPerSecond)
+	bytesBurst := burstOf(cfg.BytesBurst, cfg.BytesPerSecond)
+	if cfg.OpsPerSecond > 0 {
+		c.ops.refill(now, cfg.OpsPerSecond, opsBurst)
+		if d := c.ops.wait(1, cfg.OpsPerSecond, opsBurst); d > 0 {
+			return &RateLimitError{Client: client, Limit: "ops", RetryAfter: d}
+		}
+	}
+	if cfg.BytesPerSecond > 0 {
+		c.bytes.refill(now, cfg.BytesPerSecond, bytesBurst)
+		if d := c.bytes.wait(float64(size), cfg.BytesPerSecond, bytesBurst); d > 0 {
+			return &RateLimitError{Client: client, Limit: "bytes", RetryAfter: d}
+		}
+	}
+
+	c.ops.tokens--
+	c.bytes.tokens -= float64(size)
+	return nil
+}
+
+// sweep drops the buckets of clients that have been quiet long enough for
+// them to refill completely, since a fresh bucket behaves the same. Callers
+// hold l.mu.
+func (l *rateLimiter) sweep(cfg RateLimit, now time.Time) {
+	for client, c := range l.clients {
+		full := true
+		if cfg.OpsPerSecond > 0 {
+			c.ops.refill(now, cfg.OpsPerSecond, burstOf(cfg.OpsBurst, cfg.OpsPerSecond))
+			full = c.ops.tokens >= burstOf(cfg.OpsBurst, cfg.OpsPerSecond)
+		}
+		if cfg.BytesPerSecond > 0 {
+			c.bytes.refill(now, cfg.BytesPerSecond, burstOf(cfg.BytesBurst, cfg.BytesPerSecond))
+			full = full && c.bytes.tokens >= burstOf(cfg.BytesBurst, cfg.BytesPerSecond)
+		}
+		if full {
+			delete(l.clients, client)
+		}
+	}
+	l.lastSweep = now
+}
+
+// insertedBytes returns the number of bytes of text o inserts.
+func insertedBytes(o *OperationSeq) int {
+	n := 0
+	for _, op := range o.ops {
+		if op.Kind == KindInsert {
+			n += len(op.Text)
+		}
+	}
+	return n
+}
@@ -0,0 +1,99 @@
+package ot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := &rateLimiter{now: func() time.Time { return now }}
+	cfg := RateLimit{OpsPerSecond: 2, BytesPerSecond: 10}
+
+	for i := 0; i < 2; i++ {
+		if err := l.allow(cfg, "a", 1); err != nil {
+			t.Fatalf("op %d: unexpected error: %v", i, err)
+		}
+	}
+	err := l.allow(cfg, "a", 1)
+	var limited *RateLimitError
+	if !errors.As(err, &limited) || !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected RateLimitError, got %v", err)
+	}
+	if limited.Limit != "ops" || limited.RetryAfter != 500*time.Millisecond {
+		t.Errorf("expected ops limit with 500ms retry, got %s after %v", limited.Limit, limited.RetryAfter)
+	}
+
+	// Other clients have their own buckets.
+	if err := l.allow(cfg, "b", 1); err != nil {
+		t.Errorf("unexpected error for another client: %v", err)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if err := l.allow(cfg, "a", 1); err != nil {
+		t.Errorf("expected refill after 500ms, got %v", err)
+	}
+
+	// An insert larger than the burst goes through on a full bucket and
+	// leaves the client in debt.
+	now = now.Add(10 * time.Second)
+	if err := l.allow(cfg, "a", 30); err != nil {
+		t.Fatalf("expected oversized op on a full bucket to pass, got %v", err)
+	}
+	now = now.Add(time.Second)
+	if err := l.allow(cfg, "a", 1); !errors.As(err, &limited) || limited.Limit != "bytes" {
+		t.Fatalf("expected bytes limit, got %v", err)
+	}
+	if limited.RetryAfter != time.Second+100*time.Millisecond {
+		t.Errorf("expected 1.1s retry, got %v", limited.RetryAfter)
+	}
+
+	// Idle clients are forgotten.
+	now = now.Add(idleSweep)
+	if err := l.allow(cfg, "c", 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(l.clients) != 1 {
+		t.Errorf("expected idle buckets to be swept, got %d clients", len(l.clients))
+	}
+}
+
+func TestHubRateLimit(t *testing.T) {
+	h := &Hub{RateLimit: RateLimit{OpsPerSecond: 1, BytesPerSecond: 1000}}
+	ctx := context.Background()
+
+	op := NewOperationSeq()
+	op.Insert("hi")
+	if _, _, err := h.Submit(ctx, "doc", "a", 0, op); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	op = NewOperationSeq()
+	op.Retain(2)
+	op.Insert("!")
+	if _, _, err := h.Submit(ctx, "doc", "a", 1, op); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited, got %v", err)
+	}
+	if _, err := h.ApplyAt(ctx, "doc", "a", 1, op); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited, got %v", err)
+	}
+	if _, _, err := h.Submit(ctx, "doc", "b", 1, op); err != nil {
+		t.Errorf("expected other client to be unaffected, got %v", err)
+	}
+
+	big := NewOperationSeq()
+	big.Insert(strings.Repeat("x", 2000))
+	if _, _, err := h.Submit(ctx, "other", "c", 0, big); err != nil {
+		t.Errorf("expected oversized op on a full bucket to pass, got %v", err)
+	}
+
+	server, err := h.Server(context.Background(), "doc")
+	if err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+	if got := server.Document(); got != "hi!" {
+		t.Errorf("expected %q, got %q", "hi!", got)
+	}
+}
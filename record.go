@@ -0,0 +1,104 @@
+package ot
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Recording is a typing session captured by a Recorder: the document it
+// started from and every operation submitted during it, with its timing.
+// It encodes to JSON, so sessions can be saved and replayed elsewhere, such
+// as a production trace reproduced on a developer's machine.
+type Recording struct {
+	Snapshot string        `json:"snapshot"`
+	Revision int           `json:"revision"` // of Snapshot
+	Ops      []RecordedOp  `json:"ops"`
+	Duration time.Duration `json:"duration"` // from the start to the last operation
+}
+
+// RecordedOp is one operation in a Recording, as its client submitted it.
+type RecordedOp struct {
+	At       time.Duration `json:"at"` // since the recording started
+	Client   string        `json:"client,omitempty"`
+	Revision int           `json:"revision"` // the operation was made against
+	Op       *OperationSeq `json:"op"`
+}
+
+// Recorder captures the operations submitted to a document, with the time
+// each arrived, into a Recording. Call Record wherever operations are
+// received, such as next to Hub.Submit in a transport, with the operation
+// as the client sent it, so that playback transforms them the same way.
+//
+// A Recorder is safe for concurrent use.
+type Recorder struct {
+	mu    sync.Mutex
+	start time.Time
+	rec   Recording
+	now   func() time.Time
+}
+
+// NewRecorder starts recording a session on doc, currently at revision.
+func NewRecorder(doc string, revision int) *Recorder {
+	r := &Recorder{now: time.Now, rec: Recording{Snapshot: doc, Revision: revision}}
+	r.start = r.now()
+	return r
+}
+
+// Record captures op from client, made against revision.
+func (r *Recorder) Record(client string, revision int, op *OperationSeq) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	at := r.now().Sub(r.start)
+	r.rec.Ops = append(r.rec.Ops, RecordedOp{At: at, Client: client, Revision: revision, Op: op})
+	r.rec.Duration = at
+}
+
+// Recording returns what has been recorded so far.
+func (r *Recorder) Recording() Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec := r.rec
+	rec.Ops = append([]RecordedOp(nil), r.rec.Ops...)
+	return rec
+}
+
+// Play submits the recorded operations to s in order, as their clients did,
+// keeping their original pacing sped up by speed: 2 plays twice as fast,
+// and zero or less submits them all at once, as for a benchmark. s must
+// hold the recording's Snapshot; its revision may differ from the
+// recording's, and the recorded revisions are shifted to match.
+//
+// Play stops at the first operation s refuses, returning its error, or when
+// ctx is done.
+func (rec Recording) Play(ctx context.Context, s *Server, speed float64) error {
+	offset := s.Revision() - rec.Revision
+	start := time.Now()
+	for _, r := range rec.Ops {
+		if speed > 0 {
+			wait := time.Duration(float64(r.At)/speed) - time.Since(start)
+			if err := sleep(ctx, wait); err != nil {
+				return err
+			}
+		}
+		if _, _, err := s.SubmitOp(ctx, r.Client, "", r.Revision+offset, r.Op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
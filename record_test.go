@@ -0,0 +1,72 @@
+package ot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRecorderPlay(t *testing.T) {
+	now := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+	r := NewRecorder("", 0)
+	r.now = func() time.Time { return now }
+	r.start = now
+
+	// Two clients type concurrently against revision 0.
+	a, b := NewOperationSeq(), NewOperationSeq()
+	a.Insert("a")
+	b.Insert("b")
+	r.Record("alice", 0, a)
+	now = now.Add(20 * time.Millisecond)
+	r.Record("bob", 0, b)
+
+	rec := r.Recording()
+	if len(rec.Ops) != 2 || rec.Ops[1].At != 20*time.Millisecond || rec.Duration != 20*time.Millisecond {
+		t.Fatalf("expected two operations over 20ms, got %+v", rec)
+	}
+
+	// The recording survives a round trip through JSON.
+	data, err := json.Marshal(rec)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded Recording
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	s := NewServer(decoded.Snapshot)
+	start := time.Now()
+	if err := decoded.Play(context.Background(), s, 1); err != nil {
+		t.Fatalf("Play failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("expected playback to take 20ms, took %v", elapsed)
+	}
+	if doc := s.Document(); doc != "ab" {
+		t.Errorf("expected %q, got %q", "ab", doc)
+	}
+
+	// At speed zero it plays at once, onto a server further along.
+	s = NewServer("")
+	appendText(t, s, "")
+	if err := decoded.Play(context.Background(), s, 0); err != nil {
+		t.Fatalf("Play failed: %v", err)
+	}
+	if rev := s.Revision(); rev != 3 {
+		t.Errorf("expected revision 3, got %d", rev)
+	}
+}
+
+func TestRecordingPlayCanceled(t *testing.T) {
+	op := NewOperationSeq()
+	op.Insert("x")
+	rec := Recording{Ops: []RecordedOp{{At: time.Hour, Op: op}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := rec.Play(ctx, NewServer(""), 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package ot
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReplayError reports the operation at which a replayed history stopped
+// applying.
+type ReplayError struct {
+	// Revision is the revision of the operation that failed, counted like a
+	// Store revision: the operation takes the document from Revision to
+	// Revision+1.
+	Revision int
+
+	// DocLen is the length of the document at Revision, and BaseLen the
+	// length the operation expected.
+	DocLen  int
+	BaseLen int
+
+	Err error
+}
+
+func (e *ReplayError) Error() string {
+	return fmt.Sprintf("replaying revision %d: document has %d characters, operation expects %d: %v",
+		e.Revision, e.DocLen, e.BaseLen, e.Err)
+}
+
+func (e *ReplayError) Unwrap() error { return e.Err }
+
+// ReplayLog applies ops in order to snapshot and returns the resulting
+// document. Each operation's base length is checked against the document
+// before it is applied; on the first that does not fit, it returns a
+// *ReplayError whose Revision is the operation's index in ops.
+func ReplayLog(snapshot string, ops []*OperationSeq) (string, error) {
+	return replay(snapshot, 0, ops)
+}
+
+// replay is ReplayLog for a snapshot at revision base.
+func replay(doc string, base int, ops []*OperationSeq) (string, error) {
+	n := charCount(doc)
+	for i, op := range ops {
+		if op.baseLen != n {
+			return "", &ReplayError{Revision: base + i, DocLen: n, BaseLen: op.baseLen, Err: ErrIncompatibleLengths}
+		}
+		var err error
+		if doc, err = op.Apply(doc); err != nil {
+			return "", &ReplayError{Revision: base + i, DocLen: n, BaseLen: op.baseLen, Err: err}
+		}
+		n = op.targetLen
+	}
+	return doc, nil
+}
+
+// LoadDocument rebuilds a document from store, from its latest snapshot and
+// the operations saved after it, and returns its content and revision. It
+// is what OpenServer does, without keeping a Server around, for tools that
+// inspect or verify stored history. A corrupt history is reported as a
+// *ReplayError with the revision of the first operation that does not apply.
+func LoadDocument(ctx context.Context, store Store, name string) (string, int, error) {
+	snapshot, base, err := store.LoadLatestSnapshot(ctx, name)
+	if err != nil {
+		return "", 0, err
+	}
+	ops, err := store.LoadOpsSince(ctx, name, base)
+	if err != nil {
+		return "", 0, err
+	}
+	doc, err := replay(snapshot, base, ops)
+	if err != nil {
+		return "", 0, err
+	}
+	return doc, base + len(ops), nil
+}
@@ -0,0 +1,76 @@
+package ot
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestReplayLog(t *testing.T) {
+	a := NewOperationSeq()
+	a.Retain(2)
+	a.Insert("é!")
+	b := NewOperationSeq()
+	b.Delete(1)
+	b.Retain(3)
+
+	doc, err := ReplayLog("hi", []*OperationSeq{a, b})
+	if err != nil {
+		t.Fatalf("ReplayLog failed: %v", err)
+	}
+	if doc != "ié!" {
+		t.Errorf("expected %q, got %q", "ié!", doc)
+	}
+
+	// b applied twice no longer fits: the second copy is revision 2.
+	_, err = ReplayLog("hi", []*OperationSeq{a, b, b})
+	var replayErr *ReplayError
+	if !errors.As(err, &replayErr) {
+		t.Fatalf("expected *ReplayError, got %v", err)
+	}
+	if replayErr.Revision != 2 || replayErr.DocLen != 3 || replayErr.BaseLen != 4 {
+		t.Errorf("expected revision 2 with lengths 3 and 4, got %+v", replayErr)
+	}
+	if !errors.Is(err, ErrIncompatibleLengths) {
+		t.Errorf("expected ErrIncompatibleLengths, got %v", err)
+	}
+}
+
+func TestLoadDocument(t *testing.T) {
+	ctx := context.Background()
+	store := &memStore{}
+	s, err := OpenServer(ctx, store, "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		op := NewOperationSeq()
+		op.Retain(uint64(i))
+		op.Insert("x")
+		if _, err := s.ReceiveOperation(ctx, i, op); err != nil {
+			t.Fatalf("ReceiveOperation failed: %v", err)
+		}
+		if i == 1 {
+			if err := s.Checkpoint(ctx); err != nil {
+				t.Fatalf("Checkpoint failed: %v", err)
+			}
+		}
+	}
+
+	doc, rev, err := LoadDocument(ctx, store, "doc")
+	if err != nil || doc != "xxx" || rev != 3 {
+		t.Errorf("expected %q at revision 3, got %q at %d (%v)", "xxx", doc, rev, err)
+	}
+
+	// Corrupt the operation saved at revision 2, after the snapshot at 2.
+	bad := NewOperationSeq()
+	bad.Retain(5)
+	store.ops[2] = bad
+	var replayErr *ReplayError
+	if _, _, err := LoadDocument(ctx, store, "doc"); !errors.As(err, &replayErr) || replayErr.Revision != 2 {
+		t.Errorf("expected *ReplayError at revision 2, got %v", err)
+	}
+	if _, err := OpenServer(ctx, store, "doc"); !errors.As(err, &replayErr) {
+		t.Errorf("expected OpenServer to report *ReplayError, got %v", err)
+	}
+}
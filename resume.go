@@ -0,0 +1,111 @@
+package ot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+)
+
+// ErrBadResumeToken is returned by Hub.Resume for a token whose Secret was
+// not issued for its Doc and Client.
+var ErrBadResumeToken = fmt.Errorf("%w: invalid resume token", ErrForbidden)
+
+// ResumeToken lets a client whose connection was lost, or whose node shut
+// down, continue its session on any node sharing the same Store without
+// losing or duplicating edits.
+//
+// A client keeps one up to date as it goes: Revision is the last revision it
+// has fully applied, that is, the revision of its last acknowledgement or
+// incoming operation, and Pending is the ID it gave its in-flight operation
+// with SubmitOp, if any. Operations the client had only buffered were never
+// sent and are simply sent after resuming.
+type ResumeToken struct {
+	Doc      string `json:"doc"`
+	Client   string `json:"client"`
+	Revision int    `json:"revision"`
+	Pending  string `json:"pending,omitempty"`
+
+	// Secret is the Subscription's ResumeSecret. It proves the token was
+	// issued to Client, so that a peer who learns another client's ID
+	// cannot take over its session.
+	Secret string `json:"secret"`
+
+	// Watch resumes a subscription started with Watch.
+	Watch bool `json:"watch,omitempty"`
+}
+
+// Resume subscribes to token.Doc as token.Client, the way Subscribe does, and
+// reports what the client missed: the operations accepted since
+// token.Revision in Missed, and in PendingRevision whether its pending
+// operation was among them.
+//
+// The client transforms its pending and buffered operations against the
+// missed ones, as if they had arrived as events, except that the one at
+// PendingRevision is its own and acknowledges the pending operation. If
+// PendingRevision is zero, the pending operation is resent with SubmitOp
+// under the same ID.
+//
+// Only the node that accepted an operation remembers its ID, so after a
+// failover PendingRevision is zero even if the operation was accepted. Hub
+// Shutdown delivers every acknowledgement before ending subscriptions, so
+// this only matters when a node fails without shutting down.
+//
+// Returns ErrBadResumeToken if the token's Secret is not the one issued for
+// its Doc and Client, and ErrRevisionCompacted if the client has been away
+// too long; it must then subscribe afresh and discard its local changes.
+func (h *Hub) Resume(ctx context.Context, token ResumeToken) (*Subscription, error) {
+	want := h.ResumeSecret(token.Doc, token.Client)
+	if !hmac.Equal([]byte(token.Secret), []byte(want)) {
+		h.logger().InfoContext(ctx, "resume refused", "doc", token.Doc, "client", token.Client)
+		return nil, ErrBadResumeToken
+	}
+	return h.subscribe(ctx, token.Doc, token.Client, &token, token.Watch)
+}
+
+// SubscribeSince subscribes client to doc as Subscribe does, or as Watch
+// does if watch is set, for a client that has already applied every
+// operation up to revision: Missed holds the operations accepted since.
+//
+// Unlike Resume it takes no secret, so it does not prove the caller is
+// client, and it never reports a PendingRevision. It suits clients that
+// only need to catch up, such as a reconnecting event stream; callers must
+// authenticate client themselves, for instance with the Authorizer, before
+// giving it a client ID that others may use. Returns ErrRevisionCompacted
+// or ErrInvalidRevision if revision can no longer be caught up from.
+func (h *Hub) SubscribeSince(ctx context.Context, doc, client string, revision int, watch bool) (*Subscription, error) {
+	return h.subscribe(ctx, doc, client, &ResumeToken{Doc: doc, Client: client, Revision: revision, Watch: watch}, watch)
+}
+
+// ResumeSecret returns the secret that lets client resume its session on
+// doc: an HMAC-SHA256 of both under the Hub's ResumeKey. Subscriptions
+// carry it as ResumeSecret, so only the client that was given it can
+// resume.
+func (h *Hub) ResumeSecret(doc, client string) string {
+	mac := hmac.New(sha256.New, h.resumeKey())
+	var n [binary.MaxVarintLen64]byte
+	mac.Write([]byte("ot resume token\x00")) //nolint:errcheck // hash writes never fail
+	for _, s := range []string{doc, client} {
+		mac.Write(n[:binary.PutUvarint(n[:], uint64(len(s)))]) //nolint:errcheck // hash writes never fail
+		mac.Write([]byte(s))                                   //nolint:errcheck // hash writes never fail
+	}
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// resumeKey returns ResumeKey, or a random key generated on first use if it
+// is unset.
+func (h *Hub) resumeKey() []byte {
+	if len(h.ResumeKey) > 0 {
+		return h.ResumeKey
+	}
+	h.resumeOnce.Do(func() {
+		h.generatedKey = make([]byte, 32)
+		if _, err := rand.Read(h.generatedKey); err != nil {
+			panic(fmt.Sprintf("ot: generating resume key: %v", err))
+		}
+	})
+	return h.generatedKey
+}
@@ -0,0 +1,141 @@
+package ot
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestHubResume(t *testing.T) {
+	store := &memStore{}
+	key := []byte("shared resume key")
+	a := &Hub{Store: store, ResumeKey: key}
+	b := &Hub{Store: store, ResumeKey: key}
+	ctx := context.Background()
+
+	sub, err := a.Subscribe(ctx, "doc", "alice")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	op := NewOperationSeq()
+	op.Insert("a")
+	if _, _, err := a.SubmitOp(ctx, "doc", "alice", "1", 0, op); err != nil {
+		t.Fatalf("SubmitOp failed: %v", err)
+	}
+	other := NewOperationSeq()
+	other.Retain(1)
+	other.Insert("b")
+	if _, _, err := a.Submit(ctx, "doc", "bob", 1, other); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	sub.Close()
+	secret := sub.ResumeSecret
+
+	// alice lost the ack for "1" and saw nothing after revision 0.
+	resumed, err := a.Resume(ctx, ResumeToken{Doc: "doc", Client: "alice", Pending: "1", Secret: secret})
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if len(resumed.Missed) != 2 || resumed.PendingRevision != 1 {
+		t.Errorf("expected 2 missed ops and pending revision 1, got %d and %d", len(resumed.Missed), resumed.PendingRevision)
+	}
+	resumed.Close()
+
+	// Another node only has the store, so the pending operation is unknown.
+	elsewhere, err := b.Resume(ctx, ResumeToken{Doc: "doc", Client: "alice", Revision: 1, Pending: "1", Secret: secret})
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if len(elsewhere.Missed) != 1 || elsewhere.PendingRevision != 0 {
+		t.Errorf("expected 1 missed op and no pending revision, got %d and %d", len(elsewhere.Missed), elsewhere.PendingRevision)
+	}
+	if elsewhere.Document != "ab" || elsewhere.Revision != 2 {
+		t.Errorf("expected %q at revision 2, got %q at %d", "ab", elsewhere.Document, elsewhere.Revision)
+	}
+	elsewhere.Close()
+
+	if _, err := b.Resume(ctx, ResumeToken{Doc: "doc", Client: "alice", Revision: 5, Secret: secret}); !errors.Is(err, ErrInvalidRevision) {
+		t.Errorf("expected ErrInvalidRevision, got %v", err)
+	}
+}
+
+func TestHubResumeForged(t *testing.T) {
+	h := &Hub{}
+	ctx := context.Background()
+
+	sub, err := h.Subscribe(ctx, "doc", "alice")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Close()
+	if sub.ResumeSecret == "" {
+		t.Fatal("expected the subscription to carry a resume secret")
+	}
+
+	// Anyone can learn alice's ID from presence, but not her secret.
+	forged := []ResumeToken{
+		{Doc: "doc", Client: "alice"},
+		{Doc: "doc", Client: "alice", Secret: h.ResumeSecret("doc", "mallory")},
+		{Doc: "doc", Client: "alice", Secret: h.ResumeSecret("other", "alice")},
+		{Doc: "doc", Client: "alice", Secret: (&Hub{}).ResumeSecret("doc", "alice")},
+	}
+	for i, token := range forged {
+		if _, err := h.Resume(ctx, token); !errors.Is(err, ErrBadResumeToken) || !errors.Is(err, ErrForbidden) {
+			t.Errorf("token %d: expected ErrBadResumeToken, got %v", i, err)
+		}
+	}
+	resumed, err := h.Resume(ctx, ResumeToken{Doc: "doc", Client: "alice", Secret: sub.ResumeSecret})
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	resumed.Close()
+}
+
+func TestHubShutdown(t *testing.T) {
+	store := &memStore{}
+	h := &Hub{Store: store}
+	ctx := context.Background()
+
+	sub, err := h.Subscribe(ctx, "doc", "alice")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	<-sub.C // own join
+	op := NewOperationSeq()
+	op.Insert("a")
+	if _, _, err := h.Submit(ctx, "doc", "alice", 0, op); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	if err := h.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if ack := <-sub.C; ack.Kind != EventOp || ack.Revision != 1 {
+		t.Errorf("expected ack at revision 1 before close, got %+v", ack)
+	}
+	if _, ok := <-sub.C; ok {
+		t.Error("expected subscription closed")
+	}
+	if !errors.Is(sub.Err(), ErrHubClosed) {
+		t.Errorf("expected ErrHubClosed, got %v", sub.Err())
+	}
+
+	late := NewOperationSeq()
+	late.Retain(1)
+	if _, _, err := h.Submit(ctx, "doc", "alice", 1, late); !errors.Is(err, ErrHubClosed) {
+		t.Errorf("expected ErrHubClosed from Submit, got %v", err)
+	}
+	if _, err := h.Subscribe(ctx, "other", "bob"); !errors.Is(err, ErrHubClosed) {
+		t.Errorf("expected ErrHubClosed from Subscribe, got %v", err)
+	}
+
+	// The edit is durable and another node can pick the document up.
+	next := &Hub{Store: store}
+	server, err := next.Server(ctx, "doc")
+	if err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+	if server.Document() != "a" {
+		t.Errorf("expected %q, got %q", "a", server.Document())
+	}
+}
@@ -0,0 +1,256 @@
+package ot
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrCheckpointNotFound is returned when no named checkpoint has the
+// requested name.
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+// retentionBatch is how many operations must be past the retention policy
+// before they are compacted, so the cost of composing them and saving a
+// snapshot is spread over many operations.
+const retentionBatch = 64
+
+// RetentionPolicy controls how much history a Server keeps. Operations
+// outside it are folded into the base snapshot and dropped, as by Compact.
+// An operation is kept if either field asks for it, and never dropped while
+// a Pin holds a revision before it. A zero field keeps nothing on its own
+// account, and the zero value keeps everything.
+//
+// The policy is applied as operations are accepted, in batches; an idle
+// document is not compacted until Prune is called.
+type RetentionPolicy struct {
+	// KeepRevisions keeps the most recent operations, this many of them.
+	KeepRevisions int
+
+	// KeepFor keeps operations accepted less than this long ago. Operations
+	// restored from a Store count as accepted when the server was opened.
+	KeepFor time.Duration
+}
+
+// namedCheckpoint is a revision of the document saved under a name.
+type namedCheckpoint struct {
+	revision int
+	doc      string
+}
+
+// SetRetention sets how much history the server keeps.
+func (s *Server) SetRetention(policy RetentionPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retention = policy
+}
+
+// Prune applies the retention policy now and returns the number of
+// operations it dropped.
+func (s *Server) Prune(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	base := s.base
+	through := s.retainFrom()
+	if through == base {
+		return 0, nil
+	}
+	if err := s.compact(ctx, through); err != nil {
+		return 0, err
+	}
+	return through - base, nil
+}
+
+// retainFrom returns the oldest revision the retention policy and pins keep.
+// Callers hold s.mu.
+func (s *Server) retainFrom() int {
+	p := s.retention
+	if p.KeepRevisions <= 0 && p.KeepFor <= 0 {
+		return s.base
+	}
+	from := s.revision()
+	if p.KeepRevisions > 0 {
+		from = min(from, s.revision()-p.KeepRevisions)
+	}
+	if p.KeepFor > 0 {
+		cutoff := s.now().Add(-p.KeepFor)
+		i := sort.Search(len(s.accepted), func(i int) bool { return s.accepted[i].After(cutoff) })
+		from = min(from, s.base+i)
+	}
+	for pin := range s.pins {
+		from = min(from, pin.revision)
+	}
+	return max(from, s.base)
+}
+
+// maybeCollect applies the retention policy after operations have been
+// accepted. Callers hold s.mu.
+func (s *Server) maybeCollect(ctx context.Context) {
+	through := s.retainFrom()
+	if through-s.base < retentionBatch {
+		return
+	}
+	if err := s.compact(ctx, through); err != nil {
+		// The operation is already accepted; retry with the next one.
+		s.logger.ErrorContext(ctx, "retention compaction failed", "revision", through, "err", err)
+	}
+}
+
+// acceptedNow returns n acceptance times for operations restored at once.
+func acceptedNow(n int) []time.Time {
+	accepted := make([]time.Time, n)
+	now := time.Now()
+	for i := range accepted {
+		accepted[i] = now
+	}
+	return accepted
+}
+
+// NameCheckpoint saves the document as of revision under name, replacing any
+// checkpoint already saved under it. Named checkpoints are kept in memory
+// regardless of the retention policy or Compact, and DocumentAt serves their
+// revisions after the history around them is gone.
+//
+// Returns ErrInvalidRevision or ErrRevisionCompacted if revision is outside
+// the history.
+func (s *Server) NameCheckpoint(name string, revision int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkRevision(revision); err != nil {
+		return err
+	}
+	doc, err := s.documentAt(revision)
+	if err != nil {
+		return err
+	}
+	if s.named == nil {
+		s.named = make(map[string]namedCheckpoint)
+	}
+	s.named[name] = namedCheckpoint{revision: revision, doc: doc}
+	return nil
+}
+
+// SaveCheckpoint names the current revision, as NameCheckpoint does, and
+// returns it.
+func (s *Server) SaveCheckpoint(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.named == nil {
+		s.named = make(map[string]namedCheckpoint)
+	}
+	s.named[name] = namedCheckpoint{revision: s.revision(), doc: s.doc}
+	return s.revision()
+}
+
+// CheckpointDiff returns an operation that turns the document saved under
+// name into the current one, together with the current revision. While the
+// history since the checkpoint is kept, it is those operations composed;
+// once they are compacted, it replaces the span of text that changed.
+// Returns ErrCheckpointNotFound if there is no checkpoint named name.
+func (s *Server) CheckpointDiff(name string) (*OperationSeq, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op, err := s.checkpointDiff(name)
+	return op, s.revision(), err
+}
+
+// RestoreCheckpoint returns the document to the one saved under name, by
+// applying an operation from client that undoes every change made since.
+// History is kept, so the restore can itself be undone or reverted. Returns
+// the applied operation and the revision it produced, or
+// ErrCheckpointNotFound.
+func (s *Server) RestoreCheckpoint(ctx context.Context, client, name string) (*OperationSeq, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op, err := s.restoreOp(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	if op, _, err = s.receive(ctx, client, s.revision(), op, false); err != nil {
+		return nil, 0, err
+	}
+	return op, s.revision(), nil
+}
+
+// restoring returns the operation RestoreCheckpoint would apply, for
+// authorization.
+func (s *Server) restoring(name string) (*OperationSeq, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.restoreOp(name)
+}
+
+// restoreOp returns the operation that turns the current document into the
+// one saved under name. Callers hold s.mu.
+func (s *Server) restoreOp(name string) (*OperationSeq, error) {
+	c, ok := s.named[name]
+	if !ok {
+		return nil, ErrCheckpointNotFound
+	}
+	diff, err := s.checkpointDiff(name)
+	if err != nil {
+		return nil, err
+	}
+	return diff.InvertChecked(c.doc)
+}
+
+// checkpointDiff implements CheckpointDiff. Callers hold s.mu.
+func (s *Server) checkpointDiff(name string) (*OperationSeq, error) {
+	c, ok := s.named[name]
+	if !ok {
+		return nil, ErrCheckpointNotFound
+	}
+	if c.revision < s.base {
+		return diffText(c.doc, s.doc), nil
+	}
+	return s.squash(c.revision, s.revision())
+}
+
+// NamedCheckpoint returns the document and revision saved under name, or
+// ErrCheckpointNotFound.
+func (s *Server) NamedCheckpoint(name string) (string, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.named[name]
+	if !ok {
+		return "", 0, ErrCheckpointNotFound
+	}
+	return c.doc, c.revision, nil
+}
+
+// NamedCheckpoints returns the revision of every named checkpoint, by name.
+func (s *Server) NamedCheckpoints() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[string]int, len(s.named))
+	for name, c := range s.named {
+		out[name] = c.revision
+	}
+	return out
+}
+
+// DeleteCheckpoint removes the checkpoint saved under name, if any.
+func (s *Server) DeleteCheckpoint(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.named, name)
+}
+
+// namedDocumentAt returns the document of a named checkpoint at revision.
+// Callers hold s.mu.
+func (s *Server) namedDocumentAt(revision int) (string, bool) {
+	for _, c := range s.named {
+		if c.revision == revision {
+			return c.doc, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,205 @@
+package ot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetentionKeepRevisions(t *testing.T) {
+	store := &memStore{}
+	s, err := OpenServer(context.Background(), store, "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	s.SetRetention(RetentionPolicy{KeepRevisions: 10})
+
+	for i := 0; i < 10+retentionBatch-1; i++ {
+		appendText(t, s, "a")
+	}
+	if _, _, err := s.OperationsSince(0); err != nil {
+		t.Fatalf("expected no compaction before a full batch, got %v", err)
+	}
+
+	appendText(t, s, "b")
+	rev := s.Revision()
+	if _, _, err := s.OperationsSince(rev - 11); !errors.Is(err, ErrRevisionCompacted) {
+		t.Errorf("expected ErrRevisionCompacted, got %v", err)
+	}
+	if ops, _, err := s.OperationsSince(rev - 10); err != nil || len(ops) != 10 {
+		t.Errorf("expected the last 10 operations, got %d (%v)", len(ops), err)
+	}
+	if store.snapRev != rev-10 {
+		t.Errorf("expected snapshot at revision %d, got %d", rev-10, store.snapRev)
+	}
+	if doc, err := s.DocumentAt(rev - 10); err != nil || doc != s.Document()[:rev-10] {
+		t.Errorf("expected document at revision %d, got %q (%v)", rev-10, doc, err)
+	}
+}
+
+func TestRetentionKeepFor(t *testing.T) {
+	s := NewServer("")
+	now := time.Unix(0, 0)
+	s.now = func() time.Time { return now }
+	s.SetRetention(RetentionPolicy{KeepFor: time.Hour})
+
+	for i := 0; i < 100; i++ {
+		appendText(t, s, "a")
+	}
+	now = now.Add(30 * time.Minute)
+	appendText(t, s, "b")
+	now = now.Add(30 * time.Minute)
+
+	n, err := s.Prune(context.Background())
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if n != 100 {
+		t.Errorf("expected 100 operations dropped, got %d", n)
+	}
+	if ops, _, err := s.OperationsSince(100); err != nil || len(ops) != 1 {
+		t.Errorf("expected the newest operation kept, got %d (%v)", len(ops), err)
+	}
+
+	// Either field keeps an operation.
+	s.SetRetention(RetentionPolicy{KeepRevisions: 1, KeepFor: time.Hour})
+	appendText(t, s, "c")
+	if n, err := s.Prune(context.Background()); err != nil || n != 0 {
+		t.Errorf("expected nothing dropped, got %d (%v)", n, err)
+	}
+}
+
+func TestRetentionRespectsPins(t *testing.T) {
+	s := NewServer("")
+	pin, err := s.Pin(0)
+	if err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	s.SetRetention(RetentionPolicy{KeepRevisions: 1})
+	for i := 0; i < 2*retentionBatch; i++ {
+		appendText(t, s, "a")
+	}
+	if _, _, err := s.OperationsSince(0); err != nil {
+		t.Errorf("expected pinned history kept, got %v", err)
+	}
+
+	pin.Advance(5)
+	if n, err := s.Prune(context.Background()); err != nil || n != 5 {
+		t.Errorf("expected 5 operations dropped, got %d (%v)", n, err)
+	}
+}
+
+func TestNamedCheckpoints(t *testing.T) {
+	s := NewServer("")
+	appendText(t, s, "v1")
+	if err := s.NameCheckpoint("draft", 1); err != nil {
+		t.Fatalf("NameCheckpoint failed: %v", err)
+	}
+	appendText(t, s, " v2")
+	if err := s.Compact(context.Background(), 2); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+
+	doc, rev, err := s.NamedCheckpoint("draft")
+	if err != nil || doc != "v1" || rev != 1 {
+		t.Errorf("expected (%q, 1), got (%q, %d, %v)", "v1", doc, rev, err)
+	}
+	if doc, err := s.DocumentAt(1); err != nil || doc != "v1" {
+		t.Errorf("expected %q at the named revision, got %q (%v)", "v1", doc, err)
+	}
+	if _, err := s.DocumentAt(0); !errors.Is(err, ErrRevisionCompacted) {
+		t.Errorf("expected ErrRevisionCompacted, got %v", err)
+	}
+	if got := s.NamedCheckpoints(); len(got) != 1 || got["draft"] != 1 {
+		t.Errorf("expected map[draft:1], got %v", got)
+	}
+
+	s.DeleteCheckpoint("draft")
+	if _, _, err := s.NamedCheckpoint("draft"); !errors.Is(err, ErrCheckpointNotFound) {
+		t.Errorf("expected ErrCheckpointNotFound, got %v", err)
+	}
+	if err := s.NameCheckpoint("old", 0); !errors.Is(err, ErrRevisionCompacted) {
+		t.Errorf("expected ErrRevisionCompacted, got %v", err)
+	}
+}
+
+func TestSaveAndRestoreCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer("draft")
+	if rev := s.SaveCheckpoint("start"); rev != 0 {
+		t.Errorf("expected revision 0, got %d", rev)
+	}
+	appendText(t, s, " one")
+	appendText(t, s, " two")
+
+	diff, rev, err := s.CheckpointDiff("start")
+	if err != nil || rev != 2 {
+		t.Fatalf("expected revision 2, got %d (%v)", rev, err)
+	}
+	if got, err := diff.Apply("draft"); err != nil || got != "draft one two" {
+		t.Errorf("expected %q, got %q (%v)", "draft one two", got, err)
+	}
+
+	// Once the history is compacted the diff is computed from the text.
+	if err := s.Compact(ctx, 2); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	diff, _, err = s.CheckpointDiff("start")
+	if err != nil {
+		t.Fatalf("CheckpointDiff failed: %v", err)
+	}
+	if got, err := diff.Apply("draft"); err != nil || got != "draft one two" {
+		t.Errorf("expected %q after compaction, got %q (%v)", "draft one two", got, err)
+	}
+
+	op, rev, err := s.RestoreCheckpoint(ctx, "alice", "start")
+	if err != nil || rev != 3 {
+		t.Fatalf("expected revision 3, got %d (%v)", rev, err)
+	}
+	if doc, _ := s.State(); doc != "draft" {
+		t.Errorf("expected %q, got %q", "draft", doc)
+	}
+	if op.TargetLen() != 5 {
+		t.Errorf("expected target length 5, got %d", op.TargetLen())
+	}
+
+	if _, _, err := s.CheckpointDiff("missing"); !errors.Is(err, ErrCheckpointNotFound) {
+		t.Errorf("expected ErrCheckpointNotFound, got %v", err)
+	}
+	if _, _, err := s.RestoreCheckpoint(ctx, "alice", "missing"); !errors.Is(err, ErrCheckpointNotFound) {
+		t.Errorf("expected ErrCheckpointNotFound, got %v", err)
+	}
+}
+
+func TestHubRestoreCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	h := &Hub{}
+	s, err := h.Server(ctx, "doc")
+	if err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+	s.SaveCheckpoint("empty")
+	sub, err := h.Subscribe(ctx, "doc", "alice")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	<-sub.C
+
+	op := NewOperationSeq()
+	op.Insert("text")
+	if _, _, err := h.Submit(ctx, "doc", "bob", 0, op); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	<-sub.C
+
+	if _, rev, err := h.RestoreCheckpoint(ctx, "doc", "alice", "empty"); err != nil || rev != 2 {
+		t.Fatalf("expected revision 2, got %d (%v)", rev, err)
+	}
+	if ev := <-sub.C; ev.Kind != EventOp || ev.Client != "" || ev.Revision != 2 {
+		t.Errorf("expected the restore as an op without a client, got %+v", ev)
+	}
+	if doc, _ := s.State(); doc != "" {
+		t.Errorf("expected an empty document, got %q", doc)
+	}
+}
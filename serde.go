@@ -3,6 +3,7 @@ package ot
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 )
 
 // JSON serialization format (matching Rust operational-transform):
@@ -12,6 +13,9 @@ import (
 //
 // Example: [5, "hello", -3, 10]
 //   = Retain(5), Insert("hello"), Delete(3), Retain(10)
+//
+// Metadata, if any, follows the components as an object:
+// [5, "hello", {"author": "alice", "time": "2024-01-02T15:04:05Z"}]
 
 // MarshalJSON implements json.Marshaler for OperationSeq.
 func (o *OperationSeq) MarshalJSON() ([]byte, error) {
@@ -19,17 +23,24 @@ func (o *OperationSeq) MarshalJSON() ([]byte, error) {
 		return json.Marshal([]interface{}{})
 	}
 
-	result := make([]interface{}, len(o.ops))
-	for i, op := range o.ops {
-		switch v := op.(type) {
-		case Retain:
+	result := make([]interface{}, len(o.ops), len(o.ops)+1)
+	for i, v := range o.ops {
+		switch v.Kind {
+		case KindRetain:
 			result[i] = v.N
-		case Delete:
+		case KindDelete:
 			result[i] = -int64(v.N)
-		case Insert:
+		case KindInsert:
 			result[i] = v.Text
 		}
 	}
+	if o.meta != nil {
+		meta, err := o.meta.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, json.RawMessage(meta))
+	}
 	return json.Marshal(result)
 }
 
@@ -41,30 +52,55 @@ func (o *OperationSeq) UnmarshalJSON(data []byte) error {
 	}
 
 	*o = OperationSeq{
-		ops:       make([]Operation, 0, len(raw)),
+		ops:       make([]Component, 0, len(raw)),
 		baseLen:   0,
 		targetLen: 0,
 	}
 
-	for _, item := range raw {
+	for i, item := range raw {
 		switch v := item.(type) {
 		case string:
 			// String → Insert
-			o.Insert(v)
+			if err := (DecodeLimits{}).insert(o, v); err != nil {
+				return err
+			}
 		case float64:
-			// JSON numbers are float64
+			// JSON numbers are float64, truncated towards zero. Counts too
+			// large for the lengths are rejected rather than wrapped.
+			n := math.Trunc(math.Abs(v))
+			if n >= 1<<63 {
+				return fmt.Errorf("invalid operation count: %v", v)
+			}
+			var err error
 			if v >= 0 {
 				// Positive → Retain
-				o.Retain(uint64(v))
+				err = DecodeLimits{}.retain(o, uint64(n))
 			} else {
 				// Negative → Delete
-				o.Delete(uint64(-v))
+				err = DecodeLimits{}.delete(o, uint64(n))
+			}
+			if err != nil {
+				return err
+			}
+		case map[string]interface{}:
+			if i != len(raw)-1 {
+				return fmt.Errorf("invalid operation sequence: metadata must come last")
+			}
+			data, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			if o.meta, err = unmarshalMeta(data); err != nil {
+				return err
 			}
 		default:
 			return fmt.Errorf("invalid operation type: %T", item)
 		}
 	}
 
+	if debug {
+		assert("UnmarshalJSON", checkOp(o))
+	}
 	return nil
 }
 
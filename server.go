@@ -0,0 +1,508 @@
+package ot
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInvalidRevision is returned when a client refers to a revision the server does not have
+	ErrInvalidRevision = errors.New("invalid revision")
+
+	// ErrStaleRevision is returned by ApplyAt when the document has moved past the given revision
+	ErrStaleRevision = errors.New("stale revision")
+
+	// ErrRevisionCompacted is returned when a client refers to a revision
+	// that Compact has discarded. The client should fetch the current
+	// document and start again from its revision.
+	ErrRevisionCompacted = errors.New("revision compacted")
+
+	// ErrRevisionPinned is returned by Compact when a pinned revision would be discarded
+	ErrRevisionPinned = errors.New("revision pinned")
+
+	// ErrDocumentTooLarge is returned when an operation would grow the
+	// document past the server's MaxDocLen.
+	ErrDocumentTooLarge = errors.New("document too large")
+
+	// ErrTooFarBehind is returned when an operation was made against a
+	// revision more than the server's MaxPending operations old. The client
+	// should resync and rebase its changes locally.
+	ErrTooFarBehind = errors.New("client too far behind")
+)
+
+// Server holds the authoritative copy of a document together with the
+// operations applied to it. Revision n is the document after the first n
+// operations. The history kept in memory starts at a base revision, which is
+// zero for a new document and the snapshot revision for one restored from a
+// Store or compacted.
+//
+// A Server is safe for concurrent use.
+type Server struct {
+	mu       sync.Mutex
+	doc      string
+	base     int
+	snapshot string // the document at base
+	history  []*OperationSeq
+	accepted []time.Time // when each operation in history was accepted
+	pins     map[*Pin]struct{}
+	opIDs    opIDs
+	mode     Mode
+	frozenAt int
+	undo     map[string][]undoEntry // by client
+	audit    []AuditEntry
+	auditLen int
+	blame    *Attribution // nil unless TrackAuthors was called
+	lines    *LineIndex   // nil unless TrackLines was called
+	stamping bool         // whether to stamp metadata; see SetStampMeta
+
+	protected     AnnotationSet
+	protectPolicy ProtectPolicy
+	verifier      Verifier
+
+	name    string
+	store   Store
+	limits  ServerLimits
+	metrics Metrics
+	logger  *slog.Logger
+
+	checkpoints      CheckpointPolicy
+	lastCheckpoint   int
+	lastCheckpointAt time.Time
+	checkpointErr    error
+	retention        RetentionPolicy
+	named            map[string]namedCheckpoint
+	now              func() time.Time
+}
+
+// NewServer creates a server for a document with the given initial content.
+func NewServer(doc string) *Server {
+	return &Server{
+		doc:      doc,
+		snapshot: doc,
+		history:  make([]*OperationSeq, 0),
+		pins:     make(map[*Pin]struct{}),
+		metrics:  NopMetrics{},
+		logger:   discard,
+		now:      time.Now,
+	}
+}
+
+// OpenServer restores a document from store and returns a server that
+// persists every accepted operation to it before applying it.
+//
+// The document is rebuilt from the latest snapshot plus the operations saved
+// after it. A document with nothing stored starts empty at revision 0. A
+// history that does not replay is reported as a *ReplayError.
+func OpenServer(ctx context.Context, store Store, name string) (*Server, error) {
+	snapshot, base, err := store.LoadLatestSnapshot(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	ops, err := store.LoadOpsSince(ctx, name, base)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := replay(snapshot, base, ops)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		doc:      doc,
+		base:     base,
+		snapshot: snapshot,
+		history:  ops,
+		accepted: acceptedNow(len(ops)),
+		pins:     make(map[*Pin]struct{}),
+		name:     name,
+		store:    store,
+		metrics:  NopMetrics{},
+		logger:   discard,
+
+		lastCheckpoint:   base,
+		lastCheckpointAt: time.Now(),
+		now:              time.Now,
+	}, nil
+}
+
+// Document returns the current content of the document.
+func (s *Server) Document() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.doc
+}
+
+// Revision returns the current revision, which is the number of operations
+// applied so far.
+func (s *Server) Revision() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.revision()
+}
+
+func (s *Server) revision() int {
+	return s.base + len(s.history)
+}
+
+// State returns the current document and revision together. Documents are
+// immutable strings, so the result is a consistent snapshot in constant time,
+// without copying: slow readers such as exporters and indexers can keep
+// working from it while edits continue.
+func (s *Server) State() (string, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.doc, s.revision()
+}
+
+// DocumentAt returns the document as it was at revision, rebuilt from the
+// base snapshot and the operations after it, for showing version history.
+//
+// Revisions saved with NameCheckpoint remain available after they are
+// compacted. Returns ErrRevisionCompacted for any other revision older than
+// the history kept, and ErrInvalidRevision if it is negative or in the
+// future.
+func (s *Server) DocumentAt(revision int) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkRevision(revision); err != nil {
+		if doc, ok := s.namedDocumentAt(revision); ok && errors.Is(err, ErrRevisionCompacted) {
+			return doc, nil
+		}
+		return "", err
+	}
+	return s.documentAt(revision)
+}
+
+// documentAt implements DocumentAt. Callers hold s.mu and have checked
+// revision.
+func (s *Server) documentAt(revision int) (string, error) {
+	if revision == s.revision() {
+		return s.doc, nil
+	}
+	return replay(s.snapshot, s.base, s.history[:revision-s.base])
+}
+
+// OperationsSince returns the operations that took the document from rev to
+// the current revision, along with the current revision. It is the catch-up
+// call for a reconnecting client: applying the operations to its copy at rev
+// brings it to the returned revision.
+//
+// Returns ErrRevisionCompacted if rev is older than the history kept, in
+// which case the client must refetch the document with State, and
+// ErrInvalidRevision if rev is negative or in the future.
+func (s *Server) OperationsSince(rev int) ([]*OperationSeq, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkRevision(rev); err != nil {
+		return nil, 0, err
+	}
+	ops := make([]*OperationSeq, s.revision()-rev)
+	copy(ops, s.history[rev-s.base:])
+	return ops, s.revision(), nil
+}
+
+// checkRevision returns ErrInvalidRevision or ErrRevisionCompacted if rev is
+// not in the history. Callers hold s.mu.
+func (s *Server) checkRevision(rev int) error {
+	switch {
+	case rev < 0 || rev > s.revision():
+		return ErrInvalidRevision
+	case rev < s.base:
+		return ErrRevisionCompacted
+	}
+	return nil
+}
+
+// Sync applies operations that other writers saved to the server's Store
+// since its current revision, for servers on different nodes sharing a
+// Store. It returns the operations applied and the resulting revision.
+// Without a Store it does nothing.
+func (s *Server) Sync(ctx context.Context) ([]*OperationSeq, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.store == nil {
+		return nil, s.revision(), nil
+	}
+	ops, err := s.store.LoadOpsSince(ctx, s.name, s.revision())
+	if err != nil {
+		s.logger.Error("loading operations failed", "revision", s.revision(), "err", err)
+		return nil, 0, err
+	}
+
+	doc := s.doc
+	for i, op := range ops {
+		if doc, err = op.Apply(doc); err != nil {
+			s.logger.Error("stored operation does not apply", "revision", s.revision()+i, "err", err)
+			return nil, 0, err
+		}
+	}
+	s.doc = doc
+	s.history = append(s.history, ops...)
+	for _, op := range ops {
+		s.accepted = append(s.accepted, s.now())
+		s.attribute("", op)
+		s.indexLines(op)
+		s.protected.Transform(op)
+	}
+	s.maybeCollect(ctx)
+	return ops, s.revision(), nil
+}
+
+// ReceiveOperation accepts an operation a client made against clientRevision.
+//
+// The operation is transformed against every operation the server accepted
+// after clientRevision, applied to the document, and appended to the history.
+// The transformed operation is returned; it is what should be broadcast to
+// the other clients and acknowledged to the sender.
+//
+// Returns ErrInvalidRevision if clientRevision is negative or in the future,
+// ErrRevisionCompacted if it has been compacted away, or
+// ErrIncompatibleLengths if the operation does not fit the document. If the
+// server has a Store, errors from saving the operation are returned as is and
+// the operation is not applied; after ErrConflict, call Sync and retry.
+//
+// ctx is passed to the Store. If it is done before the operation is applied,
+// its error is returned and the operation is not applied.
+func (s *Server) ReceiveOperation(ctx context.Context, clientRevision int, op *OperationSeq) (*OperationSeq, error) {
+	op, _, err := s.Submit(ctx, clientRevision, op)
+	return op, err
+}
+
+// Submit is ReceiveOperation that also returns the revision the operation
+// produced. Reading Revision afterwards is not equivalent, since another
+// operation may have been accepted in between.
+func (s *Server) Submit(ctx context.Context, clientRevision int, op *OperationSeq) (*OperationSeq, int, error) {
+	return s.SubmitOp(ctx, "", "", clientRevision, op)
+}
+
+// ApplyAt applies op only if the document is still at revision, without
+// transforming it. It is the compare-and-swap counterpart of
+// ReceiveOperation, for callers that prefer to refetch and retry over having
+// their edit rebased.
+//
+// The operation applied is returned. It is op itself unless the server
+// changed it, by clipping it to its protected ranges or stamping its
+// metadata, and is what should be broadcast to the other clients.
+//
+// Returns ErrStaleRevision if other operations were accepted since revision.
+func (s *Server) ApplyAt(ctx context.Context, revision int, op *OperationSeq) (*OperationSeq, error) {
+	op, _, err := s.applyAt(ctx, "", revision, op)
+	return op, err
+}
+
+// applyAt implements ApplyAt for an operation submitted by client, and also
+// reports whether the operation was clipped.
+func (s *Server) applyAt(ctx context.Context, client string, revision int, op *OperationSeq) (*OperationSeq, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.receive(ctx, client, revision, op, true)
+}
+
+// receive implements ReceiveOperation and ApplyAt. It also reports whether
+// op was clipped to the protected ranges. Callers hold s.mu.
+func (s *Server) receive(ctx context.Context, client string, clientRevision int, op *OperationSeq, strict bool) (*OperationSeq, bool, error) {
+	// The caller may have given up while waiting for s.mu.
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	if err := s.checkRevision(clientRevision); err != nil {
+		return nil, false, s.reject(clientRevision, err)
+	}
+	if strict && clientRevision != s.revision() {
+		return nil, false, s.reject(clientRevision, ErrStaleRevision)
+	}
+	if err := s.checkMode(op); err != nil {
+		return nil, false, s.reject(clientRevision, err)
+	}
+	if err := s.limits.check(s.revision()-clientRevision, op); err != nil {
+		return nil, false, s.reject(clientRevision, err)
+	}
+
+	original := op
+	if concurrent := s.history[clientRevision-s.base:]; len(concurrent) > 0 {
+		start := time.Now()
+		transformed, i, err := op.transformAll(concurrent)
+		if err != nil {
+			s.logger.Warn("transform failed", "revision", clientRevision, "against", clientRevision+i, "err", err)
+			return nil, false, err
+		}
+		s.metrics.Transformed(len(concurrent), time.Since(start))
+		if m, ok := s.metrics.(ConflictMetrics); ok {
+			m.TransformOutcome(transformOutcome(op, transformed))
+		}
+		op = transformed
+	}
+
+	checked, err := s.checkProtected(op)
+	if err != nil {
+		return nil, false, s.reject(clientRevision, err)
+	}
+	clipped := checked != op
+	op = checked
+
+	if limit := s.limits.MaxDocLen; limit > 0 && op.targetLen > limit && op.targetLen > op.baseLen {
+		return nil, false, s.reject(clientRevision, ErrDocumentTooLarge)
+	}
+
+	doc, err := op.Apply(s.doc)
+	if err != nil {
+		return nil, false, s.reject(clientRevision, err)
+	}
+	at := s.now()
+	op = s.stamp(client, op, at)
+
+	if s.store != nil {
+		if err := s.store.SaveOp(ctx, s.name, s.revision(), op); err != nil {
+			level := slog.LevelError
+			if errors.Is(err, ErrConflict) {
+				level = slog.LevelDebug
+			}
+			s.logger.Log(ctx, level, "saving operation failed", "revision", s.revision(), "err", err)
+			return nil, false, err
+		}
+	}
+
+	s.doc = doc
+	s.history = append(s.history, op)
+	s.accepted = append(s.accepted, at)
+	s.attribute(client, op)
+	s.indexLines(op)
+	s.protected.Transform(op)
+	s.record(client, clientRevision, original, op)
+	s.metrics.OpAccepted()
+	s.maybeCheckpoint(ctx)
+	s.maybeCollect(ctx)
+	return op, clipped, nil
+}
+
+// reject logs an operation refused because of err and returns err. Callers
+// hold s.mu.
+func (s *Server) reject(revision int, err error) error {
+	s.logger.Info("operation rejected", "revision", revision, "err", err)
+	return err
+}
+
+// Pin marks a revision that a client may still refer to, so Compact keeps
+// the history from it onwards. Hub subscriptions hold one for each client.
+type Pin struct {
+	server   *Server
+	revision int
+}
+
+// Pin pins revision until the returned Pin is released.
+//
+// Returns ErrInvalidRevision or ErrRevisionCompacted if revision is outside
+// the history.
+func (s *Server) Pin(revision int) (*Pin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkRevision(revision); err != nil {
+		return nil, err
+	}
+	p := &Pin{server: s, revision: revision}
+	s.pins[p] = struct{}{}
+	return p, nil
+}
+
+// Revision returns the pinned revision.
+func (p *Pin) Revision() int {
+	p.server.mu.Lock()
+	defer p.server.mu.Unlock()
+	return p.revision
+}
+
+// Advance moves the pin forward to revision, once the client can no longer
+// refer to anything older. Revisions at or before the current one, or past
+// the end of the history, are ignored.
+func (p *Pin) Advance(revision int) {
+	p.server.mu.Lock()
+	defer p.server.mu.Unlock()
+
+	if revision > p.revision && revision <= p.server.revision() {
+		p.revision = revision
+	}
+}
+
+// Release removes the pin. It is safe to call more than once.
+func (p *Pin) Release() {
+	p.server.mu.Lock()
+	defer p.server.mu.Unlock()
+	delete(p.server.pins, p)
+}
+
+// Compact folds the operations up to throughRevision into the base snapshot
+// and drops them from memory, so the history of a long-lived document stays
+// bounded. Afterwards OperationsSince and ReceiveOperation reject revisions
+// before throughRevision with ErrRevisionCompacted.
+//
+// If the server has a Store, the new snapshot is saved to it first, and if
+// the Store implements Trimmer the operations it covers are discarded there
+// too.
+//
+// Returns ErrRevisionPinned if a Pin holds a revision before throughRevision,
+// and ErrInvalidRevision or ErrRevisionCompacted if throughRevision is
+// outside the history.
+func (s *Server) Compact(ctx context.Context, throughRevision int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkRevision(throughRevision); err != nil {
+		return err
+	}
+	return s.compact(ctx, throughRevision)
+}
+
+// compact implements Compact. Callers hold s.mu and have checked
+// throughRevision.
+func (s *Server) compact(ctx context.Context, throughRevision int) error {
+	for p := range s.pins {
+		if p.revision < throughRevision {
+			return ErrRevisionPinned
+		}
+	}
+	if throughRevision == s.base {
+		return nil
+	}
+
+	n := throughRevision - s.base
+	composed := s.history[0]
+	for _, op := range s.history[1:n] {
+		var err error
+		if composed, err = composed.Compose(op); err != nil {
+			return err
+		}
+	}
+	snapshot, err := composed.Apply(s.snapshot)
+	if err != nil {
+		return err
+	}
+
+	if s.store != nil {
+		if err := s.store.SaveSnapshot(ctx, s.name, throughRevision, snapshot); err != nil {
+			return err
+		}
+		if t, ok := s.store.(Trimmer); ok {
+			if err := t.TrimOps(ctx, s.name, throughRevision); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.snapshot = snapshot
+	s.base = throughRevision
+	if s.store != nil && throughRevision > s.lastCheckpoint {
+		s.lastCheckpoint = throughRevision
+		s.lastCheckpointAt = s.now()
+	}
+	s.history = append(make([]*OperationSeq, 0, len(s.history)-n), s.history[n:]...)
+	s.accepted = append(make([]time.Time, 0, len(s.accepted)-n), s.accepted[n:]...)
+	return nil
+}
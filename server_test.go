@@ -0,0 +1,488 @@
+package ot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestServerReceiveOperation(t *testing.T) {
+	s := NewServer("hello")
+
+	// Two clients edit revision 0 concurrently.
+	a := NewOperationSeq()
+	a.Retain(5)
+	a.Insert(" world")
+
+	b := NewOperationSeq()
+	b.Insert(">> ")
+	b.Retain(5)
+
+	if _, err := s.ReceiveOperation(context.Background(), 0, a); err != nil {
+		t.Fatalf("ReceiveOperation(a) failed: %v", err)
+	}
+	bPrime, err := s.ReceiveOperation(context.Background(), 0, b)
+	if err != nil {
+		t.Fatalf("ReceiveOperation(b) failed: %v", err)
+	}
+
+	if s.Document() != ">> hello world" {
+		t.Errorf("expected %q, got %q", ">> hello world", s.Document())
+	}
+	if s.Revision() != 2 {
+		t.Errorf("expected revision 2, got %d", s.Revision())
+	}
+
+	// Client A receives B' and must converge.
+	afterA, _ := a.Apply("hello")
+	got, err := bPrime.Apply(afterA)
+	if err != nil {
+		t.Fatalf("Apply B' failed: %v", err)
+	}
+	if got != s.Document() {
+		t.Errorf("client A diverged: %q != %q", got, s.Document())
+	}
+}
+
+func TestServerInvalidRevision(t *testing.T) {
+	s := NewServer("abc")
+	op := NewOperationSeq()
+	op.Retain(3)
+
+	for _, rev := range []int{-1, 1} {
+		if _, err := s.ReceiveOperation(context.Background(), rev, op); !errors.Is(err, ErrInvalidRevision) {
+			t.Errorf("revision %d: expected ErrInvalidRevision, got %v", rev, err)
+		}
+	}
+}
+
+func TestServerIncompatibleOperation(t *testing.T) {
+	s := NewServer("abc")
+	op := NewOperationSeq()
+	op.Retain(10)
+
+	if _, err := s.ReceiveOperation(context.Background(), 0, op); !errors.Is(err, ErrIncompatibleLengths) {
+		t.Errorf("expected ErrIncompatibleLengths, got %v", err)
+	}
+	if s.Revision() != 0 || s.Document() != "abc" {
+		t.Error("rejected operation must not change the server state")
+	}
+}
+
+func TestServerConcurrentClients(t *testing.T) {
+	s := NewServer("")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			op := NewOperationSeq()
+			op.Insert("x")
+			if _, err := s.ReceiveOperation(context.Background(), 0, op); err != nil {
+				t.Errorf("ReceiveOperation failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if s.Document() != "xxxxxxxxxxxxxxxxxxxx" {
+		t.Errorf("expected 20 x's, got %q", s.Document())
+	}
+}
+
+func TestServerOperationsSince(t *testing.T) {
+	s := NewServer("")
+	for _, text := range []string{"a", "b", "c"} {
+		op := NewOperationSeq()
+		op.Retain(uint64(s.Revision()))
+		op.Insert(text)
+		if _, err := s.ReceiveOperation(context.Background(), s.Revision(), op); err != nil {
+			t.Fatalf("ReceiveOperation failed: %v", err)
+		}
+	}
+
+	ops, rev, err := s.OperationsSince(1)
+	if err != nil {
+		t.Fatalf("OperationsSince failed: %v", err)
+	}
+	if rev != 3 || len(ops) != 2 {
+		t.Fatalf("expected 2 ops up to revision 3, got %d up to %d", len(ops), rev)
+	}
+
+	doc := "a"
+	for _, op := range ops {
+		if doc, err = op.Apply(doc); err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+	}
+	if doc != s.Document() {
+		t.Errorf("replay: expected %q, got %q", s.Document(), doc)
+	}
+
+	if _, _, err := s.OperationsSince(4); !errors.Is(err, ErrInvalidRevision) {
+		t.Errorf("expected ErrInvalidRevision, got %v", err)
+	}
+}
+
+func TestServerApplyAt(t *testing.T) {
+	s := NewServer("abc")
+
+	op := NewOperationSeq()
+	op.Retain(3)
+	op.Insert("d")
+	if _, err := s.ApplyAt(context.Background(), 0, op); err != nil {
+		t.Fatalf("ApplyAt failed: %v", err)
+	}
+
+	stale := NewOperationSeq()
+	stale.Delete(3)
+	if _, err := s.ApplyAt(context.Background(), 0, stale); !errors.Is(err, ErrStaleRevision) {
+		t.Errorf("expected ErrStaleRevision, got %v", err)
+	}
+	if s.Document() != "abcd" {
+		t.Errorf("expected %q, got %q", "abcd", s.Document())
+	}
+}
+
+// memStore is a Store for a single document kept in memory, for tests. Like
+// a shared database, it reports ErrConflict for revisions already saved.
+type memStore struct {
+	mu       sync.Mutex
+	ops      []*OperationSeq
+	snapshot string
+	snapRev  int
+	fail     error
+}
+
+func (m *memStore) SaveOp(_ context.Context, _ string, revision int, op *OperationSeq) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fail != nil {
+		return m.fail
+	}
+	if revision < len(m.ops) {
+		return ErrConflict
+	}
+	if revision != len(m.ops) {
+		return fmt.Errorf("expected revision %d, got %d", len(m.ops), revision)
+	}
+	m.ops = append(m.ops, op)
+	return nil
+}
+
+func (m *memStore) LoadOpsSince(_ context.Context, _ string, revision int) ([]*OperationSeq, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*OperationSeq{}, m.ops[revision:]...), nil
+}
+
+func (m *memStore) SaveSnapshot(_ context.Context, _ string, revision int, content string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.snapshot, m.snapRev = content, revision
+	return nil
+}
+
+func (m *memStore) LoadLatestSnapshot(context.Context, string) (string, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.snapshot, m.snapRev, nil
+}
+
+func TestOpenServer(t *testing.T) {
+	store := &memStore{}
+	s, err := OpenServer(context.Background(), store, "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	for i, text := range []string{"a", "b", "c"} {
+		op := NewOperationSeq()
+		op.Retain(uint64(i))
+		op.Insert(text)
+		if _, err := s.ReceiveOperation(context.Background(), i, op); err != nil {
+			t.Fatalf("ReceiveOperation failed: %v", err)
+		}
+	}
+	if err := store.SaveSnapshot(context.Background(), "doc", 2, "ab"); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	restored, err := OpenServer(context.Background(), store, "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	if doc, rev := restored.State(); doc != "abc" || rev != 3 {
+		t.Errorf("expected (%q, 3), got (%q, %d)", "abc", doc, rev)
+	}
+
+	// Only history after the snapshot is available.
+	if _, _, err := restored.OperationsSince(1); !errors.Is(err, ErrRevisionCompacted) {
+		t.Errorf("expected ErrRevisionCompacted before snapshot, got %v", err)
+	}
+	ops, rev, err := restored.OperationsSince(2)
+	if err != nil || len(ops) != 1 || rev != 3 {
+		t.Errorf("expected 1 op up to revision 3, got (%d, %d, %v)", len(ops), rev, err)
+	}
+
+	// A client at the snapshot revision is still transformed correctly.
+	op := NewOperationSeq()
+	op.Retain(2)
+	op.Insert("X")
+	if _, err := restored.ReceiveOperation(context.Background(), 2, op); err != nil {
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+	if restored.Document() != "abXc" {
+		t.Errorf("expected %q, got %q", "abXc", restored.Document())
+	}
+}
+
+func TestServerStoreFailure(t *testing.T) {
+	errDisk := errors.New("disk full")
+	store := &memStore{fail: errDisk}
+	s, err := OpenServer(context.Background(), store, "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+
+	op := NewOperationSeq()
+	op.Insert("a")
+	if _, err := s.ReceiveOperation(context.Background(), 0, op); !errors.Is(err, errDisk) {
+		t.Errorf("expected store error, got %v", err)
+	}
+	if doc, rev := s.State(); doc != "" || rev != 0 {
+		t.Errorf("expected unchanged document, got (%q, %d)", doc, rev)
+	}
+}
+
+func TestServerSync(t *testing.T) {
+	store := &memStore{}
+	a, err := OpenServer(context.Background(), store, "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	b, err := OpenServer(context.Background(), store, "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+
+	op := NewOperationSeq()
+	op.Insert("a")
+	if _, err := a.ReceiveOperation(context.Background(), 0, op); err != nil {
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+
+	// b has not seen a's operation, so saving at the same revision conflicts.
+	other := NewOperationSeq()
+	other.Insert("b")
+	if _, err := b.ReceiveOperation(context.Background(), 0, other); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+
+	ops, rev, err := b.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if len(ops) != 1 || rev != 1 {
+		t.Errorf("expected 1 op up to revision 1, got %d up to %d", len(ops), rev)
+	}
+	if _, err := b.ReceiveOperation(context.Background(), 0, other); err != nil {
+		t.Fatalf("ReceiveOperation after Sync failed: %v", err)
+	}
+	if _, _, err := a.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if a.Document() != b.Document() {
+		t.Errorf("expected convergence, got %q and %q", a.Document(), b.Document())
+	}
+}
+
+func TestServerCompact(t *testing.T) {
+	store := &memStore{}
+	s, err := OpenServer(context.Background(), store, "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	for i, text := range []string{"a", "b", "c", "d"} {
+		op := NewOperationSeq()
+		op.Retain(uint64(i))
+		op.Insert(text)
+		if _, err := s.ReceiveOperation(context.Background(), i, op); err != nil {
+			t.Fatalf("ReceiveOperation failed: %v", err)
+		}
+	}
+
+	pin, err := s.Pin(1)
+	if err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	if err := s.Compact(context.Background(), 3); !errors.Is(err, ErrRevisionPinned) {
+		t.Errorf("expected ErrRevisionPinned, got %v", err)
+	}
+	pin.Advance(3)
+	if err := s.Compact(context.Background(), 3); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if err := s.Compact(context.Background(), 5); !errors.Is(err, ErrInvalidRevision) {
+		t.Errorf("expected ErrInvalidRevision past the end, got %v", err)
+	}
+
+	if store.snapshot != "abc" || store.snapRev != 3 {
+		t.Errorf("expected snapshot (%q, 3), got (%q, %d)", "abc", store.snapshot, store.snapRev)
+	}
+	if doc, rev := s.State(); doc != "abcd" || rev != 4 {
+		t.Errorf("expected (%q, 4), got (%q, %d)", "abcd", doc, rev)
+	}
+
+	// Revisions before the compaction point are gone.
+	if _, _, err := s.OperationsSince(2); !errors.Is(err, ErrRevisionCompacted) {
+		t.Errorf("expected ErrRevisionCompacted, got %v", err)
+	}
+	old := NewOperationSeq()
+	old.Insert("x")
+	if _, err := s.ReceiveOperation(context.Background(), 0, old); !errors.Is(err, ErrRevisionCompacted) {
+		t.Errorf("expected ErrRevisionCompacted, got %v", err)
+	}
+	if _, err := s.Pin(2); !errors.Is(err, ErrRevisionCompacted) {
+		t.Errorf("expected ErrRevisionCompacted, got %v", err)
+	}
+	if _, _, err := s.OperationsSince(-1); !errors.Is(err, ErrInvalidRevision) {
+		t.Errorf("expected ErrInvalidRevision for a negative revision, got %v", err)
+	}
+
+	// A client at the compaction point is still rebased correctly.
+	op := NewOperationSeq()
+	op.Retain(3)
+	op.Insert("X")
+	if _, err := s.ReceiveOperation(context.Background(), 3, op); err != nil {
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+	if s.Document() != "abcXd" {
+		t.Errorf("expected %q, got %q", "abcXd", s.Document())
+	}
+
+	pin.Release()
+	if err := s.Compact(context.Background(), 5); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if ops, rev, err := s.OperationsSince(5); err != nil || len(ops) != 0 || rev != 5 {
+		t.Errorf("expected empty history at revision 5, got (%d, %d, %v)", len(ops), rev, err)
+	}
+}
+
+func TestServerLimits(t *testing.T) {
+	s := NewServer("hello")
+	s.SetLimits(ServerLimits{MaxDocLen: 8, MaxInsertLen: 3, MaxPending: 1})
+
+	insert := func(at uint64, text string, rest uint64) *OperationSeq {
+		op := NewOperationSeq()
+		op.Retain(at)
+		op.Insert(text)
+		op.Retain(rest)
+		return op
+	}
+
+	if _, err := s.ReceiveOperation(context.Background(), 0, insert(5, "!!!!", 0)); !errors.Is(err, ErrOpTooLarge) {
+		t.Errorf("expected ErrOpTooLarge, got %v", err)
+	}
+	if _, err := s.ReceiveOperation(context.Background(), 0, insert(5, "!!!", 0)); err != nil {
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+	if _, err := s.ReceiveOperation(context.Background(), 1, insert(8, "?", 0)); !errors.Is(err, ErrDocumentTooLarge) {
+		t.Errorf("expected ErrDocumentTooLarge, got %v", err)
+	}
+
+	replace := NewOperationSeq()
+	replace.Delete(1)
+	replace.Insert("H")
+	replace.Retain(7)
+	if _, err := s.ReceiveOperation(context.Background(), 1, replace); err != nil {
+		t.Fatalf("expected same-length edit at the limit to pass, got %v", err)
+	}
+
+	shrink := NewOperationSeq()
+	shrink.Retain(5)
+	shrink.Delete(3)
+	if _, err := s.ReceiveOperation(context.Background(), 0, shrink); !errors.Is(err, ErrTooFarBehind) {
+		t.Errorf("expected ErrTooFarBehind, got %v", err)
+	}
+	if _, err := s.ReceiveOperation(context.Background(), 1, shrink); err != nil {
+		t.Errorf("expected op one revision behind to pass, got %v", err)
+	}
+	if got := s.Document(); got != "Hello" {
+		t.Errorf("expected %q, got %q", "Hello", got)
+	}
+}
+
+// blockingStore makes SaveOp wait for its context, like a store that cannot
+// reach its database.
+type blockingStore struct {
+	*memStore
+}
+
+func (blockingStore) SaveOp(ctx context.Context, _ string, _ int, _ *OperationSeq) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestServerContext(t *testing.T) {
+	s := NewServer("hello")
+	op := NewOperationSeq()
+	op.Retain(5)
+	op.Insert("!")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.ReceiveOperation(ctx, 0, op); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	s, err := OpenServer(context.Background(), blockingStore{&memStore{}}, "doc")
+	if err != nil {
+		t.Fatalf("OpenServer failed: %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := s.ReceiveOperation(ctx, 0, NewOperationSeq()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if s.Revision() != 0 {
+		t.Errorf("expected revision 0, got %d", s.Revision())
+	}
+}
+
+func TestServerDocumentAt(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer("a")
+	for i := 1; i <= 3; i++ {
+		op := NewOperationSeq()
+		op.Retain(uint64(i))
+		op.Insert(string(rune('a' + i)))
+		if _, err := s.ReceiveOperation(ctx, i-1, op); err != nil {
+			t.Fatalf("ReceiveOperation failed: %v", err)
+		}
+	}
+
+	for rev, want := range []string{"a", "ab", "abc", "abcd"} {
+		got, err := s.DocumentAt(rev)
+		if err != nil || got != want {
+			t.Errorf("revision %d: expected %q, got %q (%v)", rev, want, got, err)
+		}
+	}
+	if _, err := s.DocumentAt(4); !errors.Is(err, ErrInvalidRevision) {
+		t.Errorf("expected ErrInvalidRevision, got %v", err)
+	}
+
+	if err := s.Compact(ctx, 2); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if got, err := s.DocumentAt(2); err != nil || got != "abc" {
+		t.Errorf("expected %q at the new base, got %q (%v)", "abc", got, err)
+	}
+	if _, err := s.DocumentAt(1); !errors.Is(err, ErrRevisionCompacted) {
+		t.Errorf("expected ErrRevisionCompacted, got %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package ot
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+)
+
+func TestSignedOpVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	tests := []struct {
+		name     string
+		signer   Signer
+		verifier Verifier
+		other    Verifier
+	}{
+		{"hmac", HMACKey("secret"), HMACKey("secret"), HMACKey("other")},
+		{"ed25519", Ed25519Signer(priv), Ed25519Verifier(pub), Ed25519Verifier(make([]byte, ed25519.PublicKeySize))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := NewOperationSeq()
+			op.Retain(5)
+			op.Insert("!")
+			op.SetMeta(Meta{Origin: "paste"})
+			so, err := Sign(tt.signer, "doc", "alice", "1", 0, op)
+			if err != nil {
+				t.Fatalf("Sign failed: %v", err)
+			}
+			if err := so.Verify(tt.verifier); err != nil {
+				t.Errorf("expected signature to verify, got %v", err)
+			}
+			if err := so.Verify(tt.other); !errors.Is(err, ErrBadSignature) {
+				t.Errorf("expected ErrBadSignature with another key, got %v", err)
+			}
+
+			tampered := []func(*SignedOp){
+				func(so *SignedOp) { so.Doc = "other" },
+				func(so *SignedOp) { so.Client = "mallory" },
+				func(so *SignedOp) { so.ID = "2" },
+				func(so *SignedOp) { so.ID = "" },
+				func(so *SignedOp) { so.Revision = 1 },
+				func(so *SignedOp) {
+					op := NewOperationSeq()
+					op.Retain(5)
+					op.Insert("?")
+					so.Op = op
+				},
+				func(so *SignedOp) { so.Op = nil },
+			}
+			for i, tamper := range tampered {
+				forged := so
+				tamper(&forged)
+				if err := forged.Verify(tt.verifier); !errors.Is(err, ErrBadSignature) {
+					t.Errorf("tamper %d: expected ErrBadSignature, got %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestServerSubmitSigned(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer("hello")
+	op := NewOperationSeq()
+	op.Retain(5)
+	op.Insert("!")
+	so, err := Sign(HMACKey("secret"), "doc", "alice", "1", 0, op)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	if _, _, err := s.SubmitSigned(ctx, so); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("expected ErrBadSignature without a verifier, got %v", err)
+	}
+	s.SetVerifier(HMACKey("secret"))
+	forged := so
+	forged.Client = "mallory"
+	if _, _, err := s.SubmitSigned(ctx, forged); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden for a forged op, got %v", err)
+	}
+	if _, rev, err := s.SubmitSigned(ctx, so); err != nil || rev != 1 {
+		t.Fatalf("expected revision 1, got %d, %v", rev, err)
+	}
+	if got := s.Document(); got != "hello!" {
+		t.Errorf("expected hello!, got %q", got)
+	}
+}
+
+func TestHubSubmitSigned(t *testing.T) {
+	h := NewHub(func(context.Context, string) (*Server, error) { return NewServer("hello"), nil })
+	ctx := context.Background()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+
+	op := NewOperationSeq()
+	op.Retain(5)
+	op.Insert("!")
+	if _, err := Sign(Ed25519Signer(priv), "doc", "alice", "", 0, op); !errors.Is(err, ErrMissingOpID) {
+		t.Errorf("expected ErrMissingOpID, got %v", err)
+	}
+	so, err := Sign(Ed25519Signer(priv), "doc", "alice", "1", 0, op)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if _, _, err := h.SubmitSigned(ctx, so); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("expected ErrBadSignature without a verifier, got %v", err)
+	}
+
+	h.Verifier = Ed25519Verifier(pub)
+	forged := so
+	forged.Doc = "other"
+	if _, _, err := h.SubmitSigned(ctx, forged); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("expected ErrBadSignature for another doc, got %v", err)
+	}
+	if _, rev, err := h.SubmitSigned(ctx, so); err != nil || rev != 1 {
+		t.Fatalf("expected revision 1, got %d, %v", rev, err)
+	}
+	// A replayed copy is acknowledged without being applied again.
+	if _, rev, err := h.SubmitSigned(ctx, so); err != nil || rev != 1 {
+		t.Errorf("expected the replay to get revision 1, got %d, %v", rev, err)
+	}
+	s, err := h.Server(ctx, "doc")
+	if err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+	if got := s.Document(); got != "hello!" {
+		t.Errorf("expected hello!, got %q", got)
+	}
+}
+
+func TestEd25519Keys(t *testing.T) {
+	alicePub, alicePriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	bobPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	keys := Ed25519Keys{"alice": alicePub, "bob": bobPub}
+
+	op := NewOperationSeq()
+	op.Insert("!")
+	so, err := Sign(Ed25519Signer(alicePriv), "doc", "alice", "1", 0, op)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if err := so.Verify(keys); err != nil {
+		t.Errorf("expected alice's signature to verify, got %v", err)
+	}
+
+	// Alice cannot sign for Bob, nor for a client with no key.
+	for _, client := range []string{"bob", "mallory"} {
+		forged, err := Sign(Ed25519Signer(alicePriv), "doc", client, "1", 0, op)
+		if err != nil {
+			t.Fatalf("Sign failed: %v", err)
+		}
+		if err := forged.Verify(keys); !errors.Is(err, ErrBadSignature) {
+			t.Errorf("%s: expected ErrBadSignature, got %v", client, err)
+		}
+	}
+}
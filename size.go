@@ -0,0 +1,60 @@
+package ot
+
+import (
+	"time"
+	"unsafe"
+)
+
+// SizeBytes estimates the heap memory held by o: the sequence itself, its
+// components, and the text they insert. Text shared with other values is
+// counted anyway.
+func (o *OperationSeq) SizeBytes() int {
+	n := int(unsafe.Sizeof(*o)) + cap(o.ops)*int(unsafe.Sizeof(Component{}))
+	for _, c := range o.ops {
+		n += len(c.Text)
+	}
+	if o.meta != nil {
+		n += int(unsafe.Sizeof(*o.meta)) + len(o.meta.Author) + len(o.meta.Origin)
+	}
+	return n
+}
+
+// SizeBytes estimates the heap memory held by the server's document: its
+// text, the history kept since the last compaction, and the undo stacks,
+// audit trail, named checkpoints, and indexes kept alongside it. It is meant
+// for enforcing per-document memory budgets and reporting them, not for
+// exact accounting; memory shared between those parts may be counted twice.
+func (s *Server) SizeBytes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := int(unsafe.Sizeof(*s)) + len(s.doc) + len(s.snapshot)
+	for _, op := range s.history {
+		n += op.SizeBytes()
+	}
+	n += cap(s.history)*int(unsafe.Sizeof(s.history[0])) + cap(s.accepted)*int(unsafe.Sizeof(time.Time{}))
+	for client, stack := range s.undo {
+		n += len(client) + cap(stack)*int(unsafe.Sizeof(undoEntry{}))
+		for _, e := range stack {
+			n += e.inverse.SizeBytes()
+		}
+	}
+	n += cap(s.audit) * int(unsafe.Sizeof(AuditEntry{}))
+	for _, e := range s.audit {
+		// Applied is the operation kept in history, and counted there.
+		n += len(e.Client)
+		if e.Original != e.Applied {
+			n += e.Original.SizeBytes()
+		}
+	}
+	for name, c := range s.named {
+		n += len(name) + len(c.doc)
+	}
+	if s.blame != nil {
+		n += cap(s.blame.spans) * int(unsafe.Sizeof(Span{}))
+	}
+	if s.lines != nil {
+		n += (cap(s.lines.lines) + cap(s.lines.starts)) * int(unsafe.Sizeof(0))
+	}
+	return n
+}
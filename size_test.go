@@ -0,0 +1,37 @@
+package ot
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestOperationSeqSizeBytes(t *testing.T) {
+	small := NewOperationSeq()
+	small.Insert("a")
+	large := NewOperationSeq()
+	large.Insert(strings.Repeat("a", 1000))
+	if d := large.SizeBytes() - small.SizeBytes(); d != 999 {
+		t.Errorf("expected the larger insert to add 999 bytes, got %d", d)
+	}
+}
+
+func TestServerSizeBytes(t *testing.T) {
+	s := NewServer("")
+	empty := s.SizeBytes()
+	for i := 0; i < 10; i++ {
+		appendText(t, s, strings.Repeat("x", 100))
+	}
+	grown := s.SizeBytes()
+	// The document and each operation's inserted text, at least.
+	if grown-empty < 2000 {
+		t.Errorf("expected at least 2000 more bytes, got %d", grown-empty)
+	}
+
+	if err := s.Compact(context.Background(), 10); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if compacted := s.SizeBytes(); compacted >= grown {
+		t.Errorf("expected compaction to shrink %d bytes, got %d", grown, compacted)
+	}
+}
@@ -0,0 +1,79 @@
+package ot
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// AuthorStats summarizes one author's contribution to a document.
+type AuthorStats struct {
+	Ops      int `json:"ops"`
+	Inserted int `json:"inserted"` // characters inserted
+	Deleted  int `json:"deleted"`  // characters deleted, whoever inserted them
+
+	// Active holds the start of every time bucket in which the author made
+	// an operation, in order.
+	Active []time.Time `json:"active,omitempty"`
+
+	// Owned counts the characters of the final document the author
+	// inserted, and Ownership is their share of it, from 0 to 1.
+	Owned     int     `json:"owned"`
+	Ownership float64 `json:"ownership"`
+}
+
+// Contributions analyzes ops, consecutive operations taking a document of
+// baseLen characters to its current state, such as a Store's log, and
+// returns statistics for each author, as recorded in the operations' Meta.
+// The initial text and operations without an author are credited to the
+// empty author. Activity is recorded in buckets of the given width, taken
+// from Meta.Time with Time.Truncate; with a bucket of zero, or for
+// operations without a time, no activity is recorded.
+func Contributions(baseLen int, ops []*OperationSeq, bucket time.Duration) (map[string]*AuthorStats, error) {
+	stats := make(map[string]*AuthorStats)
+	get := func(author string) *AuthorStats {
+		st, ok := stats[author]
+		if !ok {
+			st = &AuthorStats{}
+			stats[author] = st
+		}
+		return st
+	}
+
+	owners := NewAttribution("", baseLen)
+	for i, op := range ops {
+		meta := op.Meta()
+		if err := owners.Apply(meta.Author, op); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+		st := get(meta.Author)
+		st.Ops++
+		for _, c := range op.ops {
+			switch c.Kind {
+			case KindInsert:
+				st.Inserted += int(c.N)
+			case KindDelete:
+				st.Deleted += int(c.N)
+			}
+		}
+		if bucket > 0 && !meta.Time.IsZero() {
+			at := meta.Time.Truncate(bucket)
+			if n := len(st.Active); n == 0 || !st.Active[n-1].Equal(at) {
+				st.Active = append(st.Active, at)
+			}
+		}
+	}
+
+	for _, sp := range owners.spans {
+		get(sp.Author).Owned += sp.Len
+	}
+	for _, st := range stats {
+		if owners.len > 0 {
+			st.Ownership = float64(st.Owned) / float64(owners.len)
+		}
+		// Operations are in revision order, but clocks may not be.
+		slices.SortFunc(st.Active, func(a, b time.Time) int { return a.Compare(b) })
+		st.Active = slices.CompactFunc(st.Active, time.Time.Equal)
+	}
+	return stats, nil
+}
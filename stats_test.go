@@ -0,0 +1,49 @@
+package ot
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestContributions(t *testing.T) {
+	at := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+	edit := func(author string, offset time.Duration, build func(op *OperationSeq)) *OperationSeq {
+		op := NewOperationSeq()
+		build(op)
+		op.SetMeta(Meta{Author: author, Time: at.Add(offset)})
+		return op
+	}
+	ops := []*OperationSeq{
+		// "ab" → "abhello"
+		edit("alice", 0, func(op *OperationSeq) { op.Retain(2); op.Insert("hello") }),
+		// → "abhello world"
+		edit("bob", 10*time.Minute, func(op *OperationSeq) { op.Retain(7); op.Insert(" world") }),
+		// → "hello world"
+		edit("alice", 2*time.Hour, func(op *OperationSeq) { op.Delete(2); op.Retain(11) }),
+		// → "hello world!"
+		edit("alice", 2*time.Hour+time.Minute, func(op *OperationSeq) { op.Retain(11); op.Insert("!") }),
+	}
+
+	stats, err := Contributions(2, ops, time.Hour)
+	if err != nil {
+		t.Fatalf("Contributions failed: %v", err)
+	}
+	alice, bob := stats["alice"], stats["bob"]
+	if alice == nil || alice.Ops != 3 || alice.Inserted != 6 || alice.Deleted != 2 || alice.Owned != 6 {
+		t.Errorf("expected alice with 3 ops, 6 inserted, 2 deleted and 6 owned, got %+v", alice)
+	}
+	if len(alice.Active) != 2 || !alice.Active[0].Equal(at) || !alice.Active[1].Equal(at.Add(2*time.Hour)) {
+		t.Errorf("expected alice active at 9:00 and 11:00, got %v", alice.Active)
+	}
+	if bob == nil || bob.Inserted != 6 || bob.Owned != 6 || bob.Ownership != 0.5 {
+		t.Errorf("expected bob to own half, got %+v", bob)
+	}
+	if initial, ok := stats[""]; ok {
+		t.Errorf("expected no stats for the deleted initial text, got %+v", initial)
+	}
+
+	if _, err := Contributions(3, ops, time.Hour); !errors.Is(err, ErrIncompatibleLengths) {
+		t.Errorf("expected ErrIncompatibleLengths, got %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package ot
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrConflict is returned by Store.SaveOp when another writer already saved the revision
+var ErrConflict = errors.New("revision conflict")
+
+// Store persists the history of documents so a Server can be restored after
+// a restart. Documents are identified by name.
+//
+// Revisions follow the Server convention: the operation saved at revision n
+// takes the document from revision n to n+1, and a snapshot at revision n is
+// the document after the first n operations.
+//
+// Every method takes a context that bounds how long it may block; the
+// Server passes on the context of the call that needs the store.
+// Implementations must be safe for concurrent use. The otlog package provides
+// a file-backed implementation.
+type Store interface {
+	// SaveOp durably records the operation applied at revision. Operations
+	// are saved in revision order without gaps. A store shared by several
+	// servers returns ErrConflict if the revision is already taken.
+	SaveOp(ctx context.Context, doc string, revision int, op *OperationSeq) error
+
+	// LoadOpsSince returns the saved operations from revision onwards, in
+	// order. It returns an empty slice if there are none.
+	LoadOpsSince(ctx context.Context, doc string, revision int) ([]*OperationSeq, error)
+
+	// SaveSnapshot records the full document content at revision.
+	SaveSnapshot(ctx context.Context, doc string, revision int, content string) error
+
+	// LoadLatestSnapshot returns the most recent snapshot. A document with no
+	// snapshot returns empty content at revision 0.
+	LoadLatestSnapshot(ctx context.Context, doc string) (string, int, error)
+}
+
+// Trimmer is implemented by stores that can discard operations already
+// covered by a snapshot. Server.Compact uses it when available.
+type Trimmer interface {
+	// TrimOps discards the operations saved before revision. A store that
+	// relies on saved operations to detect conflicts may keep the newest one.
+	TrimOps(ctx context.Context, doc string, revision int) error
+}
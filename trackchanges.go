@@ -0,0 +1,169 @@
+package ot
+
+import (
+	"errors"
+	"slices"
+)
+
+// ErrSuggestionNotFound is returned by TrackChanges when there is no
+// suggestion with the given ID.
+var ErrSuggestionNotFound = errors.New("suggestion not found")
+
+// Suggestion is an edit proposed in track-changes mode. Its inserted text
+// is in the document, marked by Inserted; the text it would delete is
+// still there too, marked by Deleted, for display struck through.
+type Suggestion struct {
+	ID       string  `json:"id"`
+	Author   string  `json:"author"`
+	Inserted []Range `json:"inserted,omitempty"`
+	Deleted  []Range `json:"deleted,omitempty"`
+}
+
+// TrackChanges records edits as suggestions instead of applying them
+// destructively, until each is accepted or rejected. It keeps the
+// suggestions' ranges in step with the document: every operation applied to
+// it, other than those returned by its own methods, must be passed to
+// Transform.
+//
+// The zero value is ready to use. A TrackChanges is not safe for concurrent
+// use.
+type TrackChanges struct {
+	suggestions map[string]*Suggestion
+	order       []string // IDs in the order they were suggested
+}
+
+// Suggest records op, an edit by author to doc, as the suggestion id,
+// replacing any suggestion with that ID. It returns the operation to apply
+// in place of op: the same inserts, with deletes turned into retains so the
+// text stays until the suggestion is accepted. Returns
+// ErrIncompatibleLengths if op does not apply to doc.
+func (t *TrackChanges) Suggest(id, author string, op *OperationSeq, doc string) (*OperationSeq, error) {
+	if n := charCount(doc); n != op.baseLen {
+		return nil, lengthMismatch(op.baseLen, n)
+	}
+	out := WithCapacity(len(op.ops))
+	s := &Suggestion{ID: id, Author: author}
+	pos := 0 // in the document after out
+	for _, v := range op.ops {
+		switch v.Kind {
+		case KindRetain:
+			out.Retain(v.N)
+		case KindInsert:
+			out.insert(v.Text, v.N)
+			s.Inserted = append(s.Inserted, Range{Start: pos, End: pos + int(v.N)})
+		case KindDelete:
+			out.Retain(v.N)
+			s.Deleted = append(s.Deleted, Range{Start: pos, End: pos + int(v.N)})
+		}
+		pos += int(v.N)
+	}
+
+	out.meta = op.meta
+	t.remove(id)
+	t.Transform(out)
+	if t.suggestions == nil {
+		t.suggestions = make(map[string]*Suggestion)
+	}
+	t.suggestions[id] = s
+	t.order = append(t.order, id)
+	return out, nil
+}
+
+// Suggestion returns a copy of the suggestion id.
+func (t *TrackChanges) Suggestion(id string) (Suggestion, bool) {
+	s, ok := t.suggestions[id]
+	if !ok {
+		return Suggestion{}, false
+	}
+	return s.clone(), true
+}
+
+// Suggestions returns copies of the pending suggestions, oldest first.
+func (t *TrackChanges) Suggestions() []Suggestion {
+	out := make([]Suggestion, 0, len(t.order))
+	for _, id := range t.order {
+		out = append(out, t.suggestions[id].clone())
+	}
+	return out
+}
+
+// AcceptSuggestion returns the operation that accepts the suggestion id, to
+// apply to doc, the current document: it deletes the text the suggestion
+// marked as deleted and leaves its insertions in place. The suggestion is
+// removed and the others transformed through the operation. Returns
+// ErrSuggestionNotFound or, if doc is not the document the suggestions
+// refer to, ErrIncompatibleLengths.
+func (t *TrackChanges) AcceptSuggestion(id, doc string) (*OperationSeq, error) {
+	return t.resolve(id, doc, func(s *Suggestion) []Range { return s.Deleted })
+}
+
+// RejectSuggestion returns the operation that rejects the suggestion id, to
+// apply to doc: it deletes the text the suggestion inserted and leaves the
+// text it would have deleted. Otherwise it is like AcceptSuggestion.
+func (t *TrackChanges) RejectSuggestion(id, doc string) (*OperationSeq, error) {
+	return t.resolve(id, doc, func(s *Suggestion) []Range { return s.Inserted })
+}
+
+func (t *TrackChanges) resolve(id, doc string, ranges func(*Suggestion) []Range) (*OperationSeq, error) {
+	s, ok := t.suggestions[id]
+	if !ok {
+		return nil, ErrSuggestionNotFound
+	}
+	n := charCount(doc)
+	del := slices.Clone(ranges(s))
+	slices.SortFunc(del, func(a, b Range) int { return a.Start - b.Start })
+	if len(del) > 0 && del[len(del)-1].End > n {
+		return nil, ErrIncompatibleLengths
+	}
+
+	op := NewOperationSeq()
+	pos := 0
+	for _, r := range del {
+		start := max(r.Start, pos)
+		if r.End <= start {
+			continue
+		}
+		op.Retain(uint64(start - pos))
+		op.Delete(uint64(r.End - start))
+		pos = r.End
+	}
+	op.Retain(uint64(n - pos))
+
+	t.remove(id)
+	t.Transform(op)
+	return op, nil
+}
+
+// Transform moves every suggestion through o, an operation applied to the
+// current document. Ranges whose text o deletes entirely are dropped.
+func (t *TrackChanges) Transform(o *OperationSeq) {
+	for _, s := range t.suggestions {
+		s.Inserted = transformRanges(s.Inserted, o)
+		s.Deleted = transformRanges(s.Deleted, o)
+	}
+}
+
+func (t *TrackChanges) remove(id string) {
+	if _, ok := t.suggestions[id]; !ok {
+		return
+	}
+	delete(t.suggestions, id)
+	t.order = slices.DeleteFunc(t.order, func(v string) bool { return v == id })
+}
+
+func (s *Suggestion) clone() Suggestion {
+	c := *s
+	c.Inserted = slices.Clone(s.Inserted)
+	c.Deleted = slices.Clone(s.Deleted)
+	return c
+}
+
+func transformRanges(ranges []Range, o *OperationSeq) []Range {
+	out := ranges[:0]
+	for _, r := range ranges {
+		if r = r.Transform(o); r.Start < r.End {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,91 @@
+package ot
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTrackChanges(t *testing.T) {
+	doc := "the cat sat"
+	tc := &TrackChanges{}
+
+	// Replace "cat" with "dog".
+	op := NewOperationSeq()
+	op.Retain(4)
+	op.Insert("dog")
+	op.Delete(3)
+	op.Retain(4)
+	applied, err := tc.Suggest("s1", "alice", op, doc)
+	if err != nil {
+		t.Fatalf("Suggest failed: %v", err)
+	}
+	if doc, err = applied.Apply(doc); err != nil || doc != "the dogcat sat" {
+		t.Fatalf("expected %q, got %q (%v)", "the dogcat sat", doc, err)
+	}
+	s, ok := tc.Suggestion("s1")
+	if !ok || s.Author != "alice" || len(s.Inserted) != 1 || s.Inserted[0] != (Range{4, 7}) ||
+		len(s.Deleted) != 1 || s.Deleted[0] != (Range{7, 10}) {
+		t.Fatalf("expected dog inserted at 4-7 and cat deleted at 7-10, got %+v", s)
+	}
+
+	// Delete "the " as a second suggestion, then an edit made directly.
+	op = NewOperationSeq()
+	op.Delete(4)
+	op.Retain(10)
+	if applied, err = tc.Suggest("s2", "bob", op, doc); err != nil {
+		t.Fatalf("Suggest failed: %v", err)
+	}
+	if doc, _ = applied.Apply(doc); doc != "the dogcat sat" {
+		t.Fatalf("expected the text kept, got %q", doc)
+	}
+	direct := NewOperationSeq()
+	direct.Insert(">")
+	direct.Retain(14)
+	doc, _ = direct.Apply(doc)
+	tc.Transform(direct)
+
+	accept, err := tc.AcceptSuggestion("s1", doc)
+	if err != nil {
+		t.Fatalf("AcceptSuggestion failed: %v", err)
+	}
+	if doc, _ = accept.Apply(doc); doc != ">the dog sat" {
+		t.Fatalf("expected %q, got %q", ">the dog sat", doc)
+	}
+	reject, err := tc.RejectSuggestion("s2", doc)
+	if err != nil {
+		t.Fatalf("RejectSuggestion failed: %v", err)
+	}
+	if doc, _ = reject.Apply(doc); doc != ">the dog sat" {
+		t.Errorf("expected rejecting a deletion to change nothing, got %q", doc)
+	}
+	if got := tc.Suggestions(); len(got) != 0 {
+		t.Errorf("expected no suggestions, got %+v", got)
+	}
+	if _, err := tc.AcceptSuggestion("s1", doc); !errors.Is(err, ErrSuggestionNotFound) {
+		t.Errorf("expected ErrSuggestionNotFound, got %v", err)
+	}
+}
+
+func TestTrackChangesReject(t *testing.T) {
+	doc := "ab"
+	tc := &TrackChanges{}
+	op := NewOperationSeq()
+	op.Retain(1)
+	op.Insert("XY")
+	op.Delete(1)
+	applied, err := tc.Suggest("s", "alice", op, doc)
+	if err != nil {
+		t.Fatalf("Suggest failed: %v", err)
+	}
+	doc, _ = applied.Apply(doc)
+	reject, err := tc.RejectSuggestion("s", doc)
+	if err != nil {
+		t.Fatalf("RejectSuggestion failed: %v", err)
+	}
+	if doc, _ = reject.Apply(doc); doc != "ab" {
+		t.Errorf("expected %q, got %q", "ab", doc)
+	}
+	if _, err := tc.Suggest("s", "alice", op, "abc"); !errors.Is(err, ErrIncompatibleLengths) {
+		t.Errorf("expected ErrIncompatibleLengths, got %v", err)
+	}
+}
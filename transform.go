@@ -1,5 +1,7 @@
 package ot
 
+import "fmt"
+
 // Transform takes two concurrent operations A and B that happened on the same document state
 // and produces two new operations A' and B' such that:
 //
@@ -7,174 +9,182 @@ package ot
 //
 // This is the heart of Operational Transformation.
 //
-// Returns an error if the operations have incompatible base lengths.
+// Returns a *TransformError matching ErrNotConcurrent if the operations have
+// different base lengths, or ErrMalformedOp, wrapping a *RemainderError, if
+// the components of either do not add up to its lengths. Both also match
+// ErrIncompatibleLengths.
 //
 // This is a direct port from Rust operational-transform:
 // https://github.com/spebern/operational-transform-rs/blob/master/operational-transform/src/lib.rs#L335-L471
 func (a *OperationSeq) Transform(b *OperationSeq) (*OperationSeq, *OperationSeq, error) {
-	if a.baseLen != b.baseLen {
-		return nil, nil, ErrIncompatibleLengths
+	aPrime := AcquireOperationSeq()
+	bPrime := AcquireOperationSeq()
+	if err := a.TransformInto(b, aPrime, bPrime); err != nil {
+		aPrime.Release()
+		bPrime.Release()
+		return nil, nil, err
 	}
+	return aPrime, bPrime, nil
+}
 
-	aPrime := NewOperationSeq()
-	bPrime := NewOperationSeq()
+// TransformResult holds both results of transforming two concurrent
+// operations A and B.
+type TransformResult struct {
+	APrime *OperationSeq // A, to apply after B
+	BPrime *OperationSeq // B, to apply after A
+}
 
-	ops1 := newOpIterator(a.ops)
-	ops2 := newOpIterator(b.ops)
+// TransformPair is Transform returning both results in one value, which is
+// harder to mix up than two of the same type.
+func (a *OperationSeq) TransformPair(b *OperationSeq) (TransformResult, error) {
+	aPrime, bPrime, err := a.Transform(b)
+	if err != nil {
+		return TransformResult{}, err
+	}
+	return TransformResult{APrime: aPrime, BPrime: bPrime}, nil
+}
 
-	op1 := ops1.next()
-	op2 := ops2.next()
+// TransformAgainst returns a transformed to apply after b, for callers that
+// only need A'. B' is not kept.
+func (a *OperationSeq) TransformAgainst(b *OperationSeq) (*OperationSeq, error) {
+	aPrime := AcquireOperationSeq()
+	bPrime := AcquireOperationSeq()
+	defer bPrime.Release()
+	if err := a.TransformInto(b, aPrime, bPrime); err != nil {
+		aPrime.Release()
+		return nil, err
+	}
+	return aPrime, nil
+}
+
+// TransformInto is Transform writing A' and B' into aPrime and bPrime instead
+// of new sequences. Both are cleared first and keep their capacity, so a loop
+// that reuses them transforms without allocating. They must be distinct from
+// each other and from a and b. On error their contents are unspecified.
+func (a *OperationSeq) TransformInto(b, aPrime, bPrime *OperationSeq) error {
+	if a.baseLen != b.baseLen {
+		return &TransformError{Reason: ErrNotConcurrent, Err: lengthMismatch(a.baseLen, b.baseLen)}
+	}
+
+	aPrime.reset()
+	bPrime.reset()
+	aPrime.grow(len(a.ops) + len(b.ops))
+	bPrime.grow(len(a.ops) + len(b.ops))
+
+	ops1 := NewOpReader(a)
+	ops2 := NewOpReader(b)
+	offset := 0 // characters of the base document consumed
 
 	for {
+		op1, op2 := ops1.Peek(), ops2.Peek()
+
 		// Both operations exhausted
-		if op1 == nil && op2 == nil {
-			return aPrime, bPrime, nil
+		if op1.Kind == 0 && op2.Kind == 0 {
+			aPrime.meta, bPrime.meta = a.meta, b.meta
+			if debug {
+				assert("Transform", checkTransform(a, b, aPrime, bPrime))
+			}
+			return nil
 		}
 
 		// Handle Insert vs Insert - use string comparison for tie-breaking
-		if ins1, ok1 := op1.(Insert); ok1 {
-			if ins2, ok2 := op2.(Insert); ok2 {
-				if ins1.Text < ins2.Text {
-					aPrime.Insert(ins1.Text)
-					bPrime.Retain(uint64(charCount(ins1.Text)))
-					op1 = ops1.next()
-				} else if ins1.Text == ins2.Text {
-					aPrime.Insert(ins1.Text)
-					aPrime.Retain(uint64(charCount(ins1.Text)))
-					bPrime.Insert(ins2.Text)
-					bPrime.Retain(uint64(charCount(ins2.Text)))
-					op1 = ops1.next()
-					op2 = ops2.next()
-				} else {
-					aPrime.Retain(uint64(charCount(ins2.Text)))
-					bPrime.Insert(ins2.Text)
-					op2 = ops2.next()
-				}
-				continue
+		if op1.Kind == KindInsert && op2.Kind == KindInsert {
+			if op1.Text < op2.Text {
+				aPrime.insert(op1.Text, op1.N)
+				bPrime.Retain(op1.N)
+				ops1.Next()
+			} else if op1.Text == op2.Text {
+				aPrime.insert(op1.Text, op1.N)
+				aPrime.Retain(op1.N)
+				bPrime.insert(op2.Text, op2.N)
+				bPrime.Retain(op2.N)
+				ops1.Next()
+				ops2.Next()
+			} else {
+				aPrime.Retain(op2.N)
+				bPrime.insert(op2.Text, op2.N)
+				ops2.Next()
 			}
+			continue
 		}
 
 		// Handle Insert from first operation
-		if ins, ok := op1.(Insert); ok {
-			aPrime.Insert(ins.Text)
-			bPrime.Retain(uint64(charCount(ins.Text)))
-			op1 = ops1.next()
+		if op1.Kind == KindInsert {
+			aPrime.insert(op1.Text, op1.N)
+			bPrime.Retain(op1.N)
+			ops1.Next()
 			continue
 		}
 
 		// Handle Insert from second operation
-		if ins, ok := op2.(Insert); ok {
-			aPrime.Retain(uint64(charCount(ins.Text)))
-			bPrime.Insert(ins.Text)
-			op2 = ops2.next()
+		if op2.Kind == KindInsert {
+			aPrime.Retain(op2.N)
+			bPrime.insert(op2.Text, op2.N)
+			ops2.Next()
 			continue
 		}
 
-		// One operation is nil but other isn't (after handling inserts)
-		if op1 == nil || op2 == nil {
-			return nil, nil, ErrIncompatibleLengths
-		}
-
-		// Handle Retain vs Retain
-		if ret1, ok1 := op1.(Retain); ok1 {
-			if ret2, ok2 := op2.(Retain); ok2 {
-				if ret1.N < ret2.N {
-					aPrime.Retain(ret1.N)
-					bPrime.Retain(ret1.N)
-					op2 = Retain{N: ret2.N - ret1.N}
-					op1 = ops1.next()
-				} else if ret1.N == ret2.N {
-					aPrime.Retain(ret1.N)
-					bPrime.Retain(ret1.N)
-					op1 = ops1.next()
-					op2 = ops2.next()
-				} else {
-					aPrime.Retain(ret2.N)
-					bPrime.Retain(ret2.N)
-					op1 = Retain{N: ret1.N - ret2.N}
-					op2 = ops2.next()
-				}
-				continue
-			}
-		}
-
-		// Handle Delete vs Delete
-		if del1, ok1 := op1.(Delete); ok1 {
-			if del2, ok2 := op2.(Delete); ok2 {
-				if del1.N < del2.N {
-					op2 = Delete{N: del2.N - del1.N}
-					op1 = ops1.next()
-				} else if del1.N == del2.N {
-					op1 = ops1.next()
-					op2 = ops2.next()
-				} else {
-					op1 = Delete{N: del1.N - del2.N}
-					op2 = ops2.next()
-				}
-				continue
+		// One operation is exhausted but other isn't (after handling inserts)
+		if op1.Kind == 0 || op2.Kind == 0 {
+			return &TransformError{
+				AIndex: ops1.index(),
+				BIndex: ops2.index(),
+				Offset: offset,
+				Reason: ErrMalformedOp,
+				Err:    &RemainderError{A: ops1.remaining(KindInsert), B: ops2.remaining(KindInsert)},
 			}
 		}
 
-		// Handle Delete vs Retain
-		if del, ok1 := op1.(Delete); ok1 {
-			if ret, ok2 := op2.(Retain); ok2 {
-				if del.N < ret.N {
-					aPrime.Delete(del.N)
-					op2 = Retain{N: ret.N - del.N}
-					op1 = ops1.next()
-				} else if del.N == ret.N {
-					aPrime.Delete(del.N)
-					op1 = ops1.next()
-					op2 = ops2.next()
-				} else {
-					aPrime.Delete(ret.N)
-					op1 = Delete{N: del.N - ret.N}
-					op2 = ops2.next()
-				}
-				continue
-			}
+		// Both are Retain or Delete: consume the shorter, and as much of the
+		// longer.
+		n := min(op1.N, op2.N)
+		ops1.Take(n)
+		ops2.Take(n)
+		offset += int(n)
+		switch {
+		case op1.Kind == KindRetain && op2.Kind == KindRetain:
+			aPrime.Retain(n)
+			bPrime.Retain(n)
+		case op1.Kind == KindDelete && op2.Kind == KindRetain:
+			aPrime.Delete(n)
+		case op1.Kind == KindRetain && op2.Kind == KindDelete:
+			bPrime.Delete(n)
 		}
-
-		// Handle Retain vs Delete
-		if ret, ok1 := op1.(Retain); ok1 {
-			if del, ok2 := op2.(Delete); ok2 {
-				if ret.N < del.N {
-					bPrime.Delete(ret.N)
-					op2 = Delete{N: del.N - ret.N}
-					op1 = ops1.next()
-				} else if ret.N == del.N {
-					bPrime.Delete(ret.N)
-					op1 = ops1.next()
-					op2 = ops2.next()
-				} else {
-					bPrime.Delete(del.N)
-					op1 = Retain{N: ret.N - del.N}
-					op2 = ops2.next()
-				}
-				continue
-			}
-		}
-
-		// Should never reach here if operations are valid
-		return nil, nil, ErrIncompatibleLengths
+		// Both deleted the same characters: nothing left to do.
 	}
 }
 
-// opIterator provides iteration over operations.
-type opIterator struct {
-	ops []Operation
-	idx int
-}
-
-func newOpIterator(ops []Operation) *opIterator {
-	return &opIterator{ops: ops, idx: 0}
+// TransformAll transforms a against ops, a sequence of consecutive
+// operations concurrent with it, and returns the result, which applies after
+// all of them. It is the same as calling Transform against each in turn and
+// keeping A', but the intermediate results alternate between two reused
+// sequences, so catching a client up on thousands of missed operations does
+// not allocate a pair per operation.
+func (a *OperationSeq) TransformAll(ops []*OperationSeq) (*OperationSeq, error) {
+	op, i, err := a.transformAll(ops)
+	if err != nil {
+		return nil, fmt.Errorf("transforming against operation %d: %w", i, err)
+	}
+	return op, nil
 }
 
-func (it *opIterator) next() Operation {
-	if it.idx >= len(it.ops) {
-		return nil
+// transformAll is TransformAll, also returning the index of the operation a
+// failed to transform against.
+func (a *OperationSeq) transformAll(ops []*OperationSeq) (*OperationSeq, int, error) {
+	cur := AcquireOperationSeq()
+	cur.ops = append(cur.ops, a.ops...)
+	cur.baseLen, cur.targetLen, cur.meta = a.baseLen, a.targetLen, a.meta
+	next := AcquireOperationSeq()
+	bPrime := AcquireOperationSeq()
+	defer bPrime.Release()
+	for i, b := range ops {
+		if err := cur.TransformInto(b, next, bPrime); err != nil {
+			cur.Release()
+			next.Release()
+			return nil, i, err
+		}
+		cur, next = next, cur
 	}
-	op := it.ops[it.idx]
-	it.idx++
-	return op
+	next.Release()
+	return cur, 0, nil
 }
@@ -2,6 +2,8 @@ package ot
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -700,3 +702,174 @@ func TestTransformRetainVsRetainEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// benchmarkEdits returns two concurrent edits of a 1000-character document,
+// each touching several places, like buffered typing.
+func benchmarkEdits() (*OperationSeq, *OperationSeq) {
+	a, b := NewOperationSeq(), NewOperationSeq()
+	for i := 0; i < 10; i++ {
+		a.Retain(60)
+		a.Insert("abc")
+		a.Delete(20)
+		a.Retain(20)
+		b.Retain(50)
+		b.Delete(5)
+		b.Insert("xyz")
+		b.Retain(45)
+	}
+	return a, b
+}
+
+func BenchmarkTransform(b *testing.B) {
+	x, y := benchmarkEdits()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := x.Transform(y); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestTransformInto(t *testing.T) {
+	a, b := benchmarkEdits()
+	wantA, wantB, err := a.Transform(b)
+	if err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+
+	aPrime, bPrime := NewOperationSeq(), NewOperationSeq()
+	aPrime.Insert("stale")
+	for i := 0; i < 3; i++ {
+		if err := a.TransformInto(b, aPrime, bPrime); err != nil {
+			t.Fatalf("TransformInto failed: %v", err)
+		}
+		if !reflect.DeepEqual(aPrime, wantA) || !reflect.DeepEqual(bPrime, wantB) {
+			t.Fatalf("expected %v and %v, got %v and %v", wantA.ops, wantB.ops, aPrime.ops, bPrime.ops)
+		}
+	}
+
+	other := NewOperationSeq()
+	other.Retain(1)
+	if err := a.TransformInto(other, aPrime, bPrime); !errors.Is(err, ErrIncompatibleLengths) {
+		t.Errorf("expected ErrIncompatibleLengths, got %v", err)
+	}
+}
+
+func BenchmarkTransformInto(b *testing.B) {
+	x, y := benchmarkEdits()
+	xPrime, yPrime := NewOperationSeq(), NewOperationSeq()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := x.TransformInto(y, xPrime, yPrime); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// catchUpEdits returns an edit to "hello" and k server edits concurrent with
+// it, each appending a character.
+func catchUpEdits(k int) (*OperationSeq, []*OperationSeq) {
+	op := NewOperationSeq()
+	op.Retain(2)
+	op.Insert("XY")
+	op.Retain(3)
+
+	server := make([]*OperationSeq, k)
+	for i := range server {
+		s := NewOperationSeq()
+		s.Retain(uint64(5 + i))
+		s.Insert("z")
+		server[i] = s
+	}
+	return op, server
+}
+
+func TestTransformAll(t *testing.T) {
+	op, server := catchUpEdits(50)
+	want := op
+	for _, s := range server {
+		var err error
+		if want, _, err = want.Transform(s); err != nil {
+			t.Fatalf("Transform failed: %v", err)
+		}
+	}
+	got, err := op.TransformAll(server)
+	if err != nil {
+		t.Fatalf("TransformAll failed: %v", err)
+	}
+	if !reflect.DeepEqual(got.ops, want.ops) || got.baseLen != want.baseLen || got.targetLen != want.targetLen {
+		t.Errorf("expected %v, got %v", want.ops, got.ops)
+	}
+
+	same, err := op.TransformAll(nil)
+	if err != nil {
+		t.Fatalf("TransformAll failed: %v", err)
+	}
+	if same == op || !reflect.DeepEqual(same.ops, op.ops) {
+		t.Errorf("expected a copy of %v, got %v", op.ops, same.ops)
+	}
+
+	if _, err := op.TransformAll([]*OperationSeq{server[1]}); !errors.Is(err, ErrIncompatibleLengths) {
+		t.Errorf("expected ErrIncompatibleLengths, got %v", err)
+	}
+}
+
+func BenchmarkCatchUp(b *testing.B) {
+	for _, k := range []int{1000, 5000} {
+		op, server := catchUpEdits(k)
+		b.Run(fmt.Sprintf("Transform/%d", k), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				cur := op
+				for _, s := range server {
+					var err error
+					if cur, _, err = cur.Transform(s); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("TransformAll/%d", k), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				cur, err := op.TransformAll(server)
+				if err != nil {
+					b.Fatal(err)
+				}
+				cur.Release()
+			}
+		})
+	}
+}
+
+func TestTransformPair(t *testing.T) {
+	a, b := benchmarkEdits()
+	wantA, wantB, err := a.Transform(b)
+	if err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+	got, err := a.TransformPair(b)
+	if err != nil {
+		t.Fatalf("TransformPair failed: %v", err)
+	}
+	if !reflect.DeepEqual(got.APrime, wantA) || !reflect.DeepEqual(got.BPrime, wantB) {
+		t.Errorf("expected %v and %v, got %v and %v", wantA.ops, wantB.ops, got.APrime.ops, got.BPrime.ops)
+	}
+
+	aPrime, err := a.TransformAgainst(b)
+	if err != nil {
+		t.Fatalf("TransformAgainst failed: %v", err)
+	}
+	if !reflect.DeepEqual(aPrime, wantA) {
+		t.Errorf("expected %v, got %v", wantA.ops, aPrime.ops)
+	}
+
+	other := NewOperationSeq()
+	other.Retain(1)
+	if _, err := a.TransformPair(other); !errors.Is(err, ErrIncompatibleLengths) {
+		t.Errorf("expected ErrIncompatibleLengths, got %v", err)
+	}
+	if _, err := a.TransformAgainst(other); !errors.Is(err, ErrIncompatibleLengths) {
+		t.Errorf("expected ErrIncompatibleLengths, got %v", err)
+	}
+}
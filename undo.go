@@ -0,0 +1,196 @@
+package ot
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNothingToUndo is returned by Undo when the client has no operation left
+// to undo.
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// maxUndo is how many operations a Server remembers per client for Undo.
+const maxUndo = 100
+
+// undoEntry is an operation a client can undo: the inverse of the operation
+// that produced revision, which applies to the document at revision.
+type undoEntry struct {
+	revision int
+	inverse  *OperationSeq
+}
+
+// pushUndo records that client's operation produced the current revision
+// from before. Callers hold s.mu.
+func (s *Server) pushUndo(client, before string) error {
+	op := s.history[len(s.history)-1]
+	inverse, err := op.InvertChecked(before)
+	if err != nil {
+		return err
+	}
+	if s.undo == nil {
+		s.undo = make(map[string][]undoEntry)
+	}
+	stack := s.undo[client]
+	if len(stack) == maxUndo {
+		stack = stack[1:]
+	}
+	s.undo[client] = append(stack, undoEntry{s.revision(), inverse})
+	return nil
+}
+
+// Undo reverts client's most recent operation that has not been undone yet,
+// leaving everyone else's edits in place. The inverse of the operation is
+// transformed past every operation accepted after it and applied like one
+// the client submitted at the current revision. It returns the applied
+// operation and the revision it produced.
+//
+// Only operations submitted with SubmitOp, or through a Hub, are recorded,
+// up to the client's most recent 100. Undoing does not record anything, so
+// calling Undo repeatedly walks further back. Returns ErrNothingToUndo when
+// there is nothing left, and ErrRevisionCompacted if the operation is older
+// than the history kept.
+func (s *Server) Undo(ctx context.Context, client string) (*OperationSeq, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stack := s.undo[client]
+	if len(stack) == 0 {
+		return nil, 0, ErrNothingToUndo
+	}
+	last := stack[len(stack)-1]
+	if last.revision < s.base {
+		delete(s.undo, client)
+		return nil, 0, ErrRevisionCompacted
+	}
+
+	op, err := last.inverse.TransformAll(s.history[last.revision-s.base:])
+	if err != nil {
+		return nil, 0, err
+	}
+	op, _, err = s.receive(ctx, client, s.revision(), op, false)
+	if err != nil {
+		return nil, 0, err
+	}
+	s.undo[client] = stack[:len(stack)-1]
+	return op, s.revision(), nil
+}
+
+// nextUndo returns the inverse Undo would start from for client, before it is
+// transformed, or nil if there is none.
+func (s *Server) nextUndo(client string) *OperationSeq {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stack := s.undo[client]; len(stack) > 0 {
+		return stack[len(stack)-1].inverse
+	}
+	return nil
+}
+
+// Revert reverts the operation that produced revision, whoever made it,
+// keeping every edit made since: its inverse is transformed past the later
+// operations and applied as an operation from client, such as a moderator
+// removing another contributor's change. It returns the applied operation
+// and the revision it produced.
+//
+// Returns ErrRevisionCompacted if the operation is older than the history
+// kept, and ErrInvalidRevision if revision was never produced.
+func (s *Server) Revert(ctx context.Context, client string, revision int) (*OperationSeq, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op, err := s.revertOp(revision)
+	if err != nil {
+		return nil, 0, err
+	}
+	if op, _, err = s.receive(ctx, client, s.revision(), op, false); err != nil {
+		return nil, 0, err
+	}
+	return op, s.revision(), nil
+}
+
+// reverting returns the operation Revert would apply, for authorization.
+func (s *Server) reverting(revision int) (*OperationSeq, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.revertOp(revision)
+}
+
+// revertOp returns the inverse of the operation that produced revision,
+// transformed to apply to the current document. Callers hold s.mu.
+func (s *Server) revertOp(revision int) (*OperationSeq, error) {
+	if err := s.checkRevision(revision - 1); err != nil {
+		return nil, err
+	}
+	if revision > s.revision() {
+		return nil, ErrInvalidRevision
+	}
+	before, err := s.documentAt(revision - 1)
+	if err != nil {
+		return nil, err
+	}
+	inverse, err := s.history[revision-1-s.base].InvertChecked(before)
+	if err != nil {
+		return nil, err
+	}
+	return inverse.TransformAll(s.history[revision-s.base:])
+}
+
+// CherryPick makes the operation that produced revision again, on behalf of
+// client: it is transformed past everything accepted since, itself
+// included, and applied at the current revision. It returns the applied
+// operation and the revision it produced.
+//
+// The changes since are taken as their net effect on the text, so text the
+// operation deleted and a later one put back unchanged, such as a Revert,
+// counts as the same text: picking a reverted operation deletes it again,
+// and inserts again what the operation inserted. Text still deleted is left
+// alone, and inserts are repeated even if they were never reverted.
+//
+// Returns ErrRevisionCompacted if the operation is older than the history
+// kept, and ErrInvalidRevision if revision was never produced.
+func (s *Server) CherryPick(ctx context.Context, client string, revision int) (*OperationSeq, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op, err := s.cherryPickOp(revision)
+	if err != nil {
+		return nil, 0, err
+	}
+	if op, _, err = s.receive(ctx, client, s.revision(), op, false); err != nil {
+		return nil, 0, err
+	}
+	return op, s.revision(), nil
+}
+
+// cherryPicking returns the operation CherryPick would apply, for
+// authorization.
+func (s *Server) cherryPicking(revision int) (*OperationSeq, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cherryPickOp(revision)
+}
+
+// cherryPickOp returns the operation that produced revision, transformed to
+// apply to the current document. Callers hold s.mu.
+func (s *Server) cherryPickOp(revision int) (*OperationSeq, error) {
+	if err := s.checkRevision(revision - 1); err != nil {
+		return nil, err
+	}
+	if revision > s.revision() {
+		return nil, ErrInvalidRevision
+	}
+	before, err := s.documentAt(revision - 1)
+	if err != nil {
+		return nil, err
+	}
+	// Minimizing turns text deleted and inserted again into retains, which
+	// the operation's deletions then apply to as they did the first time.
+	since, err := s.squash(revision-1, s.revision())
+	if err != nil {
+		return nil, err
+	}
+	if since, err = since.Minimize(before); err != nil {
+		return nil, err
+	}
+	return s.history[revision-1-s.base].TransformAgainst(since)
+}
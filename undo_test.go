@@ -0,0 +1,254 @@
+package ot
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestServerUndo(t *testing.T) {
+	s := NewServer("hello")
+	ctx := context.Background()
+
+	// alice appends, bob prepends concurrently, then alice deletes "h".
+	a := NewOperationSeq()
+	a.Retain(5)
+	a.Insert(" world")
+	b := NewOperationSeq()
+	b.Insert(">> ")
+	b.Retain(5)
+	del := NewOperationSeq()
+	del.Delete(1)
+	del.Retain(10)
+	for _, step := range []struct {
+		client string
+		rev    int
+		op     *OperationSeq
+	}{{"alice", 0, a}, {"bob", 0, b}, {"alice", 1, del}} {
+		if _, _, err := s.SubmitOp(ctx, step.client, "", step.rev, step.op); err != nil {
+			t.Fatalf("SubmitOp failed: %v", err)
+		}
+	}
+	if s.Document() != ">> ello world" {
+		t.Fatalf("expected %q, got %q", ">> ello world", s.Document())
+	}
+
+	want := []string{">> hello world", ">> hello"}
+	for i, doc := range want {
+		if _, rev, err := s.Undo(ctx, "alice"); err != nil || rev != 4+i {
+			t.Fatalf("Undo %d: expected revision %d, got %d (%v)", i, 4+i, rev, err)
+		}
+		if s.Document() != doc {
+			t.Errorf("Undo %d: expected %q, got %q", i, doc, s.Document())
+		}
+	}
+	if _, _, err := s.Undo(ctx, "alice"); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("expected ErrNothingToUndo, got %v", err)
+	}
+
+	if _, _, err := s.Undo(ctx, "bob"); err != nil {
+		t.Fatalf("Undo bob failed: %v", err)
+	}
+	if s.Document() != "hello" {
+		t.Errorf("expected %q, got %q", "hello", s.Document())
+	}
+}
+
+func TestServerUndoCompacted(t *testing.T) {
+	s := NewServer("")
+	op := NewOperationSeq()
+	op.Insert("a")
+	if _, _, err := s.SubmitOp(context.Background(), "alice", "", 0, op); err != nil {
+		t.Fatalf("SubmitOp failed: %v", err)
+	}
+	other := NewOperationSeq()
+	other.Retain(1)
+	other.Insert("b")
+	if _, _, err := s.SubmitOp(context.Background(), "bob", "", 1, other); err != nil {
+		t.Fatalf("SubmitOp failed: %v", err)
+	}
+	if err := s.Compact(context.Background(), 2); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if _, _, err := s.Undo(context.Background(), "alice"); !errors.Is(err, ErrRevisionCompacted) {
+		t.Errorf("expected ErrRevisionCompacted, got %v", err)
+	}
+}
+
+func TestHubUndo(t *testing.T) {
+	h := NewHub(func(context.Context, string) (*Server, error) { return NewServer(""), nil })
+	ctx := context.Background()
+
+	sub, err := h.Subscribe(ctx, "doc", "alice")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Close()
+	<-sub.C // own join
+
+	if _, _, err := h.Undo(ctx, "doc", "alice"); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("expected ErrNothingToUndo, got %v", err)
+	}
+
+	op := NewOperationSeq()
+	op.Insert("a")
+	if _, _, err := h.Submit(ctx, "doc", "alice", 0, op); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	<-sub.C // ack
+
+	if _, _, err := h.Undo(ctx, "doc", "alice"); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	ev := <-sub.C
+	if ev.Kind != EventOp || !ev.Undo || ev.Client != "alice" || ev.Revision != 2 || ev.Op.String() != `[-1]` {
+		t.Errorf("expected undo op at revision 2, got %+v (%v)", ev, ev.Op)
+	}
+}
+
+func TestServerRevert(t *testing.T) {
+	s := NewServer("hello")
+	ctx := context.Background()
+	appendText(t, s, " world") // revision 1, to be reverted
+	op := NewOperationSeq()
+	op.Insert(">> ")
+	op.Retain(11)
+	if _, err := s.ReceiveOperation(ctx, 1, op); err != nil {
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+
+	if _, rev, err := s.Revert(ctx, "moderator", 1); err != nil || rev != 3 {
+		t.Fatalf("expected revision 3, got %d (%v)", rev, err)
+	}
+	if s.Document() != ">> hello" {
+		t.Errorf("expected %q, got %q", ">> hello", s.Document())
+	}
+
+	for _, rev := range []int{0, 4} {
+		if _, _, err := s.Revert(ctx, "moderator", rev); !errors.Is(err, ErrInvalidRevision) {
+			t.Errorf("revision %d: expected ErrInvalidRevision, got %v", rev, err)
+		}
+	}
+	if err := s.Compact(ctx, 2); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if _, _, err := s.Revert(ctx, "moderator", 2); !errors.Is(err, ErrRevisionCompacted) {
+		t.Errorf("expected ErrRevisionCompacted, got %v", err)
+	}
+}
+
+func TestHubRevert(t *testing.T) {
+	ctx := context.Background()
+	h := &Hub{}
+	sub, err := h.Subscribe(ctx, "doc", "alice")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	<-sub.C
+
+	op := NewOperationSeq()
+	op.Insert("spam")
+	if _, _, err := h.Submit(ctx, "doc", "bob", 0, op); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	<-sub.C
+
+	if _, rev, err := h.Revert(ctx, "doc", "alice", 1); err != nil || rev != 2 {
+		t.Fatalf("expected revision 2, got %d (%v)", rev, err)
+	}
+	if ev := <-sub.C; ev.Kind != EventOp || ev.Client != "" || ev.Revision != 2 {
+		t.Errorf("expected the revert as an op without a client, got %+v", ev)
+	}
+}
+
+func TestServerCherryPick(t *testing.T) {
+	s := NewServer("hello")
+	ctx := context.Background()
+	appendText(t, s, " world") // revision 1, to be picked again
+	if _, _, err := s.Revert(ctx, "moderator", 1); err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	op := NewOperationSeq()
+	op.Insert(">> ")
+	op.Retain(5)
+	if _, err := s.ReceiveOperation(ctx, 2, op); err != nil {
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+
+	if _, rev, err := s.CherryPick(ctx, "alice", 1); err != nil || rev != 4 {
+		t.Fatalf("expected revision 4, got %d (%v)", rev, err)
+	}
+	if s.Document() != ">> hello world" {
+		t.Errorf("expected %q, got %q", ">> hello world", s.Document())
+	}
+	if _, _, err := s.CherryPick(ctx, "alice", 5); !errors.Is(err, ErrInvalidRevision) {
+		t.Errorf("expected ErrInvalidRevision, got %v", err)
+	}
+}
+
+func TestServerCherryPickDeletion(t *testing.T) {
+	s := NewServer("hello world")
+	ctx := context.Background()
+	del := NewOperationSeq()
+	del.Retain(5)
+	del.Delete(6)
+	if _, err := s.ReceiveOperation(ctx, 0, del); err != nil { // revision 1
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+	if _, _, err := s.Revert(ctx, "moderator", 1); err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	op := NewOperationSeq()
+	op.Insert(">> ")
+	op.Retain(11)
+	op.Insert("!")
+	if _, err := s.ReceiveOperation(ctx, 2, op); err != nil {
+		t.Fatalf("ReceiveOperation failed: %v", err)
+	}
+
+	if _, _, err := s.CherryPick(ctx, "alice", 1); err != nil {
+		t.Fatalf("CherryPick failed: %v", err)
+	}
+	if s.Document() != ">> hello!" {
+		t.Errorf("expected %q, got %q", ">> hello!", s.Document())
+	}
+
+	// The text is gone again, so picking once more changes nothing.
+	if _, _, err := s.CherryPick(ctx, "alice", 1); err != nil {
+		t.Fatalf("CherryPick failed: %v", err)
+	}
+	if s.Document() != ">> hello!" {
+		t.Errorf("expected %q, got %q", ">> hello!", s.Document())
+	}
+}
+
+func TestHubCherryPick(t *testing.T) {
+	ctx := context.Background()
+	h := &Hub{}
+	sub, err := h.Subscribe(ctx, "doc", "alice")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	<-sub.C
+
+	op := NewOperationSeq()
+	op.Insert("ha")
+	if _, _, err := h.Submit(ctx, "doc", "bob", 0, op); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	<-sub.C
+
+	if _, rev, err := h.CherryPick(ctx, "doc", "alice", 1); err != nil || rev != 2 {
+		t.Fatalf("expected revision 2, got %d (%v)", rev, err)
+	}
+	if ev := <-sub.C; ev.Kind != EventOp || ev.Client != "" || ev.Revision != 2 {
+		t.Errorf("expected the pick as an op without a client, got %+v", ev)
+	}
+	s, err := h.Server(ctx, "doc")
+	if err != nil {
+		t.Fatalf("Server failed: %v", err)
+	}
+	if doc := s.Document(); doc != "haha" {
+		t.Errorf("expected %q, got %q", "haha", doc)
+	}
+}
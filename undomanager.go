@@ -0,0 +1,242 @@
+package ot
+
+import (
+	"errors"
+	"slices"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrNothingToRedo is returned by UndoManager.Redo when there is nothing to
+// redo.
+var ErrNothingToRedo = errors.New("nothing to redo")
+
+// UndoManager keeps a client's undo and redo stacks for local edits. Each
+// entry is the inverse of an edit, kept applicable to the current document
+// by transforming the stacks through every remote operation the client
+// applies, so undoing reverts only the client's own edit and keeps everyone
+// else's.
+//
+// Edits are undone one at a time unless grouped: between BeginGroup and
+// EndGroup, or as typing when MergeWithin is set, edits are added to the
+// same undo step, so one Undo reverts them all.
+//
+// The zero value is ready to use. An UndoManager is not safe for concurrent
+// use; it belongs with the client's document.
+type UndoManager struct {
+	// Limit bounds the number of steps that can be undone, dropping the
+	// oldest. Zero means no limit.
+	Limit int
+
+	// MergeWithin, if positive, groups typing: an edit that inserts or
+	// deletes a single character joins the previous step if that was typing
+	// too, added less than MergeWithin before.
+	MergeWithin time.Duration
+
+	// BreakOnWords, together with MergeWithin, starts a new step when a
+	// character is typed after whitespace, so typing a sentence is undone a
+	// word at a time.
+	BreakOnWords bool
+
+	undo []*OperationSeq // inverses, the last applying to the document
+	redo []*OperationSeq
+
+	depth   int       // of BeginGroup calls not yet ended
+	open    bool      // whether the top of undo is the group's step
+	typed   rune      // last character typed into the top step; 0 if not typing
+	typedAt time.Time // when it was added
+	now     func() time.Time
+}
+
+// Add records a local edit op, made to doc, so it can be undone. It clears
+// the redo stack, as a new edit starts a new branch of history. Returns a
+// *LengthMismatchError, recording nothing, if op does not apply to doc.
+func (m *UndoManager) Add(op *OperationSeq, doc string) error {
+	inverse, err := op.InvertChecked(doc)
+	if err != nil {
+		return err
+	}
+	m.redo = nil
+
+	now := m.clock()
+	r, typing := typedRune(op)
+	merge := m.open
+	if m.depth == 0 {
+		merge = typing && m.typing(r, now)
+	}
+	if merge && len(m.undo) > 0 {
+		// The edit's inverse undoes it first, then the rest of the step.
+		if step, err := inverse.Compose(m.undo[len(m.undo)-1]); err == nil {
+			m.undo[len(m.undo)-1] = step
+			m.mark(r, typing, now)
+			return nil
+		}
+	}
+	m.push(inverse)
+	m.open = m.depth > 0
+	m.mark(r, typing, now)
+	return nil
+}
+
+// BeginGroup starts a group of edits that are undone as one step, until the
+// matching EndGroup. Groups may nest; only the outermost counts.
+func (m *UndoManager) BeginGroup() {
+	m.depth++
+	if m.depth == 1 {
+		m.open = false
+		m.typed = 0
+	}
+}
+
+// EndGroup ends the group started by the matching BeginGroup. Calls without
+// a matching BeginGroup are ignored.
+func (m *UndoManager) EndGroup() {
+	if m.depth == 0 {
+		return
+	}
+	m.depth--
+	if m.depth == 0 {
+		m.open = false
+		m.typed = 0
+	}
+}
+
+// typing reports whether typing r at now continues the top step.
+func (m *UndoManager) typing(r rune, now time.Time) bool {
+	if m.MergeWithin <= 0 || m.typed == 0 || now.Sub(m.typedAt) >= m.MergeWithin {
+		return false
+	}
+	return !m.BreakOnWords || !unicode.IsSpace(m.typed) || unicode.IsSpace(r)
+}
+
+// mark records what was last added to the top step.
+func (m *UndoManager) mark(r rune, typing bool, now time.Time) {
+	m.typed = 0
+	if typing {
+		m.typed, m.typedAt = r, now
+	}
+}
+
+func (m *UndoManager) clock() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+	return time.Now()
+}
+
+// typedRune reports whether op inserts or deletes a single character and
+// nothing else, as typing and backspacing do, and returns the character
+// inserted, or utf8.RuneError for a deletion.
+func typedRune(op *OperationSeq) (rune, bool) {
+	var edit Component
+	for _, c := range op.ops {
+		if c.Kind == KindRetain {
+			continue
+		}
+		if edit.Kind != 0 || c.N != 1 {
+			return 0, false
+		}
+		edit = c
+	}
+	switch edit.Kind {
+	case KindInsert:
+		r, _ := utf8.DecodeRuneInString(edit.Text)
+		return r, true
+	case KindDelete:
+		return utf8.RuneError, true
+	}
+	return 0, false
+}
+
+// Transform moves both stacks through remote, an operation from another
+// client applied to the current document. Entries that no longer change
+// anything are dropped. On error, such as remote not applying to the
+// document, the stacks are left as they were.
+func (m *UndoManager) Transform(remote *OperationSeq) error {
+	undo, err := transformStack(m.undo, remote)
+	if err != nil {
+		return err
+	}
+	redo, err := transformStack(m.redo, remote)
+	if err != nil {
+		return err
+	}
+	if len(undo) != len(m.undo) {
+		// The step being added to may be gone.
+		m.open, m.typed = false, 0
+	}
+	m.undo, m.redo = undo, redo
+	return nil
+}
+
+// Undo returns the operation that reverts the most recent edit not yet
+// undone, to apply to doc, the current document, and send like any local
+// edit. It is not recorded with Add; Redo reapplies it. Returns
+// ErrNothingToUndo if there is nothing to undo, and a *LengthMismatchError,
+// leaving the stacks as they were, if the edit does not apply to doc.
+func (m *UndoManager) Undo(doc string) (*OperationSeq, error) {
+	if len(m.undo) == 0 {
+		return nil, ErrNothingToUndo
+	}
+	op := m.undo[len(m.undo)-1]
+	inverse, err := op.InvertChecked(doc)
+	if err != nil {
+		return nil, err
+	}
+	m.undo = m.undo[:len(m.undo)-1]
+	m.redo = append(m.redo, inverse)
+	m.open, m.typed = false, 0
+	return op, nil
+}
+
+// Redo returns the operation that reapplies the most recently undone edit,
+// to apply to doc and send like Undo's. Returns ErrNothingToRedo if there is
+// nothing to redo, and a *LengthMismatchError like Undo.
+func (m *UndoManager) Redo(doc string) (*OperationSeq, error) {
+	if len(m.redo) == 0 {
+		return nil, ErrNothingToRedo
+	}
+	op := m.redo[len(m.redo)-1]
+	inverse, err := op.InvertChecked(doc)
+	if err != nil {
+		return nil, err
+	}
+	m.redo = m.redo[:len(m.redo)-1]
+	m.push(inverse)
+	m.open, m.typed = false, 0
+	return op, nil
+}
+
+// CanUndo reports whether Undo has an edit to revert.
+func (m *UndoManager) CanUndo() bool {
+	return len(m.undo) > 0
+}
+
+// CanRedo reports whether Redo has an edit to reapply.
+func (m *UndoManager) CanRedo() bool {
+	return len(m.redo) > 0
+}
+
+func (m *UndoManager) push(inverse *OperationSeq) {
+	if m.Limit > 0 && len(m.undo) >= m.Limit {
+		m.undo = append(m.undo[:0], m.undo[len(m.undo)-m.Limit+1:]...)
+	}
+	m.undo = append(m.undo, inverse)
+}
+
+// transformStack returns the entries of stack transformed through op, which
+// applies to the document the last entry applies to. Each entry applies to
+// the document left by undoing the ones after it, so op is carried down the
+// stack, transformed past each entry in turn.
+func transformStack(stack []*OperationSeq, op *OperationSeq) ([]*OperationSeq, error) {
+	out := make([]*OperationSeq, len(stack))
+	for i := len(stack) - 1; i >= 0; i-- {
+		entry, next, err := stack[i].Transform(op)
+		if err != nil {
+			return nil, err
+		}
+		out[i], op = entry, next
+	}
+	return slices.DeleteFunc(out, (*OperationSeq).IsNoop), nil
+}
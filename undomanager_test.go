@@ -0,0 +1,206 @@
+package ot
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// edit applies op to *doc, failing the test if it does not apply.
+func edit(t *testing.T, doc *string, op *OperationSeq) {
+	t.Helper()
+	next, err := op.Apply(*doc)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	*doc = next
+}
+
+func TestUndoManager(t *testing.T) {
+	var m UndoManager
+	doc := "hello"
+
+	local := NewOperationSeq()
+	local.Retain(5)
+	local.Insert(" world")
+	if err := m.Add(local, doc); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	edit(t, &doc, local)
+
+	// Someone else edits the start of the document.
+	remote := NewOperationSeq()
+	remote.Insert(">> ")
+	remote.Retain(11)
+	if err := m.Transform(remote); err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+	edit(t, &doc, remote)
+
+	undo, err := m.Undo(doc)
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	edit(t, &doc, undo)
+	if doc != ">> hello" {
+		t.Errorf("expected %q after undo, got %q", ">> hello", doc)
+	}
+	if _, err := m.Undo(doc); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("expected ErrNothingToUndo, got %v", err)
+	}
+	if _, err := m.Redo("too short"); !errors.Is(err, ErrIncompatibleLengths) || !m.CanRedo() {
+		t.Errorf("expected ErrIncompatibleLengths with the redo kept, got %v", err)
+	}
+
+	redo, err := m.Redo(doc)
+	if err != nil {
+		t.Fatalf("Redo failed: %v", err)
+	}
+	edit(t, &doc, redo)
+	if doc != ">> hello world" {
+		t.Errorf("expected %q after redo, got %q", ">> hello world", doc)
+	}
+	if _, err := m.Redo(doc); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("expected ErrNothingToRedo, got %v", err)
+	}
+	if !m.CanUndo() || m.CanRedo() {
+		t.Error("expected the redone edit to be undoable again")
+	}
+}
+
+func TestUndoManagerStack(t *testing.T) {
+	m := UndoManager{Limit: 2}
+	doc := ""
+	for _, text := range []string{"a", "b", "c"} {
+		op := NewOperationSeq()
+		op.Retain(uint64(len(doc)))
+		op.Insert(text)
+		if err := m.Add(op, doc); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		edit(t, &doc, op)
+	}
+
+	// A remote edit between the local ones is kept when undoing past it.
+	remote := NewOperationSeq()
+	remote.Retain(1)
+	remote.Insert("X")
+	remote.Retain(2)
+	if err := m.Transform(remote); err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+	edit(t, &doc, remote)
+
+	for _, want := range []string{"aXb", "aX"} {
+		op, err := m.Undo(doc)
+		if err != nil {
+			t.Fatalf("Undo failed: %v", err)
+		}
+		edit(t, &doc, op)
+		if doc != want {
+			t.Errorf("expected %q, got %q", want, doc)
+		}
+	}
+	if m.CanUndo() {
+		t.Error("expected the oldest edit to be dropped by Limit")
+	}
+
+	// A new edit clears the redo stack.
+	op := NewOperationSeq()
+	op.Insert("!")
+	op.Retain(2)
+	if err := m.Add(op, doc); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if m.CanRedo() {
+		t.Error("expected a new edit to clear the redo stack")
+	}
+
+	bad := NewOperationSeq()
+	bad.Retain(100)
+	if err := m.Add(bad, doc); !errors.Is(err, ErrIncompatibleLengths) {
+		t.Errorf("expected ErrIncompatibleLengths, got %v", err)
+	}
+	if err := m.Transform(bad); !errors.Is(err, ErrIncompatibleLengths) {
+		t.Errorf("expected ErrIncompatibleLengths, got %v", err)
+	}
+	if !m.CanUndo() {
+		t.Error("expected a failed Transform to leave the stacks alone")
+	}
+}
+
+// typeText types text at the end of *doc one character at a time, adding
+// each edit to m.
+func typeText(t *testing.T, m *UndoManager, doc *string, text string) {
+	t.Helper()
+	for _, r := range text {
+		op := NewOperationSeq()
+		op.Retain(uint64(charCount(*doc)))
+		op.Insert(string(r))
+		if err := m.Add(op, *doc); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		edit(t, doc, op)
+	}
+}
+
+// undoAll undoes every step, returning the document after each.
+func undoAll(t *testing.T, m *UndoManager, doc string) []string {
+	t.Helper()
+	var docs []string
+	for m.CanUndo() {
+		op, err := m.Undo(doc)
+		if err != nil {
+			t.Fatalf("Undo failed: %v", err)
+		}
+		edit(t, &doc, op)
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func TestUndoManagerMergeTyping(t *testing.T) {
+	now := time.Unix(0, 0)
+	m := UndoManager{MergeWithin: time.Second, now: func() time.Time { return now }}
+	doc := ""
+	typeText(t, &m, &doc, "hello world")
+	now = now.Add(2 * time.Second)
+	typeText(t, &m, &doc, "!")
+
+	if got, want := undoAll(t, &m, doc), []string{"hello world", ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	m.BreakOnWords = true
+	doc = ""
+	typeText(t, &m, &doc, "hi there you")
+	if got, want := undoAll(t, &m, doc), []string{"hi there ", "hi ", ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestUndoManagerGroup(t *testing.T) {
+	var m UndoManager
+	doc := ""
+	typeText(t, &m, &doc, "a")
+	m.BeginGroup()
+	typeText(t, &m, &doc, "b")
+	m.BeginGroup()
+	typeText(t, &m, &doc, "cd")
+	m.EndGroup()
+	op := NewOperationSeq()
+	op.Delete(1)
+	op.Retain(3)
+	if err := m.Add(op, doc); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	edit(t, &doc, op)
+	m.EndGroup()
+	m.EndGroup() // unmatched, ignored
+	typeText(t, &m, &doc, "e")
+
+	if got, want := undoAll(t, &m, doc), []string{"bcd", "a", ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,142 @@
+package ot
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Watch subscribes client to doc as a viewer: the subscription is read-only,
+// so the client cannot submit operations while it lasts, and it receives
+// operations in batches if the hub has a ViewerBatch. It is otherwise like
+// Subscribe.
+func (h *Hub) Watch(ctx context.Context, doc, client string) (*Subscription, error) {
+	return h.subscribe(ctx, doc, client, nil, true)
+}
+
+// viewerBatch holds what read-only subscriptions have yet to receive while
+// Hub.ViewerBatch coalesces their events.
+type viewerBatch struct {
+	every      time.Duration
+	op         *OperationSeq // composed operations; nil if none are held
+	revision   int           // produced by op
+	timer      *time.Timer
+	selections map[string]bool // clients whose selection changed meanwhile
+	meta       map[string]bool // clients whose metadata changed meanwhile
+}
+
+// fanout queues ev for every subscriber accepted by filter (all if nil),
+// holding back operations for viewers when batching. Events that cannot be
+// coalesced deliver the batch first, so every subscriber still sees events
+// in order. Callers hold d.mu.
+func (d *hubDoc) fanout(ev Event, filter func(*Subscription) bool) {
+	b := &d.batch
+	if b.every <= 0 {
+		d.deliver(ev, filter)
+		return
+	}
+
+	switch {
+	case ev.Kind == EventOp && filter == nil && d.hasViewers():
+		d.deliver(ev, editors)
+		d.hold(ev)
+	case b.op != nil && ev.Kind == EventSelection:
+		d.deliver(ev, both(filter, editors))
+		b.selections[ev.Client] = true
+	case b.op != nil && ev.Kind == EventMeta:
+		d.deliver(ev, both(filter, editors))
+		b.meta[ev.Client] = true
+	default:
+		d.flush()
+		d.deliver(ev, filter)
+	}
+}
+
+// hold adds an operation to the viewers' batch, starting the interval if it
+// is the first. Callers hold d.mu.
+func (d *hubDoc) hold(ev Event) {
+	b := &d.batch
+	if b.op == nil {
+		b.op = ev.Op
+		b.revision = ev.Revision
+		b.selections = make(map[string]bool)
+		b.meta = make(map[string]bool)
+		b.timer = time.AfterFunc(b.every, func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.flush()
+		})
+		return
+	}
+	composed, err := b.op.Compose(ev.Op)
+	if err != nil {
+		// Only possible if an operation was fanned out out of order.
+		d.log.Error("batching operations failed", "revision", ev.Revision, "err", err)
+		d.flush()
+		d.deliver(ev, viewers)
+		return
+	}
+	b.op = composed
+	b.revision = ev.Revision
+}
+
+// flush delivers the viewers' batch, if any, followed by the selections and
+// metadata that changed while it was held. Callers hold d.mu.
+func (d *hubDoc) flush() {
+	b := &d.batch
+	if b.op == nil {
+		return
+	}
+	b.timer.Stop()
+	op, revision, selections, meta := b.op, b.revision, b.selections, b.meta
+	b.op, b.selections, b.meta = nil, nil, nil
+
+	d.deliver(Event{Kind: EventOp, Revision: revision, Op: op}, viewers)
+	for _, client := range sortedKeys(selections) {
+		sel, ok := d.presence.Get(client)
+		if !ok {
+			continue
+		}
+		d.deliver(Event{Kind: EventSelection, Client: client, Revision: revision, Selection: sel, Meta: d.presence.Meta(client)}, others(client))
+		delete(meta, client)
+	}
+	for _, client := range sortedKeys(meta) {
+		d.deliver(Event{Kind: EventMeta, Client: client, Revision: revision, Meta: d.presence.Meta(client)}, others(client))
+	}
+}
+
+// hasViewers reports whether any subscription is read-only. Callers hold
+// d.mu.
+func (d *hubDoc) hasViewers() bool {
+	for s := range d.subs {
+		if s.ReadOnly {
+			return true
+		}
+	}
+	return false
+}
+
+func viewers(s *Subscription) bool { return s.ReadOnly }
+func editors(s *Subscription) bool { return !s.ReadOnly }
+
+// others accepts the viewers that do not belong to client.
+func others(client string) func(*Subscription) bool {
+	return func(s *Subscription) bool { return s.ReadOnly && s.Client != client }
+}
+
+// both accepts the subscriptions accepted by f, if set, and g.
+func both(f, g func(*Subscription) bool) func(*Subscription) bool {
+	if f == nil {
+		return g
+	}
+	return func(s *Subscription) bool { return f(s) && g(s) }
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,97 @@
+package ot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHubViewerBatch(t *testing.T) {
+	ctx := context.Background()
+	h := &Hub{ViewerBatch: time.Hour}
+
+	editor, err := h.Subscribe(ctx, "notes", "ed")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	<-editor.C
+	viewer, err := h.Watch(ctx, "notes", "viewer")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	if !viewer.ReadOnly {
+		t.Error("expected a read-only subscription")
+	}
+	<-viewer.C
+	<-editor.C
+
+	for i, text := range []string{"a", "b", "c"} {
+		op := NewOperationSeq()
+		op.Retain(uint64(i))
+		op.Insert(text)
+		if _, _, err := h.Submit(ctx, "notes", "ed", i, op); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+		if ev := <-editor.C; ev.Kind != EventOp || ev.Revision != i+1 {
+			t.Errorf("expected the editor to receive revision %d at once, got %+v", i+1, ev)
+		}
+	}
+	if err := h.SetSelection(ctx, "notes", "ed", 3, Selection{Anchor: 1, Head: 1}); err != nil {
+		t.Fatalf("SetSelection failed: %v", err)
+	}
+	select {
+	case ev := <-viewer.C:
+		t.Fatalf("expected the viewer's events to be held, got %+v", ev)
+	default:
+	}
+
+	// An event that cannot be coalesced delivers the batch first.
+	if err := h.SetMode(ctx, "notes", ModeEditable); err != nil {
+		t.Fatalf("SetMode failed: %v", err)
+	}
+	ev := <-viewer.C
+	if ev.Kind != EventOp || ev.Revision != 3 || ev.Client != "" {
+		t.Fatalf("expected a batched op at revision 3, got %+v", ev)
+	}
+	if doc, err := ev.Op.Apply(""); err != nil || doc != "abc" {
+		t.Errorf("expected the batch to produce %q, got %q (%v)", "abc", doc, err)
+	}
+	if ev := <-viewer.C; ev.Kind != EventSelection || ev.Client != "ed" || ev.Revision != 3 || ev.Selection.Head != 1 {
+		t.Errorf("expected ed's selection at revision 3, got %+v", ev)
+	}
+	if ev := <-viewer.C; ev.Kind != EventMode {
+		t.Errorf("expected the mode event after the batch, got %+v", ev)
+	}
+
+	op := NewOperationSeq()
+	op.Insert("x")
+	op.Retain(3)
+	if _, _, err := h.Submit(ctx, "notes", "viewer", 3, op); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected ErrForbidden for a viewer, got %v", err)
+	}
+}
+
+func TestHubViewerBatchInterval(t *testing.T) {
+	ctx := context.Background()
+	h := &Hub{ViewerBatch: 10 * time.Millisecond}
+	viewer, err := h.Watch(ctx, "notes", "viewer")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	<-viewer.C
+
+	op := NewOperationSeq()
+	op.Insert("hi")
+	if _, _, err := h.Submit(ctx, "notes", "ed", 0, op); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	select {
+	case ev := <-viewer.C:
+		if ev.Kind != EventOp || ev.Revision != 1 {
+			t.Errorf("expected op at revision 1, got %+v", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the batch to be delivered after the interval")
+	}
+}